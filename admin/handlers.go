@@ -0,0 +1,357 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
+	"gorm.io/gorm"
+)
+
+const pageSize = 50
+
+// view is the data every template receives. Data holds the page-specific values.
+type view struct {
+	Title    string
+	Username string
+	CSRF     string
+	Notice   string
+	Error    string
+	Data     interface{}
+}
+
+// pagination describes the page of a listing being shown.
+type pagination struct {
+	Page    int
+	Pages   int
+	Total   int64
+	PrevURL string
+	NextURL string
+}
+
+func newPagination(r *http.Request, total int64) pagination {
+	p := pagination{Page: 1, Total: total}
+	if n, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && n > 1 {
+		p.Page = n
+	}
+	p.Pages = int((total + pageSize - 1) / pageSize)
+	if p.Pages == 0 {
+		p.Pages = 1
+	}
+
+	link := func(page int) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(page))
+		return r.URL.Path + "?" + q.Encode()
+	}
+	if p.Page > 1 {
+		p.PrevURL = link(p.Page - 1)
+	}
+	if p.Page < p.Pages {
+		p.NextURL = link(p.Page + 1)
+	}
+	return p
+}
+
+func pageOffset(r *http.Request) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && n > 1 {
+		return (n - 1) * pageSize
+	}
+	return 0
+}
+
+// render executes the named page into a buffer first, so a template error yields a clean 500
+// rather than half a page.
+func (s *Server) render(w http.ResponseWriter, r *http.Request, status int, name string, v view) {
+	if v.Notice == "" {
+		v.Notice = r.URL.Query().Get("notice")
+	}
+
+	var buf bytes.Buffer
+	if err := s.pages[name].ExecuteTemplate(&buf, "layout.html", v); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to render admin page %s", name)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := buf.WriteTo(w); err != nil {
+		logger.Log.WithError(err).Debug("Failed to write admin page")
+	}
+}
+
+func (s *Server) renderError(w http.ResponseWriter, r *http.Request, sess session, err error) {
+	logger.Log.WithError(err).Errorf("Admin panel request %s %s failed", r.Method, r.URL.Path)
+	s.render(w, r, http.StatusInternalServerError, "dashboard", view{
+		Title:    "Error",
+		Username: sess.username,
+		CSRF:     sess.csrf,
+		Error:    "Something went wrong loading this page. Check the bot logs for details.",
+	})
+}
+
+// redirectWithNotice sends the browser back to the listing it posted from with a message.
+func redirectWithNotice(w http.ResponseWriter, r *http.Request, notice string) {
+	path, q := "/", url.Values{}
+	if ref, err := url.Parse(r.Referer()); err == nil && strings.HasPrefix(ref.Path, "/") {
+		path, q = ref.Path, ref.Query()
+	}
+	q.Set("notice", notice)
+	http.Redirect(w, r, path+"?"+q.Encode(), http.StatusSeeOther)
+}
+
+func (s *Server) loginPage(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := s.sessions.verify(r); ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	s.render(w, r, http.StatusOK, "login", view{Title: "Log in"})
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	username := r.PostFormValue("username")
+	password := r.PostFormValue("password")
+	if !credentialsMatch(username, password, s.cfg.AdminPanel.Username, s.cfg.AdminPanel.Password) {
+		logger.Log.Warnf("Failed admin panel login from %s", clientAddr(r))
+		s.render(w, r, http.StatusUnauthorized, "login", view{Title: "Log in", Error: "Invalid username or password."})
+		return
+	}
+
+	logger.Log.Infof("Admin panel login by %s from %s", username, clientAddr(r))
+	s.sessions.issue(w, r, username)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request, _ session) {
+	s.sessions.clear(w, r)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+type dashboardData struct {
+	Overview      services.AdminOverview
+	LastCheckPass time.Time
+	TokenStats    services.CaptchaTokenStats
+}
+
+func (s *Server) dashboard(w http.ResponseWriter, r *http.Request, sess session) {
+	overview, err := services.GetAdminOverview()
+	if err != nil {
+		s.renderError(w, r, sess, err)
+		return
+	}
+	s.render(w, r, http.StatusOK, "dashboard", view{
+		Title:    "Dashboard",
+		Username: sess.username,
+		CSRF:     sess.csrf,
+		Data: dashboardData{
+			Overview:      overview,
+			LastCheckPass: services.LastCheckPass(),
+			TokenStats:    services.CaptchaTokenPoolStats(),
+		},
+	})
+}
+
+type providerRow struct {
+	Name        string
+	DisplayName string
+	Health      services.ProviderHealth
+	Balance     *services.CaptchaBalance
+}
+
+func (s *Server) captcha(w http.ResponseWriter, r *http.Request, sess session) {
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	balances := make(map[string]services.CaptchaBalance)
+	for _, b := range services.CaptchaBalances(ctx) {
+		balances[b.Provider] = b
+	}
+
+	var rows []providerRow
+	for _, health := range services.CaptchaProviderHealth() {
+		row := providerRow{Name: health.Provider, DisplayName: health.Provider, Health: health}
+		if p, ok := services.LookupCaptchaProvider(health.Provider); ok {
+			row.DisplayName = p.DisplayName
+		}
+		if b, ok := balances[health.Provider]; ok {
+			row.Balance = &b
+		}
+		rows = append(rows, row)
+	}
+
+	s.render(w, r, http.StatusOK, "captcha", view{
+		Title:    "Captcha Providers",
+		Username: sess.username,
+		CSRF:     sess.csrf,
+		Data:     rows,
+	})
+}
+
+type bansData struct {
+	Filter     services.BanFilter
+	AccountID  string
+	Bans       []models.Ban
+	Pagination pagination
+}
+
+func (s *Server) bans(w http.ResponseWriter, r *http.Request, sess session) {
+	q := r.URL.Query()
+	filter := services.BanFilter{
+		UserID:  strings.TrimSpace(q.Get("user")),
+		Status:  models.Status(q.Get("status")),
+		LogType: q.Get("type"),
+	}
+	if id, err := strconv.ParseUint(q.Get("account"), 10, 64); err == nil {
+		filter.AccountID = uint(id)
+	}
+
+	bans, total, err := services.ListBanLog(filter, pageSize, pageOffset(r))
+	if err != nil {
+		s.renderError(w, r, sess, err)
+		return
+	}
+	s.render(w, r, http.StatusOK, "bans", view{
+		Title:    "Ban Log",
+		Username: sess.username,
+		CSRF:     sess.csrf,
+		Data: bansData{
+			Filter:     filter,
+			AccountID:  q.Get("account"),
+			Bans:       bans,
+			Pagination: newPagination(r, total),
+		},
+	})
+}
+
+type accountsData struct {
+	Filter     services.AccountFilter
+	Accounts   []models.Account
+	Pagination pagination
+}
+
+func (s *Server) accounts(w http.ResponseWriter, r *http.Request, sess session) {
+	q := r.URL.Query()
+	filter := services.AccountFilter{
+		UserID: strings.TrimSpace(q.Get("user")),
+		Title:  strings.TrimSpace(q.Get("title")),
+		Status: models.Status(q.Get("status")),
+	}
+
+	accounts, total, err := services.ListAdminAccounts(filter, pageSize, pageOffset(r))
+	if err != nil {
+		s.renderError(w, r, sess, err)
+		return
+	}
+	s.render(w, r, http.StatusOK, "accounts", view{
+		Title:    "Accounts",
+		Username: sess.username,
+		CSRF:     sess.csrf,
+		Data: accountsData{
+			Filter:     filter,
+			Accounts:   accounts,
+			Pagination: newPagination(r, total),
+		},
+	})
+}
+
+type usersData struct {
+	Query      string
+	Users      []services.AdminUser
+	Pagination pagination
+}
+
+func (s *Server) users(w http.ResponseWriter, r *http.Request, sess session) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	users, total, err := services.ListAdminUsers(query, pageSize, pageOffset(r))
+	if err != nil {
+		s.renderError(w, r, sess, err)
+		return
+	}
+	s.render(w, r, http.StatusOK, "users", view{
+		Title:    "Users",
+		Username: sess.username,
+		CSRF:     sess.csrf,
+		Data: usersData{
+			Query:      query,
+			Users:      users,
+			Pagination: newPagination(r, total),
+		},
+	})
+}
+
+func accountIDParam(r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	return uint(id), err == nil && id > 0
+}
+
+func (s *Server) disableAccount(w http.ResponseWriter, r *http.Request, sess session) {
+	id, ok := accountIDParam(r)
+	if !ok {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(r.PostFormValue("reason"))
+	if err := services.AdminDisableAccount(s.discord, id, reason); err != nil {
+		s.actionFailed(w, r, sess, err)
+		return
+	}
+	logger.Log.Infof("Admin %s disabled account %d", sess.username, id)
+	redirectWithNotice(w, r, fmt.Sprintf("Account %d disabled.", id))
+}
+
+func (s *Server) enableAccount(w http.ResponseWriter, r *http.Request, sess session) {
+	id, ok := accountIDParam(r)
+	if !ok {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	if err := services.AdminEnableAccount(id); err != nil {
+		s.actionFailed(w, r, sess, err)
+		return
+	}
+	logger.Log.Infof("Admin %s re-enabled account %d", sess.username, id)
+	redirectWithNotice(w, r, fmt.Sprintf("Account %d re-enabled.", id))
+}
+
+func (s *Server) disableUser(w http.ResponseWriter, r *http.Request, sess session) {
+	userID := r.PathValue("id")
+	reason := strings.TrimSpace(r.PostFormValue("reason"))
+	if err := services.AdminDisableUser(userID, reason); err != nil {
+		s.actionFailed(w, r, sess, err)
+		return
+	}
+	logger.Log.Infof("Admin %s disabled user %s", sess.username, userID)
+	redirectWithNotice(w, r, fmt.Sprintf("User %s disabled and their accounts paused.", userID))
+}
+
+func (s *Server) enableUser(w http.ResponseWriter, r *http.Request, sess session) {
+	userID := r.PathValue("id")
+	if err := services.AdminEnableUser(userID); err != nil {
+		s.actionFailed(w, r, sess, err)
+		return
+	}
+	logger.Log.Infof("Admin %s re-enabled user %s", sess.username, userID)
+	redirectWithNotice(w, r, fmt.Sprintf("User %s re-enabled. Their accounts stay paused until re-enabled individually.", userID))
+}
+
+// actionFailed reports a failed action back on the originating page, or as an error page when
+// the failure was not the operator's doing.
+func (s *Server) actionFailed(w http.ResponseWriter, r *http.Request, sess session, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		redirectWithNotice(w, r, "Not found.")
+	case errors.Is(err, services.ErrUserDisabled):
+		redirectWithNotice(w, r, "The account's owner is disabled; re-enable the user first.")
+	default:
+		s.renderError(w, r, sess, err)
+	}
+}
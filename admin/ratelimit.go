@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A bucket refills completely within a minute, so one left idle that long is no different from
+// a new one and is dropped at the next sweep.
+const (
+	bucketIdleTTL       = time.Minute
+	bucketSweepInterval = time.Minute
+)
+
+// clientLimiter allows each client address STATS_RATE_LIMIT requests per minute, refilled
+// continuously, so the dashboard's database queries and the login form cannot be hammered.
+type clientLimiter struct {
+	mu        sync.Mutex
+	perMin    float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newClientLimiter(perMinute float64) *clientLimiter {
+	return &clientLimiter{perMin: perMinute, buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+func (l *clientLimiter) allow(client string) bool {
+	if l.perMin <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= bucketSweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.perMin, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens += now.Sub(b.last).Minutes() * l.perMin
+	if b.tokens > l.perMin {
+		b.tokens = l.perMin
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep drops clients that have been idle for bucketIdleTTL. The caller must hold l.mu.
+func (l *clientLimiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= bucketIdleTTL {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+func (l *clientLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.allow(clientAddr(r)) {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClientLimiterAllowsUpToLimit(t *testing.T) {
+	l := newClientLimiter(3)
+	for i := 0; i < 3; i++ {
+		if !l.allow("203.0.113.1") {
+			t.Fatalf("request %d refused within the limit", i+1)
+		}
+	}
+	if l.allow("203.0.113.1") {
+		t.Fatal("request over the limit allowed")
+	}
+	if !l.allow("203.0.113.2") {
+		t.Fatal("another client was limited by the first one's requests")
+	}
+}
+
+func TestClientLimiterSweepsIdleClients(t *testing.T) {
+	l := newClientLimiter(3)
+	for i := 0; i < 100; i++ {
+		l.allow(fmt.Sprintf("198.51.100.%d", i))
+	}
+	l.allow("203.0.113.1")
+
+	// Age every bucket but the last past the idle TTL and make a sweep due.
+	past := time.Now().Add(-bucketIdleTTL - time.Second)
+	for client, b := range l.buckets {
+		if client != "203.0.113.1" {
+			b.last = past
+		}
+	}
+	l.lastSweep = time.Now().Add(-bucketSweepInterval)
+
+	l.allow("203.0.113.1")
+	if len(l.buckets) != 1 {
+		t.Fatalf("%d buckets left after the sweep, want 1", len(l.buckets))
+	}
+	if b := l.buckets["203.0.113.1"]; b == nil || b.tokens >= 2 {
+		t.Fatal("the active client's bucket was reset by the sweep")
+	}
+}
+
+func TestClientLimiterDisabled(t *testing.T) {
+	l := newClientLimiter(0)
+	for i := 0; i < 100; i++ {
+		if !l.allow("203.0.113.1") {
+			t.Fatal("disabled limiter refused a request")
+		}
+	}
+	if len(l.buckets) != 0 {
+		t.Fatalf("disabled limiter kept %d buckets", len(l.buckets))
+	}
+}
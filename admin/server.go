@@ -0,0 +1,177 @@
+// Package admin is the operator's web panel: a dashboard of users and accounts, captcha provider
+// health and balances, the ban log, and actions to disable accounts or users. Pages are rendered
+// from the html/template files in TEMPLATES_DIR and styled from STATIC_DIR.
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+)
+
+// pageNames are the templates loaded from TEMPLATES_DIR. Each is parsed together with
+// layout.html, which renders the "content" block the page defines.
+var pageNames = []string{"login", "dashboard", "captcha", "bans", "accounts", "users"}
+
+// Server serves the admin panel.
+type Server struct {
+	cfg      *configuration.Config
+	discord  discordapi.Session
+	sessions sessions
+	limiter  *clientLimiter
+	pages    map[string]*template.Template
+}
+
+// NewServer checks the admin panel configuration and loads its templates.
+func NewServer(discord discordapi.Session) (*Server, error) {
+	cfg := configuration.Get()
+	panel := cfg.AdminPanel
+	if panel.Username == "" || panel.Password == "" || panel.SessionKey == "" {
+		return nil, errors.New("admin panel requires ADMIN_USERNAME, ADMIN_PASSWORD and SESSION_KEY")
+	}
+	if len(panel.SessionKey) < 32 {
+		logger.Log.Warn("SESSION_KEY is shorter than 32 characters; use a longer random value")
+	}
+
+	pages, err := loadPages(panel.TemplatesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		cfg:      cfg,
+		discord:  discord,
+		sessions: sessions{key: []byte(panel.SessionKey)},
+		limiter:  newClientLimiter(panel.StatsRateLimit),
+		pages:    pages,
+	}, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return t.UTC().Format("2006-01-02 15:04 UTC")
+	},
+	"formatUnix": func(unix int64) string {
+		if unix == 0 {
+			return "never"
+		}
+		return time.Unix(unix, 0).UTC().Format("2006-01-02 15:04 UTC")
+	},
+	"since": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return time.Since(t).Round(time.Second).String() + " ago"
+	},
+	"statusClass": func(status models.Status) string {
+		return "status-" + strings.ToLower(string(status))
+	},
+	"statuses": func() []models.Status {
+		return []models.Status{models.StatusGood, models.StatusTempban, models.StatusShadowban,
+			models.StatusPermaban, models.StatusInvalidCookie, models.StatusUnknown}
+	},
+}
+
+func loadPages(dir string) (map[string]*template.Template, error) {
+	layout := filepath.Join(dir, "layout.html")
+	pages := make(map[string]*template.Template, len(pageNames))
+	for _, name := range pageNames {
+		t, err := template.New("layout.html").Funcs(templateFuncs).ParseFiles(layout, filepath.Join(dir, name+".html"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load admin template %s: %w", name, err)
+		}
+		pages[name] = t
+	}
+	return pages, nil
+}
+
+// Handler returns the panel's routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir(s.cfg.AdminPanel.StaticDir))))
+
+	mux.HandleFunc("GET /login", s.loginPage)
+	mux.Handle("POST /login", s.limiter.middleware(http.HandlerFunc(s.login)))
+	mux.Handle("POST /logout", s.authenticated(s.logout))
+
+	mux.Handle("GET /{$}", s.authenticated(s.dashboard))
+	mux.Handle("GET /captcha", s.authenticated(s.captcha))
+	mux.Handle("GET /bans", s.authenticated(s.bans))
+	mux.Handle("GET /accounts", s.authenticated(s.accounts))
+	mux.Handle("POST /accounts/{id}/disable", s.authenticated(s.disableAccount))
+	mux.Handle("POST /accounts/{id}/enable", s.authenticated(s.enableAccount))
+	mux.Handle("GET /users", s.authenticated(s.users))
+	mux.Handle("POST /users/{id}/disable", s.authenticated(s.disableUser))
+	mux.Handle("POST /users/{id}/enable", s.authenticated(s.enableUser))
+
+	return securityHeaders(mux)
+}
+
+// HTTPServer wraps Handler in an http.Server listening on ADMIN_PORT.
+func (s *Server) HTTPServer() *http.Server {
+	addr := s.cfg.AdminPanel.Port
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+}
+
+// session carries the logged-in administrator through a request.
+type session struct {
+	username string
+	csrf     string
+}
+
+type sessionHandler func(w http.ResponseWriter, r *http.Request, sess session)
+
+// authenticated rate limits the handler, redirects to the login page without a valid session and
+// rejects form posts that lack the session's CSRF token.
+func (s *Server) authenticated(h sessionHandler) http.Handler {
+	return s.limiter.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, cookieValue, ok := s.sessions.verify(r)
+		if !ok {
+			if r.Method == http.MethodGet {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			http.Error(w, "Session expired, please log in again", http.StatusUnauthorized)
+			return
+		}
+
+		sess := session{username: username, csrf: s.sessions.csrfToken(cookieValue)}
+		if r.Method == http.MethodPost && !s.sessions.validCSRF(cookieValue, r.PostFormValue("csrf")) {
+			http.Error(w, "Invalid form token, reload the page and try again", http.StatusForbidden)
+			return
+		}
+		h(w, r, sess)
+	}))
+}
+
+func securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "same-origin")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		w.Header().Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
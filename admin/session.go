@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sessionCookie = "codstatusbot_admin"
+	sessionTTL    = 12 * time.Hour
+)
+
+// sessions issues and verifies login cookies. A cookie holds the username and expiry signed with
+// SESSION_KEY, so sessions survive restarts and changing the key logs everyone out.
+type sessions struct {
+	key []byte
+}
+
+func (s sessions) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issue sets a session cookie for username.
+func (s sessions) issue(w http.ResponseWriter, r *http.Request, username string) {
+	expires := time.Now().Add(sessionTTL)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(username + "|" + strconv.FormatInt(expires.Unix(), 10)))
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    payload + "." + s.sign(payload),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clear removes the session cookie.
+func (s sessions) clear(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// verify returns the username and raw cookie value of a valid, unexpired session.
+func (s sessions) verify(r *http.Request) (string, string, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return "", "", false
+	}
+	payload, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return "", "", false
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", false
+	}
+	username, expiry, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return "", "", false
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return "", "", false
+	}
+	return username, cookie.Value, true
+}
+
+// csrfToken is the form token for the session cookie value, so a form can only be posted from a
+// page rendered for the same session.
+func (s sessions) csrfToken(cookieValue string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("csrf|" + cookieValue))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s sessions) validCSRF(cookieValue, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(s.csrfToken(cookieValue)), []byte(token)) == 1
+}
+
+// credentialsMatch compares the submitted login with the configured one in constant time.
+func credentialsMatch(username, password, wantUsername, wantPassword string) bool {
+	userHash := sha256.Sum256([]byte(username))
+	wantUserHash := sha256.Sum256([]byte(wantUsername))
+	passHash := sha256.Sum256([]byte(password))
+	wantPassHash := sha256.Sum256([]byte(wantPassword))
+	userOK := subtle.ConstantTimeCompare(userHash[:], wantUserHash[:]) == 1
+	passOK := subtle.ConstantTimeCompare(passHash[:], wantPassHash[:]) == 1
+	return userOK && passOK
+}
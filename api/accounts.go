@@ -0,0 +1,348 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
+)
+
+const (
+	defaultBanLimit = 50
+	maxBanLimit     = 200
+	maxBodyBytes    = 16 << 10
+)
+
+// accountRequest is the body of POST /accounts and PATCH /accounts/{id}. PATCH applies only the
+// fields that are present.
+type accountRequest struct {
+	Title     *string `json:"title"`
+	SSOCookie *string `json:"sso_cookie"`
+}
+
+func (s *Server) listAccounts(w http.ResponseWriter, r *http.Request) {
+	var accounts []models.Account
+	if err := database.DB.Where("user_id = ?", userID(r)).Order("id").Find(&accounts).Error; err != nil {
+		logger.Log.WithError(err).Error("Failed to list accounts for API")
+		writeError(w, http.StatusInternalServerError, "internal", "Failed to list accounts")
+		return
+	}
+
+	out := make([]account, 0, len(accounts))
+	for _, a := range accounts {
+		out = append(out, newAccount(a))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"accounts": out})
+}
+
+func (s *Server) getAccount(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newAccount(a))
+}
+
+func (s *Server) addAccount(w http.ResponseWriter, r *http.Request) {
+	var req accountRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Title == nil || req.SSOCookie == nil || strings.TrimSpace(*req.SSOCookie) == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "title and sso_cookie are required")
+		return
+	}
+	// Reject a bad title before it can use up the add account rate limit.
+	if _, err := services.ValidateAccountTitle(*req.Title); !s.accountError(w, err) {
+		return
+	}
+
+	uid := userID(r)
+	userSettings, err := services.GetUserSettings(uid)
+	if err != nil {
+		logger.Log.WithError(err).Error("Error fetching user settings")
+		writeError(w, http.StatusInternalServerError, "internal", "Failed to fetch user settings")
+		return
+	}
+	if !s.captchaReady(w, r, userSettings) {
+		return
+	}
+	if !userSettings.HasCustomCaptchaKey() && !services.AllowAddAccount(uid) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.cfg.RateLimits.CheckNow.Seconds())))
+		writeError(w, http.StatusTooManyRequests, "rate_limited",
+			fmt.Sprintf("Please wait %v before adding another account, or set your own captcha key with /setcaptchaservice", s.cfg.RateLimits.CheckNow))
+		return
+	}
+
+	channel, err := s.discord.UserChannelCreate(uid)
+	if err != nil {
+		logger.Log.WithError(err).Error("Error creating DM channel for API account")
+		writeError(w, http.StatusBadGateway, "discord_unavailable", "Could not open a DM channel for notifications")
+		return
+	}
+
+	added, remaining, err := services.AddAccount(r.Context(), uid, *req.Title, strings.TrimSpace(*req.SSOCookie), channel.ID)
+	if !s.accountError(w, err) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := services.CommandContext()
+		defer cancel()
+		if _, _, err := services.CheckAccountNow(ctx, s.discord, added, userSettings); err != nil {
+			logger.Log.WithError(err).Error("Error performing initial status check")
+		}
+	}()
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/accounts/%d", added.ID))
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"account":         newAccount(added),
+		"remaining_slots": remaining,
+	})
+}
+
+func (s *Server) updateAccount(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var req accountRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Title == nil && req.SSOCookie == nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Provide title, sso_cookie or both")
+		return
+	}
+
+	// Check the title first so a bad one does not leave the cookie half applied.
+	if req.Title != nil {
+		if _, err := services.ValidateAccountTitle(*req.Title); !s.accountError(w, err) {
+			return
+		}
+	}
+	if req.SSOCookie != nil {
+		_, err := services.UpdateAccountCookie(r.Context(), &a, strings.TrimSpace(*req.SSOCookie))
+		if !s.accountError(w, err) {
+			return
+		}
+	}
+	if req.Title != nil {
+		if !s.accountError(w, services.RenameAccount(&a, *req.Title)) {
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, newAccount(a))
+}
+
+func (s *Server) removeAccount(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+	if err := services.RemoveAccount(a); err != nil {
+		logger.Log.WithError(err).Error("Error removing account through API")
+		writeError(w, http.StatusInternalServerError, "internal", "Failed to remove account")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) toggleAccount(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+	if err := services.SetAccountChecksDisabled(&a, !a.IsCheckDisabled); err != nil {
+		logger.Log.WithError(err).Error("Error toggling account checks through API")
+		writeError(w, http.StatusInternalServerError, "internal", "Failed to toggle account checks")
+		return
+	}
+	writeJSON(w, http.StatusOK, newAccount(a))
+}
+
+// checkAccount runs a check now, under the same captcha and manual check limits as /checknow.
+func (s *Server) checkAccount(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+	if a.IsCheckDisabled {
+		writeError(w, http.StatusConflict, "checks_disabled", "Checks are disabled for this account: "+a.DisabledReason)
+		return
+	}
+	if a.IsExpiredCookie {
+		writeError(w, http.StatusConflict, "cookie_expired", "The SSO cookie for this account has expired; update it first")
+		return
+	}
+
+	userSettings, err := services.GetUserSettings(a.UserID)
+	if err != nil {
+		logger.Log.WithError(err).Error("Error fetching user settings")
+		writeError(w, http.StatusInternalServerError, "internal", "Failed to fetch user settings")
+		return
+	}
+	if !s.captchaReady(w, r, userSettings) {
+		return
+	}
+
+	var quotaErr *services.CheckNowQuotaError
+	if err := services.ReserveCheckNow(&userSettings, 1); errors.As(err, &quotaErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(quotaErr.ResetIn.Seconds()))))
+		writeError(w, http.StatusTooManyRequests, "rate_limited",
+			fmt.Sprintf("All %d manual checks for this period are used; set your own captcha key with /setcaptchaservice to remove the limit", quotaErr.Max))
+		return
+	} else if err != nil {
+		logger.Log.WithError(err).Error("Error reserving manual check")
+		writeError(w, http.StatusInternalServerError, "internal", "Failed to update check count")
+		return
+	}
+
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+	checked, check, err := services.CheckAccountNow(ctx, s.discord, a, userSettings)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Error checking account %d through API", a.ID)
+		writeError(w, http.StatusBadGateway, "check_failed", "The account check failed, try again later")
+		return
+	}
+
+	result := map[string]interface{}{
+		"account": newAccount(checked),
+		"status":  check.Status,
+	}
+	if endsAt := check.TempBanEndsAt(); !endsAt.IsZero() {
+		result["temp_ban_ends_at"] = endsAt.UTC()
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) accountBans(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+
+	limit := queryInt(r, "limit", defaultBanLimit)
+	if limit < 1 || limit > maxBanLimit {
+		writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("limit must be between 1 and %d", maxBanLimit))
+		return
+	}
+	offset := queryInt(r, "offset", 0)
+	if offset < 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request", "offset must not be negative")
+		return
+	}
+
+	bans, total, err := services.ListBanLog(services.BanFilter{AccountID: a.ID}, limit, offset)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to list ban log for API")
+		writeError(w, http.StatusInternalServerError, "internal", "Failed to list ban history")
+		return
+	}
+
+	out := make([]ban, 0, len(bans))
+	for _, b := range bans {
+		out = append(out, newBan(b))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"bans": out, "total": total})
+}
+
+// lookupAccount loads the account named in the path if it belongs to the caller, writing the
+// error response otherwise.
+func (s *Server) lookupAccount(w http.ResponseWriter, r *http.Request) (models.Account, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Account not found")
+		return models.Account{}, false
+	}
+
+	a, err := services.GetUserAccount(userID(r), uint(id))
+	if errors.Is(err, services.ErrAccountNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "Account not found")
+		return a, false
+	}
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to fetch account for API")
+		writeError(w, http.StatusInternalServerError, "internal", "Failed to fetch account")
+		return a, false
+	}
+	return a, true
+}
+
+// captchaReady rejects work that needs a captcha solve while the user's provider is disabled or
+// their own key has no balance left.
+func (s *Server) captchaReady(w http.ResponseWriter, r *http.Request, userSettings models.UserSettings) bool {
+	if !services.IsServiceEnabled(userSettings.PreferredCaptchaProvider) {
+		writeError(w, http.StatusConflict, "captcha_service_disabled",
+			fmt.Sprintf("Your preferred captcha service (%s) is currently disabled; choose another with /setcaptchaservice", userSettings.PreferredCaptchaProvider))
+		return false
+	}
+	if !userSettings.HasCustomCaptchaKey() {
+		return true
+	}
+
+	_, balance, err := services.GetUserCaptchaKey(r.Context(), userSettings.UserID)
+	if err != nil {
+		logger.Log.WithError(err).Error("Error checking captcha balance")
+		writeError(w, http.StatusConflict, "captcha_key_invalid", "Your captcha API key could not be validated; check it with /setcaptchaservice")
+		return false
+	}
+	if balance <= 0 {
+		writeError(w, http.StatusConflict, "captcha_balance_low", fmt.Sprintf("Your captcha balance (%.2f) is too low", balance))
+		return false
+	}
+	return true
+}
+
+// accountError writes the response for an error from the shared account operations and reports
+// whether err was nil.
+func (s *Server) accountError(w http.ResponseWriter, err error) bool {
+	var limitErr *services.AccountLimitError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &limitErr):
+		writeError(w, http.StatusConflict, "account_limit", fmt.Sprintf("You have reached the maximum of %d accounts", limitErr.Max))
+	case errors.Is(err, services.ErrInvalidSSOCookie):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_sso_cookie", "Activision did not accept the SSO cookie")
+	case errors.Is(err, services.ErrInvalidAccountTitle):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_title", services.ErrInvalidAccountTitle.Error())
+	default:
+		logger.Log.WithError(err).Error("Account operation failed through API")
+		writeError(w, http.StatusInternalServerError, "internal", "The account could not be saved")
+	}
+	return false
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func queryInt(r *http.Request, key string, fallback int) int {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenLimiter allows each API token REST_API_RATE_LIMIT requests per minute, refilled
+// continuously, so one integration cannot monopolise the database or the captcha providers.
+type tokenLimiter struct {
+	mu      sync.Mutex
+	perMin  float64
+	buckets map[uint]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newTokenLimiter(perMinute float64) *tokenLimiter {
+	return &tokenLimiter{perMin: perMinute, buckets: make(map[uint]*bucket)}
+}
+
+func (l *tokenLimiter) allow(tokenID uint) bool {
+	if l.perMin <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[tokenID]
+	if !ok {
+		b = &bucket{tokens: l.perMin, last: now}
+		l.buckets[tokenID] = b
+	}
+	b.tokens += now.Sub(b.last).Minutes() * l.perMin
+	if b.tokens > l.perMin {
+		b.tokens = l.perMin
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
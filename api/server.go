@@ -0,0 +1,211 @@
+// Package api is the versioned JSON REST API served under /api/v1. Requests authenticate with a
+// bearer token issued by the /apitoken command and act on the accounts of that token's user,
+// with the same validation and limits as the slash commands.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
+)
+
+// Server serves the REST API.
+type Server struct {
+	cfg     *configuration.Config
+	discord discordapi.Session
+	limiter *tokenLimiter
+}
+
+// NewServer returns a REST API server that sends notifications through discord.
+func NewServer(discord discordapi.Session) *Server {
+	cfg := configuration.Get()
+	return &Server{
+		cfg:     cfg,
+		discord: discord,
+		limiter: newTokenLimiter(cfg.RESTAPI.RateLimit),
+	}
+}
+
+// Handler returns the API's routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/v1/accounts", s.authenticated(s.listAccounts))
+	mux.Handle("POST /api/v1/accounts", s.authenticated(s.addAccount))
+	mux.Handle("GET /api/v1/accounts/{id}", s.authenticated(s.getAccount))
+	mux.Handle("PATCH /api/v1/accounts/{id}", s.authenticated(s.updateAccount))
+	mux.Handle("DELETE /api/v1/accounts/{id}", s.authenticated(s.removeAccount))
+	mux.Handle("POST /api/v1/accounts/{id}/toggle", s.authenticated(s.toggleAccount))
+	mux.Handle("POST /api/v1/accounts/{id}/check", s.authenticated(s.checkAccount))
+	mux.Handle("GET /api/v1/accounts/{id}/bans", s.authenticated(s.accountBans))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "No such endpoint")
+	})
+	return mux
+}
+
+// HTTPServer wraps Handler in an http.Server listening on REST_API_ADDR. The write timeout leaves
+// room for a check, which may wait on a captcha solve.
+func (s *Server) HTTPServer() *http.Server {
+	return &http.Server{
+		Addr:              s.cfg.RESTAPI.Addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      6 * time.Minute,
+	}
+}
+
+type userKey struct{}
+
+// userID returns the ID of the user whose token authenticated r.
+func userID(r *http.Request) string {
+	id, _ := r.Context().Value(userKey{}).(string)
+	return id
+}
+
+// authenticated resolves the bearer token to its user, rate limits the token and turns away
+// users an administrator has disabled.
+func (s *Server) authenticated(h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Missing bearer token")
+			return
+		}
+
+		record, err := services.AuthenticateAPIToken(strings.TrimSpace(token))
+		if errors.Is(err, services.ErrInvalidAPIToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or revoked API token")
+			return
+		}
+		if err != nil {
+			logger.Log.WithError(err).Error("Failed to authenticate API token")
+			writeError(w, http.StatusInternalServerError, "internal", "Failed to authenticate request")
+			return
+		}
+
+		if !s.limiter.allow(record.ID) {
+			w.Header().Set("Retry-After", "60")
+			writeError(w, http.StatusTooManyRequests, "rate_limited", "Too many requests for this token")
+			return
+		}
+
+		if reason, disabled := services.UserDisabledReason(record.UserID); disabled {
+			writeError(w, http.StatusForbidden, "user_disabled", reason)
+			return
+		}
+
+		h(w, r.WithContext(context.WithValue(r.Context(), userKey{}, record.UserID)))
+	})
+}
+
+// apiError is the body of every error response.
+type apiError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	var body apiError
+	body.Error.Code = code
+	body.Error.Message = message
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Log.WithError(err).Error("Failed to write API response")
+	}
+}
+
+// account is the API representation of a monitored account. The SSO cookie is never returned.
+type account struct {
+	ID               uint          `json:"id"`
+	Title            string        `json:"title"`
+	Status           models.Status `json:"status"`
+	LastCheck        *time.Time    `json:"last_check"`
+	ChecksEnabled    bool          `json:"checks_enabled"`
+	DisabledReason   string        `json:"disabled_reason,omitempty"`
+	CookieExpired    bool          `json:"cookie_expired"`
+	CookieExpiresAt  *time.Time    `json:"cookie_expires_at"`
+	IsVIP            bool          `json:"is_vip"`
+	NotificationType string        `json:"notification_type"`
+	CreatedAt        time.Time     `json:"created_at"`
+}
+
+func newAccount(a models.Account) account {
+	return account{
+		ID:               a.ID,
+		Title:            a.Title,
+		Status:           a.LastStatus,
+		LastCheck:        unixTime(a.LastCheck),
+		ChecksEnabled:    !a.IsCheckDisabled,
+		DisabledReason:   a.DisabledReason,
+		CookieExpired:    a.IsExpiredCookie,
+		CookieExpiresAt:  unixTime(a.SSOCookieExpiration),
+		IsVIP:            a.IsVIP,
+		NotificationType: a.NotificationType,
+		CreatedAt:        a.CreatedAt.UTC(),
+	}
+}
+
+// ban is the API representation of an entry in an account's status log.
+type ban struct {
+	ID              uint          `json:"id"`
+	LogType         string        `json:"log_type"`
+	Status          models.Status `json:"status"`
+	PreviousStatus  models.Status `json:"previous_status,omitempty"`
+	Message         string        `json:"message"`
+	TempBanDuration string        `json:"temp_ban_duration,omitempty"`
+	TempBanEndsAt   *time.Time    `json:"temp_ban_ends_at,omitempty"`
+	AffectedGames   string        `json:"affected_games,omitempty"`
+	CanAppeal       bool          `json:"can_appeal"`
+	AppealStatus    string        `json:"appeal_status,omitempty"`
+	Initiator       string        `json:"initiator,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+func newBan(b models.Ban) ban {
+	out := ban{
+		ID:              b.ID,
+		LogType:         b.LogType,
+		Status:          b.Status,
+		PreviousStatus:  b.PreviousStatus,
+		Message:         b.Message,
+		TempBanDuration: b.TempBanDuration,
+		AffectedGames:   b.AffectedGames,
+		CanAppeal:       b.CanAppeal,
+		AppealStatus:    b.AppealStatus,
+		Initiator:       b.Initiator,
+		CreatedAt:       b.CreatedAt.UTC(),
+	}
+	if !b.TempBanEndsAt.IsZero() {
+		endsAt := b.TempBanEndsAt.UTC()
+		out.TempBanEndsAt = &endsAt
+	}
+	return out
+}
+
+func unixTime(unix int64) *time.Time {
+	if unix == 0 {
+		return nil
+	}
+	t := time.Unix(unix, 0).UTC()
+	return &t
+}
@@ -0,0 +1,342 @@
+package api_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bradselph/CODStatusBot/api"
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+	"github.com/glebarez/sqlite"
+)
+
+// startAPI points the database at a fresh in-memory SQLite database and serves the API with
+// the given per-token rate limit for the rest of the test.
+func startAPI(t *testing.T, rateLimit float64) http.Handler {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := *cfg
+	previousDB := database.DB
+	t.Cleanup(func() {
+		*cfg = saved
+		database.DB = previousDB
+	})
+
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	if err := database.SetEncryptionKeys(key, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	if err := database.Connect(sqlite.Open("file::memory:")); err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	if _, err := database.MigrateUp(database.DB); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	cfg.RESTAPI.RateLimit = rateLimit
+	return api.NewServer(fakediscord.New()).Handler()
+}
+
+func createToken(t *testing.T, userID string) (string, models.APIToken) {
+	t.Helper()
+	token, record, err := services.CreateAPIToken(userID, "test")
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	return token, record
+}
+
+func createAccount(t *testing.T, userID, title string) models.Account {
+	t.Helper()
+	account := models.Account{UserID: userID, Title: title, LastStatus: models.StatusGood, SSOCookie: "cookie-" + title}
+	if err := database.DB.Create(&account).Error; err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	return account
+}
+
+// do sends a request with token as the bearer token, or no Authorization header if it is
+// empty, and decodes the JSON response into out when out is not nil.
+func do(t *testing.T, h http.Handler, token, method, path, body string, out interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type accountBody struct {
+	ID     uint          `json:"id"`
+	Title  string        `json:"title"`
+	Status models.Status `json:"status"`
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization func(t *testing.T) string
+		wantStatus    int
+		wantCode      string
+		wantChallenge string
+	}{
+		{
+			name:          "no header",
+			authorization: func(*testing.T) string { return "" },
+			wantStatus:    http.StatusUnauthorized,
+			wantCode:      "unauthorized",
+			wantChallenge: `Bearer realm="api"`,
+		},
+		{
+			name:          "not a bearer token",
+			authorization: func(*testing.T) string { return "Basic dXNlcjpwYXNz" },
+			wantStatus:    http.StatusUnauthorized,
+			wantCode:      "unauthorized",
+			wantChallenge: `Bearer realm="api"`,
+		},
+		{
+			name:          "unknown token",
+			authorization: func(*testing.T) string { return "Bearer cod_not-a-real-token" },
+			wantStatus:    http.StatusUnauthorized,
+			wantCode:      "unauthorized",
+			wantChallenge: `Bearer realm="api", error="invalid_token"`,
+		},
+		{
+			name: "revoked token",
+			authorization: func(t *testing.T) string {
+				token, record := createToken(t, "user-1")
+				if err := services.RevokeAPIToken("user-1", record.ID); err != nil {
+					t.Fatalf("RevokeAPIToken: %v", err)
+				}
+				return "Bearer " + token
+			},
+			wantStatus:    http.StatusUnauthorized,
+			wantCode:      "unauthorized",
+			wantChallenge: `Bearer realm="api", error="invalid_token"`,
+		},
+		{
+			name: "disabled user",
+			authorization: func(t *testing.T) string {
+				token, _ := createToken(t, "user-1")
+				settings, err := services.GetUserSettings("user-1")
+				if err != nil {
+					t.Fatalf("GetUserSettings: %v", err)
+				}
+				settings.IsDisabled = true
+				settings.DisabledReason = "Abusing the check queue"
+				if err := database.DB.Save(&settings).Error; err != nil {
+					t.Fatalf("disable user: %v", err)
+				}
+				return "Bearer " + token
+			},
+			wantStatus: http.StatusForbidden,
+			wantCode:   "user_disabled",
+		},
+		{
+			name: "valid token",
+			authorization: func(t *testing.T) string {
+				token, _ := createToken(t, "user-1")
+				return "Bearer " + token
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := startAPI(t, 0)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts", nil)
+			if authorization := tt.authorization(t); authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var body errorBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode %q: %v", rec.Body.String(), err)
+			}
+			if body.Error.Code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", body.Error.Code, tt.wantCode)
+			}
+			if tt.wantCode == "user_disabled" && body.Error.Message != "Abusing the check queue" {
+				t.Errorf("error message = %q, want the reason the user was disabled", body.Error.Message)
+			}
+		})
+	}
+}
+
+func TestRateLimitPerToken(t *testing.T) {
+	h := startAPI(t, 2)
+	first, _ := createToken(t, "user-1")
+	second, _ := createToken(t, "user-1")
+
+	for i := 0; i < 2; i++ {
+		if rec := do(t, h, first, http.MethodGet, "/api/v1/accounts", "", nil); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, rec.Code, http.StatusOK)
+		}
+	}
+	var body errorBody
+	rec := do(t, h, first, http.MethodGet, "/api/v1/accounts", "", &body)
+	if rec.Code != http.StatusTooManyRequests || body.Error.Code != "rate_limited" {
+		t.Errorf("third request = %d %q, want %d rate_limited", rec.Code, body.Error.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+
+	if rec := do(t, h, second, http.MethodGet, "/api/v1/accounts", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("another token of the same user got %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestAccountScoping(t *testing.T) {
+	h := startAPI(t, 0)
+	token, _ := createToken(t, "user-1")
+	mine := []models.Account{createAccount(t, "user-1", "main"), createAccount(t, "user-1", "alt")}
+	theirs := createAccount(t, "user-2", "other")
+
+	var list struct {
+		Accounts []accountBody `json:"accounts"`
+	}
+	rec := do(t, h, token, http.MethodGet, "/api/v1/accounts", "", &list)
+	want := []accountBody{
+		{ID: mine[0].ID, Title: "main", Status: models.StatusGood},
+		{ID: mine[1].ID, Title: "alt", Status: models.StatusGood},
+	}
+	if rec.Code != http.StatusOK || !reflect.DeepEqual(list.Accounts, want) {
+		t.Errorf("list = %d %+v, want %+v", rec.Code, list.Accounts, want)
+	}
+	if strings.Contains(rec.Body.String(), "cookie-") {
+		t.Errorf("account list leaks SSO cookies: %s", rec.Body.String())
+	}
+
+	var got accountBody
+	if rec := do(t, h, token, http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d", mine[1].ID), "", &got); rec.Code != http.StatusOK || got != want[1] {
+		t.Errorf("get own account = %d %+v, want %+v", rec.Code, got, want[1])
+	}
+
+	// Another user's account answers exactly like one that does not exist.
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d", theirs.ID), ""},
+		{http.MethodPatch, fmt.Sprintf("/api/v1/accounts/%d", theirs.ID), `{"title":"stolen"}`},
+		{http.MethodDelete, fmt.Sprintf("/api/v1/accounts/%d", theirs.ID), ""},
+		{http.MethodPost, fmt.Sprintf("/api/v1/accounts/%d/toggle", theirs.ID), ""},
+		{http.MethodPost, fmt.Sprintf("/api/v1/accounts/%d/check", theirs.ID), ""},
+		{http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d/bans", theirs.ID), ""},
+		{http.MethodGet, "/api/v1/accounts/999", ""},
+		{http.MethodGet, "/api/v1/accounts/main", ""},
+	} {
+		var body errorBody
+		rec := do(t, h, token, req.method, req.path, req.body, &body)
+		if rec.Code != http.StatusNotFound || body.Error.Code != "not_found" || body.Error.Message != "Account not found" {
+			t.Errorf("%s %s = %d %+v, want 404 Account not found", req.method, req.path, rec.Code, body.Error)
+		}
+	}
+
+	var stored models.Account
+	if err := database.DB.First(&stored, theirs.ID).Error; err != nil {
+		t.Fatalf("the other user's account is gone: %v", err)
+	}
+	if stored.Title != "other" || stored.IsCheckDisabled {
+		t.Errorf("the other user's account was changed: title %q, checks disabled %v", stored.Title, stored.IsCheckDisabled)
+	}
+
+	var body errorBody
+	if rec := do(t, h, token, http.MethodGet, "/api/v1/users", "", &body); rec.Code != http.StatusNotFound || body.Error.Code != "not_found" {
+		t.Errorf("unknown endpoint = %d %q, want 404 not_found", rec.Code, body.Error.Code)
+	}
+}
+
+func TestAccountBansPagination(t *testing.T) {
+	h := startAPI(t, 0)
+	token, _ := createToken(t, "user-1")
+	account := createAccount(t, "user-1", "main")
+	other := createAccount(t, "user-1", "alt")
+
+	var ids []uint // newest first, the order the API returns them in
+	for i := 0; i < 5; i++ {
+		b := models.Ban{AccountID: account.ID, Status: models.StatusShadowban, LogType: "status_change", Message: fmt.Sprintf("change %d", i)}
+		if err := database.DB.Create(&b).Error; err != nil {
+			t.Fatalf("create ban: %v", err)
+		}
+		ids = append([]uint{b.ID}, ids...)
+	}
+	if err := database.DB.Create(&models.Ban{AccountID: other.ID, Status: models.StatusPermaban}).Error; err != nil {
+		t.Fatalf("create ban: %v", err)
+	}
+
+	tests := []struct {
+		query   string
+		wantIDs []uint
+	}{
+		{query: "", wantIDs: ids},
+		{query: "?limit=2", wantIDs: ids[:2]},
+		{query: "?limit=2&offset=2", wantIDs: ids[2:4]},
+		{query: "?limit=2&offset=4", wantIDs: ids[4:]},
+		{query: "?offset=5", wantIDs: []uint{}},
+		{query: "?limit=200", wantIDs: ids},
+	}
+	for _, tt := range tests {
+		t.Run("page"+tt.query, func(t *testing.T) {
+			var page struct {
+				Bans []struct {
+					ID uint `json:"id"`
+				} `json:"bans"`
+				Total int64 `json:"total"`
+			}
+			rec := do(t, h, token, http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d/bans%s", account.ID, tt.query), "", &page)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			got := make([]uint, 0, len(page.Bans))
+			for _, b := range page.Bans {
+				got = append(got, b.ID)
+			}
+			if !reflect.DeepEqual(got, tt.wantIDs) || page.Total != 5 {
+				t.Errorf("bans = %v of %d, want %v of 5", got, page.Total, tt.wantIDs)
+			}
+		})
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=201", "?limit=ten", "?offset=-1", "?offset=two"} {
+		t.Run("invalid"+query, func(t *testing.T) {
+			var body errorBody
+			rec := do(t, h, token, http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d/bans%s", account.ID, query), "", &body)
+			if rec.Code != http.StatusBadRequest || body.Error.Code != "invalid_request" {
+				t.Errorf("status = %d %q, want 400 invalid_request", rec.Code, body.Error.Code)
+			}
+		})
+	}
+}
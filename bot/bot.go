@@ -19,7 +19,9 @@ import (
 	"github.com/bradselph/CODStatusBot/command/togglecheck"
 	"github.com/bradselph/CODStatusBot/command/updateaccount"
 	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/discordapi"
 	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -52,7 +54,11 @@ func StartBot() (*discordgo.Session, error) {
 	command.RegisterCommands(discord)
 	logger.Log.Info("Registering global commands")
 
-	discord.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	discord.AddHandler(func(ds *discordgo.Session, i *discordgo.InteractionCreate) {
+		s := discordapi.Instrument(ds)
+		if rejectDisabledUser(s, i) {
+			return
+		}
 		switch i.Type {
 		case discordgo.InteractionApplicationCommand:
 			command.HandleCommand(s, i)
@@ -66,7 +72,32 @@ func StartBot() (*discordgo.Session, error) {
 	return discord, nil
 }
 
-func handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+// rejectDisabledUser answers the interaction with the reason an administrator gave for disabling
+// its user, and reports whether it did.
+func rejectDisabledUser(s discordapi.Session, i *discordgo.InteractionCreate) bool {
+	userID, err := services.GetUserID(i)
+	if err != nil {
+		return false
+	}
+	reason, disabled := services.UserDisabledReason(userID)
+	if !disabled {
+		return false
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "Your access to this bot has been disabled. " + reason,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding to disabled user")
+	}
+	return true
+}
+
+func handleModalSubmit(s discordapi.Session, i *discordgo.InteractionCreate) {
 	customID := i.ModalSubmitData().CustomID
 	switch {
 	case strings.HasPrefix(customID, "set_notifications_modal_"):
@@ -74,7 +105,9 @@ func handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	case customID == "set_captcha_service_modal" ||
 		strings.HasPrefix(customID, "set_captcha_service_modal_capsolver") ||
 		strings.HasPrefix(customID, "set_captcha_service_modal_ezcaptcha") ||
-		strings.HasPrefix(customID, "set_captcha_service_modal_2captcha"):
+		strings.HasPrefix(customID, "set_captcha_service_modal_2captcha") ||
+		strings.HasPrefix(customID, "set_captcha_service_modal_anticaptcha") ||
+		strings.HasPrefix(customID, "set_captcha_service_modal_capmonster"):
 		setcaptchaservice.HandleModalSubmit(s, i)
 	case customID == "add_account_modal":
 		addaccount.HandleModalSubmit(s, i)
@@ -89,7 +122,7 @@ func handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 }
 
-func handleMessageComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func handleMessageComponent(s discordapi.Session, i *discordgo.InteractionCreate) {
 	customID := i.MessageComponentData().CustomID
 	switch {
 	case customID == "listaccounts":
@@ -7,13 +7,17 @@ import (
 	"time"
 
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bwmarrin/discordgo"
 )
 
-func CommandAccountAge(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandAccountAge(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	var userID string
 	if i.Member != nil {
 		userID = i.Member.User.ID
@@ -21,7 +25,7 @@ func CommandAccountAge(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -29,12 +33,12 @@ func CommandAccountAge(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	result := database.DB.Where("user_id = ?", userID).Find(&accounts)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching user accounts")
-		respondToInteraction(s, i, "Error fetching your accounts. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_fetch_accounts"))
 		return
 	}
 
 	if len(accounts) == 0 {
-		respondToInteraction(s, i, "You don't have any monitored accounts.")
+		respondToInteraction(s, i, i18n.T(lang, "common.no_accounts"))
 		return
 	}
 
@@ -63,7 +67,7 @@ func CommandAccountAge(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content:    "Select an account to check its age:",
+			Content:    i18n.T(lang, "accountage.select"),
 			Flags:      discordgo.MessageFlagsEphemeral,
 			Components: components,
 		},
@@ -73,12 +77,17 @@ func CommandAccountAge(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 }
 
-func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleAccountSelection(s discordapi.Session, i *discordgo.InteractionCreate) {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
+	lang := services.InteractionLanguage(i)
+
 	customID := i.MessageComponentData().CustomID
 	accountID, err := strconv.Atoi(strings.TrimPrefix(customID, "account_age_"))
 	if err != nil {
 		logger.Log.WithError(err).Error("Error parsing account ID")
-		respondToInteraction(s, i, "Error processing your selection. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_selection"))
 		return
 	}
 
@@ -86,28 +95,29 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	result := database.DB.First(&account, accountID)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching account")
-		respondToInteraction(s, i, "Error: Account not found or you don't have permission to check its age.")
+		respondToInteraction(s, i, i18n.T(lang, "accountage.not_found"))
 		return
 	}
 
-	if !services.VerifySSOCookie(account.SSOCookie) {
+	if !services.VerifySSOCookie(ctx, account.SSOCookie) {
 		account.IsExpiredCookie = true
 		database.DB.Save(&account)
-		respondToInteraction(s, i, "Invalid SSOCookie. Account's cookie status updated.")
+		services.NotifyWebhooksCookieExpired(account)
+		respondToInteraction(s, i, i18n.T(lang, "common.invalid_cookie_updated"))
 		return
 	}
 
-	years, months, days, createdEpoch, err := services.CheckAccountAge(account.SSOCookie)
+	years, months, days, createdEpoch, err := services.CheckAccountAge(ctx, account.SSOCookie)
 	if err != nil {
 		logger.Log.WithError(err).Errorf("Error checking account age for account %s", account.Title)
-		respondToInteraction(s, i, "There was an error checking the account age.")
+		respondToInteraction(s, i, i18n.T(lang, "accountage.error_check"))
 		return
 	}
 
-	isVIP, vipErr := services.CheckVIPStatus(account.SSOCookie)
-	vipStatus := "No"
+	isVIP, vipErr := services.CheckVIPStatus(ctx, account.SSOCookie)
+	vipStatus := i18n.T(lang, "common.no")
 	if vipErr == nil && isVIP {
-		vipStatus = "Yes ⭐"
+		vipStatus = i18n.T(lang, "common.yes") + " ⭐"
 	}
 
 	account.Created = createdEpoch
@@ -115,37 +125,39 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 		logger.Log.WithError(err).Errorf("Error saving account creation timestamp for account %s", account.Title)
 	}
 
-	creationDate := time.Unix(createdEpoch, 0).UTC().Format("January 2, 2006")
+	creationDate := i18n.FormatDate(lang, time.Unix(createdEpoch, 0).UTC())
+	age := i18n.T(lang, "accountage.age", i18n.Plural(lang, "unit.year", years),
+		i18n.Plural(lang, "unit.month", months), i18n.Plural(lang, "unit.day", days))
 
 	embed := &discordgo.MessageEmbed{
-		Title:       fmt.Sprintf("%s - Account Age", account.Title),
-		Description: fmt.Sprintf("The account is %d years, %d months, and %d days old.", years, months, days),
+		Title:       i18n.T(lang, "accountage.title", account.Title),
+		Description: i18n.T(lang, "accountage.description", age),
 		Color:       0x00ff00,
 		Timestamp:   time.Now().Format(time.RFC3339),
 		Fields: []*discordgo.MessageEmbedField{
 			{
-				Name:   "Last Status",
-				Value:  string(account.LastStatus),
+				Name:   i18n.T(lang, "accountage.last_status"),
+				Value:  services.StatusName(lang, account.LastStatus),
 				Inline: true,
 			},
 			{
-				Name:   "VIP Status",
+				Name:   i18n.T(lang, "common.vip_status"),
 				Value:  vipStatus,
 				Inline: true,
 			},
 			{
-				Name:   "Creation Date",
+				Name:   i18n.T(lang, "accountage.creation_date"),
 				Value:  creationDate,
 				Inline: true,
 			},
 			{
-				Name:   "Account Age",
-				Value:  fmt.Sprintf("%d years, %d months, %d days", years, months, days),
+				Name:   i18n.T(lang, "common.account_age"),
+				Value:  age,
 				Inline: true,
 			},
 		},
 		Footer: &discordgo.MessageEmbedFooter{
-			Text: "VIP status indicates priority access to Activision Support services",
+			Text: i18n.T(lang, "accountage.footer"),
 		},
 	}
 
@@ -158,11 +170,11 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	})
 	if err != nil {
 		logger.Log.WithError(err).Error("Error responding to interaction with account age")
-		respondToInteraction(s, i, "Error displaying account age. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "accountage.error_display"))
 	}
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, content string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -7,13 +7,17 @@ import (
 	"time"
 
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bwmarrin/discordgo"
 )
 
-func CommandAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandAccountLogs(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	var userID string
 	if i.Member != nil {
 		userID = i.Member.User.ID
@@ -21,7 +25,7 @@ func CommandAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -29,12 +33,12 @@ func CommandAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	result := database.DB.Where("user_id = ?", userID).Find(&accounts)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching user accounts")
-		respondToInteraction(s, i, "Error fetching your accounts. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_fetch_accounts"))
 		return
 	}
 
 	if len(accounts) == 0 {
-		respondToInteraction(s, i, "You don't have any monitored accounts.")
+		respondToInteraction(s, i, i18n.T(lang, "common.no_accounts"))
 		return
 	}
 
@@ -58,7 +62,7 @@ func CommandAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 	if len(currentRow) < 5 {
 		currentRow = append(currentRow, discordgo.Button{
-			Label:    "View All Logs",
+			Label:    i18n.T(lang, "accountlogs.view_all"),
 			Style:    discordgo.SuccessButton,
 			CustomID: "account_logs_all",
 		})
@@ -66,7 +70,7 @@ func CommandAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		components = append(components, discordgo.ActionsRow{Components: currentRow})
 		currentRow = []discordgo.MessageComponent{
 			discordgo.Button{
-				Label:    "View All Logs",
+				Label:    i18n.T(lang, "accountlogs.view_all"),
 				Style:    discordgo.SuccessButton,
 				CustomID: "account_logs_all",
 			},
@@ -78,7 +82,7 @@ func CommandAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content:    "Select an account to view its logs, or 'View All Logs' to see logs for all accounts:",
+			Content:    i18n.T(lang, "accountlogs.select"),
 			Flags:      discordgo.MessageFlagsEphemeral,
 			Components: components,
 		},
@@ -88,18 +92,19 @@ func CommandAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 }
 
-func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleAccountSelection(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
 	customID := i.MessageComponentData().CustomID
 
 	if customID == "account_logs_all" {
-		handleAllAccountLogs(s, i)
+		handleAllAccountLogs(s, i, lang)
 		return
 	}
 
 	accountID, err := strconv.Atoi(strings.TrimPrefix(customID, "account_logs_"))
 	if err != nil {
 		logger.Log.WithError(err).Error("Error parsing account ID")
-		respondToInteraction(s, i, "Error processing your selection. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_selection"))
 		return
 	}
 
@@ -107,11 +112,11 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	result := database.DB.First(&account, accountID)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching account")
-		respondToInteraction(s, i, "Error: Account not found or you don't have permission to view its logs.")
+		respondToInteraction(s, i, i18n.T(lang, "accountlogs.not_found"))
 		return
 	}
 
-	embed := createAccountLogEmbed(account)
+	embed := createAccountLogEmbed(account, lang)
 
 	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseUpdateMessage,
@@ -122,11 +127,11 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	})
 	if err != nil {
 		logger.Log.WithError(err).Error("Error responding to interaction with account logs")
-		respondToInteraction(s, i, "Error displaying account logs. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "accountlogs.error_display"))
 	}
 }
 
-func handleAllAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func handleAllAccountLogs(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang) {
 	var userID string
 	if i.Member != nil {
 		userID = i.Member.User.ID
@@ -134,7 +139,7 @@ func handleAllAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate)
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -142,13 +147,13 @@ func handleAllAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate)
 	result := database.DB.Where("user_id = ?", userID).Find(&accounts)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching user accounts")
-		respondToInteraction(s, i, "Error fetching your accounts. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_fetch_accounts"))
 		return
 	}
 
 	var embeds []*discordgo.MessageEmbed
 	for _, account := range accounts {
-		embed := createAccountLogEmbed(account)
+		embed := createAccountLogEmbed(account, lang)
 		embeds = append(embeds, embed)
 	}
 
@@ -181,30 +186,30 @@ func handleAllAccountLogs(s *discordgo.Session, i *discordgo.InteractionCreate)
 	}
 }
 
-func createAccountLogEmbed(account models.Account) *discordgo.MessageEmbed {
+func createAccountLogEmbed(account models.Account, lang i18n.Lang) *discordgo.MessageEmbed {
 	var logs []models.Ban
 	database.DB.Where("account_id = ?", account.ID).Order("timestamp desc").Limit(15).Find(&logs)
 
 	embed := &discordgo.MessageEmbed{
-		Title:       fmt.Sprintf("%s - Account History", account.Title),
-		Description: fmt.Sprintf("Recent account history (showing last %d entries)", len(logs)),
+		Title:       i18n.T(lang, "accountlogs.title", account.Title),
+		Description: i18n.T(lang, "accountlogs.description", len(logs)),
 		Color:       services.GetColorForStatus(account.LastStatus, account.IsExpiredCookie, account.IsCheckDisabled),
 		Fields:      make([]*discordgo.MessageEmbedField, 0),
 		Timestamp:   time.Now().Format(time.RFC3339),
 	}
 
 	if len(logs) == 0 {
-		embed.Description = "No history found for this account."
+		embed.Description = i18n.T(lang, "accountlogs.no_history")
 		return embed
 	}
 
 	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-		Name: "Account Information",
-		Value: fmt.Sprintf("Current Status: %s\nChecks: %s\nCreated: %s\nLast Checked: %s",
-			account.LastStatus,
-			services.GetCheckStatus(account.IsCheckDisabled),
-			time.Unix(account.Created, 0).Format("Jan 02, 2006 15:04:05 MST"),
-			time.Unix(account.LastCheck, 0).Format("Jan 02, 2006 15:04:05 MST")),
+		Name: i18n.T(lang, "accountlogs.account_information"),
+		Value: i18n.T(lang, "accountlogs.account_information_value",
+			services.StatusName(lang, account.LastStatus),
+			services.GetCheckStatus(lang, account.IsCheckDisabled),
+			i18n.FormatDateTime(lang, time.Unix(account.Created, 0)),
+			i18n.FormatDateTime(lang, time.Unix(account.LastCheck, 0))),
 		Inline: false,
 	})
 
@@ -213,28 +218,36 @@ func createAccountLogEmbed(account models.Account) *discordgo.MessageEmbed {
 
 		switch log.LogType {
 		case "account_added":
-			fieldValue.WriteString("Account added to monitoring\n")
+			fieldValue.WriteString(i18n.T(lang, "accountlogs.account_added") + "\n")
 		case "status_change":
-			fieldValue.WriteString(fmt.Sprintf("%s -> %s\n", log.PreviousStatus, log.Status))
+			fieldValue.WriteString(fmt.Sprintf("%s -> %s\n", services.StatusName(lang, log.PreviousStatus), services.StatusName(lang, log.Status)))
 			if log.Message != "" {
 				fieldValue.WriteString(fmt.Sprintf("%s\n", log.Message))
 			}
 			if log.AffectedGames != "" {
-				fieldValue.WriteString(fmt.Sprintf("Affected Games: %s\n", log.AffectedGames))
+				fieldValue.WriteString(i18n.T(lang, "accountlogs.affected_games", log.AffectedGames) + "\n")
 			}
 			if log.TempBanDuration != "" {
-				fieldValue.WriteString(fmt.Sprintf("Duration: %s\n", log.TempBanDuration))
+				fieldValue.WriteString(i18n.T(lang, "accountlogs.duration", log.TempBanDuration) + "\n")
+			}
+			if !log.TempBanEndsAt.IsZero() {
+				fieldValue.WriteString(i18n.T(lang, "accountlogs.ends", log.TempBanEndsAt.Unix()) + "\n")
+			}
+			if log.AppealStatus != "" {
+				fieldValue.WriteString(i18n.T(lang, "accountlogs.appeal", log.AppealStatus) + "\n")
 			}
+		case "title_change":
+			fieldValue.WriteString(i18n.T(lang, "accountlogs.title_change", services.StatusName(lang, log.Status)) + "\n" + log.Message + "\n")
 		case "check_status":
 			fieldValue.WriteString(log.Message + "\n")
 		case "cookie_update":
-			fieldValue.WriteString("SSO Cookie updated\n")
+			fieldValue.WriteString(i18n.T(lang, "accountlogs.cookie_updated") + "\n")
 		case "error":
-			fieldValue.WriteString(fmt.Sprintf("Error: %s\n", log.ErrorDetails))
+			fieldValue.WriteString(i18n.T(lang, "accountlogs.error", log.ErrorDetails) + "\n")
 		}
 
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:   fmt.Sprintf("%s", log.Timestamp.Format("Jan 02, 2006 15:04:05 MST")),
+			Name:   i18n.FormatDateTime(lang, log.Timestamp),
 			Value:  fieldValue.String(),
 			Inline: false,
 		})
@@ -243,7 +256,7 @@ func createAccountLogEmbed(account models.Account) *discordgo.MessageEmbed {
 	return embed
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, content string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
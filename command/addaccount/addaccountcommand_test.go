@@ -0,0 +1,210 @@
+package addaccount_test
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/command/addaccount"
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bradselph/CODStatusBot/testsupport/fakeactivision"
+	"github.com/bradselph/CODStatusBot/testsupport/fakecaptcha"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+	"github.com/bwmarrin/discordgo"
+	"github.com/glebarez/sqlite"
+)
+
+const lang = i18n.English
+
+// startAddAccount points the configuration at fake Activision and Capsolver servers and the
+// database at a fresh in-memory SQLite database for the rest of the test.
+func startAddAccount(t *testing.T) *fakeactivision.Server {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := *cfg
+	previousDB := database.DB
+	t.Cleanup(func() {
+		*cfg = saved
+		database.DB = previousDB
+	})
+
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	if err := database.SetEncryptionKeys(key, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	if err := database.Connect(sqlite.Open("file::memory:")); err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	if _, err := database.MigrateUp(database.DB); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	activision := fakeactivision.New()
+	t.Cleanup(activision.Close)
+	activision.Apply(cfg)
+
+	captcha := fakecaptcha.New(fakecaptcha.Capsolver)
+	t.Cleanup(captcha.Close)
+	captcha.Apply(cfg)
+
+	cfg.RateLimits.CheckNow = time.Hour
+	cfg.RateLimits.DefaultMaxAccounts = 2
+	cfg.RateLimits.PremiumMaxAccounts = 10
+	return activision
+}
+
+// message is the ephemeral reply the command sends for anything but the modal.
+func message(content string) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}
+}
+
+func TestCommandAddAccount(t *testing.T) {
+	modal := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "add_account_modal",
+			Title:    i18n.T(lang, "addaccount.modal.title"),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "account_title",
+						Label:       i18n.T(lang, "common.modal.account_title"),
+						Style:       discordgo.TextInputShort,
+						Placeholder: i18n.T(lang, "addaccount.modal.title_placeholder"),
+						Required:    true,
+						MinLength:   3,
+						MaxLength:   40,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "sso_cookie",
+						Label:       i18n.T(lang, "common.modal.sso_cookie"),
+						Style:       discordgo.TextInputParagraph,
+						Placeholder: i18n.T(lang, "common.modal.sso_cookie_placeholder"),
+						Required:    true,
+						MinLength:   60,
+						MaxLength:   95,
+					},
+				}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		settings models.UserSettings
+		accounts int
+		want     *discordgo.InteractionResponse
+	}{
+		{
+			name:     "shows the modal",
+			settings: models.UserSettings{PreferredCaptchaProvider: "capsolver"},
+			want:     modal,
+		},
+		{
+			name:     "shows the modal below the limit",
+			settings: models.UserSettings{PreferredCaptchaProvider: "capsolver"},
+			accounts: 1,
+			want:     modal,
+		},
+		{
+			name:     "preferred service disabled",
+			settings: models.UserSettings{PreferredCaptchaProvider: "ezcaptcha"},
+			want: message(i18n.T(lang, "common.service_disabled", "ezcaptcha") + " " +
+				i18n.T(lang, "addaccount.set_up_service", "Capsolver")),
+		},
+		{
+			name:     "account limit reached",
+			settings: models.UserSettings{PreferredCaptchaProvider: "capsolver"},
+			accounts: 2,
+			want:     message(i18n.T(lang, "addaccount.limit", 2) + " " + i18n.T(lang, "addaccount.limit_upgrade")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startAddAccount(t)
+			settings := tt.settings
+			settings.UserID = "user-1"
+			if err := database.DB.Create(&settings).Error; err != nil {
+				t.Fatalf("create settings: %v", err)
+			}
+			for n := 0; n < tt.accounts; n++ {
+				if err := database.DB.Create(&models.Account{UserID: "user-1", Title: "existing"}).Error; err != nil {
+					t.Fatalf("create account: %v", err)
+				}
+			}
+
+			s := fakediscord.New()
+			addaccount.CommandAddAccount(s, fakediscord.SlashCommand("user-1", "addaccount"))
+
+			responses := s.Responses()
+			if len(responses) != 1 {
+				t.Fatalf("got %d responses, want 1", len(responses))
+			}
+			if got := responses[0].Response; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("response = %+v\nwant %+v", got.Data, tt.want.Data)
+			}
+		})
+	}
+}
+
+func TestHandleModalSubmitRefusesAccount(t *testing.T) {
+	cookie := fakeactivision.Cookie(time.Now().Add(14 * 24 * time.Hour))
+
+	tests := []struct {
+		name     string
+		title    string
+		scenario fakeactivision.Scenario
+		want     *discordgo.InteractionResponse
+	}{
+		{
+			name:     "title too short",
+			title:    "ab",
+			scenario: fakeactivision.Good,
+			want:     message(i18n.T(lang, "addaccount.invalid_title", services.ErrInvalidAccountTitle)),
+		},
+		{
+			name:     "cookie rejected",
+			title:    "main",
+			scenario: fakeactivision.InvalidCookie,
+			want:     message(i18n.T(lang, "common.invalid_cookie")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activision := startAddAccount(t)
+			activision.Script(cookie, tt.scenario)
+
+			s := fakediscord.New()
+			addaccount.HandleModalSubmit(s, fakediscord.ModalSubmit("user-1", "add_account_modal",
+				fakediscord.Field{CustomID: "account_title", Value: tt.title},
+				fakediscord.Field{CustomID: "sso_cookie", Value: cookie},
+			))
+
+			if got := s.LastResponse().Response; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("response = %+v, want %+v", got.Data, tt.want.Data)
+			}
+			if n := len(s.Messages()); n != 0 {
+				t.Errorf("sent %d messages for a refused account, want none", n)
+			}
+			var count int64
+			database.DB.Model(&models.Account{}).Count(&count)
+			if count != 0 {
+				t.Errorf("stored %d accounts, want none", count)
+			}
+		})
+	}
+}
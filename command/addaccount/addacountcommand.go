@@ -1,18 +1,19 @@
 package addaccount
 
 import (
-	"fmt"
+	"errors"
 	"strings"
 	"time"
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bradselph/CODStatusBot/utils"
 	"github.com/bwmarrin/discordgo"
-	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -24,65 +25,57 @@ func init() {
 	rateLimit = cfg.RateLimits.CheckNow
 }
 
-func getMaxAccounts(hasCustomKey bool) int {
-	cfg := configuration.Get()
-	if hasCustomKey {
-		return cfg.RateLimits.PremiumMaxAccounts
-	}
-	return cfg.RateLimits.DefaultMaxAccounts
-}
+func CommandAddAccount(s discordapi.Session, i *discordgo.InteractionCreate) {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
+	lang := services.InteractionLanguage(i)
 
-func CommandAddAccount(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	userID := getUserID(i)
 	if userID == "" {
 		logger.Log.Error("Failed to get user ID")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching user settings. Please try again.")
-		return
-	}
-
-	hasCustomKey := userSettings.CapSolverAPIKey != "" || userSettings.EZCaptchaAPIKey != "" || userSettings.TwoCaptchaAPIKey != ""
-	if !hasCustomKey && !checkRateLimit(userID) {
-		respondToInteraction(s, i, fmt.Sprintf("Please wait %v before adding another account.", rateLimit))
+		respondToInteraction(s, i, i18n.T(lang, "common.error_settings"))
 		return
 	}
 
-	if !hasCustomKey && !checkRateLimit(userID) {
-		respondToInteraction(s, i, fmt.Sprintf("Please wait %v before adding another account.", rateLimit))
+	hasCustomKey := userSettings.HasCustomCaptchaKey()
+	if !hasCustomKey && !services.AllowAddAccount(userID) {
+		respondToInteraction(s, i, i18n.T(lang, "addaccount.rate_limited", services.FormatDuration(lang, rateLimit)))
 		return
 	}
 
 	if !services.IsServiceEnabled(userSettings.PreferredCaptchaProvider) {
-		msg := fmt.Sprintf("Your preferred captcha service (%s) is currently disabled. ", userSettings.PreferredCaptchaProvider)
+		msg := i18n.T(lang, "common.service_disabled", userSettings.PreferredCaptchaProvider) + " "
 		if services.IsServiceEnabled("ezcaptcha") {
-			msg += "Please set up EZCaptcha using /setcaptchaservice before adding accounts."
+			msg += i18n.T(lang, "addaccount.set_up_service", "EZCaptcha")
 		} else if services.IsServiceEnabled("2captcha") {
-			msg += "Please set up 2Captcha using /setcaptchaservice before adding accounts."
+			msg += i18n.T(lang, "addaccount.set_up_service", "2Captcha")
 		} else if services.IsServiceEnabled("capsolver") {
-			msg += "Please set up Capsolver using /setcaptchaservice before adding accounts."
+			msg += i18n.T(lang, "addaccount.set_up_service", "Capsolver")
 		} else {
-			msg += "No captcha services are currently available. Please try again later."
+			msg += i18n.T(lang, "common.no_captcha_services")
 		}
 		respondToInteraction(s, i, msg)
 		return
 	}
 
-	if userSettings.CapSolverAPIKey != "" || userSettings.EZCaptchaAPIKey != "" || userSettings.TwoCaptchaAPIKey != "" {
-		_, balance, err := services.GetUserCaptchaKey(userID)
+	if userSettings.HasCustomCaptchaKey() {
+		_, balance, err := services.GetUserCaptchaKey(ctx, userID)
 		if err != nil {
 			logger.Log.WithError(err).Error("Error checking captcha balance")
-			respondToInteraction(s, i, "Error validating your captcha API key. Please check your key using /setcaptchaservice.")
+			respondToInteraction(s, i, i18n.T(lang, "common.error_key"))
 			return
 		}
 
 		if balance <= 0 {
-			respondToInteraction(s, i, fmt.Sprintf("Your captcha balance (%.2f) is too low to add new accounts. Please recharge your balance.", balance))
+			respondToInteraction(s, i, i18n.T(lang, "addaccount.balance_low", balance))
 			return
 		}
 	}
@@ -90,40 +83,32 @@ func CommandAddAccount(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	var accountCount int64
 	if err := database.DB.Model(&models.Account{}).Where("user_id = ?", userID).Count(&accountCount).Error; err != nil {
 		logger.Log.WithError(err).Error("Error counting user accounts")
-		respondToInteraction(s, i, "Error checking account limit. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "addaccount.error_limit"))
 		return
 	}
 
-	maxAccounts := getMaxAccounts(hasCustomKey)
-
-	if accountCount >= int64(maxAccounts) {
-		msg := fmt.Sprintf("You've reached the maximum limit of %d accounts.", maxAccounts)
-		if !hasCustomKey {
-			msg += " Upgrade to premium by adding your own API key using /setcaptchaservice to increase your account limit!"
-		} else {
-			msg += " Please remove some accounts before adding new ones."
-		}
-		respondToInteraction(s, i, msg)
+	if maxAccounts := services.MaxAccounts(hasCustomKey); accountCount >= int64(maxAccounts) {
+		respondToInteraction(s, i, accountLimitMessage(lang, &services.AccountLimitError{Max: maxAccounts, HasCustomKey: hasCustomKey}))
 		return
 	}
 
-	showAddAccountModal(s, i)
+	showAddAccountModal(s, i, lang)
 }
 
-func showAddAccountModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func showAddAccountModal(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseModal,
 		Data: &discordgo.InteractionResponseData{
 			CustomID: "add_account_modal",
-			Title:    "Add New Account",
+			Title:    i18n.T(lang, "addaccount.modal.title"),
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.TextInput{
 							CustomID:    "account_title",
-							Label:       "Account Title",
+							Label:       i18n.T(lang, "common.modal.account_title"),
 							Style:       discordgo.TextInputShort,
-							Placeholder: "Enter a name for this account",
+							Placeholder: i18n.T(lang, "addaccount.modal.title_placeholder"),
 							Required:    true,
 							MinLength:   3,
 							MaxLength:   40,
@@ -134,9 +119,9 @@ func showAddAccountModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
 					Components: []discordgo.MessageComponent{
 						discordgo.TextInput{
 							CustomID:    "sso_cookie",
-							Label:       "SSO Cookie",
+							Label:       i18n.T(lang, "common.modal.sso_cookie"),
 							Style:       discordgo.TextInputParagraph,
-							Placeholder: "Enter the SSO cookie for this account",
+							Placeholder: i18n.T(lang, "common.modal.sso_cookie_placeholder"),
 							Required:    true,
 							MinLength:   60,
 							MaxLength:   95,
@@ -151,12 +136,17 @@ func showAddAccountModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 }
 
-func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleModalSubmit(s discordapi.Session, i *discordgo.InteractionCreate) {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
+	lang := services.InteractionLanguage(i)
+
 	data := i.ModalSubmitData()
 	userID := getUserID(i)
 	if userID == "" {
 		logger.Log.Error("Failed to get user ID")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -165,136 +155,90 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 	logger.Log.Infof("Attempting to add account. Title: %s, SSO Cookie length: %d", title, len(ssoCookie))
 
-	validationResult, err := services.ValidateAndGetAccountInfo(ssoCookie)
-	if err != nil {
-		logger.Log.WithError(err).Error("Error validating account")
-		respondToInteraction(s, i, fmt.Sprintf("Error processing account: %v", err))
-		return
-	}
-
-	if !validationResult.IsValid {
-		logger.Log.Error("Invalid SSO cookie provided")
-		respondToInteraction(s, i, "Invalid SSO cookie. Please make sure you've copied the entire cookie value.")
-		return
-	}
-
 	channelID := getChannelID(s, i)
 	if channelID == "" {
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching user settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_settings"))
 		return
 	}
 
-	var accountCount int64
-	if err := database.DB.Model(&models.Account{}).Where("user_id = ?", userID).Count(&accountCount).Error; err != nil {
-		logger.Log.WithError(err).Error("Error counting user accounts")
-		respondToInteraction(s, i, "Error checking account limit. Please try again.")
+	account, remainingSlots, err := services.AddAccount(ctx, userID, title, ssoCookie, channelID)
+	var limitErr *services.AccountLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		respondToInteraction(s, i, accountLimitMessage(lang, limitErr))
 		return
-	}
-
-	hasCustomKey := userSettings.CapSolverAPIKey != "" || userSettings.EZCaptchaAPIKey != "" || userSettings.TwoCaptchaAPIKey != ""
-	maxAccounts := getMaxAccounts(hasCustomKey)
-
-	if accountCount >= int64(maxAccounts) {
-		msg := fmt.Sprintf("You've reached the maximum limit of %d accounts.", maxAccounts)
-		if !hasCustomKey {
-			msg += " Upgrade to premium by adding your own API key using /setcaptchaservice to increase your account limit!"
-		} else {
-			msg += " Please remove some accounts before adding new ones."
-		}
-		respondToInteraction(s, i, msg)
+	case errors.Is(err, services.ErrInvalidSSOCookie):
+		logger.Log.Error("Invalid SSO cookie provided")
+		respondToInteraction(s, i, i18n.T(lang, "common.invalid_cookie"))
 		return
-	}
-
-	account := models.Account{
-		UserID:              userID,
-		Title:               title,
-		SSOCookie:           ssoCookie,
-		SSOCookieExpiration: validationResult.ExpiresAt,
-		Created:             validationResult.Created,
-		IsVIP:               validationResult.IsVIP,
-		ChannelID:           channelID,
-		NotificationType:    userSettings.NotificationType,
-		LastSuccessfulCheck: time.Now(),
-		LastStatus:          models.StatusUnknown,
-	}
-
-	if err := database.DB.Create(&account).Error; err != nil {
-		logger.Log.WithError(err).WithFields(logrus.Fields{"userID": userID, "title": title}).Error("Error creating account")
-		respondToInteraction(s, i, "Error creating account. Please try again.")
+	case errors.Is(err, services.ErrInvalidAccountTitle):
+		respondToInteraction(s, i, i18n.T(lang, "addaccount.invalid_title", err))
+		return
+	case err != nil:
+		logger.Log.WithError(err).Error("Error adding account")
+		respondToInteraction(s, i, i18n.T(lang, "addaccount.error_add", err))
 		return
 	}
 
-	accountLog := models.Ban{
-		AccountID: account.ID,
-		Status:    models.StatusUnknown,
-		LogType:   "account_added",
-		Message:   fmt.Sprintf("Account '%s' was added to monitoring", account.Title),
-		Timestamp: time.Now(),
-	}
-
-	if err := database.DB.Create(&accountLog).Error; err != nil {
-		logger.Log.WithError(err).Error("Failed to create account creation log")
-	}
-
-	vipStatus := "Regular Account"
+	vipStatus := i18n.T(lang, "notify.regular_account")
 	if account.IsVIP {
-		vipStatus = "VIP Account"
+		vipStatus = i18n.T(lang, "notify.vip_account")
 	}
 
-	remainingSlots := maxAccounts - int(accountCount) - 1
-	slotInfo := fmt.Sprintf("\nYou have %d account slot(s) remaining.", remainingSlots)
-
 	embed := &discordgo.MessageEmbed{
-		Title:       "Account Added Successfully",
-		Description: fmt.Sprintf("Account '%s' has been added to monitoring.%s", account.Title, slotInfo),
+		Title:       i18n.T(lang, "addaccount.added.title"),
+		Description: i18n.T(lang, "addaccount.added.description", account.Title) + "\n" + i18n.Plural(lang, "addaccount.slots_remaining", remainingSlots),
 		Color:       0x00ff00,
 		Fields: []*discordgo.MessageEmbedField{
 			{
-				Name:   "Account Type",
+				Name:   i18n.T(lang, "addaccount.account_type"),
 				Value:  vipStatus,
 				Inline: true,
 			},
 			{
-				Name:   "Cookie Expiration",
-				Value:  services.FormatExpirationTime(account.SSOCookieExpiration),
+				Name:   i18n.T(lang, "common.cookie_expiration"),
+				Value:  services.FormatExpirationTime(lang, account.SSOCookieExpiration),
 				Inline: true,
 			},
 			{
-				Name:   "Account Age",
-				Value:  formatAccountAge(time.Unix(account.Created, 0)),
+				Name:   i18n.T(lang, "common.account_age"),
+				Value:  services.FormatAccountAge(lang, time.Unix(account.Created, 0)),
 				Inline: true,
 			},
 			{
-				Name:   "Notification Type",
+				Name:   i18n.T(lang, "common.notification_type"),
 				Value:  account.NotificationType,
 				Inline: true,
 			},
 		},
 		Timestamp: time.Now().Format(time.RFC3339),
 		Footer: &discordgo.MessageEmbedFooter{
-			Text: "Use /listaccounts to view all your monitored accounts",
+			Text: i18n.T(lang, "addaccount.added.footer"),
 		},
 	}
 
 	if err := services.SendNotification(s, account, embed, "", "account_added"); err != nil {
 		logger.Log.WithError(err).Error("Failed to send account added notification")
-		respondToInteraction(s, i, fmt.Sprintf("Account added successfully, but there was an error sending the confirmation message: %v", err))
+		respondToInteraction(s, i, i18n.T(lang, "addaccount.error_confirmation", err))
 		return
 	}
 
-	respondToInteraction(s, i, "Account added successfully!")
+	respondToInteraction(s, i, i18n.T(lang, "addaccount.success"))
 
 	go func() {
+		ctx, cancel := services.CommandContext()
+		defer cancel()
+
 		time.Sleep(2 * time.Second)
 
-		status, err := services.CheckAccount(ssoCookie, userID, "")
+		check, err := services.CheckAccount(ctx, ssoCookie, userID, "")
 		if err != nil {
 			logger.Log.WithError(err).Error("Error performing initial status check")
 			return
@@ -306,17 +250,10 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 			return
 		}
 
-		services.HandleStatusChange(s, updatedAccount, status, userSettings)
+		services.HandleStatusChange(ctx, s, updatedAccount, check, userSettings)
 	}()
 }
 
-func formatAccountAge(created time.Time) string {
-	age := time.Since(created)
-	years := int(age.Hours() / 24 / 365)
-	months := int(age.Hours()/24/30.44) % 12
-	return fmt.Sprintf("%d years, %d months", years, months)
-}
-
 func getUserID(i *discordgo.InteractionCreate) string {
 	if i.Member != nil {
 		return i.Member.User.ID
@@ -327,7 +264,7 @@ func getUserID(i *discordgo.InteractionCreate) string {
 	return ""
 }
 
-func getChannelID(s *discordgo.Session, i *discordgo.InteractionCreate) string {
+func getChannelID(s discordapi.Session, i *discordgo.InteractionCreate) string {
 	userID := getUserID(i)
 	if userID == "" {
 		return ""
@@ -344,29 +281,19 @@ func getChannelID(s *discordgo.Session, i *discordgo.InteractionCreate) string {
 	}
 	return channel.ID
 }
-func checkRateLimit(userID string) bool {
-	var userSettings models.UserSettings
-	if err := database.DB.Where("user_id = ?", userID).First(&userSettings).Error; err != nil {
-		logger.Log.WithError(err).Error("Error fetching user settings")
-		return false
-	}
-
-	userSettings.EnsureMapsInitialized()
-	now := time.Now()
-	lastAddTime := userSettings.LastCommandTimes["add_account"]
 
-	if lastAddTime.IsZero() || time.Since(lastAddTime) >= rateLimit {
-		userSettings.LastCommandTimes["add_account"] = now
-		if err := database.DB.Save(&userSettings).Error; err != nil {
-			logger.Log.WithError(err).Error("Error saving user settings")
-			return false
-		}
-		return true
+// accountLimitMessage explains the account limit and how to raise it.
+func accountLimitMessage(lang i18n.Lang, limitErr *services.AccountLimitError) string {
+	msg := i18n.T(lang, "addaccount.limit", limitErr.Max)
+	if !limitErr.HasCustomKey {
+		msg += " " + i18n.T(lang, "addaccount.limit_upgrade")
+	} else {
+		msg += " " + i18n.T(lang, "addaccount.limit_remove")
 	}
-	return false
+	return msg
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
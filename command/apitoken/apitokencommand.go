@@ -0,0 +1,164 @@
+package apitoken
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bradselph/CODStatusBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+func CommandAPIToken(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
+	userID, err := services.GetUserID(i)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to get user ID")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
+		return
+	}
+
+	if configuration.Get().RESTAPI.Addr == "" {
+		respondToInteraction(s, i, i18n.T(lang, "apitoken.disabled"))
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "apitoken.choose"))
+		return
+	}
+
+	subcommand := options[0]
+	switch subcommand.Name {
+	case "create":
+		createToken(s, i, lang, userID, subcommand.Options)
+	case "list":
+		listTokens(s, i, lang, userID)
+	case "revoke":
+		revokeToken(s, i, lang, userID, subcommand.Options)
+	default:
+		respondToInteraction(s, i, i18n.T(lang, "common.unknown_subcommand"))
+	}
+}
+
+func createToken(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var name string
+	for _, option := range options {
+		if option.Name == "name" {
+			name = utils.SanitizeInput(option.StringValue())
+		}
+	}
+	if name == "" {
+		name = i18n.T(lang, "apitoken.default_name")
+	}
+
+	token, record, err := services.CreateAPIToken(userID, name)
+	if errors.Is(err, services.ErrAPITokenLimit) {
+		respondToInteraction(s, i, i18n.T(lang, "apitoken.limit", services.MaxAPITokensPerUser))
+		return
+	}
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to create API token for user %s", userID)
+		respondToInteraction(s, i, i18n.T(lang, "apitoken.error_create"))
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "apitoken.created.title"),
+		Description: i18n.T(lang, "apitoken.created.description", record.Name, record.ID, token),
+		Color:       0x00ff00,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+	respondWithEmbed(s, i, embed)
+}
+
+func listTokens(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string) {
+	tokens, err := services.ListAPITokens(userID)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to list API tokens for user %s", userID)
+		respondToInteraction(s, i, i18n.T(lang, "apitoken.error_list"))
+		return
+	}
+
+	if len(tokens) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "apitoken.none"))
+		return
+	}
+
+	var lines []string
+	for _, token := range tokens {
+		lastUsed := i18n.T(lang, "apitoken.never_used")
+		if !token.LastUsedAt.IsZero() {
+			lastUsed = i18n.T(lang, "apitoken.last_used", fmt.Sprintf("<t:%d:R>", token.LastUsedAt.Unix()))
+		}
+		lines = append(lines, fmt.Sprintf("**%d** · %s · `%s…` · %s · %s",
+			token.ID, token.Name, token.Prefix, i18n.T(lang, "apitoken.created_at", fmt.Sprintf("<t:%d:d>", token.CreatedAt.Unix())), lastUsed))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "apitoken.list.title"),
+		Description: strings.Join(lines, "\n"),
+		Color:       0x00ff00,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: i18n.T(lang, "apitoken.list.footer", len(tokens), services.MaxAPITokensPerUser),
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	respondWithEmbed(s, i, embed)
+}
+
+func revokeToken(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var tokenID int64
+	for _, option := range options {
+		if option.Name == "id" {
+			tokenID = option.IntValue()
+		}
+	}
+
+	err := services.RevokeAPIToken(userID, uint(tokenID))
+	if errors.Is(err, services.ErrAPITokenNotFound) {
+		respondToInteraction(s, i, i18n.T(lang, "apitoken.not_found", tokenID))
+		return
+	}
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to revoke API token %d for user %s", tokenID, userID)
+		respondToInteraction(s, i, i18n.T(lang, "apitoken.error_revoke"))
+		return
+	}
+
+	respondToInteraction(s, i, i18n.T(lang, "apitoken.revoked", tokenID))
+}
+
+func respondWithEmbed(s discordapi.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding to interaction with embed")
+	}
+}
+
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding to interaction")
+	}
+}
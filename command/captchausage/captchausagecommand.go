@@ -0,0 +1,138 @@
+package captchausage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bwmarrin/discordgo"
+)
+
+func CommandCaptchaUsage(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
+	userID, err := services.GetUserID(i)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to get user ID")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
+		return
+	}
+
+	var notice string
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name != "monthly_budget" {
+			continue
+		}
+		budget := option.FloatValue()
+		if err := services.SetMonthlyCaptchaBudget(userID, budget); err != nil {
+			logger.Log.WithError(err).Errorf("Failed to set captcha budget for user %s", userID)
+			respondToInteraction(s, i, i18n.T(lang, "captchausage.error_budget"))
+			return
+		}
+		if budget == 0 {
+			notice = i18n.T(lang, "captchausage.budget_removed")
+		} else {
+			notice = i18n.T(lang, "captchausage.budget_set", budget)
+		}
+	}
+
+	report, err := services.GetCaptchaUsage(userID)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to load captcha usage for user %s", userID)
+		respondToInteraction(s, i, i18n.T(lang, "captchausage.error_load"))
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: notice,
+			Embeds:  []*discordgo.MessageEmbed{createUsageEmbed(lang, report)},
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding with captcha usage")
+	}
+}
+
+func createUsageEmbed(lang i18n.Lang, report services.CaptchaUsageReport) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "captchausage.title"),
+		Description: i18n.T(lang, "captchausage.description"),
+		Color:       0x00ff00,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   i18n.T(lang, "captchausage.today"),
+				Value:  formatUsage(lang, report.Today),
+				Inline: true,
+			},
+			{
+				Name:   i18n.T(lang, "captchausage.month"),
+				Value:  formatUsage(lang, report.Month),
+				Inline: true,
+			},
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	if len(report.ByProvider) > 0 {
+		names := make([]string, 0, len(report.ByProvider))
+		for name := range report.ByProvider {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var lines []string
+		for _, name := range names {
+			usage := report.ByProvider[name]
+			label := name
+			if p, ok := services.LookupCaptchaProvider(name); ok {
+				label = p.DisplayName
+			}
+			lines = append(lines, label+": "+i18n.T(lang, "captchausage.provider_line", usage.Solves, usage.Cost))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   i18n.T(lang, "captchausage.by_provider"),
+			Value:  strings.Join(lines, "\n"),
+			Inline: false,
+		})
+	}
+
+	budget := i18n.T(lang, "captchausage.no_budget")
+	if report.Budget > 0 {
+		budget = i18n.T(lang, "captchausage.budget_used", report.Month.Cost, report.Budget)
+		if report.BudgetReached() {
+			budget += "\n" + i18n.T(lang, "captchausage.budget_reached")
+			embed.Color = 0xFFA500
+		}
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:   i18n.T(lang, "captchausage.budget"),
+		Value:  budget,
+		Inline: false,
+	})
+
+	return embed
+}
+
+func formatUsage(lang i18n.Lang, usage services.CaptchaUsage) string {
+	return i18n.T(lang, "captchausage.usage", usage.Solves, usage.Successes, usage.Cost)
+}
+
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding to interaction")
+	}
+}
@@ -6,12 +6,19 @@ import (
 	"time"
 
 	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bwmarrin/discordgo"
 )
 
-func CommandCheckCaptchaBalance(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandCheckCaptchaBalance(s discordapi.Session, i *discordgo.InteractionCreate) {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
+	lang := services.InteractionLanguage(i)
+
 	var userID string
 	if i.Member != nil {
 		userID = i.Member.User.ID
@@ -19,49 +26,39 @@ func CommandCheckCaptchaBalance(s *discordgo.Session, i *discordgo.InteractionCr
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching your settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_settings"))
 		return
 	}
 
 	cfg := configuration.Get()
 
 	// Initial check for any enabled services
-	if !services.IsServiceEnabled("capsolver") &&
-		!services.IsServiceEnabled("ezcaptcha") &&
-		!services.IsServiceEnabled("2captcha") {
-		respondToInteraction(s, i, "No captcha services are currently available. Please try again later.")
+	if len(services.EnabledCaptchaProviders()) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "common.no_captcha_services"))
 		return
 	}
 
 	embed := &discordgo.MessageEmbed{
-		Title:     "Captcha Service Status",
+		Title:     i18n.T(lang, "checkcaptchabalance.title"),
 		Color:     0x00ff00,
 		Timestamp: time.Now().Format(time.RFC3339),
 		Fields:    []*discordgo.MessageEmbedField{},
 	}
 
 	var availableServices []string
-	if services.IsServiceEnabled("capsolver") {
-		availableServices = append(availableServices, "Capsolver")
-	}
-	if services.IsServiceEnabled("ezcaptcha") {
-		availableServices = append(availableServices, "EZCaptcha")
-	}
-	if services.IsServiceEnabled("2captcha") {
-		availableServices = append(availableServices, "2Captcha")
+	for _, p := range services.EnabledCaptchaProviders() {
+		availableServices = append(availableServices, p.DisplayName)
 	}
 
 	if !services.IsServiceEnabled(userSettings.PreferredCaptchaProvider) {
-		embed.Description = fmt.Sprintf("Warning: Your preferred service (%s) is currently disabled.\n"+
-			"Available services: %s\n"+
-			"Use /setcaptchaservice to switch to an available service.",
+		embed.Description = i18n.T(lang, "checkcaptchabalance.preferred_disabled",
 			userSettings.PreferredCaptchaProvider,
 			strings.Join(availableServices, ", "))
 		embed.Color = 0xFFA500
@@ -70,11 +67,11 @@ func CommandCheckCaptchaBalance(s *discordgo.Session, i *discordgo.InteractionCr
 	hasUserKey := false
 
 	if userSettings.CapSolverAPIKey != "" && services.IsServiceEnabled("capsolver") {
-		isValid, balance, err := services.ValidateCaptchaKey(userSettings.CapSolverAPIKey, "capsolver")
+		isValid, balance, err := services.ValidateCaptchaKey(ctx, userSettings.CapSolverAPIKey, "capsolver")
 		if err == nil && isValid {
 			hasUserKey = true
 			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "Capsolver Balance",
+				Name:   i18n.T(lang, "checkcaptchabalance.balance", "Capsolver"),
 				Value:  fmt.Sprintf("$%.3f", balance),
 				Inline: true,
 			})
@@ -82,23 +79,47 @@ func CommandCheckCaptchaBalance(s *discordgo.Session, i *discordgo.InteractionCr
 	}
 
 	if userSettings.EZCaptchaAPIKey != "" && services.IsServiceEnabled("ezcaptcha") {
-		isValid, balance, err := services.ValidateCaptchaKey(userSettings.EZCaptchaAPIKey, "ezcaptcha")
+		isValid, balance, err := services.ValidateCaptchaKey(ctx, userSettings.EZCaptchaAPIKey, "ezcaptcha")
 		if err == nil && isValid {
 			hasUserKey = true
 			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "EZCaptcha Balance",
-				Value:  fmt.Sprintf("%.0f points", balance),
+				Name:   i18n.T(lang, "checkcaptchabalance.balance", "EZCaptcha"),
+				Value:  i18n.T(lang, "checkcaptchabalance.points", balance),
 				Inline: true,
 			})
 		}
 	}
 
 	if userSettings.TwoCaptchaAPIKey != "" && services.IsServiceEnabled("2captcha") {
-		isValid, balance, err := services.ValidateCaptchaKey(userSettings.TwoCaptchaAPIKey, "2captcha")
+		isValid, balance, err := services.ValidateCaptchaKey(ctx, userSettings.TwoCaptchaAPIKey, "2captcha")
 		if err == nil && isValid {
 			hasUserKey = true
 			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "2Captcha Balance",
+				Name:   i18n.T(lang, "checkcaptchabalance.balance", "2Captcha"),
+				Value:  fmt.Sprintf("$%.2f", balance),
+				Inline: true,
+			})
+		}
+	}
+
+	if userSettings.AntiCaptchaAPIKey != "" && services.IsServiceEnabled("anticaptcha") {
+		isValid, balance, err := services.ValidateCaptchaKey(ctx, userSettings.AntiCaptchaAPIKey, "anticaptcha")
+		if err == nil && isValid {
+			hasUserKey = true
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:   i18n.T(lang, "checkcaptchabalance.balance", "Anti-Captcha"),
+				Value:  fmt.Sprintf("$%.2f", balance),
+				Inline: true,
+			})
+		}
+	}
+
+	if userSettings.CapMonsterAPIKey != "" && services.IsServiceEnabled("capmonster") {
+		isValid, balance, err := services.ValidateCaptchaKey(ctx, userSettings.CapMonsterAPIKey, "capmonster")
+		if err == nil && isValid {
+			hasUserKey = true
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:   i18n.T(lang, "checkcaptchabalance.balance", "CapMonster Cloud"),
 				Value:  fmt.Sprintf("$%.2f", balance),
 				Inline: true,
 			})
@@ -106,19 +127,19 @@ func CommandCheckCaptchaBalance(s *discordgo.Session, i *discordgo.InteractionCr
 	}
 
 	if !hasUserKey {
-		embed.Description = "You are currently using the bot's default API key. Consider setting up your own key using /setcaptchaservice for unlimited checks."
+		embed.Description = i18n.T(lang, "checkcaptchabalance.default_key")
 		embed.Color = 0xFFA500
 
 		if services.IsServiceEnabled(userSettings.PreferredCaptchaProvider) {
 			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "Default Service",
+				Name:   i18n.T(lang, "checkcaptchabalance.default_service"),
 				Value:  userSettings.PreferredCaptchaProvider,
 				Inline: true,
 			})
 		}
 	} else {
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:   "Preferred Provider",
+			Name:   i18n.T(lang, "checkcaptchabalance.preferred_provider"),
 			Value:  userSettings.PreferredCaptchaProvider,
 			Inline: false,
 		})
@@ -129,15 +150,21 @@ func CommandCheckCaptchaBalance(s *discordgo.Session, i *discordgo.InteractionCr
 		thresholds = append(thresholds, fmt.Sprintf("Capsolver: $%.3f", cfg.CaptchaService.Capsolver.BalanceMin))
 	}
 	if services.IsServiceEnabled("ezcaptcha") {
-		thresholds = append(thresholds, fmt.Sprintf("EZCaptcha: %.0f points", cfg.CaptchaService.EZCaptcha.BalanceMin))
+		thresholds = append(thresholds, "EZCaptcha: "+i18n.T(lang, "checkcaptchabalance.points", cfg.CaptchaService.EZCaptcha.BalanceMin))
 	}
 	if services.IsServiceEnabled("2captcha") {
 		thresholds = append(thresholds, fmt.Sprintf("2Captcha: $%.2f", cfg.CaptchaService.TwoCaptcha.BalanceMin))
 	}
+	if services.IsServiceEnabled("anticaptcha") {
+		thresholds = append(thresholds, fmt.Sprintf("Anti-Captcha: $%.2f", cfg.CaptchaService.AntiCaptcha.BalanceMin))
+	}
+	if services.IsServiceEnabled("capmonster") {
+		thresholds = append(thresholds, fmt.Sprintf("CapMonster Cloud: $%.2f", cfg.CaptchaService.CapMonster.BalanceMin))
+	}
 
 	if len(thresholds) > 0 {
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:   "Minimum Balance Thresholds",
+			Name:   i18n.T(lang, "checkcaptchabalance.thresholds"),
 			Value:  strings.Join(thresholds, "\n"),
 			Inline: false,
 		})
@@ -155,7 +182,7 @@ func CommandCheckCaptchaBalance(s *discordgo.Session, i *discordgo.InteractionCr
 	}
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
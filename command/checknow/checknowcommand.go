@@ -1,6 +1,8 @@
 package checknow
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,10 +11,13 @@ import (
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bradselph/CODStatusBot/services"
 
+	"github.com/bradselph/CODStatusBot/discordapi"
+
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -27,29 +32,34 @@ func init() {
 	rateLimit = cfg.RateLimits.CheckNow
 }
 
-func CommandCheckNow(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandCheckNow(s discordapi.Session, i *discordgo.InteractionCreate) {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
+	lang := services.InteractionLanguage(i)
+
 	userID, err := getUserID(i)
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to get user ID")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching user settings. Please try again later.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_settings"))
 		return
 	}
 
 	if !services.IsServiceEnabled(userSettings.PreferredCaptchaProvider) {
-		msg := fmt.Sprintf("Your preferred captcha service (%s) is currently disabled. ", userSettings.PreferredCaptchaProvider)
+		msg := i18n.T(lang, "common.service_disabled", userSettings.PreferredCaptchaProvider) + " "
 		if services.IsServiceEnabled("ezcaptcha") {
-			msg += "Please switch to EZCaptcha using /setcaptchaservice."
+			msg += i18n.T(lang, "common.switch_service", "EZCaptcha")
 		} else if services.IsServiceEnabled("2captcha") {
-			msg += "Please switch to 2Captcha using /setcaptchaservice."
+			msg += i18n.T(lang, "common.switch_service", "2Captcha")
 		} else {
-			msg += "No captcha services are currently available. Please try again later."
+			msg += i18n.T(lang, "common.no_captcha_services")
 		}
 		respondToInteraction(s, i, msg)
 		return
@@ -57,21 +67,21 @@ func CommandCheckNow(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 	if userSettings.CapSolverAPIKey != "" && userSettings.EZCaptchaAPIKey == "" && userSettings.TwoCaptchaAPIKey == "" {
 		if !checkRateLimit(userID) {
-			respondToInteraction(s, i, fmt.Sprintf("You're using the bot's default API key and are rate limited. Please wait %v before trying again, or set up your own API key using /setcaptchaservice for unlimited checks.", rateLimit))
+			respondToInteraction(s, i, i18n.T(lang, "checknow.rate_limited", services.FormatDuration(lang, rateLimit)))
 			return
 		}
 	}
 
-	if userSettings.CapSolverAPIKey != "" || userSettings.EZCaptchaAPIKey != "" || userSettings.TwoCaptchaAPIKey != "" {
-		_, balance, err := services.GetUserCaptchaKey(userID)
+	if userSettings.HasCustomCaptchaKey() {
+		_, balance, err := services.GetUserCaptchaKey(ctx, userID)
 		if err != nil {
 			logger.Log.WithError(err).Error("Error getting captcha key")
-			respondToInteraction(s, i, "Error validating your captcha API key. Please check your key using /setcaptchaservice.")
+			respondToInteraction(s, i, i18n.T(lang, "common.error_key"))
 			return
 		}
 
 		if balance < 0 {
-			respondToInteraction(s, i, fmt.Sprintf("Your captcha balance (%.2f) is too low for checking accounts. Please recharge your balance.", balance))
+			respondToInteraction(s, i, i18n.T(lang, "checknow.balance_low", balance))
 			return
 		}
 	}
@@ -82,23 +92,23 @@ func CommandCheckNow(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching accounts")
-		respondToInteraction(s, i, "Error fetching accounts. Please try again later.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_fetch_accounts"))
 		return
 	}
 
 	if len(accounts) == 0 {
-		respondToInteraction(s, i, "You don't have any monitored accounts.")
+		respondToInteraction(s, i, i18n.T(lang, "common.no_accounts"))
 		return
 	}
 
-	showAccountButtons(s, i, accounts)
+	showAccountButtons(s, i, lang, accounts)
 }
 
-func showAccountButtons(s *discordgo.Session, i *discordgo.InteractionCreate, accounts []models.Account) {
+func showAccountButtons(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, accounts []models.Account) {
 	userID, err := getUserID(i)
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to get user ID")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -120,7 +130,7 @@ func showAccountButtons(s *discordgo.Session, i *discordgo.InteractionCreate, ac
 
 	if len(currentRow) < 5 {
 		currentRow = append(currentRow, discordgo.Button{
-			Label:    "Check All",
+			Label:    i18n.T(lang, "checknow.check_all"),
 			Style:    discordgo.SuccessButton,
 			CustomID: fmt.Sprintf("check_now_%s_all", userID),
 		})
@@ -133,7 +143,7 @@ func showAccountButtons(s *discordgo.Session, i *discordgo.InteractionCreate, ac
 	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content:    "Select an account to check, or 'Check All' to check all accounts:",
+			Content:    i18n.T(lang, "checknow.select", i18n.T(lang, "checknow.check_all")),
 			Flags:      discordgo.MessageFlagsEphemeral,
 			Components: components,
 		},
@@ -143,13 +153,18 @@ func showAccountButtons(s *discordgo.Session, i *discordgo.InteractionCreate, ac
 	}
 }
 
-func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleAccountSelection(s discordapi.Session, i *discordgo.InteractionCreate) {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
+	lang := services.InteractionLanguage(i)
+
 	customID := i.MessageComponentData().CustomID
 	parts := strings.Split(customID, "_")
 
 	if len(parts) != 4 {
 		logger.Log.Error("Invalid custom ID format")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -159,112 +174,41 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_settings"))
 		return
 	}
 
-	isUsingDefaultKey := userSettings.CapSolverAPIKey == "" &&
-		userSettings.EZCaptchaAPIKey == "" &&
-		userSettings.TwoCaptchaAPIKey == ""
-
-	if isUsingDefaultKey {
-		lastCheck := userSettings.LastCommandTimes["check_now"]
-		cfg := configuration.Get()
-
-		userSettings.EnsureMapsInitialized()
-		checksUsed := userSettings.ActionCounts["check_now"]
-		maxChecks := cfg.RateLimits.DefaultMaxAccounts
-
-		if !lastCheck.IsZero() && time.Since(lastCheck) >= cfg.RateLimits.CheckNow {
-			checksUsed = 0
-			userSettings.ActionCounts["check_now"] = 0
-			userSettings.LastCommandTimes["check_now"] = time.Now()
-			if err := database.DB.Save(&userSettings).Error; err != nil {
-				logger.Log.WithError(err).Error("Error resetting check count")
-			}
-		}
-
+	if !userSettings.HasCustomCaptchaKey() {
+		checksNeeded := 1
 		if accountIDOrAll == "all" {
 			var accountCount int64
 			if err := database.DB.Model(&models.Account{}).Where("user_id = ?", userID).Count(&accountCount).Error; err != nil {
 				logger.Log.WithError(err).Error("Error counting accounts")
-				respondToInteraction(s, i, "Error counting accounts. Please try again.")
-				return
-			}
-
-			if int(accountCount) > (maxChecks - checksUsed) {
-				timeUntilNext := cfg.RateLimits.CheckNow - time.Since(lastCheck)
-				embed := &discordgo.MessageEmbed{
-					Title: "Insufficient Checks Available",
-					Description: fmt.Sprintf("You need %d checks but only have %d remaining.\n\n"+
-						"Next reset in: %s\n\n"+
-						"To remove this limit, set up your own API key using `/setcaptchaservice`",
-						accountCount, maxChecks-checksUsed, formatDuration(timeUntilNext)),
-					Color: 0xFFA500,
-					Fields: []*discordgo.MessageEmbedField{
-						{
-							Name:   "Available Checks",
-							Value:  fmt.Sprintf("%d/%d checks remaining", maxChecks-checksUsed, maxChecks),
-							Inline: true,
-						},
-						{
-							Name:   "Remove Limits",
-							Value:  "Add your own API key to get:\n• Unlimited checks\n• Faster intervals\n• More account slots",
-							Inline: true,
-						},
-					},
-					Timestamp: time.Now().Format(time.RFC3339),
-				}
-				respondToInteractionWithEmbed(s, i, "", embed)
+				respondToInteraction(s, i, i18n.T(lang, "checknow.error_count"))
 				return
 			}
-
-			userSettings.ActionCounts["check_now"] += int(accountCount)
-		} else {
-			if checksUsed >= maxChecks {
-				timeUntilNext := cfg.RateLimits.CheckNow - time.Since(lastCheck)
-				embed := &discordgo.MessageEmbed{
-					Title: "Rate Limit Reached",
-					Description: fmt.Sprintf("You have used all available checks.\n\n"+
-						"Next reset in: %s\n\n"+
-						"To remove this limit, set up your own API key using `/setcaptchaservice`",
-						formatDuration(timeUntilNext)),
-					Color: 0xFFA500,
-					Fields: []*discordgo.MessageEmbedField{
-						{
-							Name:   "Check Status",
-							Value:  fmt.Sprintf("%d/%d checks used", checksUsed, maxChecks),
-							Inline: true,
-						},
-						{
-							Name:   "Remove Limits",
-							Value:  "Add your own API key to get:\n• Unlimited checks\n• Faster intervals\n• More account slots",
-							Inline: true,
-						},
-					},
-					Timestamp: time.Now().Format(time.RFC3339),
-				}
-				respondToInteractionWithEmbed(s, i, "", embed)
-				return
-			}
-			userSettings.ActionCounts["check_now"]++
+			checksNeeded = int(accountCount)
 		}
 
-		if err := database.DB.Save(&userSettings).Error; err != nil {
+		var quotaErr *services.CheckNowQuotaError
+		if err := services.ReserveCheckNow(&userSettings, checksNeeded); errors.As(err, &quotaErr) {
+			respondToInteractionWithEmbed(s, i, "", quotaEmbed(lang, accountIDOrAll == "all", quotaErr))
+			return
+		} else if err != nil {
 			logger.Log.WithError(err).Error("Error saving check count")
-			respondToInteraction(s, i, "Error updating check count. Please try again.")
+			respondToInteraction(s, i, i18n.T(lang, "checknow.error_quota"))
 			return
 		}
 	} else {
-		apiKey, balance, err := services.GetUserCaptchaKey(userID)
+		apiKey, balance, err := services.GetUserCaptchaKey(ctx, userID)
 		if err != nil || apiKey == "" {
 			logger.Log.WithError(err).Error("Error getting captcha key")
-			respondToInteraction(s, i, "Error validating your captcha API key. Please check your key using /setcaptchaservice.")
+			respondToInteraction(s, i, i18n.T(lang, "common.error_key"))
 			return
 		}
 
 		if balance < 0 {
-			respondToInteraction(s, i, fmt.Sprintf("Your captcha balance (%.2f) is too low for checking accounts. Please recharge your balance.", balance))
+			respondToInteraction(s, i, i18n.T(lang, "checknow.balance_low", balance))
 			return
 		}
 	}
@@ -274,14 +218,14 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 		result := database.DB.Where("user_id = ?", userID).Find(&accounts)
 		if result.Error != nil {
 			logger.Log.WithError(result.Error).Error("Error fetching accounts")
-			respondToInteraction(s, i, "Error fetching accounts. Please try again later.")
+			respondToInteraction(s, i, i18n.T(lang, "common.error_fetch_accounts"))
 			return
 		}
 	} else {
 		accountID, err := strconv.Atoi(accountIDOrAll)
 		if err != nil {
 			logger.Log.WithError(err).Error("Error parsing account ID")
-			respondToInteraction(s, i, "Error processing your selection. Please try again.")
+			respondToInteraction(s, i, i18n.T(lang, "common.error_selection"))
 			return
 		}
 
@@ -289,17 +233,61 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 		result := database.DB.First(&account, accountID)
 		if result.Error != nil {
 			logger.Log.WithError(result.Error).Error("Error fetching account")
-			respondToInteraction(s, i, "Error: Account not found or you don't have permission to check it.")
+			respondToInteraction(s, i, i18n.T(lang, "checknow.not_found"))
 			return
 		}
 
 		accounts = append(accounts, account)
 	}
 
-	checkAccounts(s, i, accounts)
+	checkAccounts(ctx, s, i, lang, accounts)
+}
+
+// quotaEmbed explains that a default key user has run out of manual checks.
+func quotaEmbed(lang i18n.Lang, checkAll bool, quotaErr *services.CheckNowQuotaError) *discordgo.MessageEmbed {
+	if checkAll {
+		return &discordgo.MessageEmbed{
+			Title: i18n.T(lang, "checknow.insufficient.title"),
+			Description: i18n.T(lang, "checknow.insufficient.description",
+				quotaErr.Needed, quotaErr.Remaining, formatDuration(lang, quotaErr.ResetIn)),
+			Color: 0xFFA500,
+			Fields: []*discordgo.MessageEmbedField{
+				{
+					Name:   i18n.T(lang, "checknow.available_checks"),
+					Value:  i18n.T(lang, "checknow.checks_remaining", quotaErr.Remaining, quotaErr.Max),
+					Inline: true,
+				},
+				{
+					Name:   i18n.T(lang, "checknow.remove_limits.name"),
+					Value:  i18n.T(lang, "checknow.remove_limits.value"),
+					Inline: true,
+				},
+			},
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "checknow.rate_limit.title"),
+		Description: i18n.T(lang, "checknow.rate_limit.description", formatDuration(lang, quotaErr.ResetIn)),
+		Color:       0xFFA500,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   i18n.T(lang, "checknow.check_status"),
+				Value:  i18n.T(lang, "checknow.checks_used", quotaErr.Max-quotaErr.Remaining, quotaErr.Max),
+				Inline: true,
+			},
+			{
+				Name:   i18n.T(lang, "checknow.remove_limits.name"),
+				Value:  i18n.T(lang, "checknow.remove_limits.value"),
+				Inline: true,
+			},
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
 }
 
-func formatDuration(d time.Duration) string {
+func formatDuration(lang i18n.Lang, d time.Duration) string {
 	d = d.Round(time.Second)
 	h := d / time.Hour
 	d -= h * time.Hour
@@ -308,15 +296,15 @@ func formatDuration(d time.Duration) string {
 	s := d / time.Second
 
 	if h > 0 {
-		return fmt.Sprintf("%dh %dm %ds", h, m, s)
+		return i18n.T(lang, "duration.precise.hours", h, m, s)
 	}
 	if m > 0 {
-		return fmt.Sprintf("%dm %ds", m, s)
+		return i18n.T(lang, "duration.precise.minutes", m, s)
 	}
-	return fmt.Sprintf("%ds", s)
+	return i18n.T(lang, "duration.precise.seconds", s)
 }
 
-func respondToInteractionWithEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, content string, embed *discordgo.MessageEmbed) {
+func respondToInteractionWithEmbed(s discordapi.Session, i *discordgo.InteractionCreate, content string, embed *discordgo.MessageEmbed) {
 	responseData := &discordgo.InteractionResponseData{
 		Flags: discordgo.MessageFlagsEphemeral,
 	}
@@ -337,18 +325,18 @@ func respondToInteractionWithEmbed(s *discordgo.Session, i *discordgo.Interactio
 	}
 }
 
-func checkAccounts(s *discordgo.Session, i *discordgo.InteractionCreate, accounts []models.Account) {
+func checkAccounts(ctx context.Context, s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, accounts []models.Account) {
 	userID, err := services.GetUserID(i)
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to get user ID")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_settings"))
 		return
 	}
 
@@ -364,7 +352,7 @@ func checkAccounts(s *discordgo.Session, i *discordgo.InteractionCreate, account
 	}
 
 	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-		Content: fmt.Sprintf("Starting check of %d accounts...", len(accounts)),
+		Content: i18n.T(lang, "checknow.starting", len(accounts)),
 		Flags:   discordgo.MessageFlagsEphemeral,
 	})
 	if err != nil {
@@ -377,53 +365,61 @@ func checkAccounts(s *discordgo.Session, i *discordgo.InteractionCreate, account
 
 		if account.IsCheckDisabled {
 			embed = &discordgo.MessageEmbed{
-				Title:       fmt.Sprintf("%s - Checks Disabled", account.Title),
-				Description: fmt.Sprintf("Checks are disabled for this account. Reason: %s", account.DisabledReason),
+				Title:       i18n.T(lang, "checknow.disabled.title", account.Title),
+				Description: i18n.T(lang, "checknow.disabled.description", account.DisabledReason),
 				Color:       services.GetColorForStatus(account.LastStatus, account.IsExpiredCookie, true),
 				Timestamp:   time.Now().Format(time.RFC3339),
 			}
 		} else if account.IsExpiredCookie {
 			embed = &discordgo.MessageEmbed{
-				Title:       fmt.Sprintf("%s - Expired Cookie", account.Title),
-				Description: "The SSO cookie for this account has expired. Please update it using the /updateaccount command.",
+				Title:       i18n.T(lang, "checknow.expired.title", account.Title),
+				Description: i18n.T(lang, "checknow.expired.description"),
 				Color:       services.GetColorForStatus(models.StatusUnknown, true, false),
 				Timestamp:   time.Now().Format(time.RFC3339),
 			}
 		} else {
-			status, err := services.CheckAccount(account.SSOCookie, account.UserID, "")
+			check, err := services.CheckAccount(services.WithCaptchaAccount(ctx, account.ID), account.SSOCookie, account.UserID, "")
 			if err != nil {
 				logger.Log.WithError(err).Errorf("Error checking account %s", account.Title)
-				description := "An error occurred while checking this account. "
+				description := i18n.T(lang, "checknow.error") + " "
 				if strings.Contains(err.Error(), "insufficient balance") {
-					description += "Your captcha balance is too low. Please recharge your balance."
+					description += i18n.T(lang, "checknow.error_balance")
 				} else if strings.Contains(err.Error(), "invalid captcha") {
-					description += "There was an issue with your captcha API key. Please verify it using /setcaptchaservice."
+					description += i18n.T(lang, "checknow.error_key")
 				} else {
-					description += "Please try again later."
+					description += i18n.T(lang, "checknow.error_later")
 				}
 
 				embed = &discordgo.MessageEmbed{
-					Title:       fmt.Sprintf("%s - Error", account.Title),
+					Title:       i18n.T(lang, "checknow.error.title", account.Title),
 					Description: description,
 					Color:       0xFF0000,
 					Timestamp:   time.Now().Format(time.RFC3339),
 				}
 			} else {
-				services.HandleStatusChange(s, account, status, userSettings)
+				services.HandleStatusChange(ctx, s, account, check, userSettings)
 
 				embed = &discordgo.MessageEmbed{
-					Title:       fmt.Sprintf("%s - Status Check", account.Title),
-					Description: fmt.Sprintf("Current status: %s", status),
-					Color:       services.GetColorForStatus(status, account.IsExpiredCookie, account.IsCheckDisabled),
+					Title:       i18n.T(lang, "checknow.result.title", account.Title),
+					Description: i18n.T(lang, "checknow.result.description", services.StatusName(lang, check.Status)),
+					Color:       services.GetColorForStatus(check.Status, account.IsExpiredCookie, account.IsCheckDisabled),
 					Fields: []*discordgo.MessageEmbedField{
 						{
-							Name:   "Last Checked",
-							Value:  time.Now().Format(time.RFC1123),
+							Name:   i18n.T(lang, "notify.field.last_checked"),
+							Value:  i18n.FormatDateTime(lang, time.Now()),
 							Inline: true,
 						},
 					},
 					Timestamp: time.Now().Format(time.RFC3339),
 				}
+
+				if endsAt := check.TempBanEndsAt(); check.Status == models.StatusTempban && !endsAt.IsZero() {
+					embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+						Name:   i18n.T(lang, "notify.field.ban_ends"),
+						Value:  fmt.Sprintf("<t:%d:R>", endsAt.Unix()),
+						Inline: true,
+					})
+				}
 			}
 		}
 
@@ -439,7 +435,7 @@ func checkAccounts(s *discordgo.Session, i *discordgo.InteractionCreate, account
 		time.Sleep(time.Second)
 	}
 
-	completionMessage := fmt.Sprintf("Completed checking all %d accounts.", processedCount)
+	completionMessage := i18n.T(lang, "checknow.completed", processedCount)
 	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
 		Content: completionMessage,
 		Flags:   discordgo.MessageFlagsEphemeral,
@@ -492,7 +488,7 @@ func getUserID(i *discordgo.InteractionCreate) (string, error) {
 	return "", fmt.Errorf("unable to determine user ID")
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
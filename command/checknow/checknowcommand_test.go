@@ -0,0 +1,333 @@
+package checknow_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/command/checknow"
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bradselph/CODStatusBot/testsupport/fakeactivision"
+	"github.com/bradselph/CODStatusBot/testsupport/fakecaptcha"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+	"github.com/bwmarrin/discordgo"
+	"github.com/glebarez/sqlite"
+)
+
+const lang = i18n.English
+
+// startCheckNow points the configuration at fake Activision and Capsolver servers and the
+// database at a fresh in-memory SQLite database for the rest of the test.
+func startCheckNow(t *testing.T) *fakeactivision.Server {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := *cfg
+	previousDB := database.DB
+	t.Cleanup(func() {
+		*cfg = saved
+		database.DB = previousDB
+	})
+
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	if err := database.SetEncryptionKeys(key, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	if err := database.Connect(sqlite.Open("file::memory:")); err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	if _, err := database.MigrateUp(database.DB); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	activision := fakeactivision.New()
+	t.Cleanup(activision.Close)
+	activision.Apply(cfg)
+
+	captcha := fakecaptcha.New(fakecaptcha.Capsolver)
+	t.Cleanup(captcha.Close)
+	captcha.Apply(cfg)
+
+	cfg.CaptchaService.RecaptchaSiteKey = "site-key"
+	cfg.CaptchaService.RecaptchaURL = "https://support.activision.com"
+	cfg.CaptchaTokenPool.Enabled = false
+	cfg.CaptchaTokenPool.Prefetch = 0
+	cfg.RateLimits.CheckNow = time.Hour
+	cfg.RateLimits.DefaultMaxAccounts = 3
+	cfg.RateLimits.PremiumMaxAccounts = 10
+	return activision
+}
+
+// addAccounts stores accounts for user-1 with a good cookie each, filling in what the tests
+// leave out.
+func addAccounts(t *testing.T, accounts ...models.Account) []models.Account {
+	t.Helper()
+	for n := range accounts {
+		accounts[n].UserID = "user-1"
+		accounts[n].ChannelID = fakediscord.DMChannelID("user-1")
+		if accounts[n].Title == "" {
+			accounts[n].Title = fmt.Sprintf("account-%d", n+1)
+		}
+		if accounts[n].LastStatus == "" {
+			accounts[n].LastStatus = models.StatusGood
+		}
+		accounts[n].SSOCookie = fakeactivision.Cookie(time.Now().Add(time.Duration(14*24+n) * time.Hour))
+		if err := database.DB.Create(&accounts[n]).Error; err != nil {
+			t.Fatalf("create account: %v", err)
+		}
+	}
+	return accounts
+}
+
+func accountButton(account models.Account) discordgo.MessageComponent {
+	return discordgo.Button{
+		Label:    account.Title,
+		Style:    discordgo.PrimaryButton,
+		CustomID: fmt.Sprintf("check_now_user-1_%d", account.ID),
+	}
+}
+
+var checkAllButton = discordgo.Button{
+	Label:    i18n.T(lang, "checknow.check_all"),
+	Style:    discordgo.SuccessButton,
+	CustomID: "check_now_user-1_all",
+}
+
+func accountList(rows ...[]discordgo.MessageComponent) *discordgo.InteractionResponse {
+	components := make([]discordgo.MessageComponent, 0, len(rows))
+	for _, row := range rows {
+		components = append(components, discordgo.ActionsRow{Components: row})
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    i18n.T(lang, "checknow.select", i18n.T(lang, "checknow.check_all")),
+			Flags:      discordgo.MessageFlagsEphemeral,
+			Components: components,
+		},
+	}
+}
+
+func TestCommandCheckNow(t *testing.T) {
+	tests := []struct {
+		name     string
+		accounts int
+		want     func(accounts []models.Account) *discordgo.InteractionResponse
+	}{
+		{
+			name: "no accounts",
+			want: func([]models.Account) *discordgo.InteractionResponse {
+				return &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseChannelMessageWithSource,
+					Data: &discordgo.InteractionResponseData{
+						Content: i18n.T(lang, "common.no_accounts"),
+						Flags:   discordgo.MessageFlagsEphemeral,
+					},
+				}
+			},
+		},
+		{
+			name:     "check all shares the last row",
+			accounts: 2,
+			want: func(a []models.Account) *discordgo.InteractionResponse {
+				return accountList([]discordgo.MessageComponent{accountButton(a[0]), accountButton(a[1]), checkAllButton})
+			},
+		},
+		{
+			name:     "check all starts a new row",
+			accounts: 5,
+			want: func(a []models.Account) *discordgo.InteractionResponse {
+				return accountList(
+					[]discordgo.MessageComponent{accountButton(a[0]), accountButton(a[1]), accountButton(a[2]), accountButton(a[3]), accountButton(a[4])},
+					[]discordgo.MessageComponent{checkAllButton},
+				)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startCheckNow(t)
+			accounts := addAccounts(t, make([]models.Account, tt.accounts)...)
+
+			s := fakediscord.New()
+			checknow.CommandCheckNow(s, fakediscord.SlashCommand("user-1", "checknow"))
+
+			responses := s.Responses()
+			if len(responses) != 1 {
+				t.Fatalf("got %d responses, want 1", len(responses))
+			}
+			want := tt.want(accounts)
+			if got := responses[0].Response; !reflect.DeepEqual(got, want) {
+				t.Errorf("response = %+v\nwant %+v", got.Data, want.Data)
+			}
+		})
+	}
+}
+
+// withoutTimes clears the parts of embed that depend on when the check ran, after checking they
+// were filled in.
+func withoutTimes(t *testing.T, embed *discordgo.MessageEmbed) *discordgo.MessageEmbed {
+	t.Helper()
+	if embed == nil {
+		return nil
+	}
+	copied := *embed
+	if copied.Timestamp == "" {
+		t.Errorf("embed %q has no timestamp", copied.Title)
+	}
+	copied.Timestamp = ""
+	copied.Fields = nil
+	for _, field := range embed.Fields {
+		f := *field
+		if f.Name == i18n.T(lang, "notify.field.last_checked") {
+			if f.Value == "" {
+				t.Errorf("embed %q has no check time", copied.Title)
+			}
+			f.Value = ""
+		}
+		copied.Fields = append(copied.Fields, &f)
+	}
+	return &copied
+}
+
+func TestCheckNowSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		account  models.Account
+		scenario fakeactivision.Scenario
+		want     *discordgo.MessageEmbed
+	}{
+		{
+			name:     "checked",
+			account:  models.Account{Title: "main"},
+			scenario: fakeactivision.Good,
+			want: &discordgo.MessageEmbed{
+				Title:       i18n.T(lang, "checknow.result.title", "main"),
+				Description: i18n.T(lang, "checknow.result.description", services.StatusName(lang, models.StatusGood)),
+				Color:       services.GetColorForStatus(models.StatusGood, false, false),
+				Fields: []*discordgo.MessageEmbedField{
+					{Name: i18n.T(lang, "notify.field.last_checked"), Inline: true},
+				},
+			},
+		},
+		{
+			name:     "check failed",
+			account:  models.Account{Title: "main"},
+			scenario: fakeactivision.ServerError,
+			want: &discordgo.MessageEmbed{
+				Title:       i18n.T(lang, "checknow.error.title", "main"),
+				Description: i18n.T(lang, "checknow.error") + " " + i18n.T(lang, "checknow.error_later"),
+				Color:       0xFF0000,
+			},
+		},
+		{
+			name:    "checks disabled",
+			account: models.Account{Title: "main", IsCheckDisabled: true, DisabledReason: "too many errors"},
+			want: &discordgo.MessageEmbed{
+				Title:       i18n.T(lang, "checknow.disabled.title", "main"),
+				Description: i18n.T(lang, "checknow.disabled.description", "too many errors"),
+				Color:       services.GetColorForStatus(models.StatusGood, false, true),
+			},
+		},
+		{
+			name:    "cookie expired",
+			account: models.Account{Title: "main", IsExpiredCookie: true},
+			want: &discordgo.MessageEmbed{
+				Title:       i18n.T(lang, "checknow.expired.title", "main"),
+				Description: i18n.T(lang, "checknow.expired.description"),
+				Color:       services.GetColorForStatus(models.StatusUnknown, true, false),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activision := startCheckNow(t)
+			account := addAccounts(t, tt.account)[0]
+			if tt.scenario != "" {
+				activision.Script(account.SSOCookie, tt.scenario)
+			}
+
+			s := fakediscord.New()
+			checknow.HandleAccountSelection(s, fakediscord.Component("user-1", fmt.Sprintf("check_now_user-1_%d", account.ID)))
+
+			wantDeferred := &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+			}
+			if got := s.LastResponse().Response; !reflect.DeepEqual(got, wantDeferred) {
+				t.Errorf("response = %+v, want a deferred ephemeral reply", got)
+			}
+
+			followups := s.Followups()
+			if len(followups) != 3 {
+				t.Fatalf("got %d follow-ups, want 3", len(followups))
+			}
+			wantFollowups := []*discordgo.WebhookParams{
+				{Content: i18n.T(lang, "checknow.starting", 1), Flags: discordgo.MessageFlagsEphemeral},
+				{Embeds: []*discordgo.MessageEmbed{tt.want}, Flags: discordgo.MessageFlagsEphemeral},
+				{Content: i18n.T(lang, "checknow.completed", 1), Flags: discordgo.MessageFlagsEphemeral},
+			}
+			for n, followup := range followups {
+				got := *followup.Params
+				if len(got.Embeds) == 1 {
+					got.Embeds = []*discordgo.MessageEmbed{withoutTimes(t, got.Embeds[0])}
+				}
+				if !reflect.DeepEqual(&got, wantFollowups[n]) {
+					t.Errorf("follow-up %d = %+v, want %+v", n, &got, wantFollowups[n])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckNowQuota(t *testing.T) {
+	startCheckNow(t)
+	addAccounts(t, make([]models.Account, 4)...)
+
+	s := fakediscord.New()
+	checknow.HandleAccountSelection(s, fakediscord.Component("user-1", "check_now_user-1_all"))
+
+	hour := time.Duration(1)
+	want := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+			Embeds: []*discordgo.MessageEmbed{{
+				Title: i18n.T(lang, "checknow.insufficient.title"),
+				Description: i18n.T(lang, "checknow.insufficient.description",
+					4, 3, i18n.T(lang, "duration.precise.hours", hour, time.Duration(0), time.Duration(0))),
+				Color: 0xFFA500,
+				Fields: []*discordgo.MessageEmbedField{
+					{
+						Name:   i18n.T(lang, "checknow.available_checks"),
+						Value:  i18n.T(lang, "checknow.checks_remaining", 3, 3),
+						Inline: true,
+					},
+					{
+						Name:   i18n.T(lang, "checknow.remove_limits.name"),
+						Value:  i18n.T(lang, "checknow.remove_limits.value"),
+						Inline: true,
+					},
+				},
+			}},
+		},
+	}
+
+	got := s.LastResponse().Response
+	if got == nil || len(got.Data.Embeds) != 1 {
+		t.Fatalf("response = %+v, want one embed", got)
+	}
+	got.Data.Embeds[0] = withoutTimes(t, got.Data.Embeds[0])
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("response = %+v\nwant %+v", got.Data.Embeds[0], want.Data.Embeds[0])
+	}
+	if n := len(s.Followups()); n != 0 {
+		t.Errorf("sent %d follow-ups, want none once the quota is used up", n)
+	}
+}
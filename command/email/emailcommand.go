@@ -0,0 +1,150 @@
+package email
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandEmail manages the address email notifications are sent to. Sending mail can take
+// longer than Discord allows for a reply, so the response is deferred.
+func CommandEmail(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
+	userID, err := services.GetUserID(i)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to get user ID")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
+		return
+	}
+
+	if !services.EmailEnabled() {
+		respondToInteraction(s, i, i18n.T(lang, "email.not_enabled"))
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "common.choose_subcommand"))
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to defer response")
+		return
+	}
+
+	subcommand := options[0]
+	switch subcommand.Name {
+	case "set":
+		setEmail(s, i, lang, userID, stringOption(subcommand.Options, "address"))
+	case "verify":
+		verifyEmail(s, i, lang, userID, stringOption(subcommand.Options, "code"))
+	case "remove":
+		removeEmail(s, i, lang, userID)
+	case "test":
+		testEmail(s, i, lang, userID)
+	default:
+		sendFollowup(s, i, i18n.T(lang, "common.unknown_subcommand"))
+	}
+}
+
+func setEmail(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID, address string) {
+	err := services.StartEmailVerification(userID, address)
+	switch {
+	case errors.Is(err, services.ErrInvalidEmail):
+		sendFollowup(s, i, i18n.T(lang, "email.invalid_address"))
+	case errors.Is(err, services.ErrEmailCodeTooSoon):
+		sendFollowup(s, i, i18n.T(lang, "email.code_too_soon"))
+	case err != nil:
+		logger.Log.WithError(err).Errorf("Failed to start email verification for user %s", userID)
+		sendFollowup(s, i, i18n.T(lang, "email.error_send_code"))
+	default:
+		sendFollowup(s, i, i18n.T(lang, "email.code_sent", address))
+	}
+}
+
+func verifyEmail(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID, code string) {
+	address, err := services.VerifyEmail(userID, code)
+	switch {
+	case errors.Is(err, services.ErrNoEmailVerification):
+		sendFollowup(s, i, i18n.T(lang, "email.no_verification"))
+	case errors.Is(err, services.ErrEmailCodeExpired):
+		sendFollowup(s, i, i18n.T(lang, "email.code_expired"))
+	case errors.Is(err, services.ErrEmailCodeAttempts):
+		sendFollowup(s, i, i18n.T(lang, "email.code_attempts"))
+	case errors.Is(err, services.ErrEmailCodeInvalid):
+		sendFollowup(s, i, i18n.T(lang, "email.code_invalid"))
+	case err != nil:
+		logger.Log.WithError(err).Errorf("Failed to verify email for user %s", userID)
+		sendFollowup(s, i, i18n.T(lang, "email.error_verify"))
+	default:
+		sendFollowup(s, i, i18n.T(lang, "email.verified", address))
+	}
+}
+
+func removeEmail(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string) {
+	if err := services.RemoveEmail(userID); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to remove email for user %s", userID)
+		sendFollowup(s, i, i18n.T(lang, "email.error_remove"))
+		return
+	}
+	sendFollowup(s, i, i18n.T(lang, "email.removed"))
+}
+
+func testEmail(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string) {
+	err := services.SendTestEmail(userID)
+	if errors.Is(err, services.ErrEmailNotVerified) {
+		sendFollowup(s, i, i18n.T(lang, "email.not_verified"))
+		return
+	}
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to send test email for user %s", userID)
+		sendFollowup(s, i, i18n.T(lang, "email.error_test"))
+		return
+	}
+	sendFollowup(s, i, i18n.T(lang, "email.test_sent"))
+}
+
+func stringOption(options []*discordgo.ApplicationCommandInteractionDataOption, name string) string {
+	for _, option := range options {
+		if option.Name == name {
+			return strings.TrimSpace(option.StringValue())
+		}
+	}
+	return ""
+}
+
+func sendFollowup(s discordapi.Session, i *discordgo.InteractionCreate, content string) {
+	_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error sending followup message")
+	}
+}
+
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding to interaction")
+	}
+}
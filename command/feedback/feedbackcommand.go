@@ -8,7 +8,10 @@ import (
 	"time"
 
 	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -24,20 +27,22 @@ type feedbackEntry struct {
 
 const feedbackTimeout = 5 * time.Minute
 
-func CommandFeedback(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandFeedback(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	feedbackMessage := i.ApplicationCommandData().Options[0].StringValue()
 	cfg := configuration.Get()
 	developerID := cfg.Discord.DeveloperID
 	if developerID == "" {
 		logger.Log.Error("Developer ID not configured")
-		sendResponse(s, i, "Configuration error. Please try again later.", true)
+		sendResponse(s, i, i18n.T(lang, "feedback.error_config"), true)
 		return
 	}
 
 	userID, err := getUserID(i)
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to get user ID")
-		sendResponse(s, i, "An error occurred while processing your request.", true)
+		sendResponse(s, i, i18n.T(lang, "common.error_processing"), true)
 		return
 	}
 
@@ -53,18 +58,18 @@ func CommandFeedback(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: "Do you want to send this feedback anonymously?",
+			Content: i18n.T(lang, "feedback.anonymous_prompt"),
 			Flags:   discordgo.MessageFlagsEphemeral,
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.Button{
-							Label:    "Send Anonymously",
+							Label:    i18n.T(lang, "feedback.send_anonymous"),
 							Style:    discordgo.PrimaryButton,
 							CustomID: fmt.Sprintf("feedback_anonymous_%s", userID),
 						},
 						discordgo.Button{
-							Label:    "Send with ID",
+							Label:    i18n.T(lang, "feedback.send_with_id"),
 							Style:    discordgo.SecondaryButton,
 							CustomID: fmt.Sprintf("feedback_with_id_%s", userID),
 						},
@@ -75,17 +80,19 @@ func CommandFeedback(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	})
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to send anonymity choice message")
-		sendResponse(s, i, "There was an error processing your feedback. Please try again later.", true)
+		sendResponse(s, i, i18n.T(lang, "feedback.error_processing"), true)
 		return
 	}
 }
 
-func HandleFeedbackChoice(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleFeedbackChoice(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	customID := i.MessageComponentData().CustomID
 	parts := strings.SplitN(customID, "_", 3)
 	if len(parts) != 3 {
 		logger.Log.Error("Invalid custom ID format for feedback choice")
-		sendResponse(s, i, "An error occurred while processing your request.", true)
+		sendResponse(s, i, i18n.T(lang, "common.error_processing"), true)
 		return
 	}
 
@@ -97,7 +104,7 @@ func HandleFeedbackChoice(s *discordgo.Session, i *discordgo.InteractionCreate)
 	interactionUserID, err := getUserID(i)
 	if err != nil || interactionUserID != userID {
 		logger.Log.WithField("buttonUserID", userID).WithField("interactionUserID", interactionUserID).Error("User ID mismatch")
-		sendResponse(s, i, "An error occurred while processing your request.", true)
+		sendResponse(s, i, i18n.T(lang, "common.error_processing"), true)
 		return
 	}
 
@@ -107,7 +114,7 @@ func HandleFeedbackChoice(s *discordgo.Session, i *discordgo.InteractionCreate)
 
 	if !ok || time.Since(entry.timestamp) > feedbackTimeout {
 		logger.Log.WithField("userID", userID).Error("Feedback message not found or expired")
-		sendResponse(s, i, "Your feedback session has expired. Please submit your feedback again.", true)
+		sendResponse(s, i, i18n.T(lang, "feedback.expired"), true)
 		return
 	}
 
@@ -124,14 +131,14 @@ func HandleFeedbackChoice(s *discordgo.Session, i *discordgo.InteractionCreate)
 
 	if err := sendFeedbackToDeveloper(s, feedbackToSend); err != nil {
 		logger.Log.WithError(err).Error("Failed to send feedback to developer")
-		sendResponse(s, i, "There was an error sending your feedback. Please try again later.", true)
+		sendResponse(s, i, i18n.T(lang, "feedback.error_send"), true)
 		return
 	}
 
-	sendResponse(s, i, "Your feedback has been sent to the developer. Thank you for your input!", true)
+	sendResponse(s, i, i18n.T(lang, "feedback.sent"), true)
 }
 
-func sendFeedbackToDeveloper(s *discordgo.Session, feedback string) error {
+func sendFeedbackToDeveloper(s discordapi.Session, feedback string) error {
 	developerID := os.Getenv("DEVELOPER_ID")
 	channel, err := s.UserChannelCreate(developerID)
 	if err != nil {
@@ -146,7 +153,7 @@ func sendFeedbackToDeveloper(s *discordgo.Session, feedback string) error {
 	return nil
 }
 
-func sendResponse(s *discordgo.Session, i *discordgo.InteractionCreate, content string, ephemeral bool) {
+func sendResponse(s discordapi.Session, i *discordgo.InteractionCreate, content string, ephemeral bool) {
 	flags := discordgo.MessageFlags(0)
 	if ephemeral {
 		flags = discordgo.MessageFlagsEphemeral
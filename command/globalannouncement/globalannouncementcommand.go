@@ -7,6 +7,8 @@ import (
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bradselph/CODStatusBot/services"
@@ -14,7 +16,7 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
-func SendGlobalAnnouncement(s *discordgo.Session, userID string) error {
+func SendGlobalAnnouncement(s discordapi.Session, userID string) error {
 	var userSettings models.UserSettings
 	result := database.DB.Where(models.UserSettings{UserID: userID}).FirstOrCreate(&userSettings)
 	if result.Error != nil {
@@ -29,7 +31,7 @@ func SendGlobalAnnouncement(s *discordgo.Session, userID string) error {
 			return err
 		}
 
-		announcementEmbed := services.CreateAnnouncementEmbed()
+		announcementEmbed := services.CreateAnnouncementEmbed(services.UserLanguage(userSettings))
 
 		_, err = s.ChannelMessageSendEmbed(channelID, announcementEmbed)
 		if err != nil {
@@ -47,7 +49,7 @@ func SendGlobalAnnouncement(s *discordgo.Session, userID string) error {
 	return nil
 }
 
-func CommandGlobalAnnouncement(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandGlobalAnnouncement(s discordapi.Session, i *discordgo.InteractionCreate) {
 	cfg := configuration.Get()
 	developerID := cfg.Discord.DeveloperID
 	if developerID == "" {
@@ -63,13 +65,14 @@ func CommandGlobalAnnouncement(s *discordgo.Session, i *discordgo.InteractionCre
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(services.InteractionLanguage(i), "common.error_processing"))
 		return
 	}
 
 	if userID != developerID {
 		logger.Log.Warnf("Unauthorized user %s attempted to use global announcement command", userID)
-		respondToInteraction(s, i, "You don't have permission to use this command. Only the bot developer can send global announcements.")
+		lang := services.InteractionLanguage(i)
+		respondToInteraction(s, i, i18n.T(lang, "globalannouncement.not_permitted"))
 		return
 	}
 
@@ -126,7 +129,7 @@ func CommandGlobalAnnouncement(s *discordgo.Session, i *discordgo.InteractionCre
 	}
 }
 
-func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleModalSubmit(s discordapi.Session, i *discordgo.InteractionCreate) {
 	data := i.ModalSubmitData()
 
 	var title, content, resetFlags string
@@ -188,7 +191,7 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	respondToInteraction(s, i, fmt.Sprintf("Announcement sent successfully to %d users. %d users could not be reached.", successCount, failCount))
 }
 
-func sendDynamicAnnouncementToUser(s *discordgo.Session, userID string, embed *discordgo.MessageEmbed) error {
+func sendDynamicAnnouncementToUser(s discordapi.Session, userID string, embed *discordgo.MessageEmbed) error {
 	var userSettings models.UserSettings
 	result := database.DB.Where(models.UserSettings{UserID: userID}).FirstOrCreate(&userSettings)
 	if result.Error != nil {
@@ -205,7 +208,7 @@ func sendDynamicAnnouncementToUser(s *discordgo.Session, userID string, embed *d
 	return err
 }
 
-func getChannelForAnnouncement(s *discordgo.Session, userID string, userSettings models.UserSettings) (string, error) {
+func getChannelForAnnouncement(s discordapi.Session, userID string, userSettings models.UserSettings) (string, error) {
 	if userSettings.NotificationType == "dm" {
 		channel, err := s.UserChannelCreate(userID)
 		if err != nil {
@@ -225,7 +228,7 @@ func getChannelForAnnouncement(s *discordgo.Session, userID string, userSettings
 	return account.ChannelID, nil
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
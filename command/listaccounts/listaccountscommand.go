@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bradselph/CODStatusBot/services"
@@ -20,7 +22,9 @@ var (
 	questionCircle = os.Getenv("QUESTIONCIRCLE")
 )
 
-func CommandListAccounts(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandListAccounts(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -39,7 +43,7 @@ func CommandListAccounts(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		sendFollowup(s, i, "An error occurred while processing your request.")
+		sendFollowup(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -47,64 +51,68 @@ func CommandListAccounts(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	result := database.DB.Where("user_id = ?", userID).Find(&accounts)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching user accounts")
-		sendFollowup(s, i, "Error fetching your accounts. Please try again.")
+		sendFollowup(s, i, i18n.T(lang, "common.error_fetch_your_accounts"))
 		return
 	}
 
 	if len(accounts) == 0 {
-		sendFollowup(s, i, "You don't have any monitored accounts.")
+		sendFollowup(s, i, i18n.T(lang, "common.no_accounts"))
 		return
 	}
 
-	balanceInfo := getBalanceInfo(userID)
-	description := "Here's a detailed list of all your monitored accounts:"
+	balanceInfo := getBalanceInfo(lang, userID)
+	description := i18n.T(lang, "listaccounts.description")
 	if balanceInfo != "" {
 		description += balanceInfo
 	}
 
 	embed := &discordgo.MessageEmbed{
-		Title:       "Your Monitored Accounts",
+		Title:       i18n.T(lang, "listaccounts.title"),
 		Description: description,
 		Color:       0x00ff00,
 		Fields:      make([]*discordgo.MessageEmbedField, 0),
 	}
 
 	for _, account := range accounts {
-		checkStatus := services.GetCheckStatus(account.IsCheckDisabled)
-		cookieExpiration := services.FormatExpirationTime(account.SSOCookieExpiration)
+		checkStatus := services.GetCheckStatus(lang, account.IsCheckDisabled)
+		cookieExpiration := services.FormatExpirationTime(lang, account.SSOCookieExpiration)
 		creationDate := time.Unix(account.Created, 0).Format("2006-01-02")
 		lastCheckTime := time.Unix(account.LastCheck, 0).Format("2006-01-02 15:04:05")
 
-		vipStatus := "No"
+		vipStatus := i18n.T(lang, "common.no")
 		if account.IsVIP {
-			vipStatus = "Yes ✓"
+			vipStatus = i18n.T(lang, "common.yes") + " ✓"
 		}
 
-		fieldValue := fmt.Sprintf("Status: %s\n", account.LastStatus)
+		fieldValue := i18n.T(lang, "listaccounts.status", services.StatusName(lang, account.LastStatus))
 
-		if account.IsPermabanned {
-			fieldValue += banCircle + "Account Permanently Banned\n"
-		}
-		if account.IsTempbanned {
-			fieldValue += stopWatch + "Account Temporarily Banned\n"
+		switch account.LastStatus {
+		case models.StatusPermaban:
+			fieldValue += banCircle + i18n.T(lang, "listaccounts.permaban")
+		case models.StatusTempban:
+			fieldValue += stopWatch + i18n.T(lang, "listaccounts.tempban")
+		case models.StatusShadowban:
+			fieldValue += questionCircle + i18n.T(lang, "notify.under_review") + "\n"
 		}
-		if account.IsShadowbanned {
-			fieldValue += questionCircle + "Account Under Review\n"
+		titleStatuses, err := services.GetTitleStatuses(account.ID)
+		if err != nil {
+			logger.Log.WithError(err).Errorf("Error fetching title statuses for account %s", account.Title)
+		} else if len(titleStatuses) > 0 {
+			fieldValue += services.FormatTitleStatuses(lang, titleStatuses) + "\n"
 		}
 		if account.IsExpiredCookie {
-			fieldValue += "⚠ Cookie Expired\n"
+			fieldValue += i18n.T(lang, "listaccounts.cookie_expired")
 		}
 		if account.ConsecutiveErrors > 0 {
-			fieldValue += fmt.Sprintf("⚠ Check Errors: %d\n", account.ConsecutiveErrors)
+			fieldValue += i18n.T(lang, "listaccounts.check_errors", account.ConsecutiveErrors)
 		}
 
-		fieldValue += fmt.Sprintf("VIP Status: %s\nChecks: %s\nNotification Type: %s\n"+
-			"Cookie Expires: %s\nCreated: %s\nLast Checked: %s",
+		fieldValue += i18n.T(lang, "listaccounts.details",
 			vipStatus, checkStatus, account.NotificationType,
 			cookieExpiration, creationDate, lastCheckTime)
 
 		if account.IsCheckDisabled {
-			fieldValue += fmt.Sprintf("\nDisabled Reason: %s", account.DisabledReason)
+			fieldValue += i18n.T(lang, "listaccounts.disabled_reason", account.DisabledReason)
 		}
 
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
@@ -128,7 +136,7 @@ func CommandListAccounts(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 }
 
-func sendFollowup(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+func sendFollowup(s discordapi.Session, i *discordgo.InteractionCreate, content string) {
 	_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
 		Content: content,
 		Flags:   discordgo.MessageFlagsEphemeral,
@@ -145,7 +153,10 @@ func getDisabledEmoji(isDisabled bool) string {
 	return "✓"
 }
 
-func getBalanceInfo(userID string) string {
+func getBalanceInfo(lang i18n.Lang, userID string) string {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
@@ -156,31 +167,30 @@ func getBalanceInfo(userID string) string {
 		return ""
 	}
 
-	apiKey, balance, err := services.GetUserCaptchaKey(userID)
+	apiKey, balance, err := services.GetUserCaptchaKey(ctx, userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error getting user captcha key")
 		return ""
 	}
 
 	if apiKey == "" {
-		return "\n\nYou are using the bot's default API key. Consider setting up your own key using /setcaptchaservice for unlimited checks."
+		return "\n\n" + i18n.T(lang, "checkcaptchabalance.default_key")
 	}
 
 	var threshold float64
 	switch userSettings.PreferredCaptchaProvider {
 	case "ezcaptcha":
 		threshold = 250
-	case "2captcha":
+	case "2captcha", "anticaptcha", "capmonster":
 		threshold = 0.25
 	default:
 		threshold = 250
 	}
 
-	balanceMsg := fmt.Sprintf("\n\nYour current %s balance: %.2f points",
-		userSettings.PreferredCaptchaProvider, balance)
+	balanceMsg := "\n\n" + i18n.T(lang, "listaccounts.balance", userSettings.PreferredCaptchaProvider, balance)
 
 	if balance < threshold {
-		balanceMsg += fmt.Sprintf(" (Warning: Below recommended %.2f points)", threshold)
+		balanceMsg += " " + i18n.T(lang, "listaccounts.balance_low", threshold)
 	}
 
 	return balanceMsg
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"slices"
+
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bwmarrin/discordgo"
+)
+
+// localizeCommands fills in the translated names and descriptions of the commands, their options
+// and choices from the i18n catalogs, see i18n.CommandKey.
+func localizeCommands(commands []*discordgo.ApplicationCommand) {
+	for _, command := range commands {
+		key := i18n.CommandKey(command.Name)
+		if names := i18n.Localizations(key + ".name"); names != nil {
+			command.NameLocalizations = &names
+		}
+		if descriptions := i18n.Localizations(key + ".description"); descriptions != nil {
+			command.DescriptionLocalizations = &descriptions
+		}
+		localizeOptions(command.Options, command.Name)
+	}
+}
+
+func localizeOptions(options []*discordgo.ApplicationCommandOption, path ...string) {
+	for _, option := range options {
+		optionPath := append(slices.Clone(path), option.Name)
+		key := i18n.CommandKey(optionPath...)
+		option.NameLocalizations = i18n.Localizations(key + ".name")
+		option.DescriptionLocalizations = i18n.Localizations(key + ".description")
+		for _, choice := range option.Choices {
+			choice.NameLocalizations = i18n.Localizations(key + ".choice." + choiceValue(choice))
+		}
+		localizeOptions(option.Options, optionPath...)
+	}
+}
+
+func choiceValue(choice *discordgo.ApplicationCommandOptionChoice) string {
+	if value, ok := choice.Value.(string); ok {
+		return value
+	}
+	return choice.Name
+}
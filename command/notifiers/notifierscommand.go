@@ -0,0 +1,153 @@
+package notifiers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandNotifiers connects the Telegram and ntfy destinations notifications can be routed to
+// with /setnotifications. Connecting sends a message through the backend, so the response is
+// deferred.
+func CommandNotifiers(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
+	userID, err := services.GetUserID(i)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to get user ID")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "common.choose_subcommand"))
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to defer response")
+		return
+	}
+
+	subcommand := options[0]
+	switch subcommand.Name {
+	case "telegram":
+		connectTelegram(s, i, lang, userID, stringOption(subcommand.Options, "chat_id"))
+	case "ntfy":
+		connectNtfy(s, i, lang, userID, stringOption(subcommand.Options, "topic"))
+	case "remove":
+		removeDestination(s, i, lang, userID, stringOption(subcommand.Options, "destination"))
+	case "test":
+		testDestination(s, i, lang, userID, stringOption(subcommand.Options, "destination"))
+	default:
+		sendFollowup(s, i, i18n.T(lang, "common.unknown_subcommand"))
+	}
+}
+
+func connectTelegram(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID, chatID string) {
+	err := services.SetTelegramChat(userID, chatID)
+	switch {
+	case errors.Is(err, services.ErrNotifierDisabled):
+		sendFollowup(s, i, i18n.T(lang, "notifiers.telegram.disabled"))
+	case errors.Is(err, services.ErrInvalidTelegramChat):
+		sendFollowup(s, i, i18n.T(lang, "notifiers.telegram.invalid"))
+	case err != nil:
+		logger.Log.WithError(err).Errorf("Failed to connect Telegram for user %s", userID)
+		sendFollowup(s, i, i18n.T(lang, "notifiers.telegram.rejected"))
+	default:
+		sendFollowup(s, i, i18n.T(lang, "notifiers.telegram.connected"))
+	}
+}
+
+func connectNtfy(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID, topic string) {
+	err := services.SetNtfyTopic(userID, topic)
+	switch {
+	case errors.Is(err, services.ErrNotifierDisabled):
+		sendFollowup(s, i, i18n.T(lang, "notifiers.ntfy.disabled"))
+	case errors.Is(err, services.ErrInvalidNtfyTopic):
+		sendFollowup(s, i, i18n.T(lang, "notifiers.ntfy.invalid"))
+	case err != nil:
+		logger.Log.WithError(err).Errorf("Failed to connect ntfy for user %s", userID)
+		sendFollowup(s, i, i18n.T(lang, "notifiers.ntfy.rejected"))
+	default:
+		sendFollowup(s, i, i18n.T(lang, "notifiers.ntfy.connected"))
+	}
+}
+
+func removeDestination(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID, destination string) {
+	var err error
+	switch destination {
+	case "telegram":
+		err = services.RemoveTelegramChat(userID)
+	case "ntfy":
+		err = services.RemoveNtfyTopic(userID)
+	default:
+		sendFollowup(s, i, i18n.T(lang, "notifiers.unknown_destination"))
+		return
+	}
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to remove %s for user %s", destination, userID)
+		sendFollowup(s, i, i18n.T(lang, "notifiers.error_remove"))
+		return
+	}
+	sendFollowup(s, i, i18n.T(lang, "notifiers.removed", destination))
+}
+
+func testDestination(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID, destination string) {
+	err := services.SendTestNotification(s, userID, destination)
+	switch {
+	case errors.Is(err, services.ErrNotifierDisabled), errors.Is(err, services.ErrUnknownDestination):
+		sendFollowup(s, i, i18n.T(lang, "notifiers.not_enabled", destination))
+	case errors.Is(err, services.ErrDestinationNotSetUp):
+		sendFollowup(s, i, i18n.T(lang, "notifiers.not_set_up", destination))
+	case err != nil:
+		logger.Log.WithError(err).Errorf("Failed to send test notification through %s for user %s", destination, userID)
+		sendFollowup(s, i, i18n.T(lang, "notifiers.error_test", destination))
+	default:
+		sendFollowup(s, i, i18n.T(lang, "notifiers.test_sent", destination))
+	}
+}
+
+func stringOption(options []*discordgo.ApplicationCommandInteractionDataOption, name string) string {
+	for _, option := range options {
+		if option.Name == name {
+			return strings.TrimSpace(option.StringValue())
+		}
+	}
+	return ""
+}
+
+func sendFollowup(s discordapi.Session, i *discordgo.InteractionCreate, content string) {
+	_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error sending followup message")
+	}
+}
+
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding to interaction")
+	}
+}
@@ -6,13 +6,19 @@ import (
 	"strings"
 
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
+
+	"github.com/bradselph/CODStatusBot/discordapi"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-func CommandRemoveAccount(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandRemoveAccount(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	var userID string
 	if i.Member != nil {
 		userID = i.Member.User.ID
@@ -20,7 +26,7 @@ func CommandRemoveAccount(s *discordgo.Session, i *discordgo.InteractionCreate)
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -28,12 +34,12 @@ func CommandRemoveAccount(s *discordgo.Session, i *discordgo.InteractionCreate)
 	result := database.DB.Where("user_id = ?", userID).Find(&accounts)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching user accounts")
-		respondToInteraction(s, i, "Error fetching your accounts. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_fetch_your_accounts"))
 		return
 	}
 
 	if len(accounts) == 0 {
-		respondToInteraction(s, i, "You don't have any monitored accounts to remove.")
+		respondToInteraction(s, i, i18n.T(lang, "removeaccount.no_accounts"))
 		return
 	}
 
@@ -64,7 +70,7 @@ func CommandRemoveAccount(s *discordgo.Session, i *discordgo.InteractionCreate)
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content:    "Select an account to remove:",
+			Content:    i18n.T(lang, "removeaccount.select"),
 			Flags:      discordgo.MessageFlagsEphemeral,
 			Components: components,
 		},
@@ -74,12 +80,14 @@ func CommandRemoveAccount(s *discordgo.Session, i *discordgo.InteractionCreate)
 	}
 }
 
-func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleAccountSelection(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	customID := i.MessageComponentData().CustomID
 	accountID, err := strconv.Atoi(strings.TrimPrefix(customID, "remove_account_"))
 	if err != nil {
 		logger.Log.WithError(err).Error("Error parsing account ID")
-		respondToInteraction(s, i, "Error processing your selection. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_selection"))
 		return
 	}
 
@@ -87,7 +95,7 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	result := database.DB.First(&account, accountID)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching account")
-		respondToInteraction(s, i, "Error: Account not found or you don't have permission to remove it.")
+		respondToInteraction(s, i, i18n.T(lang, "removeaccount.not_found"))
 		return
 	}
 
@@ -95,17 +103,17 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseUpdateMessage,
 		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("Are you sure you want to remove the account '%s'? This action is permanent and cannot be undone.", account.Title),
+			Content: i18n.T(lang, "removeaccount.confirm", account.Title),
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.Button{
-							Label:    "Delete",
+							Label:    i18n.T(lang, "removeaccount.delete"),
 							Style:    discordgo.DangerButton,
 							CustomID: fmt.Sprintf("confirm_remove_%d", account.ID),
 						},
 						discordgo.Button{
-							Label:    "Cancel",
+							Label:    i18n.T(lang, "common.cancel"),
 							Style:    discordgo.SecondaryButton,
 							CustomID: "cancel_remove",
 						},
@@ -116,22 +124,24 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	})
 	if err != nil {
 		logger.Log.WithError(err).Error("Error showing confirmation buttons")
-		respondToInteraction(s, i, "An error occurred. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_retry"))
 	}
 }
 
-func HandleConfirmation(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleConfirmation(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	customID := i.MessageComponentData().CustomID
 
 	if customID == "cancel_remove" {
-		respondToInteraction(s, i, "Account removal cancelled.")
+		respondToInteraction(s, i, i18n.T(lang, "removeaccount.cancelled"))
 		return
 	}
 
 	accountID, err := strconv.Atoi(strings.TrimPrefix(customID, "confirm_remove_"))
 	if err != nil {
 		logger.Log.WithError(err).Error("Error parsing account ID")
-		respondToInteraction(s, i, "Error processing your confirmation. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_confirmation"))
 		return
 	}
 
@@ -139,40 +149,20 @@ func HandleConfirmation(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	result := database.DB.First(&account, accountID)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching account")
-		respondToInteraction(s, i, "Error: Account not found or you don't have permission to remove it.")
-		return
-	}
-
-	// Start a transaction
-	tx := database.DB.Begin()
-
-	// Delete associated bans
-	if err := tx.Where("account_id = ?", account.ID).Delete(&models.Ban{}).Error; err != nil {
-		tx.Rollback()
-		logger.Log.WithError(err).Error("Error deleting associated bans")
-		respondToInteraction(s, i, "Error removing account. Please try again.")
-		return
-	}
-
-	// Delete the account
-	if err := tx.Delete(&account).Error; err != nil {
-		tx.Rollback()
-		logger.Log.WithError(err).Error("Error deleting account")
-		respondToInteraction(s, i, "Error removing account. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "removeaccount.not_found"))
 		return
 	}
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		logger.Log.WithError(err).Error("Error committing transaction")
-		respondToInteraction(s, i, "Error removing account. Please try again.")
+	if err := services.RemoveAccount(account); err != nil {
+		logger.Log.WithError(err).Error("Error removing account")
+		respondToInteraction(s, i, i18n.T(lang, "removeaccount.error_remove"))
 		return
 	}
 
-	respondToInteraction(s, i, fmt.Sprintf("Account '%s' has been successfully removed from the database.", account.Title))
+	respondToInteraction(s, i, i18n.T(lang, "removeaccount.removed", account.Title))
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseUpdateMessage,
 		Data: &discordgo.InteractionResponseData{
@@ -0,0 +1,222 @@
+package removeaccount_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bradselph/CODStatusBot/command/removeaccount"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+	"github.com/bwmarrin/discordgo"
+	"github.com/glebarez/sqlite"
+)
+
+const lang = i18n.English
+
+// useDatabase points the database at a fresh in-memory SQLite database holding count accounts
+// for user-1, titled account-1 and up, for the rest of the test.
+func useDatabase(t *testing.T, count int) []models.Account {
+	t.Helper()
+	previousDB := database.DB
+	t.Cleanup(func() { database.DB = previousDB })
+
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	if err := database.SetEncryptionKeys(key, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	if err := database.Connect(sqlite.Open("file::memory:")); err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	if _, err := database.MigrateUp(database.DB); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	var accounts []models.Account
+	for n := 1; n <= count; n++ {
+		account := models.Account{UserID: "user-1", Title: fmt.Sprintf("account-%d", n), LastStatus: models.StatusGood}
+		if err := database.DB.Create(&account).Error; err != nil {
+			t.Fatalf("create account: %v", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
+
+func button(account models.Account) discordgo.MessageComponent {
+	return discordgo.Button{
+		Label:    account.Title,
+		Style:    discordgo.PrimaryButton,
+		CustomID: fmt.Sprintf("remove_account_%d", account.ID),
+	}
+}
+
+// update is the message edit every step after the account list responds with.
+func update(content string) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	}
+}
+
+func TestCommandRemoveAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		accounts int
+		want     func(accounts []models.Account) *discordgo.InteractionResponse
+	}{
+		{
+			name: "no accounts",
+			want: func([]models.Account) *discordgo.InteractionResponse {
+				return update(i18n.T(lang, "removeaccount.no_accounts"))
+			},
+		},
+		{
+			name:     "one row of buttons",
+			accounts: 2,
+			want: func(accounts []models.Account) *discordgo.InteractionResponse {
+				return &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseChannelMessageWithSource,
+					Data: &discordgo.InteractionResponseData{
+						Content: i18n.T(lang, "removeaccount.select"),
+						Flags:   discordgo.MessageFlagsEphemeral,
+						Components: []discordgo.MessageComponent{
+							discordgo.ActionsRow{Components: []discordgo.MessageComponent{button(accounts[0]), button(accounts[1])}},
+						},
+					},
+				}
+			},
+		},
+		{
+			name:     "five buttons to a row",
+			accounts: 6,
+			want: func(accounts []models.Account) *discordgo.InteractionResponse {
+				return &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseChannelMessageWithSource,
+					Data: &discordgo.InteractionResponseData{
+						Content: i18n.T(lang, "removeaccount.select"),
+						Flags:   discordgo.MessageFlagsEphemeral,
+						Components: []discordgo.MessageComponent{
+							discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+								button(accounts[0]), button(accounts[1]), button(accounts[2]), button(accounts[3]), button(accounts[4]),
+							}},
+							discordgo.ActionsRow{Components: []discordgo.MessageComponent{button(accounts[5])}},
+						},
+					},
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts := useDatabase(t, tt.accounts)
+
+			s := fakediscord.New()
+			removeaccount.CommandRemoveAccount(s, fakediscord.SlashCommand("user-1", "removeaccount"))
+
+			want := tt.want(accounts)
+			if got := s.LastResponse().Response; !reflect.DeepEqual(got, want) {
+				t.Errorf("response = %+v\nwant %+v", got.Data, want.Data)
+			}
+		})
+	}
+}
+
+func TestRemoveAccountConfirmation(t *testing.T) {
+	tests := []struct {
+		name    string
+		presses func(account models.Account) []string
+		want    func(account models.Account) *discordgo.InteractionResponse
+		removed bool
+	}{
+		{
+			name: "asks for confirmation",
+			presses: func(account models.Account) []string {
+				return []string{fmt.Sprintf("remove_account_%d", account.ID)}
+			},
+			want: func(account models.Account) *discordgo.InteractionResponse {
+				return &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseUpdateMessage,
+					Data: &discordgo.InteractionResponseData{
+						Content: i18n.T(lang, "removeaccount.confirm", account.Title),
+						Components: []discordgo.MessageComponent{
+							discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+								discordgo.Button{
+									Label:    i18n.T(lang, "removeaccount.delete"),
+									Style:    discordgo.DangerButton,
+									CustomID: fmt.Sprintf("confirm_remove_%d", account.ID),
+								},
+								discordgo.Button{
+									Label:    i18n.T(lang, "common.cancel"),
+									Style:    discordgo.SecondaryButton,
+									CustomID: "cancel_remove",
+								},
+							}},
+						},
+					},
+				}
+			},
+		},
+		{
+			name: "confirmed",
+			presses: func(account models.Account) []string {
+				return []string{fmt.Sprintf("remove_account_%d", account.ID), fmt.Sprintf("confirm_remove_%d", account.ID)}
+			},
+			want: func(account models.Account) *discordgo.InteractionResponse {
+				return update(i18n.T(lang, "removeaccount.removed", account.Title))
+			},
+			removed: true,
+		},
+		{
+			name: "cancelled",
+			presses: func(account models.Account) []string {
+				return []string{fmt.Sprintf("remove_account_%d", account.ID), "cancel_remove"}
+			},
+			want: func(models.Account) *discordgo.InteractionResponse {
+				return update(i18n.T(lang, "removeaccount.cancelled"))
+			},
+		},
+		{
+			name: "account already gone",
+			presses: func(account models.Account) []string {
+				return []string{fmt.Sprintf("confirm_remove_%d", account.ID+100)}
+			},
+			want: func(models.Account) *discordgo.InteractionResponse {
+				return update(i18n.T(lang, "removeaccount.not_found"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := useDatabase(t, 1)[0]
+			s := fakediscord.New()
+
+			for _, customID := range tt.presses(account) {
+				press := fakediscord.Component("user-1", customID)
+				if strings.HasPrefix(customID, "remove_account_") {
+					removeaccount.HandleAccountSelection(s, press)
+				} else {
+					removeaccount.HandleConfirmation(s, press)
+				}
+			}
+
+			want := tt.want(account)
+			if got := s.LastResponse().Response; !reflect.DeepEqual(got, want) {
+				t.Errorf("response = %+v\nwant %+v", got.Data, want.Data)
+			}
+
+			var count int64
+			database.DB.Model(&models.Account{}).Where("id = ?", account.ID).Count(&count)
+			if removed := count == 0; removed != tt.removed {
+				t.Errorf("account removed = %v, want %v", removed, tt.removed)
+			}
+		})
+	}
+}
@@ -1,12 +1,15 @@
 package setcaptchaservice
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bradselph/CODStatusBot/services"
@@ -15,12 +18,15 @@ import (
 )
 
 var providerLabels = map[string]string{
-	"capsolver": "Capsolver",
-	"ezcaptcha": "EZCaptcha",
-	"2captcha":  "2Captcha",
+	"capsolver":   "Capsolver",
+	"ezcaptcha":   "EZCaptcha",
+	"2captcha":    "2Captcha",
+	"anticaptcha": "Anti-Captcha",
+	"capmonster":  "CapMonster Cloud",
 }
 
-func CommandSetCaptchaService(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandSetCaptchaService(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
 	cfg := configuration.Get()
 	var components []discordgo.MessageComponent
 
@@ -36,23 +42,33 @@ func CommandSetCaptchaService(s *discordgo.Session, i *discordgo.InteractionCrea
 		components = append(components, createProviderButton("2captcha"))
 	}
 
-	components = append(components, discordgo.Button{
-		Label:    "Remove API Key",
-		Style:    discordgo.DangerButton,
-		CustomID: "set_captcha_remove",
-	})
+	if cfg.CaptchaService.AntiCaptcha.Enabled {
+		components = append(components, createProviderButton("anticaptcha"))
+	}
+
+	if cfg.CaptchaService.CapMonster.Enabled {
+		components = append(components, createProviderButton("capmonster"))
+	}
 
-	if len(components) == 1 {
-		respondToInteraction(s, i, "No captcha services are currently enabled. Please contact the bot administrator.")
+	if len(components) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "setcaptchaservice.none_enabled"))
 		return
 	}
 
+	// An action row holds at most five buttons, so the removal button gets its own row.
+	removeButton := discordgo.Button{
+		Label:    i18n.T(lang, "setcaptchaservice.remove_button"),
+		Style:    discordgo.DangerButton,
+		CustomID: "set_captcha_remove",
+	}
+
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: "Select a captcha service provider:",
+			Content: i18n.T(lang, "setcaptchaservice.select"),
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{Components: components},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{removeButton}},
 			},
 			Flags: discordgo.MessageFlagsEphemeral,
 		},
@@ -62,36 +78,38 @@ func CommandSetCaptchaService(s *discordgo.Session, i *discordgo.InteractionCrea
 	}
 }
 
-func HandleCaptchaServiceSelection(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleCaptchaServiceSelection(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
 	customID := i.MessageComponentData().CustomID
 
 	if customID == "set_captcha_remove" {
-		handleAPIKeyRemoval(s, i)
+		handleAPIKeyRemoval(s, i, lang)
 		return
 	}
 
 	provider := strings.TrimPrefix(customID, "set_captcha_")
 	if _, ok := providerLabels[provider]; !ok {
-		respondToInteraction(s, i, "Invalid service selection")
+		respondToInteraction(s, i, i18n.T(lang, "setcaptchaservice.invalid_selection"))
 		return
 	}
 
-	showAPIKeyModal(s, i, provider)
+	showAPIKeyModal(s, i, lang, provider)
 }
 
-func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleModalSubmit(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
 	data := i.ModalSubmitData()
 	provider := strings.TrimPrefix(data.CustomID, "set_captcha_service_modal_")
 
 	userID, err := services.GetUserID(i)
 	if err != nil {
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	apiKey := getAPIKeyFromModal(data)
-	if err := validateAndSaveAPIKey(s, i, userID, provider, apiKey); err != nil {
-		respondToInteraction(s, i, fmt.Sprintf("Error: %v", err))
+	if err := validateAndSaveAPIKey(s, i, lang, userID, provider, apiKey); err != nil {
+		respondToInteraction(s, i, i18n.T(lang, "setcaptchaservice.error", err))
 		return
 	}
 }
@@ -104,35 +122,35 @@ func createProviderButton(provider string) discordgo.Button {
 	}
 }
 
-func handleAPIKeyRemoval(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func handleAPIKeyRemoval(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang) {
 	userID, err := services.GetUserID(i)
 	if err != nil {
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	if err := services.RemoveCaptchaKey(userID); err != nil {
-		respondToInteraction(s, i, "Error removing API key. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "setcaptchaservice.error_remove"))
 		return
 	}
 
-	respondToInteraction(s, i, "Your API key has been removed. The bot's default API key will be used. Your check interval and notification settings have been reset to default values.")
+	respondToInteraction(s, i, i18n.T(lang, "setcaptchaservice.removed"))
 }
 
-func showAPIKeyModal(s *discordgo.Session, i *discordgo.InteractionCreate, provider string) {
+func showAPIKeyModal(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, provider string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseModal,
 		Data: &discordgo.InteractionResponseData{
 			CustomID: fmt.Sprintf("set_captcha_service_modal_%s", provider),
-			Title:    fmt.Sprintf("Set %s API Key", providerLabels[provider]),
+			Title:    i18n.T(lang, "setcaptchaservice.modal.title", providerLabels[provider]),
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.TextInput{
 							CustomID:    "api_key",
-							Label:       fmt.Sprintf("Enter your %s API key", providerLabels[provider]),
+							Label:       i18n.T(lang, "setcaptchaservice.modal.label", providerLabels[provider]),
 							Style:       discordgo.TextInputShort,
-							Placeholder: "Enter your new API key",
+							Placeholder: i18n.T(lang, "setcaptchaservice.modal.placeholder"),
 							Required:    true,
 							MinLength:   32,
 							MaxLength:   90,
@@ -160,22 +178,25 @@ func getAPIKeyFromModal(data discordgo.ModalSubmitInteractionData) string {
 	return ""
 }
 
-func validateAndSaveAPIKey(s *discordgo.Session, i *discordgo.InteractionCreate, userID, provider, apiKey string) error {
-	isValid, balance, err := services.ValidateCaptchaKey(apiKey, provider)
+func validateAndSaveAPIKey(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID, provider, apiKey string) error {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
+	isValid, balance, err := services.ValidateCaptchaKey(ctx, apiKey, provider)
 	if err != nil {
-		return fmt.Errorf("error validating the %s API key: %v", provider, err)
+		return errors.New(i18n.T(lang, "setcaptchaservice.error_validate", provider, err))
 	}
 	if !isValid {
-		return fmt.Errorf("the provided %s API key is invalid", provider)
+		return errors.New(i18n.T(lang, "setcaptchaservice.invalid_key", provider))
 	}
 
 	settings := models.UserSettings{UserID: userID}
 	if err := database.DB.Where("user_id = ?", userID).FirstOrCreate(&settings).Error; err != nil {
-		return fmt.Errorf("error updating settings")
+		return errors.New(i18n.T(lang, "setcaptchaservice.error_settings_update"))
 	}
 
 	cfg := configuration.Get()
-	settings.PreferredCaptchaProvider = provider
+	services.PreferCaptchaProvider(&settings, provider)
 	settings.CheckInterval = cfg.Intervals.Check
 	settings.NotificationInterval = cfg.Intervals.Notification
 	settings.CustomSettings = true
@@ -185,31 +206,33 @@ func validateAndSaveAPIKey(s *discordgo.Session, i *discordgo.InteractionCreate,
 	updateAPIKeys(&settings, provider, apiKey)
 
 	if err := database.DB.Save(&settings).Error; err != nil {
-		return fmt.Errorf("error saving settings")
+		return errors.New(i18n.T(lang, "setcaptchaservice.error_settings_save"))
 	}
 
 	if apiKey != cfg.CaptchaService.Capsolver.ClientKey &&
 		apiKey != cfg.CaptchaService.EZCaptcha.ClientKey &&
-		apiKey != cfg.CaptchaService.TwoCaptcha.ClientKey {
+		apiKey != cfg.CaptchaService.TwoCaptcha.ClientKey &&
+		apiKey != cfg.CaptchaService.AntiCaptcha.ClientKey &&
+		apiKey != cfg.CaptchaService.CapMonster.ClientKey {
 
 		embed := &discordgo.MessageEmbed{
-			Title:       "API Key Configuration Updated",
-			Description: fmt.Sprintf("Your %s API key has been configured successfully!", providerLabels[provider]),
+			Title:       i18n.T(lang, "setcaptchaservice.updated.title"),
+			Description: i18n.T(lang, "setcaptchaservice.updated.description", providerLabels[provider]),
 			Color:       0x00ff00,
 			Fields: []*discordgo.MessageEmbedField{
 				{
-					Name:   "Premium Features Unlocked",
-					Value:  "• Faster check intervals\n• Increased account limits\n• Priority status updates",
+					Name:   i18n.T(lang, "setcaptchaservice.premium.name"),
+					Value:  i18n.T(lang, "setcaptchaservice.premium.value"),
 					Inline: false,
 				},
 				{
-					Name:   "Service Provider",
+					Name:   i18n.T(lang, "setcaptchaservice.service_provider"),
 					Value:  providerLabels[provider],
 					Inline: true,
 				},
 				{
-					Name:   "Current Balance",
-					Value:  fmt.Sprintf("%.2f points", balance),
+					Name:   i18n.T(lang, "setcaptchaservice.current_balance"),
+					Value:  i18n.T(lang, "setcaptchaservice.points", balance),
 					Inline: true,
 				},
 			},
@@ -218,12 +241,12 @@ func validateAndSaveAPIKey(s *discordgo.Session, i *discordgo.InteractionCreate,
 		respondToInteractionWithEmbed(s, i, "", embed)
 	} else {
 		embed := &discordgo.MessageEmbed{
-			Title:       "API Key Configuration Updated",
-			Description: fmt.Sprintf("Your %s API key has been configured successfully!", providerLabels[provider]),
+			Title:       i18n.T(lang, "setcaptchaservice.updated.title"),
+			Description: i18n.T(lang, "setcaptchaservice.updated.description", providerLabels[provider]),
 			Color:       0x00ff00,
 			Fields: []*discordgo.MessageEmbedField{
 				{
-					Name:   "Service Provider",
+					Name:   i18n.T(lang, "setcaptchaservice.service_provider"),
 					Value:  providerLabels[provider],
 					Inline: true,
 				},
@@ -236,11 +259,9 @@ func validateAndSaveAPIKey(s *discordgo.Session, i *discordgo.InteractionCreate,
 	return nil
 }
 
+// updateAPIKeys stores the key for provider. Keys saved earlier for other providers are kept so
+// solves can fail over to them.
 func updateAPIKeys(settings *models.UserSettings, provider, apiKey string) {
-	settings.CapSolverAPIKey = ""
-	settings.EZCaptchaAPIKey = ""
-	settings.TwoCaptchaAPIKey = ""
-
 	switch provider {
 	case "capsolver":
 		settings.CapSolverAPIKey = apiKey
@@ -248,10 +269,14 @@ func updateAPIKeys(settings *models.UserSettings, provider, apiKey string) {
 		settings.EZCaptchaAPIKey = apiKey
 	case "2captcha":
 		settings.TwoCaptchaAPIKey = apiKey
+	case "anticaptcha":
+		settings.AntiCaptchaAPIKey = apiKey
+	case "capmonster":
+		settings.CapMonsterAPIKey = apiKey
 	}
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -264,7 +289,7 @@ func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate,
 	}
 }
 
-func respondToInteractionWithEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, message string, embed *discordgo.MessageEmbed) {
+func respondToInteractionWithEmbed(s discordapi.Session, i *discordgo.InteractionCreate, message string, embed *discordgo.MessageEmbed) {
 	response := &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
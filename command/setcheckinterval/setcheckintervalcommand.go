@@ -7,14 +7,19 @@ import (
 	"time"
 
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bradselph/CODStatusBot/utils"
 
+	"github.com/bradselph/CODStatusBot/discordapi"
+
 	"github.com/bwmarrin/discordgo"
 )
 
-func CommandSetCheckInterval(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandSetCheckInterval(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	var userID string
 	if i.Member != nil {
 		userID = i.Member.User.ID
@@ -22,41 +27,28 @@ func CommandSetCheckInterval(s *discordgo.Session, i *discordgo.InteractionCreat
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching your settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "setcheckinterval.error_settings"))
 		return
 	}
 
-	if userSettings.CapSolverAPIKey == "" && userSettings.EZCaptchaAPIKey == "" && userSettings.TwoCaptchaAPIKey == "" {
-		respondToInteraction(s, i, "You need to set your own Capsolver or EZ-Captcha or 2captcha API key using the /setcaptchaservice command before you can modify these settings.")
+	if !userSettings.HasCustomCaptchaKey() {
+		respondToInteraction(s, i, i18n.T(lang, "setcheckinterval.requires_key"))
 		return
 	}
 
 	explanationEmbed := &discordgo.MessageEmbed{
-		Title: "Configure Check & Notification Settings",
-		Description: "You're about to configure the following settings:\n\n" +
-			"**• Check Interval (1-1440 minutes)**\n" +
-			"How often the bot checks your account status. Lower values mean more frequent checks but use more captcha credits.\n" +
-			"Recommended: 15-30 minutes for active monitoring.\n\n" +
-			"**• Notification Interval (1-24 hours)**\n" +
-			"How often you receive regular status updates, even when nothing changes.\n" +
-			"Recommended: 12-24 hours for daily updates.\n\n" +
-			"**• Cooldown Duration (1-24 hours)**\n" +
-			"Minimum time between repeated notifications of the same type to prevent spam.\n" +
-			"Recommended: 6-12 hours.\n\n" +
-			"**• Status Change Cooldown (1-24 hours)**\n" +
-			"Minimum time between notifications when your account status changes.\n" +
-			"Recommended: 1-2 hours.\n\n" +
-			"Click 'Configure' to set these values.",
-		Color: 0x00ff00,
+		Title:       i18n.T(lang, "setcheckinterval.explanation.title"),
+		Description: i18n.T(lang, "setcheckinterval.explanation.description"),
+		Color:       0x00ff00,
 		Footer: &discordgo.MessageEmbedFooter{
-			Text: "Note: Lower intervals provide more frequent updates but use more captcha credits",
+			Text: i18n.T(lang, "setcheckinterval.explanation.footer"),
 		},
 	}
 
@@ -68,7 +60,7 @@ func CommandSetCheckInterval(s *discordgo.Session, i *discordgo.InteractionCreat
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.Button{
-							Label:    "Configure",
+							Label:    i18n.T(lang, "setcheckinterval.configure"),
 							Style:    discordgo.PrimaryButton,
 							CustomID: "show_interval_modal",
 						},
@@ -83,11 +75,13 @@ func CommandSetCheckInterval(s *discordgo.Session, i *discordgo.InteractionCreat
 	}
 }
 
-func HandleButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleButton(s discordapi.Session, i *discordgo.InteractionCreate) {
 	if i.MessageComponentData().CustomID != "show_interval_modal" {
 		return
 	}
 
+	lang := services.InteractionLanguage(i)
+
 	userID := ""
 	if i.Member != nil {
 		userID = i.Member.User.ID
@@ -98,7 +92,7 @@ func HandleButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching your settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "setcheckinterval.error_settings"))
 		return
 	}
 
@@ -106,13 +100,13 @@ func HandleButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		Type: discordgo.InteractionResponseModal,
 		Data: &discordgo.InteractionResponseData{
 			CustomID: "set_check_interval_modal",
-			Title:    "Configure Settings",
+			Title:    i18n.T(lang, "setcheckinterval.modal.title"),
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.TextInput{
 							CustomID:  "check_interval",
-							Label:     "Check Interval (minutes)",
+							Label:     i18n.T(lang, "setcheckinterval.modal.check_interval"),
 							Style:     discordgo.TextInputShort,
 							Required:  false,
 							MinLength: 0,
@@ -125,7 +119,7 @@ func HandleButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
 					Components: []discordgo.MessageComponent{
 						discordgo.TextInput{
 							CustomID:  "notification_interval",
-							Label:     "Notification Interval (hours)",
+							Label:     i18n.T(lang, "setcheckinterval.modal.notification_interval"),
 							Style:     discordgo.TextInputShort,
 							Required:  false,
 							MinLength: 0,
@@ -138,7 +132,7 @@ func HandleButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
 					Components: []discordgo.MessageComponent{
 						discordgo.TextInput{
 							CustomID:  "cooldown_duration",
-							Label:     "Cooldown Duration (hours)",
+							Label:     i18n.T(lang, "setcheckinterval.modal.cooldown"),
 							Style:     discordgo.TextInputShort,
 							Required:  false,
 							MinLength: 0,
@@ -151,7 +145,7 @@ func HandleButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
 					Components: []discordgo.MessageComponent{
 						discordgo.TextInput{
 							CustomID:  "status_change_cooldown",
-							Label:     "Status Change Cooldown (hours)",
+							Label:     i18n.T(lang, "setcheckinterval.modal.status_cooldown"),
 							Style:     discordgo.TextInputShort,
 							Required:  false,
 							MinLength: 0,
@@ -168,7 +162,8 @@ func HandleButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 }
 
-func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleModalSubmit(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
 	data := i.ModalSubmitData()
 
 	var userID string
@@ -178,21 +173,21 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching your settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "setcheckinterval.error_settings"))
 		return
 	}
 
 	defaultSettings, err := services.GetDefaultSettings()
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching default settings")
-		respondToInteraction(s, i, "Error fetching default settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "setcheckinterval.error_defaults"))
 		return
 	}
 
@@ -211,7 +206,7 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 						} else {
 							interval, err := strconv.Atoi(value)
 							if err != nil || interval < 1 || interval > 1440 {
-								errors = append(errors, "Check interval must be between 1 and 1440 minutes.")
+								errors = append(errors, i18n.T(lang, "setcheckinterval.invalid.check_interval"))
 								continue
 							}
 							userSettings.CheckInterval = interval
@@ -223,7 +218,7 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 						} else {
 							interval, err := strconv.ParseFloat(value, 64)
 							if err != nil || interval < 1 || interval > 24 {
-								errors = append(errors, "Notification interval must be between 1 and 24 hours.")
+								errors = append(errors, i18n.T(lang, "setcheckinterval.invalid.notification_interval"))
 								continue
 							}
 							userSettings.NotificationInterval = interval
@@ -235,7 +230,7 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 						} else {
 							duration, err := strconv.ParseFloat(value, 64)
 							if err != nil || duration < 1 || duration > 24 {
-								errors = append(errors, "Cooldown duration must be between 1 and 24 hours.")
+								errors = append(errors, i18n.T(lang, "setcheckinterval.invalid.cooldown"))
 								continue
 							}
 							userSettings.CooldownDuration = duration
@@ -247,7 +242,7 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 						} else {
 							cooldown, err := strconv.ParseFloat(value, 64)
 							if err != nil || cooldown < 1 || cooldown > 24 {
-								errors = append(errors, "Status change cooldown must be between 1 and 24 hours.")
+								errors = append(errors, i18n.T(lang, "setcheckinterval.invalid.status_cooldown"))
 								continue
 							}
 							userSettings.StatusChangeCooldown = cooldown
@@ -259,23 +254,19 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 
 	if len(errors) > 0 {
-		respondToInteraction(s, i, fmt.Sprintf("Error updating settings:\n%s", strings.Join(errors, "\n")))
+		respondToInteraction(s, i, i18n.T(lang, "setcheckinterval.invalid", strings.Join(errors, "\n")))
 		return
 	}
 
 	if err := database.DB.Save(&userSettings).Error; err != nil {
 		logger.Log.WithError(err).Error("Error saving user settings")
-		respondToInteraction(s, i, "Error updating your settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "setcheckinterval.error_save"))
 		return
 	}
 
 	successEmbed := &discordgo.MessageEmbed{
-		Title: "Settings Updated Successfully",
-		Description: fmt.Sprintf("Your new settings:\n\n"+
-			"• Check Interval: %d minutes\n"+
-			"• Notification Interval: %.1f hours\n"+
-			"• Cooldown Duration: %.1f hours\n"+
-			"• Status Change Cooldown: %.1f hours",
+		Title: i18n.T(lang, "setcheckinterval.success.title"),
+		Description: i18n.T(lang, "setcheckinterval.success.description",
 			userSettings.CheckInterval,
 			userSettings.NotificationInterval,
 			userSettings.CooldownDuration,
@@ -287,7 +278,7 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	respondToInteraction(s, i, "", successEmbed)
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string, embeds ...*discordgo.MessageEmbed) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string, embeds ...*discordgo.MessageEmbed) {
 	response := &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
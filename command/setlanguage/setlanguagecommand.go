@@ -0,0 +1,67 @@
+package setlanguage
+
+import (
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Auto is the language option value that follows the user's Discord client.
+const Auto = "auto"
+
+func CommandSetLanguage(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
+	userID, err := services.GetUserID(i)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to get user ID")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
+		return
+	}
+
+	code := Auto
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "language" {
+			code = option.StringValue()
+		}
+	}
+
+	var setting string
+	if code != Auto {
+		chosen, ok := i18n.Parse(code)
+		if !ok {
+			respondToInteraction(s, i, i18n.T(lang, "setlanguage.unsupported"))
+			return
+		}
+		setting = string(chosen)
+	}
+
+	if err := services.SetUserLanguage(userID, setting); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to set language for user %s", userID)
+		respondToInteraction(s, i, i18n.T(lang, "setlanguage.error_save"))
+		return
+	}
+
+	if setting == "" {
+		lang = i18n.FromLocale(i.Locale)
+		respondToInteraction(s, i, i18n.T(lang, "setlanguage.auto", i18n.Name(lang)))
+		return
+	}
+	lang = i18n.Lang(setting)
+	respondToInteraction(s, i, i18n.T(lang, "setlanguage.set", i18n.Name(lang)))
+}
+
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding to interaction")
+	}
+}
@@ -1,51 +1,53 @@
 package setnotifications
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bradselph/CODStatusBot/utils"
 	"github.com/bwmarrin/discordgo"
 )
 
-func CommandSetNotifications(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandSetNotifications(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	userID := getUserID(i)
 	if userID == "" {
 		logger.Log.Error("Could not determine user ID")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	var userSettings models.UserSettings
 	if err := database.DB.Where("user_id = ?", userID).FirstOrCreate(&userSettings).Error; err != nil {
 		logger.Log.WithError(err).Error("Error getting user settings")
-		respondToInteraction(s, i, "Error retrieving your current settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "setnotifications.error_settings"))
 		return
 	}
 
+	defaults := userSettings.NotificationRoutes[services.RouteDefault]
+	if len(defaults) == 0 {
+		defaults = []string{userSettings.NotificationType}
+	}
+	options := strings.Join(services.NotifierNames(), ", ")
+
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseModal,
 		Data: &discordgo.InteractionResponseData{
 			CustomID: fmt.Sprintf("set_notifications_modal_%s", userID),
-			Title:    "Set Notification Preferences",
+			Title:    i18n.T(lang, "setnotifications.modal.title"),
 			Components: []discordgo.MessageComponent{
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						discordgo.TextInput{
-							CustomID:    "notification_type",
-							Label:       "Notification Type (channel or dm)",
-							Style:       discordgo.TextInputShort,
-							Placeholder: "Enter 'channel' or 'dm'",
-							Required:    true,
-							MinLength:   2,
-							MaxLength:   7,
-							Value:       userSettings.NotificationType,
-						},
-					},
-				},
+				routeInput("notification_type", i18n.T(lang, "setnotifications.modal.default"), i18n.T(lang, "setnotifications.modal.default_placeholder", options), defaults, true),
+				routeInput("route_status", i18n.T(lang, "setnotifications.modal.status"), i18n.T(lang, "setnotifications.modal.example", "dm, telegram"), userSettings.NotificationRoutes[services.RouteStatus], false),
+				routeInput("route_daily", i18n.T(lang, "setnotifications.modal.daily"), i18n.T(lang, "setnotifications.modal.example", "email"), userSettings.NotificationRoutes[services.RouteDaily], false),
+				routeInput("route_cookie", i18n.T(lang, "setnotifications.modal.cookie"), i18n.T(lang, "setnotifications.modal.example", "channel, ntfy"), userSettings.NotificationRoutes[services.RouteCookie], false),
 			},
 		},
 	})
@@ -54,13 +56,38 @@ func CommandSetNotifications(s *discordgo.Session, i *discordgo.InteractionCreat
 	}
 }
 
-func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func routeInput(customID, label, placeholder string, value []string, required bool) discordgo.ActionsRow {
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:    customID,
+				Label:       label,
+				Style:       discordgo.TextInputShort,
+				Placeholder: placeholder,
+				Required:    required,
+				MaxLength:   100,
+				Value:       strings.Join(value, ", "),
+			},
+		},
+	}
+}
+
+// routeCategories maps the modal's inputs to the notification categories they route.
+var routeCategories = map[string]string{
+	"notification_type": services.RouteDefault,
+	"route_status":      services.RouteStatus,
+	"route_daily":       services.RouteDaily,
+	"route_cookie":      services.RouteCookie,
+}
+
+func HandleModalSubmit(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
 	data := i.ModalSubmitData()
 
 	parts := strings.Split(data.CustomID, "_")
 	if len(parts) < 4 {
 		logger.Log.Error("Invalid modal custom ID format")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 	userID := parts[len(parts)-1]
@@ -68,39 +95,52 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	interactionUserID := getUserID(i)
 	if interactionUserID == "" || interactionUserID != userID {
 		logger.Log.Error("User ID mismatch or not found")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
+		return
+	}
+
+	var userSettings models.UserSettings
+	if err := database.DB.Where("user_id = ?", userID).FirstOrCreate(&userSettings).Error; err != nil {
+		logger.Log.WithError(err).Error("Error getting/creating user settings")
+		respondToInteraction(s, i, i18n.T(lang, "setnotifications.error_update"))
 		return
 	}
 
-	var notificationType string
+	routes := make(map[string][]string)
 	for _, comp := range data.Components {
 		if row, ok := comp.(*discordgo.ActionsRow); ok {
 			for _, rowComp := range row.Components {
-				if textInput, ok := rowComp.(*discordgo.TextInput); ok {
-					if textInput.CustomID == "notification_type" {
-						notificationType = strings.ToLower(utils.SanitizeInput(textInput.Value))
-					}
+				textInput, ok := rowComp.(*discordgo.TextInput)
+				if !ok {
+					continue
+				}
+				category, ok := routeCategories[textInput.CustomID]
+				if !ok {
+					continue
+				}
+				destinations, err := services.ParseNotificationDestinations(userSettings, utils.SanitizeInput(textInput.Value))
+				if err != nil {
+					respondToInteraction(s, i, destinationError(lang, err))
+					return
+				}
+				if len(destinations) > 0 {
+					routes[category] = destinations
 				}
 			}
 		}
 	}
 
-	if notificationType != "channel" && notificationType != "dm" {
-		respondToInteraction(s, i, "Invalid notification type. Please enter 'channel' or 'dm'.")
-		return
-	}
-
-	var userSettings models.UserSettings
-	if err := database.DB.Where("user_id = ?", userID).FirstOrCreate(&userSettings).Error; err != nil {
-		logger.Log.WithError(err).Error("Error getting/creating user settings")
-		respondToInteraction(s, i, "Error updating settings. Please try again.")
+	if len(routes[services.RouteDefault]) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "setnotifications.destination_required", strings.Join(services.NotifierNames(), ", ")))
 		return
 	}
+	notificationType := routes[services.RouteDefault][0]
 
 	userSettings.NotificationType = notificationType
+	userSettings.NotificationRoutes = routes
 	if err := database.DB.Save(&userSettings).Error; err != nil {
 		logger.Log.WithError(err).Error("Error saving user settings")
-		respondToInteraction(s, i, "Error saving settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "setnotifications.error_save"))
 		return
 	}
 
@@ -112,15 +152,42 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error updating user accounts")
-		respondToInteraction(s, i, "Error updating accounts with new settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "setnotifications.error_accounts"))
 		return
 	}
 
-	logger.Log.Infof("Updated notification preferences for user %s to %s", userID, notificationType)
-	message := fmt.Sprintf("Your notification preferences have been updated. You will now receive notifications via %s.", notificationType)
+	logger.Log.Infof("Updated notification preferences for user %s to %v", userID, routes)
+	message := i18n.T(lang, "setnotifications.updated", strings.Join(routes[services.RouteDefault], ", "))
+	for _, route := range []struct{ category, id string }{
+		{services.RouteStatus, "setnotifications.route.status"},
+		{services.RouteDaily, "setnotifications.route.daily"},
+		{services.RouteCookie, "setnotifications.route.cookie"},
+	} {
+		if destinations := routes[route.category]; len(destinations) > 0 {
+			message += "\n" + i18n.T(lang, route.id, strings.Join(destinations, ", "))
+		}
+	}
 	respondToInteraction(s, i, message)
 }
 
+func destinationError(lang i18n.Lang, err error) string {
+	var destErr *services.DestinationError
+	if !errors.As(err, &destErr) {
+		return i18n.T(lang, "setnotifications.invalid")
+	}
+	name := destErr.Name
+	switch {
+	case errors.Is(err, services.ErrUnknownDestination), errors.Is(err, services.ErrNotifierDisabled):
+		return i18n.T(lang, "setnotifications.unavailable", name, strings.Join(services.NotifierNames(), ", "))
+	case errors.Is(err, services.ErrDestinationNotSetUp) && name == "email":
+		return i18n.T(lang, "setnotifications.email_required")
+	case errors.Is(err, services.ErrDestinationNotSetUp):
+		return i18n.T(lang, "setnotifications.connect_first", name)
+	default:
+		return i18n.T(lang, "setnotifications.invalid")
+	}
+}
+
 func getUserID(i *discordgo.InteractionCreate) string {
 	if i.Member != nil && i.Member.User != nil {
 		return i.Member.User.ID
@@ -133,7 +200,7 @@ func getUserID(i *discordgo.InteractionCreate) string {
 	return ""
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
 	var err error
 	if i.Type == discordgo.InteractionMessageComponent {
 		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
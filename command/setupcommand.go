@@ -4,31 +4,39 @@ import (
 	"github.com/bradselph/CODStatusBot/command/accountage"
 	"github.com/bradselph/CODStatusBot/command/accountlogs"
 	"github.com/bradselph/CODStatusBot/command/addaccount"
+	"github.com/bradselph/CODStatusBot/command/apitoken"
+	"github.com/bradselph/CODStatusBot/command/captchausage"
 	"github.com/bradselph/CODStatusBot/command/checkcaptchabalance"
 	"github.com/bradselph/CODStatusBot/command/checknow"
+	"github.com/bradselph/CODStatusBot/command/email"
 	"github.com/bradselph/CODStatusBot/command/feedback"
 	"github.com/bradselph/CODStatusBot/command/globalannouncement"
 	"github.com/bradselph/CODStatusBot/command/helpapi"
 	"github.com/bradselph/CODStatusBot/command/helpcookie"
 	"github.com/bradselph/CODStatusBot/command/listaccounts"
+	"github.com/bradselph/CODStatusBot/command/notifiers"
 	"github.com/bradselph/CODStatusBot/command/removeaccount"
 	"github.com/bradselph/CODStatusBot/command/setcaptchaservice"
 	"github.com/bradselph/CODStatusBot/command/setcheckinterval"
+	"github.com/bradselph/CODStatusBot/command/setlanguage"
 	"github.com/bradselph/CODStatusBot/command/setnotifications"
 	"github.com/bradselph/CODStatusBot/command/togglecheck"
 	"github.com/bradselph/CODStatusBot/command/updateaccount"
+	"github.com/bradselph/CODStatusBot/command/webhooks"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bwmarrin/discordgo"
 )
 
-var Handlers = map[string]func(*discordgo.Session, *discordgo.InteractionCreate){}
+var Handlers = map[string]func(discordapi.Session, *discordgo.InteractionCreate){}
 
-func RegisterCommands(s *discordgo.Session) error {
-	logger.Log.Info("Registering global commands")
-
-	commands := []*discordgo.ApplicationCommand{
+// Commands returns the slash commands the bot registers, with their English names and
+// descriptions.
+func Commands() []*discordgo.ApplicationCommand {
+	return []*discordgo.ApplicationCommand{
 		{
 			Name:         "globalannouncement",
 			Description:  "Send a global announcement to all users (Admin only)",
@@ -36,7 +44,7 @@ func RegisterCommands(s *discordgo.Session) error {
 		},
 		{
 			Name:         "setcaptchaservice",
-			Description:  "Set your Captcha service provider and API key (EZCaptcha/2Captcha)",
+			Description:  "Set your Captcha service provider and API key",
 			DMPermission: BoolPtr(true),
 		},
 		{
@@ -59,6 +67,20 @@ func RegisterCommands(s *discordgo.Session) error {
 			Description:  "Check your captcha service balance",
 			DMPermission: BoolPtr(true),
 		},
+		{
+			Name:         "captchausage",
+			Description:  "Show your captcha spend and set a monthly budget",
+			DMPermission: BoolPtr(true),
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionNumber,
+					Name:        "monthly_budget",
+					Description: "Pause scheduled checks once this month's estimated spend reaches this many USD (0 removes it)",
+					Required:    false,
+					MinValue:    Float64Ptr(0),
+				},
+			},
+		},
 		{
 			Name:         "helpapi",
 			DMPermission: BoolPtr(true),
@@ -117,18 +139,265 @@ func RegisterCommands(s *discordgo.Session) error {
 			Description:  "Toggle checks on/off for a monitored account",
 			DMPermission: BoolPtr(true),
 		},
+		{
+			Name:         "apitoken",
+			Description:  "Manage tokens for the REST API",
+			DMPermission: BoolPtr(true),
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "create",
+					Description: "Create a new API token",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "A label to tell this token apart from your others",
+							Required:    false,
+							MaxLength:   40,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List your API tokens",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "revoke",
+					Description: "Revoke an API token",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "id",
+							Description: "The token ID shown by /apitoken list",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "webhooks",
+			Description:  "Send account events to your own HTTPS endpoints",
+			DMPermission: BoolPtr(true),
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "add",
+					Description: "Register a webhook URL",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "url",
+							Description: "The HTTPS URL events are posted to",
+							Required:    true,
+							MaxLength:   512,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "account",
+							Description: "Only send events for the account with this title; leave empty for all accounts",
+							Required:    false,
+							MaxLength:   40,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List your webhooks",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "remove",
+					Description: "Remove a webhook",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "id",
+							Description: "The webhook ID shown by /webhooks list",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "test",
+					Description: "Send a test event to a webhook",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "id",
+							Description: "The webhook ID shown by /webhooks list",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "secret",
+					Description: "Show the secret your webhook deliveries are signed with",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "rotate",
+							Description: "Replace the secret with a new one",
+							Required:    false,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "failed",
+					Description: "List events that could not be delivered",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "redeliver",
+					Description: "Queue a failed event for delivery again",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "id",
+							Description: "The delivery ID shown by /webhooks failed",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "email",
+			Description:  "Receive notifications by email",
+			DMPermission: BoolPtr(true),
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "set",
+					Description: "Send a verification code to an email address",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "address",
+							Description: "The email address notifications are sent to",
+							Required:    true,
+							MaxLength:   254,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "verify",
+					Description: "Enter the code from the verification email",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "code",
+							Description: "The 6-digit verification code",
+							Required:    true,
+							MaxLength:   6,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "remove",
+					Description: "Remove your email address",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "test",
+					Description: "Send a test notification to your email address",
+				},
+			},
+		},
+		{
+			Name:         "notifiers",
+			Description:  "Connect Telegram or ntfy for notifications",
+			DMPermission: BoolPtr(true),
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "telegram",
+					Description: "Send notifications to a Telegram chat",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "chat_id",
+							Description: "Your chat ID with the bot, or @name of a channel the bot can post in",
+							Required:    true,
+							MaxLength:   33,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "ntfy",
+					Description: "Publish notifications to an ntfy topic",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "topic",
+							Description: "A hard to guess topic name",
+							Required:    true,
+							MaxLength:   64,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "remove",
+					Description: "Disconnect a destination",
+					Options:     []*discordgo.ApplicationCommandOption{notifierDestinationOption()},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "test",
+					Description: "Send a test notification to a destination",
+					Options:     []*discordgo.ApplicationCommandOption{notifierDestinationOption()},
+				},
+			},
+		},
+		{
+			Name:         "setlanguage",
+			Description:  "Choose the language the bot uses with you",
+			DMPermission: BoolPtr(true),
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "language",
+					Description: "The language for responses and notifications",
+					Required:    true,
+					Choices:     languageChoices(),
+				},
+			},
+		},
 	}
+}
+
+func RegisterCommands(s *discordgo.Session) error {
+	logger.Log.Info("Registering global commands")
+
+	commands := Commands()
+	localizeCommands(commands)
 
 	Handlers["set_captcha_service_modal_capsolver"] = setcaptchaservice.HandleModalSubmit
 	Handlers["set_captcha_service_modal_ezcaptcha"] = setcaptchaservice.HandleModalSubmit
 	Handlers["set_captcha_service_modal_2captcha"] = setcaptchaservice.HandleModalSubmit
+	Handlers["set_captcha_service_modal_anticaptcha"] = setcaptchaservice.HandleModalSubmit
+	Handlers["set_captcha_service_modal_capmonster"] = setcaptchaservice.HandleModalSubmit
 
 	Handlers["set_captcha_capsolver"] = setcaptchaservice.HandleCaptchaServiceSelection
 	Handlers["set_captcha_ezcaptcha"] = setcaptchaservice.HandleCaptchaServiceSelection
 	Handlers["set_captcha_2captcha"] = setcaptchaservice.HandleCaptchaServiceSelection
+	Handlers["set_captcha_anticaptcha"] = setcaptchaservice.HandleCaptchaServiceSelection
+	Handlers["set_captcha_capmonster"] = setcaptchaservice.HandleCaptchaServiceSelection
 	Handlers["set_captcha_remove"] = setcaptchaservice.HandleCaptchaServiceSelection
 
 	Handlers["checkcaptchabalance"] = checkcaptchabalance.CommandCheckCaptchaBalance
+	Handlers["captchausage"] = captchausage.CommandCaptchaUsage
 	Handlers["globalannouncement"] = globalannouncement.CommandGlobalAnnouncement
 	Handlers["setcaptchaservice"] = setcaptchaservice.CommandSetCaptchaService
 	Handlers["setcheckinterval"] = setcheckinterval.CommandSetCheckInterval
@@ -144,6 +413,11 @@ func RegisterCommands(s *discordgo.Session) error {
 	Handlers["updateaccount"] = updateaccount.CommandUpdateAccount
 	Handlers["togglecheck"] = togglecheck.CommandToggleCheck
 	Handlers["setnotifications"] = setnotifications.CommandSetNotifications
+	Handlers["apitoken"] = apitoken.CommandAPIToken
+	Handlers["webhooks"] = webhooks.CommandWebhooks
+	Handlers["email"] = email.CommandEmail
+	Handlers["notifiers"] = notifiers.CommandNotifiers
+	Handlers["setlanguage"] = setlanguage.CommandSetLanguage
 
 	Handlers["set_notifications_modal"] = setnotifications.HandleModalSubmit
 	Handlers["setcaptchaservice_modal"] = setcaptchaservice.HandleModalSubmit
@@ -174,7 +448,7 @@ func RegisterCommands(s *discordgo.Session) error {
 	return nil
 }
 
-func HandleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleCommand(s discordapi.Session, i *discordgo.InteractionCreate) {
 	var userID string
 	if i.Member != nil {
 		userID = i.Member.User.ID
@@ -212,3 +486,32 @@ func HandleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
 func BoolPtr(b bool) *bool {
 	return &b
 }
+
+func Float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func notifierDestinationOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "destination",
+		Description: "The destination",
+		Required:    true,
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: "Telegram", Value: "telegram"},
+			{Name: "ntfy", Value: "ntfy"},
+		},
+	}
+}
+
+// languageChoices lists every language under its own name, after the option to follow the
+// Discord client.
+func languageChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "Same as my Discord client", Value: setlanguage.Auto},
+	}
+	for _, lang := range i18n.Languages {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: i18n.Name(lang), Value: string(lang)})
+	}
+	return choices
+}
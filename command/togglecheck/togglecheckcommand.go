@@ -6,18 +6,23 @@ import (
 	"strings"
 
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bradselph/CODStatusBot/services"
 
+	"github.com/bradselph/CODStatusBot/discordapi"
+
 	"github.com/bwmarrin/discordgo"
 )
 
-func CommandToggleCheck(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandToggleCheck(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	userID, err := services.GetUserID(i)
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to get user ID")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -25,12 +30,12 @@ func CommandToggleCheck(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	result := database.DB.Where("user_id = ?", userID).Find(&accounts)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching user accounts")
-		respondToInteraction(s, i, "Error fetching your accounts. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_fetch_your_accounts"))
 		return
 	}
 
 	if len(accounts) == 0 {
-		respondToInteraction(s, i, "You don't have any monitored accounts.")
+		respondToInteraction(s, i, i18n.T(lang, "common.no_accounts"))
 		return
 	}
 
@@ -40,7 +45,7 @@ func CommandToggleCheck(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	)
 
 	for _, account := range accounts {
-		label := fmt.Sprintf("%s (%s)", account.Title, services.GetCheckStatus(account.IsCheckDisabled))
+		label := fmt.Sprintf("%s (%s)", account.Title, services.GetCheckStatus(lang, account.IsCheckDisabled))
 		currentRow = append(currentRow, discordgo.Button{
 			Label:    label,
 			Style:    discordgo.PrimaryButton,
@@ -60,7 +65,7 @@ func CommandToggleCheck(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content:    "Select an account to toggle auto check On/Off:",
+			Content:    i18n.T(lang, "togglecheck.select"),
 			Flags:      discordgo.MessageFlagsEphemeral,
 			Components: components,
 		},
@@ -70,11 +75,13 @@ func CommandToggleCheck(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 }
 
-func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleAccountSelection(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	userID, err := services.GetUserID(i)
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to get user ID")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
@@ -82,7 +89,7 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	accountIDParsed, err := strconv.ParseUint(strings.TrimPrefix(customID, "toggle_check_"), 10, 64)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error parsing account ID")
-		respondToInteraction(s, i, "Error processing your selection. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_selection"))
 		return
 	}
 	accountID := uint(accountIDParsed)
@@ -91,31 +98,29 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	result := database.DB.First(&account, accountID)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching account")
-		respondToInteraction(s, i, "Error: Account not found or you don't have permission to modify it.")
+		respondToInteraction(s, i, i18n.T(lang, "common.account_not_found_modify"))
 		return
 	}
 
 	if account.UserID != userID {
-		respondToInteraction(s, i, "You don't have permission to modify this account.")
+		respondToInteraction(s, i, i18n.T(lang, "common.no_permission_modify"))
 		return
 	}
 
 	if account.IsCheckDisabled {
-		showConfirmationButtons(s, i, accountID, fmt.Sprintf("Are you sure you want to re-enable checks for account '%s'?", account.Title))
+		showConfirmationButtons(s, i, lang, accountID, i18n.T(lang, "togglecheck.confirm_prompt", account.Title))
 	} else {
-		account.IsCheckDisabled = true
-		account.DisabledReason = "Manually disabled by user"
-		message := fmt.Sprintf("Checks for account '%s' have been disabled.", account.Title)
-		if err = database.DB.Save(&account).Error; err != nil {
+		message := i18n.T(lang, "togglecheck.disabled", account.Title)
+		if err = services.SetAccountChecksDisabled(&account, true); err != nil {
 			logger.Log.WithError(err).Error("Failed to update account after toggling check")
-			respondToInteraction(s, i, "Error toggling account checks. Please try again.")
+			respondToInteraction(s, i, i18n.T(lang, "togglecheck.error_toggle"))
 			return
 		}
 		respondToInteraction(s, i, message)
 	}
 }
 
-func showConfirmationButtons(s *discordgo.Session, i *discordgo.InteractionCreate, accountID uint, message string) {
+func showConfirmationButtons(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, accountID uint, message string) {
 	logger.Log.Infof("Showing confirmation buttons for account %d", accountID)
 
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -127,12 +132,12 @@ func showConfirmationButtons(s *discordgo.Session, i *discordgo.InteractionCreat
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.Button{
-							Label:    "Confirm Re-enable",
+							Label:    i18n.T(lang, "togglecheck.confirm"),
 							Style:    discordgo.SuccessButton,
 							CustomID: fmt.Sprintf("confirm_reenable_%d", accountID),
 						},
 						discordgo.Button{
-							Label:    "Cancel",
+							Label:    i18n.T(lang, "common.cancel"),
 							Style:    discordgo.DangerButton,
 							CustomID: "cancel_reenable",
 						},
@@ -144,30 +149,32 @@ func showConfirmationButtons(s *discordgo.Session, i *discordgo.InteractionCreat
 
 	if err != nil {
 		logger.Log.WithError(err).Error("Error showing confirmation buttons")
-		respondToInteraction(s, i, "An error occurred. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_retry"))
 		return
 	}
 }
 
-func HandleConfirmation(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleConfirmation(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	userID, err := services.GetUserID(i)
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to get user ID")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	customID := i.MessageComponentData().CustomID
 
 	if customID == "cancel_reenable" {
-		respondToInteraction(s, i, "Re-enabling cancelled.")
+		respondToInteraction(s, i, i18n.T(lang, "togglecheck.cancelled"))
 		return
 	}
 
 	accountIDParsed, err := strconv.ParseUint(strings.TrimPrefix(customID, "confirm_reenable_"), 10, 64)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error parsing account ID")
-		respondToInteraction(s, i, "Error processing your confirmation. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_confirmation"))
 		return
 	}
 	accountID := uint(accountIDParsed)
@@ -176,28 +183,25 @@ func HandleConfirmation(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	result := database.DB.First(&account, accountID)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching account")
-		respondToInteraction(s, i, "Error: Account not found or you don't have permission to modify it.")
+		respondToInteraction(s, i, i18n.T(lang, "common.account_not_found_modify"))
 		return
 	}
 
 	if account.UserID != userID {
-		respondToInteraction(s, i, "You don't have permission to modify this account.")
+		respondToInteraction(s, i, i18n.T(lang, "common.no_permission_modify"))
 		return
 	}
 
-	account.IsCheckDisabled = false
-	account.DisabledReason = ""
-	account.ConsecutiveErrors = 0
-	if err = database.DB.Save(&account).Error; err != nil {
+	if err = services.SetAccountChecksDisabled(&account, false); err != nil {
 		logger.Log.WithError(err).Error("Error saving account changes")
-		respondToInteraction(s, i, "Error re-enabling account checks. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "togglecheck.error_reenable"))
 		return
 	}
 
-	respondToInteraction(s, i, fmt.Sprintf("Checks for account '%s' have been re-enabled.", account.Title))
+	respondToInteraction(s, i, i18n.T(lang, "togglecheck.reenabled", account.Title))
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseUpdateMessage,
 		Data: &discordgo.InteractionResponseData{
@@ -1,22 +1,30 @@
 package updateaccount
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bradselph/CODStatusBot/utils"
 
+	"github.com/bradselph/CODStatusBot/discordapi"
+
 	"github.com/bwmarrin/discordgo"
 )
 
-func CommandUpdateAccount(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func CommandUpdateAccount(s discordapi.Session, i *discordgo.InteractionCreate) {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
+	lang := services.InteractionLanguage(i)
+
 	var userID string
 	if i.Member != nil {
 		userID = i.Member.User.ID
@@ -24,30 +32,30 @@ func CommandUpdateAccount(s *discordgo.Session, i *discordgo.InteractionCreate)
 		userID = i.User.ID
 	} else {
 		logger.Log.Error("Interaction doesn't have Member or User")
-		respondToInteraction(s, i, "An error occurred while processing your request.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
 		return
 	}
 
 	if !services.IsServiceEnabled("ezcaptcha") && !services.IsServiceEnabled("2captcha") {
-		respondToInteraction(s, i, "Account updates are currently unavailable as no captcha services are enabled. Please try again later.")
+		respondToInteraction(s, i, i18n.T(lang, "updateaccount.unavailable"))
 		return
 	}
 
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteraction(s, i, "Error fetching user settings. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "updateaccount.error_settings"))
 		return
 	}
 
 	if !services.IsServiceEnabled(userSettings.PreferredCaptchaProvider) {
-		msg := fmt.Sprintf("Your preferred captcha service (%s) is currently disabled. ", userSettings.PreferredCaptchaProvider)
+		msg := i18n.T(lang, "common.service_disabled", userSettings.PreferredCaptchaProvider) + " "
 		if services.IsServiceEnabled("ezcaptcha") {
-			msg += "Please switch to EZCaptcha using /setcaptchaservice."
+			msg += i18n.T(lang, "common.switch_service", "EZCaptcha")
 		} else if services.IsServiceEnabled("2captcha") {
-			msg += "Please switch to 2Captcha using /setcaptchaservice."
+			msg += i18n.T(lang, "common.switch_service", "2Captcha")
 		} else {
-			msg += "No captcha services are currently available. Please try again later."
+			msg += i18n.T(lang, "common.no_captcha_services")
 		}
 		respondToInteraction(s, i, msg)
 		return
@@ -57,12 +65,12 @@ func CommandUpdateAccount(s *discordgo.Session, i *discordgo.InteractionCreate)
 	result := database.DB.Where("user_id = ?", userID).Find(&accounts)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching user accounts")
-		respondToInteraction(s, i, "Error fetching your accounts. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_fetch_your_accounts"))
 		return
 	}
 
 	if len(accounts) == 0 {
-		respondToInteraction(s, i, "You don't have any monitored accounts to update.")
+		respondToInteraction(s, i, i18n.T(lang, "updateaccount.no_accounts"))
 		return
 	}
 
@@ -73,12 +81,12 @@ func CommandUpdateAccount(s *discordgo.Session, i *discordgo.InteractionCreate)
 
 	for _, account := range accounts {
 		label := account.Title
-		if isVIP, err := services.CheckVIPStatus(account.SSOCookie); err == nil && isVIP {
+		if isVIP, err := services.CheckVIPStatus(ctx, account.SSOCookie); err == nil && isVIP {
 			label += " ⭐"
 		}
 
 		if account.IsCheckDisabled {
-			label += " (Disabled)"
+			label += i18n.T(lang, "updateaccount.disabled_label")
 			button := discordgo.Button{
 				Label:    label,
 				Style:    discordgo.SecondaryButton,
@@ -107,7 +115,7 @@ func CommandUpdateAccount(s *discordgo.Session, i *discordgo.InteractionCreate)
 	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content:    "Select an account to update:",
+			Content:    i18n.T(lang, "updateaccount.select"),
 			Flags:      discordgo.MessageFlagsEphemeral,
 			Components: components,
 		},
@@ -117,12 +125,14 @@ func CommandUpdateAccount(s *discordgo.Session, i *discordgo.InteractionCreate)
 	}
 }
 
-func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleAccountSelection(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
 	customID := i.MessageComponentData().CustomID
 	accountID, err := strconv.Atoi(strings.TrimPrefix(customID, "update_account_"))
 	if err != nil {
 		logger.Log.WithError(err).Error("Error parsing account ID")
-		respondToInteraction(s, i, "Error processing your selection. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_selection"))
 		return
 	}
 
@@ -130,7 +140,7 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	result := database.DB.First(&account, accountID)
 	if result.Error != nil {
 		logger.Log.WithError(result.Error).Error("Error fetching account")
-		respondToInteraction(s, i, "Error: Account not found or you don't have permission to update it.")
+		respondToInteraction(s, i, i18n.T(lang, "updateaccount.not_found"))
 		return
 	}
 
@@ -142,7 +152,7 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 	}
 
 	if account.UserID != userID {
-		respondToInteraction(s, i, "Error: You don't have permission to update this account.")
+		respondToInteraction(s, i, i18n.T(lang, "updateaccount.no_permission"))
 		return
 	}
 
@@ -150,15 +160,15 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 		Type: discordgo.InteractionResponseModal,
 		Data: &discordgo.InteractionResponseData{
 			CustomID: fmt.Sprintf("update_account_modal_%d", accountID),
-			Title:    "Update Account",
+			Title:    i18n.T(lang, "updateaccount.modal.title"),
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
 						discordgo.TextInput{
 							CustomID:    "new_sso_cookie",
-							Label:       "Enter the new SSO cookie",
+							Label:       i18n.T(lang, "updateaccount.modal.cookie"),
 							Style:       discordgo.TextInputParagraph,
-							Placeholder: "Enter the new SSO cookie for this account",
+							Placeholder: i18n.T(lang, "updateaccount.modal.placeholder"),
 							Required:    true,
 							MinLength:   1,
 							MaxLength:   4000,
@@ -171,18 +181,23 @@ func HandleAccountSelection(s *discordgo.Session, i *discordgo.InteractionCreate
 
 	if err != nil {
 		logger.Log.WithError(err).Error("Error showing update modal")
-		respondToInteraction(s, i, "Error showing update form. Please try again.")
+		respondToInteraction(s, i, i18n.T(lang, "updateaccount.error_form"))
 		return
 	}
 }
 
-func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func HandleModalSubmit(s discordapi.Session, i *discordgo.InteractionCreate) {
+	ctx, cancel := services.CommandContext()
+	defer cancel()
+
+	lang := services.InteractionLanguage(i)
+
 	data := i.ModalSubmitData()
 	accountIDStr := strings.TrimPrefix(data.CustomID, "update_account_modal_")
 	accountID, err := strconv.Atoi(accountIDStr)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error parsing account ID")
-		respondToInteractionWithEmbed(s, i, "Error processing your update. Please try again.", nil)
+		respondToInteractionWithEmbed(s, i, i18n.T(lang, "updateaccount.error_update"), nil)
 		return
 	}
 
@@ -198,27 +213,7 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 
 	if newSSOCookie == "" {
-		respondToInteractionWithEmbed(s, i, "Error: New SSO cookie must be provided.", nil)
-		return
-	}
-
-	validationResult, err := services.ValidateAndGetAccountInfo(newSSOCookie)
-	if err != nil {
-		logger.Log.WithError(err).Error("Error validating new SSO cookie")
-		respondToInteractionWithEmbed(s, i, fmt.Sprintf("Error validating cookie: %v", err), nil)
-		return
-	}
-
-	if !validationResult.IsValid {
-		respondToInteractionWithEmbed(s, i, "Error: The provided SSO cookie is invalid.", nil)
-		return
-	}
-
-	var account models.Account
-	result := database.DB.First(&account, accountID)
-	if result.Error != nil {
-		logger.Log.WithError(result.Error).Error("Error fetching account")
-		respondToInteractionWithEmbed(s, i, "Error: Account not found or you don't have permission to update it.", nil)
+		respondToInteractionWithEmbed(s, i, i18n.T(lang, "updateaccount.cookie_required"), nil)
 		return
 	}
 
@@ -229,110 +224,69 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		userID = i.User.ID
 	}
 
-	if account.UserID != userID {
-		respondToInteractionWithEmbed(s, i, "Error: You don't have permission to update this account.", nil)
+	account, err := services.GetUserAccount(userID, uint(accountID))
+	if err != nil {
+		logger.Log.WithError(err).Error("Error fetching account")
+		respondToInteractionWithEmbed(s, i, i18n.T(lang, "updateaccount.not_found"), nil)
 		return
 	}
 
 	userSettings, err := services.GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error fetching user settings")
-		respondToInteractionWithEmbed(s, i, "Error fetching user settings. Please try again.", nil)
+		respondToInteractionWithEmbed(s, i, i18n.T(lang, "updateaccount.error_settings"), nil)
 		return
 	}
 
 	if !services.IsServiceEnabled(userSettings.PreferredCaptchaProvider) {
-		msg := fmt.Sprintf("Your preferred captcha service (%s) is currently disabled. ", userSettings.PreferredCaptchaProvider)
+		msg := i18n.T(lang, "common.service_disabled", userSettings.PreferredCaptchaProvider) + " "
 		if services.IsServiceEnabled("ezcaptcha") {
-			msg += "Please switch to EZCaptcha using /setcaptchaservice."
+			msg += i18n.T(lang, "common.switch_service", "EZCaptcha")
 		} else if services.IsServiceEnabled("2captcha") {
-			msg += "Please switch to 2Captcha using /setcaptchaservice."
+			msg += i18n.T(lang, "common.switch_service", "2Captcha")
 		}
 		respondToInteractionWithEmbed(s, i, msg, nil)
 		return
 	}
 
-	var statusCheck bool
-	var statusErr error
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		statusCheck = services.VerifySSOCookie(newSSOCookie)
-		if !statusCheck {
-			statusErr = fmt.Errorf("invalid SSO cookie verification")
-		}
-	}()
-
-	wg.Wait()
-	if !statusCheck {
-		logger.Log.WithError(statusErr).Error("SSO cookie validation failed")
-		respondToInteractionWithEmbed(s, i, "Error: SSO cookie validation failed. Please try again.", nil)
+	wasVIP := account.IsVIP
+	wasDisabled, err := services.UpdateAccountCookie(ctx, &account, newSSOCookie)
+	if errors.Is(err, services.ErrInvalidSSOCookie) {
+		respondToInteractionWithEmbed(s, i, i18n.T(lang, "common.invalid_cookie_provided"), nil)
 		return
 	}
-
-	services.DBMutex.Lock()
-	account.LastNotification = time.Now().Unix()
-	account.LastCookieNotification = 0
-	account.SSOCookie = newSSOCookie
-	account.SSOCookieExpiration = validationResult.ExpiresAt
-	account.Created = validationResult.Created
-	account.IsVIP = validationResult.IsVIP
-	account.IsExpiredCookie = false
-	wasDisabled := account.IsCheckDisabled
-	account.IsCheckDisabled = false
-	account.DisabledReason = ""
-	account.ConsecutiveErrors = 0
-	account.LastSuccessfulCheck = time.Now()
-
-	if err := database.DB.Save(&account).Error; err != nil {
-		services.DBMutex.Unlock()
+	if err != nil {
 		logger.Log.WithError(err).Error("Failed to update account")
-		respondToInteractionWithEmbed(s, i, "Error updating account. Please try again.", nil)
+		respondToInteractionWithEmbed(s, i, i18n.T(lang, "updateaccount.error_updating", err), nil)
 		return
 	}
-	services.DBMutex.Unlock()
-
-	statusLog := models.Ban{
-		AccountID: account.ID,
-		Status:    account.LastStatus,
-		LogType:   "cookie_update",
-		Message:   "SSO Cookie updated",
-		Timestamp: time.Now(),
-		Initiator: "user",
-	}
-
-	if err := database.DB.Create(&statusLog).Error; err != nil {
-		logger.Log.WithError(err).Error("Failed to log cookie update")
-	}
-
-	oldVIP, _ := services.CheckVIPStatus(account.SSOCookie)
-	newVIP, _ := services.CheckVIPStatus(newSSOCookie)
 
 	var vipStatusChange string
-	if oldVIP != newVIP {
-		if newVIP {
-			vipStatusChange = "Your account is now a VIP account!"
+	if wasVIP != account.IsVIP {
+		if account.IsVIP {
+			vipStatusChange = i18n.T(lang, "updateaccount.now_vip")
 		} else {
-			vipStatusChange = "Your account is no longer a VIP account"
+			vipStatusChange = i18n.T(lang, "updateaccount.no_longer_vip")
 		}
 	}
 
-	embed := createSuccessEmbed(&account, wasDisabled, vipStatusChange, validationResult.ExpiresAt, account.IsVIP)
+	embed := createSuccessEmbed(lang, &account, wasDisabled, vipStatusChange, account.SSOCookieExpiration, account.IsVIP)
 	respondToInteractionWithEmbed(s, i, "", embed)
 
 	statusCheckDone := make(chan bool)
 	go func() {
 		defer close(statusCheckDone)
+		ctx, cancel := services.CommandContext()
+		defer cancel()
+
 		time.Sleep(1 * time.Second)
-		status, err := services.CheckAccount(newSSOCookie, userID, "")
+		check, err := services.CheckAccount(services.WithCaptchaAccount(ctx, account.ID), newSSOCookie, userID, "")
 		if err != nil {
 			logger.Log.WithError(err).Error("Error performing status check after update")
 			return
 		}
 
-		services.HandleStatusChange(s, account, status, userSettings)
+		services.HandleStatusChange(ctx, s, account, check, userSettings)
 	}()
 
 	select {
@@ -342,20 +296,20 @@ func HandleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 }
 
-func createSuccessEmbed(account *models.Account, wasDisabled bool, vipStatusChange string, expirationTimestamp int64, isVIP bool) *discordgo.MessageEmbed {
+func createSuccessEmbed(lang i18n.Lang, account *models.Account, wasDisabled bool, vipStatusChange string, expirationTimestamp int64, isVIP bool) *discordgo.MessageEmbed {
 	embed := &discordgo.MessageEmbed{
-		Title:       "Account Update Successful",
-		Description: fmt.Sprintf("Account '%s' has been updated successfully.", account.Title),
+		Title:       i18n.T(lang, "updateaccount.success.title"),
+		Description: i18n.T(lang, "updateaccount.success.description", account.Title),
 		Color:       0x00ff00,
 		Fields: []*discordgo.MessageEmbedField{
 			{
-				Name:   "Cookie Expiration",
-				Value:  services.FormatExpirationTime(expirationTimestamp),
+				Name:   i18n.T(lang, "common.cookie_expiration"),
+				Value:  services.FormatExpirationTime(lang, expirationTimestamp),
 				Inline: true,
 			},
 			{
-				Name:   "VIP Status",
-				Value:  getVIPStatusText(isVIP),
+				Name:   i18n.T(lang, "common.vip_status"),
+				Value:  getVIPStatusText(lang, isVIP),
 				Inline: true,
 			},
 		},
@@ -364,41 +318,41 @@ func createSuccessEmbed(account *models.Account, wasDisabled bool, vipStatusChan
 
 	if wasDisabled {
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:   "Account Status",
-			Value:  "Account checks have been re-enabled",
+			Name:   i18n.T(lang, "updateaccount.account_status"),
+			Value:  i18n.T(lang, "updateaccount.reenabled"),
 			Inline: false,
 		})
 	}
 
 	if vipStatusChange != "" {
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:   "Status Change",
+			Name:   i18n.T(lang, "updateaccount.status_change"),
 			Value:  vipStatusChange,
 			Inline: false,
 		})
 	}
 
 	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-		Name:   "Notification Type",
+		Name:   i18n.T(lang, "common.notification_type"),
 		Value:  account.NotificationType,
 		Inline: true,
 	})
 
 	embed.Footer = &discordgo.MessageEmbedFooter{
-		Text: "Use /listaccounts to view all your monitored accounts",
+		Text: i18n.T(lang, "updateaccount.footer"),
 	}
 
 	return embed
 }
 
-func getVIPStatusText(isVIP bool) string {
+func getVIPStatusText(lang i18n.Lang, isVIP bool) string {
 	if isVIP {
-		return "VIP Account"
+		return i18n.T(lang, "notify.vip_account")
 	}
-	return "Regular Account"
+	return i18n.T(lang, "notify.regular_account")
 }
 
-func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
 	var err error
 	if i.Type == discordgo.InteractionMessageComponent {
 		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -423,7 +377,7 @@ func respondToInteraction(s *discordgo.Session, i *discordgo.InteractionCreate,
 	}
 }
 
-func respondToInteractionWithEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, message string, embed *discordgo.MessageEmbed) {
+func respondToInteractionWithEmbed(s discordapi.Session, i *discordgo.InteractionCreate, message string, embed *discordgo.MessageEmbed) {
 	responseData := &discordgo.InteractionResponseData{
 		Flags: discordgo.MessageFlagsEphemeral,
 	}
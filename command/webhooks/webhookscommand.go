@@ -0,0 +1,339 @@
+package webhooks
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bradselph/CODStatusBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxFailedShown is how many dead-letter deliveries /webhooks failed lists.
+const maxFailedShown = 15
+
+func CommandWebhooks(s discordapi.Session, i *discordgo.InteractionCreate) {
+	lang := services.InteractionLanguage(i)
+
+	userID, err := services.GetUserID(i)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to get user ID")
+		respondToInteraction(s, i, i18n.T(lang, "common.error_processing"))
+		return
+	}
+
+	if !configuration.Get().Webhooks.Enabled {
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.not_enabled"))
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "common.choose_subcommand"))
+		return
+	}
+
+	subcommand := options[0]
+	switch subcommand.Name {
+	case "add":
+		addWebhook(s, i, lang, userID, subcommand.Options)
+	case "list":
+		listWebhooks(s, i, lang, userID)
+	case "remove":
+		removeWebhook(s, i, lang, userID, subcommand.Options)
+	case "test":
+		testWebhook(s, i, lang, userID, subcommand.Options)
+	case "secret":
+		showSecret(s, i, lang, userID, subcommand.Options)
+	case "failed":
+		listFailed(s, i, lang, userID)
+	case "redeliver":
+		redeliver(s, i, lang, userID, subcommand.Options)
+	default:
+		respondToInteraction(s, i, i18n.T(lang, "common.unknown_subcommand"))
+	}
+}
+
+func addWebhook(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var rawURL, accountTitle string
+	for _, option := range options {
+		switch option.Name {
+		case "url":
+			rawURL = option.StringValue()
+		case "account":
+			accountTitle = utils.SanitizeInput(option.StringValue())
+		}
+	}
+
+	var account models.Account
+	if accountTitle != "" {
+		var ok bool
+		if account, ok = findAccountByTitle(s, i, lang, userID, accountTitle); !ok {
+			return
+		}
+	}
+
+	webhook, err := services.AddWebhook(userID, account.ID, rawURL)
+	var urlErr *services.InvalidWebhookURLError
+	switch {
+	case errors.As(err, &urlErr):
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.invalid_url", urlErr.Reason))
+		return
+	case errors.Is(err, services.ErrWebhookLimit):
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.limit", configuration.Get().Webhooks.MaxPerUser))
+		return
+	case err != nil:
+		logger.Log.WithError(err).Errorf("Failed to add webhook for user %s", userID)
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.error_add"))
+		return
+	}
+
+	scope := i18n.T(lang, "webhooks.scope.all")
+	if account.ID != 0 {
+		scope = i18n.T(lang, "webhooks.scope.account", account.Title)
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: i18n.T(lang, "webhooks.added.title"),
+		Description: i18n.T(lang, "webhooks.added.description",
+			webhook.ID, scope, services.WebhookSignatureHeader),
+		Color:     0x00ff00,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	respondWithEmbed(s, i, embed)
+}
+
+func listWebhooks(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string) {
+	webhooks, err := services.ListWebhooks(userID)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to list webhooks for user %s", userID)
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.error_load"))
+		return
+	}
+
+	if len(webhooks) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.none"))
+		return
+	}
+
+	titles := accountTitles(userID)
+	var lines []string
+	for _, webhook := range webhooks {
+		scope := i18n.T(lang, "webhooks.list.all_accounts")
+		if webhook.AccountID != 0 {
+			scope = titles[webhook.AccountID]
+		}
+		lines = append(lines, i18n.T(lang, "webhooks.list.line",
+			webhook.ID, scope, webhook.URL, webhook.CreatedAt.Unix()))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "webhooks.list.title"),
+		Description: strings.Join(lines, "\n"),
+		Color:       0x00ff00,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: i18n.T(lang, "webhooks.list.footer", len(webhooks), configuration.Get().Webhooks.MaxPerUser),
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	respondWithEmbed(s, i, embed)
+}
+
+func removeWebhook(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	webhookID := idOption(options)
+
+	err := services.RemoveWebhook(userID, webhookID)
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.not_found", webhookID))
+		return
+	}
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to remove webhook %d for user %s", webhookID, userID)
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.error_remove"))
+		return
+	}
+
+	respondToInteraction(s, i, i18n.T(lang, "webhooks.removed", webhookID))
+}
+
+func testWebhook(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	webhookID := idOption(options)
+
+	err := services.SendTestWebhook(userID, webhookID)
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.not_found", webhookID))
+		return
+	}
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to queue test event for webhook %d", webhookID)
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.error_test"))
+		return
+	}
+
+	respondToInteraction(s, i, i18n.T(lang, "webhooks.test_queued", webhookID))
+}
+
+func showSecret(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var rotate bool
+	for _, option := range options {
+		if option.Name == "rotate" {
+			rotate = option.BoolValue()
+		}
+	}
+
+	secret, err := services.WebhookSecret(userID, rotate)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to load webhook secret for user %s", userID)
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.error_secret"))
+		return
+	}
+
+	description := i18n.T(lang, "webhooks.secret.description", secret, services.WebhookSignatureHeader)
+	if rotate {
+		description = i18n.T(lang, "webhooks.secret.rotated") + "\n" + description
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "webhooks.secret.title"),
+		Description: description,
+		Color:       0x00ff00,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+	respondWithEmbed(s, i, embed)
+}
+
+func listFailed(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string) {
+	deliveries, err := services.ListDeadWebhookDeliveries(userID, maxFailedShown)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to list failed webhook deliveries for user %s", userID)
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.error_failed"))
+		return
+	}
+
+	if len(deliveries) == 0 {
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.all_delivered"))
+		return
+	}
+
+	var fields []*discordgo.MessageEmbedField
+	for _, delivery := range deliveries {
+		lastError := delivery.LastError
+		if len(lastError) > 200 {
+			lastError = lastError[:200] + "…"
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: i18n.T(lang, "webhooks.failed.name", delivery.ID, delivery.Event),
+			Value: i18n.T(lang, "webhooks.failed.value",
+				delivery.WebhookID, delivery.Attempts, delivery.CreatedAt.Unix(), lastError),
+			Inline: false,
+		})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "webhooks.failed.title"),
+		Description: i18n.T(lang, "webhooks.failed.description"),
+		Color:       0xFFA500,
+		Fields:      fields,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+	respondWithEmbed(s, i, embed)
+}
+
+func redeliver(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	deliveryID := idOption(options)
+
+	err := services.RedeliverWebhook(userID, deliveryID)
+	if errors.Is(err, services.ErrDeliveryNotFound) {
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.delivery_not_found", deliveryID))
+		return
+	}
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to requeue webhook delivery %d", deliveryID)
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.error_redeliver"))
+		return
+	}
+
+	respondToInteraction(s, i, i18n.T(lang, "webhooks.redelivered", deliveryID))
+}
+
+// findAccountByTitle resolves the account option, which names one of the user's accounts by
+// title, responding to the interaction when it does not name exactly one.
+func findAccountByTitle(s discordapi.Session, i *discordgo.InteractionCreate, lang i18n.Lang, userID, title string) (models.Account, bool) {
+	var accounts []models.Account
+	if err := database.DB.Where("user_id = ?", userID).Find(&accounts).Error; err != nil {
+		logger.Log.WithError(err).Errorf("Failed to fetch accounts for user %s", userID)
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.error_accounts"))
+		return models.Account{}, false
+	}
+
+	var matches []models.Account
+	for _, account := range accounts {
+		if strings.EqualFold(account.Title, title) {
+			matches = append(matches, account)
+		}
+	}
+
+	switch len(matches) {
+	case 1:
+		return matches[0], true
+	case 0:
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.account_not_found", title))
+	default:
+		respondToInteraction(s, i, i18n.T(lang, "webhooks.account_ambiguous", title))
+	}
+	return models.Account{}, false
+}
+
+func accountTitles(userID string) map[uint]string {
+	var accounts []models.Account
+	if err := database.DB.Select("id", "title").Where("user_id = ?", userID).Find(&accounts).Error; err != nil {
+		logger.Log.WithError(err).Errorf("Failed to fetch accounts for user %s", userID)
+	}
+
+	titles := make(map[uint]string, len(accounts))
+	for _, account := range accounts {
+		titles[account.ID] = account.Title
+	}
+	return titles
+}
+
+func idOption(options []*discordgo.ApplicationCommandInteractionDataOption) uint {
+	for _, option := range options {
+		if option.Name == "id" {
+			return uint(option.IntValue())
+		}
+	}
+	return 0
+}
+
+func respondWithEmbed(s discordapi.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding to interaction with embed")
+	}
+}
+
+func respondToInteraction(s discordapi.Session, i *discordgo.InteractionCreate, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Log.WithError(err).Error("Error responding to interaction")
+	}
+}
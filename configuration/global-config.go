@@ -3,7 +3,9 @@ package configuration
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bradselph/CODStatusBot/logger"
@@ -16,6 +18,8 @@ type Config struct {
 
 	// Database Settings
 	Database struct {
+		Driver   string
+		Path     string
 		User     string
 		Password string
 		Name     string
@@ -24,6 +28,12 @@ type Config struct {
 		Var      string
 	}
 
+	// Encryption Settings
+	Encryption struct {
+		Key          string
+		PreviousKeys []string
+	}
+
 	// Discord Settings
 	Discord struct {
 		Token       string
@@ -39,18 +49,34 @@ type Config struct {
 			BalanceMin    float64
 			MaxRetries    int
 			RetryInterval time.Duration
+			MaxConcurrent int
 		}
 		EZCaptcha struct {
-			Enabled    bool
-			ClientKey  string
-			AppID      string
-			BalanceMin float64
+			Enabled       bool
+			ClientKey     string
+			AppID         string
+			BalanceMin    float64
+			MaxConcurrent int
 		}
 		TwoCaptcha struct {
-			Enabled    bool
-			ClientKey  string
-			SoftID     string
-			BalanceMin float64
+			Enabled       bool
+			ClientKey     string
+			SoftID        string
+			BalanceMin    float64
+			MaxConcurrent int
+		}
+		AntiCaptcha struct {
+			Enabled       bool
+			ClientKey     string
+			SoftID        string
+			BalanceMin    float64
+			MaxConcurrent int
+		}
+		CapMonster struct {
+			Enabled       bool
+			ClientKey     string
+			BalanceMin    float64
+			MaxConcurrent int
 		}
 		RecaptchaSiteKey string
 		RecaptchaURL     string
@@ -58,22 +84,42 @@ type Config struct {
 	}
 
 	CaptchaEndpoints struct {
-		Capsolver struct {
-			Create string
-			Result string
-		}
-		EZCaptcha struct {
-			Create string
-			Result string
-		}
-		TwoCaptcha struct {
-			Create string
-			Result string
-		}
+		Capsolver     CaptchaProviderEndpoints
+		EZCaptcha     CaptchaProviderEndpoints
+		TwoCaptcha    CaptchaProviderEndpoints
+		AntiCaptcha   CaptchaProviderEndpoints
+		CapMonster    CaptchaProviderEndpoints
 		MaxRetries    int
 		RetryInterval time.Duration
 	}
 
+	CaptchaFailover struct {
+		SolveTimeout     time.Duration
+		BreakerThreshold int
+		BreakerCooldown  time.Duration
+	}
+
+	// Solved reCAPTCHA tokens shared between checks
+	CaptchaTokenPool struct {
+		Enabled  bool
+		TTL      time.Duration
+		MaxUses  int
+		Prefetch int
+	}
+
+	// Metrics and health endpoints, served together on StatusServer.Addr
+	StatusServer struct {
+		Addr string
+	}
+	Metrics struct {
+		Enabled bool
+	}
+	Health struct {
+		Enabled      bool
+		MaxCheckAge  time.Duration
+		StallTimeout time.Duration
+	}
+
 	// API Endpoints
 	API struct {
 		CheckEndpoint      string
@@ -93,6 +139,46 @@ type Config struct {
 		StatsRateLimit float64
 	}
 
+	// REST API Settings
+	RESTAPI struct {
+		Addr      string
+		RateLimit float64
+	}
+
+	// Outbound Webhooks
+	Webhooks struct {
+		Enabled              bool
+		Timeout              time.Duration
+		MaxAttempts          int
+		MaxPerUser           int
+		AllowPrivateNetworks bool
+	}
+
+	// Email Notifications
+	SMTP struct {
+		Host     string
+		Port     int
+		Username string
+		Password string
+		From     string
+		TLS      string
+		Timeout  time.Duration
+	}
+
+	// Telegram and ntfy Notifications
+	Telegram struct {
+		BotToken string
+		APIURL   string
+	}
+	Ntfy struct {
+		ServerURL string
+		Token     string
+	}
+	NotifierTimeout time.Duration
+
+	// Notification Templates
+	NotificationTemplatesDir string
+
 	// Rate Limits and Intervals
 	RateLimits struct {
 		CheckNow           time.Duration
@@ -101,6 +187,18 @@ type Config struct {
 		PremiumMaxAccounts int
 	}
 
+	// Periodic Check Worker Pool
+	CheckPool struct {
+		Workers int
+		PerUser int
+	}
+
+	// Job Scheduler
+	Scheduler struct {
+		PollInterval time.Duration
+		MaxAttempts  int
+	}
+
 	// Intervals
 	Intervals struct {
 		Check              int
@@ -130,6 +228,23 @@ type Config struct {
 	}
 }
 
+// CaptchaProviderEndpoints are the task API URLs of one captcha provider.
+type CaptchaProviderEndpoints struct {
+	Create  string
+	Result  string
+	Balance string
+}
+
+// NewCaptchaProviderEndpoints derives a provider's endpoints from its API base URL.
+func NewCaptchaProviderEndpoints(baseURL string) CaptchaProviderEndpoints {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return CaptchaProviderEndpoints{
+		Create:  baseURL + "/createTask",
+		Result:  baseURL + "/getTaskResult",
+		Balance: baseURL + "/getBalance",
+	}
+}
+
 type DonationsConfig struct {
 	Enabled        bool
 	BitcoinAddress string
@@ -146,6 +261,8 @@ func Load() error {
 	AppConfig.LogDir = getEnvWithDefault("LOG_DIR", "logs")
 
 	// Database Settings
+	AppConfig.Database.Driver = strings.ToLower(getEnvWithDefault("DB_DRIVER", "mysql"))
+	AppConfig.Database.Path = getEnvWithDefault("DB_PATH", "codstatusbot.db")
 	AppConfig.Database.User = os.Getenv("DB_USER")
 	AppConfig.Database.Password = os.Getenv("DB_PASSWORD")
 	AppConfig.Database.Name = os.Getenv("DB_NAME")
@@ -153,6 +270,13 @@ func Load() error {
 	AppConfig.Database.Port = os.Getenv("DB_PORT")
 	AppConfig.Database.Var = os.Getenv("DB_VAR")
 
+	// Encryption Settings
+	AppConfig.Encryption.Key = os.Getenv("ENCRYPTION_KEY")
+	AppConfig.Encryption.PreviousKeys = nil
+	if previous := os.Getenv("ENCRYPTION_PREVIOUS_KEYS"); previous != "" {
+		AppConfig.Encryption.PreviousKeys = strings.Split(previous, ",")
+	}
+
 	// Discord Settings
 	AppConfig.Discord.Token = os.Getenv("DISCORD_TOKEN")
 	AppConfig.Discord.DeveloperID = os.Getenv("DEVELOPER_ID")
@@ -164,20 +288,61 @@ func Load() error {
 	AppConfig.CaptchaService.Capsolver.BalanceMin = getEnvAsFloat("CAPSOLVER_BALANCE_MIN", 0.10)
 	AppConfig.CaptchaService.Capsolver.MaxRetries = getEnvAsInt("CAPSOLVER_MAX_RETRIES", 6)                                     // TODO: Merge with MAX_RETRIES
 	AppConfig.CaptchaService.Capsolver.RetryInterval = time.Duration(getEnvAsInt("CAPSOLVER_RETRY_INTERVAL", 10)) * time.Second // TODO: Merge with RETRY_INTERVAL
+	AppConfig.CaptchaService.Capsolver.MaxConcurrent = getEnvAsInt("CAPSOLVER_MAX_CONCURRENT", 3)
 
 	AppConfig.CaptchaService.EZCaptcha.Enabled = os.Getenv("EZCAPTCHA_ENABLED") == "true"
 	AppConfig.CaptchaService.EZCaptcha.ClientKey = os.Getenv("EZCAPTCHA_CLIENT_KEY")
 	AppConfig.CaptchaService.EZCaptcha.AppID = os.Getenv("EZAPPID")
 	AppConfig.CaptchaService.EZCaptcha.BalanceMin = getEnvAsFloat("EZCAPBALMIN", 50)
+	AppConfig.CaptchaService.EZCaptcha.MaxConcurrent = getEnvAsInt("EZCAPTCHA_MAX_CONCURRENT", 3)
 
 	AppConfig.CaptchaService.TwoCaptcha.Enabled = os.Getenv("TWOCAPTCHA_ENABLED") == "true"
+	AppConfig.CaptchaService.TwoCaptcha.ClientKey = os.Getenv("TWOCAPTCHA_CLIENT_KEY")
 	AppConfig.CaptchaService.TwoCaptcha.SoftID = os.Getenv("SOFT_ID")
 	AppConfig.CaptchaService.TwoCaptcha.BalanceMin = getEnvAsFloat("TWOCAPBALMIN", 0.10)
+	AppConfig.CaptchaService.TwoCaptcha.MaxConcurrent = getEnvAsInt("TWOCAPTCHA_MAX_CONCURRENT", 3)
+
+	AppConfig.CaptchaService.AntiCaptcha.Enabled = os.Getenv("ANTICAPTCHA_ENABLED") == "true"
+	AppConfig.CaptchaService.AntiCaptcha.ClientKey = os.Getenv("ANTICAPTCHA_CLIENT_KEY")
+	AppConfig.CaptchaService.AntiCaptcha.SoftID = os.Getenv("ANTICAPTCHA_SOFT_ID")
+	AppConfig.CaptchaService.AntiCaptcha.BalanceMin = getEnvAsFloat("ANTICAPTCHA_BALANCE_MIN", 0.10)
+	AppConfig.CaptchaService.AntiCaptcha.MaxConcurrent = getEnvAsInt("ANTICAPTCHA_MAX_CONCURRENT", 3)
+
+	AppConfig.CaptchaService.CapMonster.Enabled = os.Getenv("CAPMONSTER_ENABLED") == "true"
+	AppConfig.CaptchaService.CapMonster.ClientKey = os.Getenv("CAPMONSTER_CLIENT_KEY")
+	AppConfig.CaptchaService.CapMonster.BalanceMin = getEnvAsFloat("CAPMONSTER_BALANCE_MIN", 0.10)
+	AppConfig.CaptchaService.CapMonster.MaxConcurrent = getEnvAsInt("CAPMONSTER_MAX_CONCURRENT", 3)
 
 	AppConfig.CaptchaService.RecaptchaSiteKey = os.Getenv("RECAPTCHA_SITE_KEY")
 	AppConfig.CaptchaService.RecaptchaURL = os.Getenv("RECAPTCHA_URL")
 	AppConfig.CaptchaService.MaxRetries = getEnvAsInt("MAX_RETRIES", 3)
 
+	// Captcha Provider Endpoints
+	AppConfig.CaptchaEndpoints.Capsolver = NewCaptchaProviderEndpoints(getEnvWithDefault("CAPSOLVER_API_URL", "https://api.capsolver.com"))
+	AppConfig.CaptchaEndpoints.EZCaptcha = NewCaptchaProviderEndpoints(getEnvWithDefault("EZCAPTCHA_API_URL", "https://api.ez-captcha.com"))
+	AppConfig.CaptchaEndpoints.TwoCaptcha = NewCaptchaProviderEndpoints(getEnvWithDefault("TWOCAPTCHA_API_URL", "https://api.2captcha.com"))
+	AppConfig.CaptchaEndpoints.AntiCaptcha = NewCaptchaProviderEndpoints(getEnvWithDefault("ANTICAPTCHA_API_URL", "https://api.anti-captcha.com"))
+	AppConfig.CaptchaEndpoints.CapMonster = NewCaptchaProviderEndpoints(getEnvWithDefault("CAPMONSTER_API_URL", "https://api.capmonster.cloud"))
+	AppConfig.CaptchaEndpoints.MaxRetries = getEnvAsInt("CAPTCHA_RESULT_MAX_RETRIES", 6)
+	AppConfig.CaptchaEndpoints.RetryInterval = time.Duration(getEnvAsInt("CAPTCHA_RESULT_RETRY_INTERVAL", 10)) * time.Second
+
+	// Captcha Failover
+	AppConfig.CaptchaFailover.SolveTimeout = time.Duration(getEnvAsInt("CAPTCHA_SOLVE_TIMEOUT", 180)) * time.Second
+	AppConfig.CaptchaFailover.BreakerThreshold = getEnvAsInt("CAPTCHA_BREAKER_THRESHOLD", 3)
+	AppConfig.CaptchaFailover.BreakerCooldown = time.Duration(getEnvAsInt("CAPTCHA_BREAKER_COOLDOWN", 300)) * time.Second
+
+	AppConfig.CaptchaTokenPool.Enabled = os.Getenv("CAPTCHA_TOKEN_REUSE") == "true"
+	AppConfig.CaptchaTokenPool.TTL = time.Duration(getEnvAsInt("CAPTCHA_TOKEN_TTL", 100)) * time.Second
+	AppConfig.CaptchaTokenPool.MaxUses = getEnvAsInt("CAPTCHA_TOKEN_MAX_USES", 3)
+	AppConfig.CaptchaTokenPool.Prefetch = getEnvAsInt("CAPTCHA_TOKEN_PREFETCH", 0)
+
+	// Metrics and health endpoints
+	AppConfig.StatusServer.Addr = getEnvWithDefault("STATUS_ADDR", ":9090")
+	AppConfig.Metrics.Enabled = os.Getenv("METRICS_ENABLED") == "true"
+	AppConfig.Health.Enabled = os.Getenv("HEALTH_ENABLED") == "true"
+	AppConfig.Health.MaxCheckAge = time.Duration(getEnvAsInt("HEALTH_MAX_CHECK_AGE", 900)) * time.Second
+	AppConfig.Health.StallTimeout = time.Duration(getEnvAsInt("HEALTH_STALL_TIMEOUT", 3600)) * time.Second
+
 	// API Endpoints
 	AppConfig.API.CheckEndpoint = os.Getenv("CHECK_ENDPOINT")
 	AppConfig.API.ProfileEndpoint = os.Getenv("PROFILE_ENDPOINT")
@@ -193,12 +358,50 @@ func Load() error {
 	AppConfig.AdminPanel.Password = os.Getenv("ADMIN_PASSWORD")
 	AppConfig.AdminPanel.StatsRateLimit = getEnvAsFloat("STATS_RATE_LIMIT", 25.0)
 
+	// REST API Settings
+	AppConfig.RESTAPI.Addr = os.Getenv("REST_API_ADDR")
+	AppConfig.RESTAPI.RateLimit = getEnvAsFloat("REST_API_RATE_LIMIT", 60)
+
+	// Outbound Webhooks
+	AppConfig.Webhooks.Enabled = os.Getenv("WEBHOOKS_ENABLED") == "true"
+	AppConfig.Webhooks.Timeout = time.Duration(getEnvAsInt("WEBHOOK_TIMEOUT", 10)) * time.Second
+	AppConfig.Webhooks.MaxAttempts = getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 10)
+	AppConfig.Webhooks.MaxPerUser = getEnvAsInt("WEBHOOK_MAX_PER_USER", 5)
+	AppConfig.Webhooks.AllowPrivateNetworks = os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true"
+
+	// Email Notifications
+	AppConfig.SMTP.Host = os.Getenv("SMTP_HOST")
+	AppConfig.SMTP.Port = getEnvAsInt("SMTP_PORT", 587)
+	AppConfig.SMTP.Username = os.Getenv("SMTP_USERNAME")
+	AppConfig.SMTP.Password = os.Getenv("SMTP_PASSWORD")
+	AppConfig.SMTP.From = os.Getenv("SMTP_FROM")
+	AppConfig.SMTP.TLS = getEnvWithDefault("SMTP_TLS", "starttls")
+	AppConfig.SMTP.Timeout = time.Duration(getEnvAsInt("SMTP_TIMEOUT", 15)) * time.Second
+
+	// Telegram and ntfy Notifications
+	AppConfig.Telegram.BotToken = os.Getenv("TELEGRAM_BOT_TOKEN")
+	AppConfig.Telegram.APIURL = strings.TrimSuffix(getEnvWithDefault("TELEGRAM_API_URL", "https://api.telegram.org"), "/")
+	AppConfig.Ntfy.ServerURL = strings.TrimSuffix(os.Getenv("NTFY_SERVER_URL"), "/")
+	AppConfig.Ntfy.Token = os.Getenv("NTFY_TOKEN")
+	AppConfig.NotifierTimeout = time.Duration(getEnvAsInt("NOTIFIER_TIMEOUT", 10)) * time.Second
+
+	// Notification Templates
+	AppConfig.NotificationTemplatesDir = os.Getenv("NOTIFICATION_TEMPLATES_DIR")
+
 	// Rate Limits
 	AppConfig.RateLimits.CheckNow = time.Duration(getEnvAsInt("CHECK_NOW_RATE_LIMIT", 3600)) * time.Second
 	AppConfig.RateLimits.Default = time.Duration(getEnvAsInt("DEFAULT_RATE_LIMIT", 180)) * time.Minute
 	AppConfig.RateLimits.DefaultMaxAccounts = getEnvAsInt("DEFAULT_USER_MAXACCOUNTS", 3)
 	AppConfig.RateLimits.PremiumMaxAccounts = getEnvAsInt("PREM_USER_MAXACCOUNTS", 10)
 
+	// Periodic Check Worker Pool
+	AppConfig.CheckPool.Workers = getEnvAsInt("CHECK_WORKERS", 5)
+	AppConfig.CheckPool.PerUser = getEnvAsInt("CHECK_WORKERS_PER_USER", 1)
+
+	// Job Scheduler
+	AppConfig.Scheduler.PollInterval = time.Duration(getEnvAsInt("SCHEDULER_POLL_INTERVAL", 30)) * time.Second
+	AppConfig.Scheduler.MaxAttempts = getEnvAsInt("SCHEDULER_MAX_ATTEMPTS", 5)
+
 	// Intervals
 	AppConfig.Intervals.Check = getEnvAsInt("CHECK_INTERVAL", 15)
 	AppConfig.Intervals.Notification = getEnvAsFloat("NOTIFICATION_INTERVAL", 24)
@@ -249,14 +452,23 @@ func validate() error {
 	required := map[string]string{
 		"DISCORD_TOKEN":      AppConfig.Discord.Token,
 		"DEVELOPER_ID":       AppConfig.Discord.DeveloperID,
-		"DB_USER":            AppConfig.Database.User,
-		"DB_PASSWORD":        AppConfig.Database.Password,
-		"DB_NAME":            AppConfig.Database.Name,
-		"DB_HOST":            AppConfig.Database.Host,
-		"DB_PORT":            AppConfig.Database.Port,
 		"PROFILE_ENDPOINT":   AppConfig.API.ProfileEndpoint,
 		"CHECK_VIP_ENDPOINT": AppConfig.API.CheckVIPEndpoint,
 		"CHECK_ENDPOINT":     AppConfig.API.CheckEndpoint,
+		"ENCRYPTION_KEY":     AppConfig.Encryption.Key,
+	}
+
+	switch AppConfig.Database.Driver {
+	case "mysql", "postgres":
+		required["DB_USER"] = AppConfig.Database.User
+		required["DB_PASSWORD"] = AppConfig.Database.Password
+		required["DB_NAME"] = AppConfig.Database.Name
+		required["DB_HOST"] = AppConfig.Database.Host
+		required["DB_PORT"] = AppConfig.Database.Port
+	case "sqlite":
+		required["DB_PATH"] = AppConfig.Database.Path
+	default:
+		return fmt.Errorf("unsupported DB_DRIVER %q (expected mysql, postgres or sqlite)", AppConfig.Database.Driver)
 	}
 
 	var missing []string
@@ -281,18 +493,26 @@ func validate() error {
 					AppConfig.Database.Host = val
 				case "DB_PORT":
 					AppConfig.Database.Port = val
+				case "DB_PATH":
+					AppConfig.Database.Path = val
 				case "CHECK_ENDPOINT":
 					AppConfig.API.CheckEndpoint = val
 				case "PROFILE_ENDPOINT":
 					AppConfig.API.ProfileEndpoint = val
 				case "CHECK_VIP_ENDPOINT":
 					AppConfig.API.CheckVIPEndpoint = val
+				case "ENCRYPTION_KEY":
+					AppConfig.Encryption.Key = val
 				}
 			}
 		}
 	}
 
 	if len(missing) > 0 {
+		sort.Strings(missing)
+		if AppConfig.Encryption.Key == "" {
+			return fmt.Errorf("missing required environment variables: %v (ENCRYPTION_KEY is required since stored secrets are encrypted; generate one with `openssl rand -base64 32`)", missing)
+		}
 		return fmt.Errorf("missing required environment variables: %v", missing)
 	}
 
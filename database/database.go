@@ -1,42 +1,142 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
+// Models is the set of core tables; the initial schema migration creates them and later
+// migrations bring them up to date with these structs.
+var Models = []interface{}{
+	&models.Account{},
+	&models.Ban{},
+	&models.UserSettings{},
+	&models.SuppressedNotification{},
+}
+
 func Databaselogin() error {
-	logger.Log.Info("Connecting to database...")
+	if err := Open(); err != nil {
+		return err
+	}
+
+	applied, err := MigrateUp(DB)
+	if err != nil {
+		logger.Log.WithError(err).WithField("Bot Startup ", "Database Migrations Problem ").Error()
+		return err
+	}
+	if applied > 0 {
+		logger.Log.Infof("Applied %d database migration(s)", applied)
+	}
+	return nil
+}
 
+// Open connects to the configured database without applying migrations.
+func Open() error {
 	cfg := configuration.Get()
-	dbConfig := cfg.Database
+	if err := SetEncryptionKeys(cfg.Encryption.Key, cfg.Encryption.PreviousKeys); err != nil {
+		logger.Log.WithError(err).WithField("Bot Startup ", "encryption configuration ").Error()
+		return err
+	}
+
+	logger.Log.Infof("Connecting to %s database...", cfg.Database.Driver)
 
-	if dbConfig.User == "" || dbConfig.Password == "" || dbConfig.Host == "" ||
-		dbConfig.Port == "" || dbConfig.Name == "" || dbConfig.Var == "" {
-		err := errors.New("one or more database configuration values not set")
+	dialector, err := newDialector(cfg.Database.Driver)
+	if err != nil {
 		logger.Log.WithError(err).WithField("Bot Startup ", "database configuration ").Error()
 		return err
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s%s",
-		dbConfig.User,
-		dbConfig.Password,
-		dbConfig.Host,
-		dbConfig.Port,
-		dbConfig.Name,
-		dbConfig.Var)
+	return Connect(dialector)
+}
+
+func newDialector(driver string) (gorm.Dialector, error) {
+	dbConfig := configuration.Get().Database
+
+	switch driver {
+	case "", "mysql":
+		if dbConfig.User == "" || dbConfig.Password == "" || dbConfig.Host == "" ||
+			dbConfig.Port == "" || dbConfig.Name == "" || dbConfig.Var == "" {
+			return nil, errors.New("one or more database configuration values not set")
+		}
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s%s",
+			dbConfig.User,
+			dbConfig.Password,
+			dbConfig.Host,
+			dbConfig.Port,
+			dbConfig.Name,
+			dbConfig.Var)
+		return mysql.Open(dsn), nil
+
+	case "postgres":
+		if dbConfig.User == "" || dbConfig.Password == "" || dbConfig.Host == "" ||
+			dbConfig.Port == "" || dbConfig.Name == "" {
+			return nil, errors.New("one or more database configuration values not set")
+		}
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s",
+			pgQuote(dbConfig.Host),
+			pgQuote(dbConfig.Port),
+			pgQuote(dbConfig.User),
+			pgQuote(dbConfig.Password),
+			pgQuote(dbConfig.Name))
+		if dbConfig.Var != "" {
+			dsn += " " + dbConfig.Var
+		}
+		return postgres.Open(dsn), nil
+
+	case "sqlite":
+		if dbConfig.Path == "" {
+			return nil, errors.New("DB_PATH must be set when using the sqlite driver")
+		}
+		return sqlite.Open(sqliteDSN(dbConfig.Path)), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+}
+
+// pgQuote renders value for a libpq key/value connection string, quoting it
+// when it is empty or contains whitespace, quotes or backslashes.
+func pgQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\r\f\v'\\") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+func sqliteDSN(path string) string {
+	if path == ":memory:" {
+		return "file::memory:?_pragma=foreign_keys(1)"
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+func Connect(dialector gorm.Dialector) error {
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
-		logger.Log.WithError(err).WithField("Bot Startup ", "MySQL Config ").Error()
+		logger.Log.WithError(err).WithField("Bot Startup ", dialector.Name()+" Config ").Error()
+		return err
+	}
+
+	if err := registerQueryMetrics(db); err != nil {
+		logger.Log.WithError(err).Error("Failed to register database query metrics")
 		return err
 	}
 
@@ -48,14 +148,15 @@ func Databaselogin() error {
 		logger.Log.WithError(err).Error("Failed to get database instance")
 		return err
 	}
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-
-	err = DB.AutoMigrate(&models.Account{}, &models.Ban{}, &models.UserSettings{}, &models.SuppressedNotification{})
-	if err != nil {
-		logger.Log.WithError(err).WithField("Bot Startup ", "Database Models Problem ").Error()
-		return err
+	if dialector.Name() == "sqlite" {
+		// SQLite allows a single writer, and every connection to an in-memory
+		// database would otherwise see its own empty copy.
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxIdleConns(10)
+		sqlDB.SetMaxOpenConns(100)
 	}
+
 	return nil
 }
 
@@ -72,3 +173,15 @@ func CloseConnection() error {
 	}
 	return nil
 }
+
+// Ping checks that the database is reachable.
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database not connected")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+)
+
+func TestNewDialector(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  string
+		config  func(c *configuration.Config)
+		wantDSN string
+		wantErr bool
+	}{
+		{
+			name:    "mysql",
+			driver:  "mysql",
+			config:  func(c *configuration.Config) { c.Database.Port = "3306"; c.Database.Var = "?parseTime=true" },
+			wantDSN: "bot:secret@tcp(db.internal:3306)/codstatus?parseTime=true",
+		},
+		{
+			name:    "empty driver defaults to mysql",
+			driver:  "",
+			config:  func(c *configuration.Config) { c.Database.Port = "3306"; c.Database.Var = "?parseTime=true" },
+			wantDSN: "bot:secret@tcp(db.internal:3306)/codstatus?parseTime=true",
+		},
+		{
+			name:    "mysql requires connection options",
+			driver:  "mysql",
+			config:  func(c *configuration.Config) { c.Database.Var = "" },
+			wantErr: true,
+		},
+		{
+			name:    "postgres",
+			driver:  "postgres",
+			config:  func(c *configuration.Config) {},
+			wantDSN: "host=db.internal port=5432 user=bot password=secret dbname=codstatus",
+		},
+		{
+			name:    "postgres with options",
+			driver:  "postgres",
+			config:  func(c *configuration.Config) { c.Database.Var = "sslmode=require" },
+			wantDSN: "host=db.internal port=5432 user=bot password=secret dbname=codstatus sslmode=require",
+		},
+		{
+			name:    "postgres quotes special characters",
+			driver:  "postgres",
+			config:  func(c *configuration.Config) { c.Database.Password = `p a's\w` },
+			wantDSN: `host=db.internal port=5432 user=bot password='p a\'s\\w' dbname=codstatus`,
+		},
+		{
+			name:    "postgres requires a password",
+			driver:  "postgres",
+			config:  func(c *configuration.Config) { c.Database.Password = "" },
+			wantErr: true,
+		},
+		{
+			name:    "sqlite",
+			driver:  "sqlite",
+			config:  func(c *configuration.Config) { c.Database.Path = "bot.db" },
+			wantDSN: "bot.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)",
+		},
+		{
+			name:    "sqlite requires a path",
+			driver:  "sqlite",
+			config:  func(c *configuration.Config) {},
+			wantErr: true,
+		},
+		{
+			name:    "unknown driver",
+			driver:  "oracle",
+			config:  func(c *configuration.Config) {},
+			wantErr: true,
+		},
+	}
+
+	cfg := configuration.Get()
+	saved := cfg.Database
+	t.Cleanup(func() { cfg.Database = saved })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Database = saved
+			cfg.Database.User = "bot"
+			cfg.Database.Password = "secret"
+			cfg.Database.Host = "db.internal"
+			cfg.Database.Port = "5432"
+			cfg.Database.Name = "codstatus"
+			cfg.Database.Var = ""
+			cfg.Database.Path = ""
+			tt.config(cfg)
+
+			dialector, err := newDialector(tt.driver)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("newDialector(%q) succeeded, want an error", tt.driver)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newDialector(%q): %v", tt.driver, err)
+			}
+
+			var dsn string
+			switch d := dialector.(type) {
+			case *mysql.Dialector:
+				dsn = d.DSN
+			case *postgres.Dialector:
+				dsn = d.DSN
+			case *sqlite.Dialector:
+				dsn = d.DSN
+			default:
+				t.Fatalf("newDialector(%q) returned %T", tt.driver, dialector)
+			}
+			if dsn != tt.wantDSN {
+				t.Errorf("DSN = %q, want %q", dsn, tt.wantDSN)
+			}
+		})
+	}
+}
+
+func TestPgQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"secret", "secret"},
+		{"", "''"},
+		{"two words", "'two words'"},
+		{"it's", `'it\'s'`},
+		{`back\slash`, `'back\\slash'`},
+		{"p@ss=word", "p@ss=word"},
+	}
+	for _, tt := range tests {
+		if got := pgQuote(tt.value); got != tt.want {
+			t.Errorf("pgQuote(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{":memory:", "file::memory:?_pragma=foreign_keys(1)"},
+		{"codstatusbot.db", "codstatusbot.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"},
+		{"file:bot.db?mode=rwc", "file:bot.db?mode=rwc&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"},
+	}
+	for _, tt := range tests {
+		if got := sqliteDSN(tt.path); got != tt.want {
+			t.Errorf("sqliteDSN(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
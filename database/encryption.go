@@ -0,0 +1,373 @@
+package database
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Values are stored as enc:v1:<key id>:<wrapped data key>:<sealed value>. Each value gets its
+// own random data key, which is sealed with the configured master key.
+const encryptedPrefix = "enc:v1:"
+
+var ErrEncryptionKeyMissing = errors.New("ENCRYPTION_KEY is not configured")
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+var (
+	keyMu      sync.RWMutex
+	currentKey *masterKey
+	knownKeys  = map[string]*masterKey{}
+)
+
+// encryptedColumns lists every column stored through the "encrypted" serializer.
+var encryptedColumns = []struct {
+	Table   string
+	Columns []string
+}{
+	{Table: "accounts", Columns: []string{"sso_cookie"}},
+	{Table: "user_settings", Columns: []string{"cap_solver_api_key", "ez_captcha_api_key", "two_captcha_api_key", "anti_captcha_api_key", "cap_monster_api_key", "webhook_secret", "email", "telegram_chat_id", "ntfy_topic"}},
+	{Table: "webhooks", Columns: []string{"url"}},
+	{Table: "email_verifications", Columns: []string{"email"}},
+}
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// SetEncryptionKeys installs the master key used for new writes. Previous keys are only used to
+// decrypt rows that have not been rotated yet. Keys are base64 encoded 32 byte values.
+func SetEncryptionKeys(current string, previous []string) error {
+	key, err := parseMasterKey(current)
+	if err != nil {
+		return fmt.Errorf("invalid ENCRYPTION_KEY: %w", err)
+	}
+
+	keys := map[string]*masterKey{key.id: key}
+	for _, encoded := range previous {
+		if strings.TrimSpace(encoded) == "" {
+			continue
+		}
+		old, err := parseMasterKey(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid ENCRYPTION_PREVIOUS_KEYS entry: %w", err)
+		}
+		if _, exists := keys[old.id]; !exists {
+			keys[old.id] = old
+		}
+	}
+
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	currentKey = key
+	knownKeys = keys
+	return nil
+}
+
+func parseMasterKey(encoded string) (*masterKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("key is not valid base64: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(raw))
+	}
+
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(raw)
+	return &masterKey{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func unseal(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// EncryptValue seals plaintext under the current master key. Empty strings are stored as-is so
+// "is a key set" checks keep working without decrypting.
+func EncryptValue(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	keyMu.RLock()
+	key := currentKey
+	keyMu.RUnlock()
+	if key == nil {
+		return "", ErrEncryptionKeyMissing
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrappedKey, err := seal(key.aead, dataKey, []byte(key.id))
+	if err != nil {
+		return "", err
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataAEAD, []byte(plaintext), wrappedKey)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedPrefix + key.id + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue reverses EncryptValue. Values without the encryption prefix are legacy plaintext
+// rows and are returned unchanged.
+func DecryptValue(stored string) (string, error) {
+	if !IsEncrypted(stored) {
+		return stored, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(stored, encryptedPrefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	keyMu.RLock()
+	key, ok := knownKeys[parts[0]]
+	keyMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no encryption key configured for key id %s", parts[0])
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed data key: %w", err)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	dataKey, err := unseal(key.aead, wrappedKey, []byte(key.id))
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := unseal(dataAEAD, sealed, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func IsEncrypted(stored string) bool {
+	return strings.HasPrefix(stored, encryptedPrefix)
+}
+
+func encryptedWithCurrentKey(stored string) bool {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	return currentKey != nil && strings.HasPrefix(stored, encryptedPrefix+currentKey.id+":")
+}
+
+// EncryptedSerializer transparently encrypts string fields tagged with `gorm:"serializer:encrypted"`.
+type EncryptedSerializer struct{}
+
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for encrypted field %s", dbValue, field.Name)
+	}
+
+	plaintext, err := DecryptValue(stored)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %w", field.Name, err)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+func (EncryptedSerializer) Value(_ context.Context, field *schema.Field, _ reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted field %s must be a string, got %T", field.Name, fieldValue)
+	}
+	ciphertext, err := EncryptValue(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s: %w", field.Name, err)
+	}
+	return ciphertext, nil
+}
+
+// RotateEncryptionKey re-encrypts every encrypted column under the current key, including any
+// legacy plaintext values. Rows already sealed with the current key are left alone, so an
+// interrupted rotation can simply be re-run. It returns the number of values rewritten.
+func RotateEncryptionKey(db *gorm.DB) (int, error) {
+	return rewriteEncryptedColumns(db, func(stored string) (string, bool, error) {
+		if stored == "" || encryptedWithCurrentKey(stored) {
+			return stored, false, nil
+		}
+		plaintext, err := DecryptValue(stored)
+		if err != nil {
+			return "", false, err
+		}
+		ciphertext, err := EncryptValue(plaintext)
+		return ciphertext, true, err
+	})
+}
+
+func decryptAllColumns(db *gorm.DB) (int, error) {
+	return rewriteEncryptedColumns(db, func(stored string) (string, bool, error) {
+		if !IsEncrypted(stored) {
+			return stored, false, nil
+		}
+		plaintext, err := DecryptValue(stored)
+		return plaintext, true, err
+	})
+}
+
+func rewriteEncryptedColumns(db *gorm.DB, rewrite func(stored string) (string, bool, error)) (int, error) {
+	const batchSize = 500
+	rewritten := 0
+
+	for _, target := range encryptedColumns {
+		// Columns added by later migrations do not exist yet when an old database is upgraded.
+		var columns []string
+		for _, column := range target.Columns {
+			if db.Migrator().HasColumn(target.Table, column) {
+				columns = append(columns, column)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		var lastID uint
+		for {
+			var rows []map[string]interface{}
+			err := db.Table(target.Table).
+				Select(append([]string{"id"}, columns...)).
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(batchSize).
+				Find(&rows).Error
+			if err != nil {
+				return rewritten, fmt.Errorf("failed to read %s: %w", target.Table, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+
+			for _, row := range rows {
+				id, err := toUint(row["id"])
+				if err != nil {
+					return rewritten, fmt.Errorf("unexpected id in %s: %w", target.Table, err)
+				}
+				lastID = id
+
+				updates := map[string]interface{}{}
+				for _, column := range columns {
+					stored := toString(row[column])
+					value, changed, err := rewrite(stored)
+					if err != nil {
+						return rewritten, fmt.Errorf("%s.%s id %d: %w", target.Table, column, id, err)
+					}
+					if changed {
+						updates[column] = value
+					}
+				}
+				if len(updates) == 0 {
+					continue
+				}
+
+				if err := db.Table(target.Table).Where("id = ?", id).UpdateColumns(updates).Error; err != nil {
+					return rewritten, fmt.Errorf("failed to update %s id %d: %w", target.Table, id, err)
+				}
+				rewritten += len(updates)
+			}
+		}
+	}
+
+	return rewritten, nil
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return ""
+	}
+}
+
+func toUint(value interface{}) (uint, error) {
+	switch v := value.(type) {
+	case int64:
+		return uint(v), nil
+	case int32:
+		return uint(v), nil
+	case int:
+		return uint(v), nil
+	case uint64:
+		return uint(v), nil
+	case uint32:
+		return uint(v), nil
+	case uint:
+		return v, nil
+	case []byte:
+		var id uint
+		_, err := fmt.Sscan(string(v), &id)
+		return id, err
+	default:
+		return 0, fmt.Errorf("unsupported id type %T", value)
+	}
+}
@@ -0,0 +1,208 @@
+package database
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/bradselph/CODStatusBot/models"
+	"gorm.io/gorm"
+)
+
+func testKey(fill string) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(fill, 32)))
+}
+
+// rawColumn reads a column without going through the encrypted serializer.
+func rawColumn(t *testing.T, db *gorm.DB, table, column string, id uint) string {
+	t.Helper()
+	var value string
+	if err := db.Table(table).Select(column).Where("id = ?", id).Scan(&value).Error; err != nil {
+		t.Fatalf("read %s.%s: %v", table, column, err)
+	}
+	return value
+}
+
+func TestEncryptDecryptValue(t *testing.T) {
+	if err := SetEncryptionKeys(testKey("a"), nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+
+	ciphertext, err := EncryptValue("cookie-value")
+	if err != nil {
+		t.Fatalf("EncryptValue: %v", err)
+	}
+	if !IsEncrypted(ciphertext) || strings.Contains(ciphertext, "cookie-value") {
+		t.Fatalf("EncryptValue returned %q", ciphertext)
+	}
+	again, err := EncryptValue("cookie-value")
+	if err != nil {
+		t.Fatalf("EncryptValue: %v", err)
+	}
+	if again == ciphertext {
+		t.Error("EncryptValue is deterministic, want a fresh data key per value")
+	}
+
+	plaintext, err := DecryptValue(ciphertext)
+	if err != nil || plaintext != "cookie-value" {
+		t.Fatalf("DecryptValue = %q, %v; want cookie-value, nil", plaintext, err)
+	}
+	if empty, err := EncryptValue(""); err != nil || empty != "" {
+		t.Errorf("EncryptValue(\"\") = %q, %v; want empty", empty, err)
+	}
+	if legacy, err := DecryptValue("plain"); err != nil || legacy != "plain" {
+		t.Errorf("DecryptValue(plain) = %q, %v; want it unchanged", legacy, err)
+	}
+
+	tampered := ciphertext[:len(ciphertext)-2] + "AA"
+	if _, err := DecryptValue(tampered); err == nil {
+		t.Error("DecryptValue accepted a tampered value")
+	}
+}
+
+func TestSetEncryptionKeysRejectsBadKeys(t *testing.T) {
+	for _, key := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if err := SetEncryptionKeys(key, nil); err == nil {
+			t.Errorf("SetEncryptionKeys(%q) succeeded", key)
+		}
+	}
+	if err := SetEncryptionKeys(testKey("a"), []string{"bad"}); err == nil {
+		t.Error("SetEncryptionKeys accepted a bad previous key")
+	}
+}
+
+func TestEncryptedSerializerRoundTrip(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	account := models.Account{UserID: "1", SSOCookie: "sso-cookie"}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	if raw := rawColumn(t, db, "accounts", "sso_cookie", account.ID); !IsEncrypted(raw) {
+		t.Fatalf("sso_cookie stored as %q, want it encrypted", raw)
+	}
+
+	var loaded models.Account
+	if err := db.First(&loaded, account.ID).Error; err != nil {
+		t.Fatalf("load account: %v", err)
+	}
+	if loaded.SSOCookie != "sso-cookie" {
+		t.Errorf("SSOCookie = %q, want sso-cookie", loaded.SSOCookie)
+	}
+
+	settings := models.UserSettings{UserID: "1"}
+	if err := db.Create(&settings).Error; err != nil {
+		t.Fatalf("create settings: %v", err)
+	}
+	if raw := rawColumn(t, db, "user_settings", "cap_solver_api_key", settings.ID); raw != "" {
+		t.Errorf("empty key stored as %q, want it left empty", raw)
+	}
+}
+
+func TestRotateEncryptionKey(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	oldKey, newKey := testKey("o"), testKey("n")
+	if err := SetEncryptionKeys(oldKey, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	account := models.Account{UserID: "1", SSOCookie: "sso-cookie"}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	settings := models.UserSettings{UserID: "1", TwoCaptchaAPIKey: "two-key"}
+	if err := db.Create(&settings).Error; err != nil {
+		t.Fatalf("create settings: %v", err)
+	}
+	before := rawColumn(t, db, "accounts", "sso_cookie", account.ID)
+
+	if err := SetEncryptionKeys(newKey, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	if _, err := RotateEncryptionKey(db); err == nil {
+		t.Fatal("RotateEncryptionKey succeeded without the old key")
+	}
+
+	if err := SetEncryptionKeys(newKey, []string{oldKey}); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	rewritten, err := RotateEncryptionKey(db)
+	if err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+	if rewritten != 2 {
+		t.Errorf("RotateEncryptionKey rewrote %d values, want 2", rewritten)
+	}
+	after := rawColumn(t, db, "accounts", "sso_cookie", account.ID)
+	if after == before || !encryptedWithCurrentKey(after) {
+		t.Errorf("sso_cookie not re-encrypted under the new key: %q", after)
+	}
+	if again, err := RotateEncryptionKey(db); err != nil || again != 0 {
+		t.Errorf("second RotateEncryptionKey = %d, %v; want 0, nil", again, err)
+	}
+
+	// The old key is no longer needed once every row is rotated.
+	if err := SetEncryptionKeys(newKey, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	var loaded models.UserSettings
+	if err := db.First(&loaded, settings.ID).Error; err != nil {
+		t.Fatalf("load settings: %v", err)
+	}
+	if loaded.TwoCaptchaAPIKey != "two-key" {
+		t.Errorf("TwoCaptchaAPIKey = %q, want two-key", loaded.TwoCaptchaAPIKey)
+	}
+}
+
+func TestEncryptSecretsMigrationConvertsPlaintext(t *testing.T) {
+	db := openTestDB(t)
+	if err := Migrations[0].Up(db); err != nil {
+		t.Fatalf("initial_schema: %v", err)
+	}
+	legacyAccount := v1Account{UserID: "1", SSOCookie: "plain-cookie"}
+	if err := db.Create(&legacyAccount).Error; err != nil {
+		t.Fatalf("create legacy account: %v", err)
+	}
+	legacySettings := v1UserSettings{UserID: "1", CapSolverAPIKey: "plain-key"}
+	if err := db.Create(&legacySettings).Error; err != nil {
+		t.Fatalf("create legacy settings: %v", err)
+	}
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		t.Fatalf("create schema_migrations: %v", err)
+	}
+	if err := db.Create(&SchemaMigration{Version: 1, Name: Migrations[0].Name}).Error; err != nil {
+		t.Fatalf("record initial_schema: %v", err)
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if raw := rawColumn(t, db, "accounts", "sso_cookie", legacyAccount.ID); !IsEncrypted(raw) {
+		t.Errorf("sso_cookie left as %q after encrypt_secrets", raw)
+	}
+	if raw := rawColumn(t, db, "user_settings", "cap_solver_api_key", legacySettings.ID); !IsEncrypted(raw) {
+		t.Errorf("cap_solver_api_key left as %q after encrypt_secrets", raw)
+	}
+
+	var account models.Account
+	if err := db.First(&account, legacyAccount.ID).Error; err != nil {
+		t.Fatalf("load account: %v", err)
+	}
+	if account.SSOCookie != "plain-cookie" {
+		t.Errorf("SSOCookie = %q, want plain-cookie", account.SSOCookie)
+	}
+
+	// Rolling back to before encrypt_secrets restores the plaintext.
+	if _, err := MigrateDown(db, len(Migrations)-2); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if raw := rawColumn(t, db, "accounts", "sso_cookie", legacyAccount.ID); raw != "plain-cookie" {
+		t.Errorf("sso_cookie = %q after rolling back encrypt_secrets, want plain-cookie", raw)
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"time"
+
+	"github.com/bradselph/CODStatusBot/metrics"
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "metrics:query_start"
+
+type registerFunc func(name string, fn func(*gorm.DB)) error
+
+// registerQueryMetrics times every statement db runs into the db_query_duration_seconds
+// histogram, labelled with the GORM operation that issued it.
+func registerQueryMetrics(db *gorm.DB) error {
+	cb := db.Callback()
+	operations := []struct {
+		name          string
+		before, after registerFunc
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, op := range operations {
+		name := op.name
+		if err := op.before("metrics:before_"+name, func(tx *gorm.DB) {
+			tx.InstanceSet(queryStartKey, time.Now())
+		}); err != nil {
+			return err
+		}
+		if err := op.after("metrics:after_"+name, func(tx *gorm.DB) {
+			if start, ok := tx.InstanceGet(queryStartKey); ok {
+				metrics.DBQueryDuration.WithLabelValues(name).Observe(time.Since(start.(time.Time)).Seconds())
+			}
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,472 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"gorm.io/gorm"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+type SchemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"type:varchar(255)"`
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type MigrationState struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrations is the ordered schema history. Append new steps with the next
+// version number; never renumber or edit a step that has shipped.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(v1Schema...)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v1SuppressedNotification{}, &v1UserSettings{}, &v1Ban{}, &v1Account{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "drop_account_ban_flags",
+		Up:      dropAccountBanFlags,
+		Down:    restoreAccountBanFlags,
+	},
+	{
+		Version: 3,
+		Name:    "encrypt_secrets",
+		Up: func(tx *gorm.DB) error {
+			_, err := RotateEncryptionKey(tx)
+			return err
+		},
+		Down: func(tx *gorm.DB) error {
+			_, err := decryptAllColumns(tx)
+			return err
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create_scheduled_jobs",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.ScheduledJob{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.ScheduledJob{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_ban_details",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range banDetailColumns {
+				if !tx.Migrator().HasColumn(&models.Ban{}, column) {
+					if err := tx.Migrator().AddColumn(&models.Ban{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range banDetailColumns {
+				if tx.Migrator().HasColumn(&models.Ban{}, column) {
+					if err := tx.Migrator().DropColumn(&models.Ban{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create_account_title_statuses",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.AccountTitleStatus{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.AccountTitleStatus{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_captcha_provider_order",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&models.UserSettings{}, "CaptchaProviderOrder") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.UserSettings{}, "CaptchaProviderOrder")
+		},
+		Down: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.UserSettings{}, "CaptchaProviderOrder") {
+				return nil
+			}
+			return tx.Migrator().DropColumn(&models.UserSettings{}, "CaptchaProviderOrder")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add_anticaptcha_capmonster_keys",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range captchaKeyColumns {
+				if !tx.Migrator().HasColumn(&models.UserSettings{}, column) {
+					if err := tx.Migrator().AddColumn(&models.UserSettings{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range captchaKeyColumns {
+				if tx.Migrator().HasColumn(&models.UserSettings{}, column) {
+					if err := tx.Migrator().DropColumn(&models.UserSettings{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create_captcha_solves",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.CaptchaSolve{}); err != nil {
+				return err
+			}
+			for _, column := range captchaBudgetColumns {
+				if !tx.Migrator().HasColumn(&models.UserSettings{}, column) {
+					if err := tx.Migrator().AddColumn(&models.UserSettings{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range captchaBudgetColumns {
+				if tx.Migrator().HasColumn(&models.UserSettings{}, column) {
+					if err := tx.Migrator().DropColumn(&models.UserSettings{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return tx.Migrator().DropTable(&models.CaptchaSolve{})
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add_user_disabled",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range userDisabledColumns {
+				if !tx.Migrator().HasColumn(&models.UserSettings{}, column) {
+					if err := tx.Migrator().AddColumn(&models.UserSettings{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range userDisabledColumns {
+				if tx.Migrator().HasColumn(&models.UserSettings{}, column) {
+					if err := tx.Migrator().DropColumn(&models.UserSettings{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create_api_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.APIToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.APIToken{})
+		},
+	},
+	{
+		Version: 12,
+		Name:    "create_webhooks",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{}); err != nil {
+				return err
+			}
+			if tx.Migrator().HasColumn(&models.UserSettings{}, "WebhookSecret") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.UserSettings{}, "WebhookSecret")
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&models.UserSettings{}, "WebhookSecret") {
+				if err := tx.Migrator().DropColumn(&models.UserSettings{}, "WebhookSecret"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&models.WebhookDelivery{}, &models.Webhook{})
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add_email_notifications",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.EmailVerification{}); err != nil {
+				return err
+			}
+			if tx.Migrator().HasColumn(&models.UserSettings{}, "Email") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.UserSettings{}, "Email")
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&models.UserSettings{}, "Email") {
+				if err := tx.Migrator().DropColumn(&models.UserSettings{}, "Email"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&models.EmailVerification{})
+		},
+	},
+	{
+		Version: 14,
+		Name:    "add_notification_backends",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range notificationBackendColumns {
+				if !tx.Migrator().HasColumn(&models.UserSettings{}, column) {
+					if err := tx.Migrator().AddColumn(&models.UserSettings{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range notificationBackendColumns {
+				if tx.Migrator().HasColumn(&models.UserSettings{}, column) {
+					if err := tx.Migrator().DropColumn(&models.UserSettings{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 15,
+		Name:    "add_user_language",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&models.UserSettings{}, "Language") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.UserSettings{}, "Language")
+		},
+		Down: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.UserSettings{}, "Language") {
+				return nil
+			}
+			return tx.Migrator().DropColumn(&models.UserSettings{}, "Language")
+		},
+	},
+}
+
+// notificationBackendColumns hold per-category notification routing and the Telegram and ntfy
+// destinations.
+var notificationBackendColumns = []string{"NotificationRoutes", "TelegramChatID", "NtfyTopic"}
+
+// userDisabledColumns let an administrator block a user from the bot.
+var userDisabledColumns = []string{"IsDisabled", "DisabledReason"}
+
+// captchaBudgetColumns hold the per-user monthly captcha budget.
+var captchaBudgetColumns = []string{"MonthlyCaptchaBudget", "LastBudgetNotification"}
+
+// captchaKeyColumns are the API key columns for providers added after the original three.
+var captchaKeyColumns = []string{"AntiCaptchaAPIKey", "CapMonsterAPIKey"}
+
+// banDetailColumns are the structured ban fields added alongside the original free-text ones.
+var banDetailColumns = []string{"TempBanEndsAt", "CanAppeal", "AppealStatus", "Enforcements"}
+
+// accountBanFlags mirrors the ban flag columns that used to live on models.Account.
+type accountBanFlags struct {
+	IsPermabanned  bool `gorm:"default:false"`
+	IsShadowbanned bool `gorm:"default:false"`
+	IsTempbanned   bool `gorm:"default:false"`
+}
+
+func (accountBanFlags) TableName() string {
+	return "accounts"
+}
+
+var accountBanFlagColumns = []struct {
+	Column string
+	Field  string
+	Status models.Status
+}{
+	// Ordered by severity so a permaban wins when several flags are set.
+	{Column: "is_shadowbanned", Field: "IsShadowbanned", Status: models.StatusShadowban},
+	{Column: "is_tempbanned", Field: "IsTempbanned", Status: models.StatusTempban},
+	{Column: "is_permabanned", Field: "IsPermabanned", Status: models.StatusPermaban},
+}
+
+func dropAccountBanFlags(tx *gorm.DB) error {
+	for _, flag := range accountBanFlagColumns {
+		if !tx.Migrator().HasColumn(&accountBanFlags{}, flag.Column) {
+			continue
+		}
+		// last_status is the source of truth from now on, so carry over any flag it disagrees with.
+		if err := tx.Table("accounts").Where(flag.Column+" = ? AND last_status <> ?", true, flag.Status).
+			Update("last_status", flag.Status).Error; err != nil {
+			return fmt.Errorf("failed to backfill last_status from %s: %w", flag.Column, err)
+		}
+		if err := tx.Migrator().DropColumn(&accountBanFlags{}, flag.Column); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", flag.Column, err)
+		}
+	}
+	return nil
+}
+
+func restoreAccountBanFlags(tx *gorm.DB) error {
+	for _, flag := range accountBanFlagColumns {
+		if !tx.Migrator().HasColumn(&accountBanFlags{}, flag.Column) {
+			if err := tx.Migrator().AddColumn(&accountBanFlags{}, flag.Field); err != nil {
+				return fmt.Errorf("failed to add %s: %w", flag.Column, err)
+			}
+		}
+		if err := tx.Table("accounts").Where("last_status = ?", flag.Status).
+			Update(flag.Column, true).Error; err != nil {
+			return fmt.Errorf("failed to backfill %s: %w", flag.Column, err)
+		}
+	}
+	return nil
+}
+
+func sortedMigrations() []Migration {
+	sorted := make([]Migration, len(Migrations))
+	copy(sorted, Migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return sorted
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// MigrateUp applies every pending migration in version order and returns how many ran.
+func MigrateUp(db *gorm.DB) (int, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range sortedMigrations() {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		logger.Log.Infof("Applying migration %d_%s", m.Version, m.Name)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown rolls back up to steps applied migrations, newest first, and returns how many ran.
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	sorted := sortedMigrations()
+	count := 0
+	for i := len(sorted) - 1; i >= 0 && count < steps; i-- {
+		m := sorted[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return count, fmt.Errorf("migration %d_%s cannot be rolled back", m.Version, m.Name)
+		}
+
+		logger.Log.Infof("Rolling back migration %d_%s", m.Version, m.Name)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("rollback of %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrationStatus reports every known migration and whether it has been applied.
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []MigrationState
+	for _, m := range sortedMigrations() {
+		row, ok := applied[m.Version]
+		states = append(states, MigrationState{
+			Migration: m,
+			Applied:   ok,
+			AppliedAt: row.AppliedAt,
+		})
+	}
+	return states, nil
+}
@@ -0,0 +1,200 @@
+package database
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens a private in-memory SQLite database with an encryption key installed, which
+// the encrypt_secrets migration needs.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	if err := SetEncryptionKeys(key, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(sqliteDSN(":memory:")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql.DB: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := openTestDB(t)
+
+	applied, err := MigrateUp(db)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if applied != len(Migrations) {
+		t.Fatalf("MigrateUp applied %d migrations, want %d", applied, len(Migrations))
+	}
+	for _, model := range Models {
+		if !db.Migrator().HasTable(model) {
+			t.Errorf("table for %T missing after MigrateUp", model)
+		}
+	}
+
+	if again, err := MigrateUp(db); err != nil || again != 0 {
+		t.Fatalf("second MigrateUp = %d, %v; want 0, nil", again, err)
+	}
+
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	for _, state := range states {
+		if !state.Applied {
+			t.Errorf("migration %d_%s not marked applied", state.Version, state.Name)
+		}
+	}
+
+	rolledBack, err := MigrateDown(db, len(Migrations))
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if rolledBack != len(Migrations) {
+		t.Fatalf("MigrateDown rolled back %d migrations, want %d", rolledBack, len(Migrations))
+	}
+	for _, model := range Models {
+		if db.Migrator().HasTable(model) {
+			t.Errorf("table for %T still present after MigrateDown", model)
+		}
+	}
+
+	if reapplied, err := MigrateUp(db); err != nil || reapplied != len(Migrations) {
+		t.Fatalf("MigrateUp after rollback = %d, %v; want %d, nil", reapplied, err, len(Migrations))
+	}
+}
+
+func TestMigrateDownOneStep(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	if n, err := MigrateDown(db, 1); err != nil || n != 1 {
+		t.Fatalf("MigrateDown(1) = %d, %v; want 1, nil", n, err)
+	}
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	for i, state := range states {
+		if want := i < len(states)-1; state.Applied != want {
+			t.Errorf("migration %d_%s applied = %v, want %v", state.Version, state.Name, state.Applied, want)
+		}
+	}
+}
+
+func TestAutoMigrateModels(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(Models...); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	for _, model := range Models {
+		if !db.Migrator().HasTable(model) {
+			t.Errorf("table for %T missing after AutoMigrate", model)
+		}
+	}
+}
+
+func TestInitialSchemaIsFrozen(t *testing.T) {
+	db := openTestDB(t)
+	if err := Migrations[0].Up(db); err != nil {
+		t.Fatalf("initial_schema: %v", err)
+	}
+
+	// Columns that later migrations add or drop must keep their pre-migration state here.
+	for _, column := range []string{"is_permabanned", "is_shadowbanned", "is_tempbanned"} {
+		if !db.Migrator().HasColumn(&v1Account{}, column) {
+			t.Errorf("accounts.%s missing after initial_schema", column)
+		}
+	}
+	for _, column := range banDetailColumns {
+		if db.Migrator().HasColumn(&models.Ban{}, column) {
+			t.Errorf("bans.%s created by initial_schema", column)
+		}
+	}
+	if db.Migrator().HasColumn(&models.UserSettings{}, "Language") {
+		t.Error("user_settings.language created by initial_schema")
+	}
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	all := append([]interface{}{}, Models...)
+	all = append(all,
+		&models.ScheduledJob{},
+		&models.AccountTitleStatus{},
+		&models.CaptchaSolve{},
+		&models.APIToken{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.EmailVerification{},
+	)
+	for _, model := range all {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("parse %T: %v", model, err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !db.Migrator().HasColumn(model, field.DBName) {
+				t.Errorf("%s.%s missing after MigrateUp", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+	for _, column := range accountBanFlagColumns {
+		if db.Migrator().HasColumn(&accountBanFlags{}, column.Column) {
+			t.Errorf("accounts.%s still present after MigrateUp", column.Column)
+		}
+	}
+}
+
+func TestDropAccountBanFlagsBackfillsStatus(t *testing.T) {
+	db := openTestDB(t)
+	if err := Migrations[0].Up(db); err != nil {
+		t.Fatalf("initial_schema: %v", err)
+	}
+	legacy := v1Account{UserID: "1", LastStatus: models.StatusGood, IsPermabanned: true, IsTempbanned: true}
+	if err := db.Create(&legacy).Error; err != nil {
+		t.Fatalf("create legacy account: %v", err)
+	}
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		t.Fatalf("create schema_migrations: %v", err)
+	}
+	if err := db.Create(&SchemaMigration{Version: 1, Name: Migrations[0].Name}).Error; err != nil {
+		t.Fatalf("record initial_schema: %v", err)
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	var account models.Account
+	if err := db.First(&account, legacy.ID).Error; err != nil {
+		t.Fatalf("load account: %v", err)
+	}
+	if account.LastStatus != models.StatusPermaban {
+		t.Errorf("LastStatus = %q, want %q", account.LastStatus, models.StatusPermaban)
+	}
+}
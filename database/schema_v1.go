@@ -0,0 +1,119 @@
+package database
+
+import (
+	"time"
+
+	"github.com/bradselph/CODStatusBot/models"
+	"gorm.io/gorm"
+)
+
+// The v1 structs freeze the tables created by the initial_schema migration as they were before
+// versioned migrations existed. Later migrations alter these tables; do not edit these structs to
+// follow the models package.
+
+type v1Account struct {
+	gorm.Model
+	UserID                 string `gorm:"index"`
+	ChannelID              string
+	Title                  string
+	LastStatus             models.Status `gorm:"default:unknown"`
+	LastCheck              int64         `gorm:"default:0"`
+	LastNotification       int64
+	LastCookieNotification int64
+	SSOCookie              string
+	Created                int64
+	IsExpiredCookie        bool   `gorm:"default:false"`
+	NotificationType       string `gorm:"default:channel"`
+	IsPermabanned          bool   `gorm:"default:false"`
+	IsShadowbanned         bool   `gorm:"default:false"`
+	IsTempbanned           bool   `gorm:"default:false"`
+	IsVIP                  bool   `gorm:"default:false"`
+	LastCookieCheck        int64  `gorm:"default:0"`
+	LastStatusChange       int64  `gorm:"default:0"`
+	IsCheckDisabled        bool   `gorm:"default:false"`
+	DisabledReason         string
+	SSOCookieExpiration    int64
+	ConsecutiveErrors      int `gorm:"default:0"`
+	LastSuccessfulCheck    time.Time
+	LastErrorTime          time.Time
+	Last24HourNotification time.Time
+	LastCheckNowTime       time.Time
+	LastAddAccountTime     time.Time
+}
+
+func (v1Account) TableName() string {
+	return "accounts"
+}
+
+type v1UserSettings struct {
+	gorm.Model
+	UserID                       string `gorm:"type:varchar(255);uniqueIndex"`
+	CapSolverAPIKey              string
+	EZCaptchaAPIKey              string
+	TwoCaptchaAPIKey             string
+	PreferredCaptchaProvider     string `gorm:"default:'capsolver'"`
+	CaptchaBalance               float64
+	LastBalanceCheck             time.Time
+	CheckInterval                int
+	NotificationInterval         float64
+	CooldownDuration             float64
+	StatusChangeCooldown         float64
+	HasSeenAnnouncement          bool                 `gorm:"default:false"`
+	NotificationType             string               `gorm:"default:channel"`
+	NotificationTimes            map[string]time.Time `gorm:"serializer:json"`
+	ActionCounts                 map[string]int       `gorm:"serializer:json"`
+	LastActionTimes              map[string]time.Time `gorm:"serializer:json"`
+	LastNotification             time.Time
+	LastDisabledNotification     time.Time
+	LastStatusChangeNotification time.Time
+	LastDailyUpdateNotification  time.Time
+	LastCookieExpirationWarning  time.Time
+	LastBalanceNotification      time.Time
+	LastErrorNotification        time.Time
+	CustomSettings               bool                 `gorm:"default:false"`
+	LastCommandTimes             map[string]time.Time `gorm:"serializer:json"`
+	RateLimitExpiration          map[string]time.Time `gorm:"serializer:json"`
+}
+
+func (v1UserSettings) TableName() string {
+	return "user_settings"
+}
+
+type v1Ban struct {
+	gorm.Model
+	Account         v1Account
+	AccountID       uint
+	Status          models.Status
+	LogType         string
+	Message         string
+	PreviousStatus  models.Status
+	TempBanDuration string
+	AffectedGames   string
+	Timestamp       time.Time
+	Initiator       string
+	ErrorDetails    string
+}
+
+func (v1Ban) TableName() string {
+	return "bans"
+}
+
+type v1SuppressedNotification struct {
+	gorm.Model
+	UserID           string `gorm:"index"`
+	NotificationType string
+	Content          string    `gorm:"type:text"`
+	Timestamp        time.Time `gorm:"index"`
+}
+
+func (v1SuppressedNotification) TableName() string {
+	return "suppressed_notifications"
+}
+
+// v1Schema lists the initial_schema tables in creation order.
+var v1Schema = []interface{}{
+	&v1Account{},
+	&v1Ban{},
+	&v1UserSettings{},
+	&v1SuppressedNotification{},
+}
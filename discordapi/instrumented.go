@@ -0,0 +1,67 @@
+package discordapi
+
+import (
+	"github.com/bradselph/CODStatusBot/metrics"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Instrument wraps s so every failed call is counted in discord_api_errors_total under the name of
+// the method that failed.
+func Instrument(s Session) Session {
+	if _, ok := s.(instrumented); ok {
+		return s
+	}
+	return instrumented{s}
+}
+
+type instrumented struct {
+	Session
+}
+
+func countError(method string, err error) {
+	if err != nil {
+		metrics.DiscordAPIErrors.WithLabelValues(method).Inc()
+	}
+}
+
+func (s instrumented) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	err := s.Session.InteractionRespond(interaction, resp, options...)
+	countError("InteractionRespond", err)
+	return err
+}
+
+func (s instrumented) FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	msg, err := s.Session.FollowupMessageCreate(interaction, wait, data, options...)
+	countError("FollowupMessageCreate", err)
+	return msg, err
+}
+
+func (s instrumented) UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	channel, err := s.Session.UserChannelCreate(recipientID, options...)
+	countError("UserChannelCreate", err)
+	return channel, err
+}
+
+func (s instrumented) ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	msg, err := s.Session.ChannelMessageSend(channelID, content, options...)
+	countError("ChannelMessageSend", err)
+	return msg, err
+}
+
+func (s instrumented) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	msg, err := s.Session.ChannelMessageSendEmbed(channelID, embed, options...)
+	countError("ChannelMessageSendEmbed", err)
+	return msg, err
+}
+
+func (s instrumented) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	msg, err := s.Session.ChannelMessageSendComplex(channelID, data, options...)
+	countError("ChannelMessageSendComplex", err)
+	return msg, err
+}
+
+func (s instrumented) UpdateWatchStatus(idle int, name string) error {
+	err := s.Session.UpdateWatchStatus(idle, name)
+	countError("UpdateWatchStatus", err)
+	return err
+}
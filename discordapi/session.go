@@ -0,0 +1,19 @@
+// Package discordapi describes the part of the Discord API the bot uses, so command handlers and
+// notifications can run against testsupport/fakediscord instead of a live gateway connection.
+package discordapi
+
+import "github.com/bwmarrin/discordgo"
+
+// Session is the subset of *discordgo.Session that handlers and services call. Startup-only
+// operations such as opening the gateway and registering commands stay on the concrete type.
+type Session interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	UpdateWatchStatus(idle int, name string) error
+}
+
+var _ Session = (*discordgo.Session)(nil)
@@ -0,0 +1,141 @@
+// Package health serves the /healthz and /readyz probes. Liveness only fails when the bot is
+// wedged, so an orchestrator restarts it; readiness also fails while a dependency is down and
+// the bot cannot do useful work until it recovers.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// probeTimeout bounds the work one probe may do, including captcha balance lookups.
+	probeTimeout = 10 * time.Second
+	// heartbeatMaxAge is how long the gateway may go without a heartbeat ACK. discordgo
+	// reconnects on its own after five missed ACKs, a little under four minutes.
+	heartbeatMaxAge = 3 * time.Minute
+)
+
+// Checker reports on the bot's dependencies.
+type Checker struct {
+	discord   *discordgo.Session
+	startedAt time.Time
+}
+
+// NewChecker returns a Checker for the bot's Discord session. The check loop is given until
+// HEALTH_MAX_CHECK_AGE after startedAt to finish its first pass.
+func NewChecker(discord *discordgo.Session, startedAt time.Time) *Checker {
+	return &Checker{discord: discord, startedAt: startedAt}
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the body of a probe response.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Liveness serves /healthz. It fails only when the periodic check loop has made no progress for
+// HEALTH_STALL_TIMEOUT, which a restart is the only way out of.
+func (c *Checker) Liveness(w http.ResponseWriter, _ *http.Request) {
+	writeReport(w, map[string]Result{
+		"account_checks": c.checkPass(configuration.Get().Health.StallTimeout),
+	})
+}
+
+// Readiness serves /readyz: the database answers, the Discord gateway is connected, a check pass
+// finished within HEALTH_MAX_CHECK_AGE and at least one captcha provider is enabled and funded.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+	defer cancel()
+
+	writeReport(w, map[string]Result{
+		"database":       c.database(ctx),
+		"discord":        c.gateway(),
+		"account_checks": c.checkPass(configuration.Get().Health.MaxCheckAge),
+		"captcha":        c.captcha(ctx),
+	})
+}
+
+func (c *Checker) database(ctx context.Context) Result {
+	if err := database.Ping(ctx); err != nil {
+		return Result{Detail: err.Error()}
+	}
+	return Result{OK: true}
+}
+
+func (c *Checker) gateway() Result {
+	if c.discord == nil {
+		return Result{Detail: "no Discord session"}
+	}
+	c.discord.RLock()
+	ready := c.discord.DataReady
+	lastAck := c.discord.LastHeartbeatAck
+	c.discord.RUnlock()
+
+	if !ready {
+		return Result{Detail: "gateway not connected"}
+	}
+	if since := time.Since(lastAck); since > heartbeatMaxAge {
+		return Result{Detail: fmt.Sprintf("no heartbeat ACK for %s", since.Round(time.Second))}
+	}
+	return Result{OK: true}
+}
+
+func (c *Checker) checkPass(maxAge time.Duration) Result {
+	last := services.LastCheckPass()
+	if last.IsZero() {
+		if since := time.Since(c.startedAt); since > maxAge {
+			return Result{Detail: fmt.Sprintf("no check pass finished since startup %s ago", since.Round(time.Second))}
+		}
+		return Result{OK: true, Detail: "first check pass in progress"}
+	}
+
+	since := time.Since(last)
+	detail := fmt.Sprintf("last check pass finished %s ago", since.Round(time.Second))
+	return Result{OK: since <= maxAge, Detail: detail}
+}
+
+func (c *Checker) captcha(ctx context.Context) Result {
+	if len(services.EnabledCaptchaProviders()) == 0 {
+		return Result{Detail: "no captcha provider enabled"}
+	}
+	funded := services.FundedCaptchaProviders(ctx)
+	if len(funded) == 0 {
+		return Result{Detail: "no enabled captcha provider has a valid, funded default key"}
+	}
+	return Result{OK: true, Detail: fmt.Sprintf("%d funded provider(s)", len(funded))}
+}
+
+func writeReport(w http.ResponseWriter, checks map[string]Result) {
+	report := Report{Status: "ok", Checks: checks}
+	status := http.StatusOK
+	for _, result := range checks {
+		if !result.OK {
+			report.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.Log.WithError(err).Error("Failed to write health report")
+	}
+}
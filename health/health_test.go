@@ -0,0 +1,174 @@
+package health_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/health"
+	"github.com/bradselph/CODStatusBot/testsupport/fakecaptcha"
+	"github.com/bwmarrin/discordgo"
+	"github.com/glebarez/sqlite"
+)
+
+// useDependencies points the configuration at a funded fake Capsolver server and the database
+// at a fresh in-memory SQLite database for the rest of the test.
+func useDependencies(t *testing.T) {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := *cfg
+	previousDB := database.DB
+	t.Cleanup(func() {
+		*cfg = saved
+		database.DB = previousDB
+	})
+
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	if err := database.SetEncryptionKeys(key, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	if err := database.Connect(sqlite.Open("file::memory:")); err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+
+	captcha := fakecaptcha.New(fakecaptcha.Capsolver)
+	t.Cleanup(captcha.Close)
+	captcha.Apply(cfg)
+
+	cfg.Health.MaxCheckAge = time.Hour
+	cfg.Health.StallTimeout = time.Hour
+}
+
+// connectedSession is a Discord session whose gateway is up and acknowledging heartbeats.
+func connectedSession() *discordgo.Session {
+	return &discordgo.Session{DataReady: true, LastHeartbeatAck: time.Now()}
+}
+
+func probe(t *testing.T, handler http.HandlerFunc) (int, health.Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var report health.Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode report %q: %v", rec.Body.String(), err)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+	return rec.Code, report
+}
+
+func TestReadinessDatabase(t *testing.T) {
+	tests := []struct {
+		name       string
+		down       func(t *testing.T)
+		wantStatus int
+		wantDetail string
+	}{
+		{
+			name:       "database up",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "database closed",
+			down: func(t *testing.T) {
+				sqlDB, err := database.DB.DB()
+				if err != nil {
+					t.Fatalf("DB: %v", err)
+				}
+				sqlDB.Close()
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantDetail: "database is closed",
+		},
+		{
+			name:       "never connected",
+			down:       func(*testing.T) { database.DB = nil },
+			wantStatus: http.StatusServiceUnavailable,
+			wantDetail: "database not connected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useDependencies(t)
+			if tt.down != nil {
+				tt.down(t)
+			}
+			checker := health.NewChecker(connectedSession(), time.Now())
+
+			code, report := probe(t, checker.Readiness)
+			if code != tt.wantStatus {
+				t.Errorf("readiness status = %d, want %d (%+v)", code, tt.wantStatus, report)
+			}
+			db := report.Checks["database"]
+			if db.OK != (tt.down == nil) || !strings.Contains(db.Detail, tt.wantDetail) {
+				t.Errorf("database check = %+v, want ok %v with detail %q", db, tt.down == nil, tt.wantDetail)
+			}
+			for _, name := range []string{"discord", "account_checks", "captcha"} {
+				if !report.Checks[name].OK {
+					t.Errorf("%s check = %+v, want ok", name, report.Checks[name])
+				}
+			}
+			wantReport := "ok"
+			if tt.down != nil {
+				wantReport = "unavailable"
+			}
+			if report.Status != wantReport {
+				t.Errorf("report status = %q, want %q", report.Status, wantReport)
+			}
+
+			// A database outage is something to wait out, not a reason to restart the bot.
+			if code, report := probe(t, checker.Liveness); code != http.StatusOK {
+				t.Errorf("liveness status = %d, want %d (%+v)", code, http.StatusOK, report)
+			}
+		})
+	}
+}
+
+func TestReadinessGateway(t *testing.T) {
+	tests := []struct {
+		name    string
+		session *discordgo.Session
+		want    string
+	}{
+		{name: "no session", want: "no Discord session"},
+		{name: "not connected", session: &discordgo.Session{LastHeartbeatAck: time.Now()}, want: "gateway not connected"},
+		{name: "heartbeats missed", session: &discordgo.Session{DataReady: true, LastHeartbeatAck: time.Now().Add(-10 * time.Minute)}, want: "no heartbeat ACK for 10m0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useDependencies(t)
+
+			code, report := probe(t, health.NewChecker(tt.session, time.Now()).Readiness)
+			if code != http.StatusServiceUnavailable {
+				t.Errorf("readiness status = %d, want %d", code, http.StatusServiceUnavailable)
+			}
+			if got := report.Checks["discord"]; got.OK || got.Detail != tt.want {
+				t.Errorf("discord check = %+v, want failed with %q", got, tt.want)
+			}
+			if !report.Checks["database"].OK {
+				t.Errorf("database check = %+v, want ok", report.Checks["database"])
+			}
+		})
+	}
+}
+
+func TestLivenessStalled(t *testing.T) {
+	useDependencies(t)
+	configuration.Get().Health.StallTimeout = time.Minute
+
+	code, report := probe(t, health.NewChecker(connectedSession(), time.Now().Add(-time.Hour)).Liveness)
+	if code != http.StatusServiceUnavailable || report.Status != "unavailable" {
+		t.Errorf("liveness = %d %q, want %d unavailable", code, report.Status, http.StatusServiceUnavailable)
+	}
+	if got := report.Checks["account_checks"]; got.OK || !strings.Contains(got.Detail, "no check pass finished since startup") {
+		t.Errorf("account_checks = %+v, want a stalled first pass", got)
+	}
+}
@@ -0,0 +1,227 @@
+package i18n_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bradselph/CODStatusBot/command"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord's limits for localized command names and descriptions.
+var (
+	commandNamePattern   = regexp.MustCompile(`^[-_\p{L}\p{N}]{1,32}$`)
+	maxDescriptionLength = 100
+)
+
+// verbPattern matches a fmt verb, with an optional explicit argument index.
+var verbPattern = regexp.MustCompile(`%(?:\[(\d+)\])?[-+# 0]*\d*(?:\.\d+)?([a-zA-Z%])`)
+
+// templateCallPattern matches the message IDs templates look up with {{t "id"}}.
+var templateCallPattern = regexp.MustCompile(`\bt\s+"([^"]+)"`)
+
+// TestCatalog checks that every language translates every English message with the same fmt
+// arguments and has no messages English does not, that every slash command, option and choice
+// from command.Commands has a valid translated name and description, and that every message ID
+// the code and built-in templates look up literally is in the English catalog.
+func TestCatalog(t *testing.T) {
+	english := make(map[string]bool)
+	for _, id := range i18n.IDs(i18n.English) {
+		english[id] = true
+	}
+
+	for _, lang := range i18n.Languages[1:] {
+		t.Run(string(lang)+"/messages", func(t *testing.T) {
+			checkMessages(t, lang, english)
+		})
+		t.Run(string(lang)+"/commands", func(t *testing.T) {
+			checkCommands(t, lang)
+		})
+	}
+
+	t.Run("lookups", func(t *testing.T) {
+		for _, id := range literalIDs(t) {
+			if !english[id] {
+				t.Errorf("%s is looked up but missing from the English catalog", id)
+			}
+		}
+	})
+}
+
+func checkMessages(t *testing.T, lang i18n.Lang, english map[string]bool) {
+	for id := range english {
+		message, ok := i18n.Lookup(lang, id)
+		if !ok {
+			t.Errorf("%s is not translated", id)
+			continue
+		}
+		want, _ := i18n.Lookup(i18n.English, id)
+		if got, wantVerbs := verbs(message), verbs(want); got != wantVerbs {
+			t.Errorf("%s takes arguments %s, English takes %s", id, got, wantVerbs)
+		}
+	}
+	for _, id := range i18n.IDs(lang) {
+		if !english[id] && !strings.HasPrefix(id, "command.") {
+			t.Errorf("%s is not in the English catalog", id)
+		}
+	}
+}
+
+// verbs describes the arguments a format string takes, e.g. "[s d]", resolving explicit argument
+// indexes so that translations may reorder them.
+func verbs(format string) string {
+	var args []string
+	next := 0
+	for _, match := range verbPattern.FindAllStringSubmatch(format, -1) {
+		if match[2] == "%" {
+			continue
+		}
+		if match[1] != "" {
+			n, _ := strconv.Atoi(match[1])
+			next = n - 1
+		}
+		for len(args) <= next {
+			args = append(args, "")
+		}
+		args[next] = match[2]
+		next++
+	}
+	return "[" + strings.Join(args, " ") + "]"
+}
+
+func checkCommands(t *testing.T, lang i18n.Lang) {
+	for _, cmd := range command.Commands() {
+		checkCommand(t, lang, cmd.Name)
+		checkOptions(t, lang, cmd.Options, cmd.Name)
+	}
+}
+
+func checkOptions(t *testing.T, lang i18n.Lang, options []*discordgo.ApplicationCommandOption, path ...string) {
+	for _, option := range options {
+		optionPath := append(append([]string(nil), path...), option.Name)
+		checkCommand(t, lang, optionPath...)
+		key := i18n.CommandKey(optionPath...)
+		for _, choice := range option.Choices {
+			value, ok := choice.Value.(string)
+			if !ok {
+				value = choice.Name
+			}
+			name, ok := i18n.Lookup(lang, key+".choice."+value)
+			if !ok {
+				t.Errorf("%s.choice.%s is not translated", key, value)
+			} else if utf8.RuneCountInString(name) > maxDescriptionLength {
+				t.Errorf("%s.choice.%s is longer than %d characters", key, value, maxDescriptionLength)
+			}
+		}
+		checkOptions(t, lang, option.Options, optionPath...)
+	}
+}
+
+func checkCommand(t *testing.T, lang i18n.Lang, path ...string) {
+	key := i18n.CommandKey(path...)
+	name, ok := i18n.Lookup(lang, key+".name")
+	switch {
+	case !ok:
+		t.Errorf("%s.name is not translated", key)
+	case !commandNamePattern.MatchString(name) || strings.ToLower(name) != name:
+		t.Errorf("%s.name %q is not a valid command name", key, name)
+	}
+	description, ok := i18n.Lookup(lang, key+".description")
+	switch {
+	case !ok:
+		t.Errorf("%s.description is not translated", key)
+	case description == "" || utf8.RuneCountInString(description) > maxDescriptionLength:
+		t.Errorf("%s.description must be 1 to %d characters", key, maxDescriptionLength)
+	}
+}
+
+// literalIDs returns the message IDs passed as string literals to i18n.T and i18n.Plural in the
+// module's Go source and to t in the built-in templates.
+func literalIDs(t *testing.T) []string {
+	root := ".."
+
+	var ids []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".go":
+			found, err := goIDs(path)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, found...)
+		case ".tmpl":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, match := range templateCallPattern.FindAllStringSubmatch(string(data), -1) {
+				ids = append(ids, match[1])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scan source: %v", err)
+	}
+	if len(ids) == 0 {
+		t.Fatalf("found no message lookups under %s", root)
+	}
+	return ids
+}
+
+func goIDs(path string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if pkg, ok := selector.X.(*ast.Ident); !ok || pkg.Name != "i18n" {
+			return true
+		}
+		literal, ok := call.Args[1].(*ast.BasicLit)
+		if !ok || literal.Kind != token.STRING {
+			return true
+		}
+		id, err := strconv.Unquote(literal.Value)
+		if err != nil {
+			return true
+		}
+		switch selector.Sel.Name {
+		case "T":
+			ids = append(ids, id)
+		case "Plural":
+			ids = append(ids, id+".one", id+".other")
+		}
+		return true
+	})
+	return ids, nil
+}
@@ -0,0 +1,181 @@
+// Package i18n holds the translations of everything the bot says to users.
+//
+// Messages are looked up by ID in the catalogs under locales/, one JSON file per language. The
+// English catalog is the reference: every other language should translate every English ID, and
+// a message missing from a translation falls back to English. Messages are fmt format strings;
+// translations that need their arguments in a different order use explicit indexes such as %[2]s.
+//
+// Slash command names and descriptions are translated with IDs derived from the command, see
+// CommandKey.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Lang is a language the bot has a catalog for.
+type Lang string
+
+const (
+	English Lang = "en"
+	Spanish Lang = "es"
+	German  Lang = "de"
+)
+
+// Languages lists every language with a catalog, English first.
+var Languages = []Lang{English, Spanish, German}
+
+// discordLocales are the Discord client locales each language is used for.
+var discordLocales = map[Lang][]discordgo.Locale{
+	English: {discordgo.EnglishUS, discordgo.EnglishGB},
+	Spanish: {discordgo.SpanishES, discordgo.SpanishLATAM},
+	German:  {discordgo.German},
+}
+
+// languageNames are the names languages are listed under, in the language itself.
+var languageNames = map[Lang]string{
+	English: "English",
+	Spanish: "Español",
+	German:  "Deutsch",
+}
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+var catalogs = make(map[Lang]map[string]string)
+
+func init() {
+	for _, lang := range Languages {
+		data, err := localesFS.ReadFile("locales/" + string(lang) + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: %v", err))
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: locales/%s.json: %v", lang, err))
+		}
+		catalogs[lang] = messages
+	}
+}
+
+// Parse returns the language for a code such as "es", reporting whether there is a catalog for it.
+func Parse(code string) (Lang, bool) {
+	lang := Lang(strings.ToLower(strings.TrimSpace(code)))
+	_, ok := catalogs[lang]
+	return lang, ok
+}
+
+// FromLocale returns the language for a Discord client locale, or English if there is no catalog
+// for it.
+func FromLocale(locale discordgo.Locale) Lang {
+	for lang, locales := range discordLocales {
+		for _, l := range locales {
+			if l == locale {
+				return lang
+			}
+		}
+	}
+	return English
+}
+
+// DiscordLocales returns the Discord client locales lang is used for.
+func DiscordLocales(lang Lang) []discordgo.Locale {
+	return discordLocales[lang]
+}
+
+// Name returns the name of lang in that language.
+func Name(lang Lang) string {
+	if name, ok := languageNames[lang]; ok {
+		return name
+	}
+	return string(lang)
+}
+
+// T returns the message with the given ID in lang, formatted with args. Messages missing from
+// lang are taken from the English catalog, and unknown IDs are returned as they are so a missing
+// message is visible rather than blank.
+func T(lang Lang, id string, args ...any) string {
+	message, ok := Lookup(lang, id)
+	if !ok {
+		if message, ok = Lookup(English, id); !ok {
+			return id
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Plural returns the message id+".one" when n is 1 and id+".other" otherwise, formatted with n.
+// English, Spanish and German all pluralize this way.
+func Plural(lang Lang, id string, n int) string {
+	if n == 1 {
+		return T(lang, id+".one", n)
+	}
+	return T(lang, id+".other", n)
+}
+
+// FormatDate formats a date the way lang writes it out, e.g. "January 2, 2006".
+func FormatDate(lang Lang, t time.Time) string {
+	return T(lang, "date.long", t.Day(), T(lang, fmt.Sprintf("month.%d", int(t.Month()))), t.Year())
+}
+
+// FormatDateTime formats a date and time of day the way lang writes them.
+func FormatDateTime(lang Lang, t time.Time) string {
+	return T(lang, "date.time", FormatDate(lang, t), t.Format("15:04:05 MST"))
+}
+
+// Lookup returns the unformatted message with the given ID in lang's own catalog, without
+// falling back to English.
+func Lookup(lang Lang, id string) (string, bool) {
+	message, ok := catalogs[lang][id]
+	return message, ok
+}
+
+// IDs returns the message IDs in lang's catalog, sorted.
+func IDs(lang Lang) []string {
+	ids := make([]string, 0, len(catalogs[lang]))
+	for id := range catalogs[lang] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// CommandKey is the catalog ID prefix for a slash command or one of its options, e.g.
+// "command.webhooks.add.url" for the url option of /webhooks add. The name of the command or
+// option is translated by the ID CommandKey(...)+".name" and its description by
+// CommandKey(...)+".description". Choices are translated by CommandKey(...)+".choice."+value.
+// English uses the names and descriptions the command is declared with, so the English catalog
+// has no command IDs.
+func CommandKey(path ...string) string {
+	return "command." + strings.Join(path, ".")
+}
+
+// Localizations returns the translations of a message for every Discord locale other than
+// English, keyed the way Discord expects name and description localizations. It returns nil when
+// no language translates id.
+func Localizations(id string) map[discordgo.Locale]string {
+	localized := make(map[discordgo.Locale]string)
+	for _, lang := range Languages[1:] {
+		message, ok := Lookup(lang, id)
+		if !ok {
+			continue
+		}
+		for _, locale := range discordLocales[lang] {
+			localized[locale] = message
+		}
+	}
+	if len(localized) == 0 {
+		return nil
+	}
+	return localized
+}
@@ -55,6 +55,7 @@ func init() {
 	rotateLog()
 
 	go checkRotation()
+	Log.AddHook(NewRedactionHook())
 	Log.AddHook(NewSentryHook())
 
 }
@@ -126,7 +127,7 @@ func sentryLevel(level logrus.Level) sentry.Level {
 
 func LogAndCapture(message string, args ...interface{}) {
 	Log.Info(message)
-	sentry.CaptureMessage(message)
+	sentry.CaptureMessage(Redact(message))
 	sentry.Flush(2 * time.Second)
 }
 
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// secretPatterns match secrets that appear inside otherwise useful text. Each pattern keeps its
+// first group (the label) and masks the rest. They only match known secret shapes and the field
+// names the bot and its providers use for secrets, so IDs, hashes and URLs are left readable.
+var secretPatterns = []*regexp.Regexp{
+	// Activision session cookies, e.g. the Cookie header built by GenerateHeaders.
+	regexp.MustCompile(`(ACT_SSO_[A-Z_]+=)("[^"]*"|[^;\s"'&,\]}]+)`),
+	// Captcha responses, provider keys and credentials under the names they are sent with,
+	// including the ssoToken query parameter of the VIP check URL.
+	regexp.MustCompile(`(?i)(\b(?:g-cc|g-recaptcha-response|grecaptcharesponse|clientkey|client_key|api_?key|sso_?cookie|sso_?token|password|webhook_?secret)["']?\s*[=:]\s*["']?)([^"'&\s,;}\]]+)`),
+	// Authorization headers, with or without a scheme.
+	regexp.MustCompile(`(?i)(\bauthorization["']?\s*[=:]\s*["']?(?:(?:bearer|basic)\s+)?)([^"'&\s,;}\]]+)`),
+	// Capsolver API keys.
+	regexp.MustCompile(`()\bCAP-[A-Za-z0-9]{32,}\b`),
+	// reCAPTCHA responses, which always start with 03A.
+	regexp.MustCompile(`()\b03A[A-Za-z0-9_\-]{40,}`),
+	// Telegram bot tokens, bare or in a Bot API URL.
+	regexp.MustCompile(`(\bbot|\b)\d{6,12}:[A-Za-z0-9_\-]{30,}`),
+	// Bot API tokens issued by CreateAPIToken.
+	regexp.MustCompile(`()\bcsb_[A-Za-z0-9_\-]{20,}`),
+}
+
+// sensitiveFields are masked outright, whatever their value looks like.
+var sensitiveFields = map[string]bool{
+	"apikey":        true,
+	"authorization": true,
+	"capsolverkey":  true,
+	"clientkey":     true,
+	"cookie":        true,
+	"password":      true,
+	"secret":        true,
+	"ssocookie":     true,
+	"token":         true,
+}
+
+// Redact masks cookies, captcha tokens and API keys in s.
+func Redact(s string) string {
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+	return s
+}
+
+// redactedError keeps the original error reachable through errors.Is/As while hiding its text.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+func redactValue(key string, value interface{}) interface{} {
+	if sensitiveFields[strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))] {
+		return redacted
+	}
+
+	switch v := value.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return value
+	case string:
+		return Redact(v)
+	case []byte:
+		return Redact(string(v))
+	case error:
+		msg := v.Error()
+		if clean := Redact(msg); clean != msg {
+			return &redactedError{msg: clean, err: v}
+		}
+		return v
+	default:
+		formatted := fmt.Sprintf("%+v", v)
+		if clean := Redact(formatted); clean != formatted {
+			return clean
+		}
+		return value
+	}
+}
+
+// RedactionHook scrubs the message and fields of every entry. It must be registered before any
+// hook that ships entries elsewhere, since hooks run in registration order.
+type RedactionHook struct{}
+
+func NewRedactionHook() *RedactionHook {
+	return &RedactionHook{}
+}
+
+func (hook *RedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook *RedactionHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Redact(entry.Message)
+	for key, value := range entry.Data {
+		entry.Data[key] = redactValue(key, value)
+	}
+	return nil
+}
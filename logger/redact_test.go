@@ -0,0 +1,196 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	testCookie       = "OTY0MzU6MTcyOTk4NDQ0Njk0NToyZjNhZDFlNWQ2OWM0MDAy"
+	testCaptchaToken = "03AFcWeA5x1vNXl2Zb_9qT-Hk8mR4pLw7cJyD3sEoUaGiV0nB6tQrYfKzMhPjWxCdSe"
+	testCapsolverKey = "CAP-4F1E8A9B2C3D4E5F60718293A4B5C6D7"
+	testHexKey       = "5d41402abc4b2a76b9719d911017c592"
+	testTelegramBot  = "7123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw4"
+)
+
+func TestLogRedaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     func(l *logrus.Logger)
+		secrets []string
+		keep    []string
+	}{
+		{
+			name: "sso cookie header",
+			log: func(l *logrus.Logger) {
+				l.Infof("Cookie: ACT_SSO_COOKIE=%s; ACT_SSO_COOKIE_EXPIRY=1729984446945", testCookie)
+			},
+			secrets: []string{testCookie, "1729984446945"},
+			keep:    []string{"ACT_SSO_COOKIE="},
+		},
+		{
+			name: "captcha response in the appeal url",
+			log: func(l *logrus.Logger) {
+				l.Infof("GET https://support.activision.com/api/bans/v2/appeal?locale=en&g-cc=%s", testCaptchaToken)
+			},
+			secrets: []string{testCaptchaToken},
+			keep:    []string{"https://support.activision.com/api/bans/v2/appeal?locale=en&g-cc="},
+		},
+		{
+			name: "bare captcha response",
+			log: func(l *logrus.Logger) {
+				l.Infof("Solved captcha: %s", testCaptchaToken)
+			},
+			secrets: []string{testCaptchaToken},
+		},
+		{
+			name: "captcha response in a provider payload",
+			log: func(l *logrus.Logger) {
+				l.Debugf(`{"status":"ready","solution":{"gRecaptchaResponse":"%s"}}`, "opaque-solution-value")
+			},
+			secrets: []string{"opaque-solution-value"},
+			keep:    []string{`"status":"ready"`},
+		},
+		{
+			name: "capsolver key",
+			log: func(l *logrus.Logger) {
+				l.Warnf("Capsolver rejected key %s", testCapsolverKey)
+			},
+			secrets: []string{testCapsolverKey},
+		},
+		{
+			name: "hex client key in a request body",
+			log: func(l *logrus.Logger) {
+				l.Debugf(`{"clientKey":"%s","task":{"type":"ReCaptchaV2TaskProxyless"}}`, testHexKey)
+			},
+			secrets: []string{testHexKey},
+			keep:    []string{"ReCaptchaV2TaskProxyless"},
+		},
+		{
+			name: "telegram bot url",
+			log: func(l *logrus.Logger) {
+				l.WithError(errors.New(`Post "https://api.telegram.org/bot` + testTelegramBot + `/sendMessage": timeout`)).Error("Telegram delivery failed")
+			},
+			secrets: []string{testTelegramBot, "AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw4"},
+			keep:    []string{"https://api.telegram.org/", "/sendMessage", "Telegram delivery failed"},
+		},
+		{
+			name: "sso token in the vip check url",
+			log: func(l *logrus.Logger) {
+				err := &url.Error{
+					Op:  "Get",
+					URL: "https://support.activision.com/services/apexrest/web/vip/isvip?ssoToken=" + testCookie,
+					Err: errors.New("context deadline exceeded"),
+				}
+				l.WithError(fmt.Errorf("failed to send HTTP request to check VIP status: %w", err)).Error("Failed to check VIP status")
+			},
+			secrets: []string{testCookie},
+			keep:    []string{"https://support.activision.com/services/apexrest/web/vip/isvip?ssoToken=", "context deadline exceeded"},
+		},
+		{
+			name: "cookie inside an error",
+			log: func(l *logrus.Logger) {
+				l.WithError(errors.New("request failed: ACT_SSO_COOKIE=" + testCookie)).Error("Account check failed")
+			},
+			secrets: []string{testCookie},
+			keep:    []string{"request failed"},
+		},
+		{
+			name: "sensitive field names",
+			log: func(l *logrus.Logger) {
+				l.WithFields(logrus.Fields{
+					"ssoCookie": testCookie,
+					"apiKey":    testHexKey,
+					"userID":    "123456789012345678",
+				}).Info("Validating account")
+			},
+			secrets: []string{testCookie, testHexKey},
+			keep:    []string{"123456789012345678"},
+		},
+		{
+			name: "secrets inside other fields",
+			log: func(l *logrus.Logger) {
+				l.WithField("request", "g-cc="+testCaptchaToken).WithField("provider", testCapsolverKey).Info("Retrying")
+			},
+			secrets: []string{testCaptchaToken, testCapsolverKey},
+		},
+		{
+			name: "identifiers stay readable",
+			log: func(l *logrus.Logger) {
+				l.WithField("commit", "5d41402abc4b2a76b9719d911017c5925d41402a").
+					Infof("Loaded page token=next-page key=locale-en digest %s", "dGhpcyBpcyBub3QgYSBzZWNyZXQsIGp1c3QgYSBsb25nIGJhc2U2NCBydW4=")
+			},
+			keep: []string{
+				"5d41402abc4b2a76b9719d911017c5925d41402a",
+				"token=next-page",
+				"key=locale-en",
+				"dGhpcyBpcyBub3QgYSBzZWNyZXQsIGp1c3QgYSBsb25nIGJhc2U2NCBydW4=",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	out, level := Log.Out, Log.GetLevel()
+	Log.SetOutput(&buf)
+	Log.SetLevel(logrus.DebugLevel)
+	t.Cleanup(func() {
+		Log.SetOutput(out)
+		Log.SetLevel(level)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			tt.log(Log)
+			got := buf.String()
+			for _, secret := range tt.secrets {
+				if strings.Contains(got, secret) {
+					t.Errorf("%q leaked into %q", secret, got)
+				}
+			}
+			if len(tt.secrets) > 0 && !strings.Contains(got, redacted) {
+				t.Errorf("nothing was redacted in %q", got)
+			}
+			for _, keep := range tt.keep {
+				if !strings.Contains(got, keep) {
+					t.Errorf("%q was lost from %q", keep, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   "https://support.activision.com/services/apexrest/web/vip/isvip?ssoToken=" + testCookie,
+			want: "https://support.activision.com/services/apexrest/web/vip/isvip?ssoToken=" + redacted,
+		},
+		{
+			in:   `Get "https://example.com/isvip?sso_token=` + testCookie + `&locale=en": EOF`,
+			want: `Get "https://example.com/isvip?sso_token=` + redacted + `&locale=en": EOF`,
+		},
+		{
+			in:   "ACT_SSO_COOKIE=" + testCookie + "; path=/",
+			want: "ACT_SSO_COOKIE=" + redacted + "; path=/",
+		},
+		{
+			in:   "account 42 checked",
+			want: "account 42 checked",
+		},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -3,25 +3,38 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/bradselph/CODStatusBot/admin"
+	"github.com/bradselph/CODStatusBot/api"
 	"github.com/bradselph/CODStatusBot/bot"
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/health"
 	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/metrics"
 	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/notifytemplates"
 	"github.com/bradselph/CODStatusBot/services"
 	"github.com/bwmarrin/discordgo"
 	"github.com/getsentry/sentry-go"
 )
 
-var discord *discordgo.Session
+var (
+	discord       *discordgo.Session
+	periodicTasks sync.WaitGroup
+)
 
 func loadEnv(filename string) error {
 	file, err := os.Open(filename)
@@ -71,6 +84,23 @@ func main() {
 		}
 	}()
 
+	migrate := flag.String("migrate", "", "run database migrations and exit: up, down or status")
+	steps := flag.Int("steps", 1, "number of migrations to roll back with -migrate=down")
+	rotateKey := flag.Bool("rotate-encryption-key", false, "re-encrypt stored secrets with ENCRYPTION_KEY and exit")
+	flag.Parse()
+
+	if *rotateKey {
+		*migrate = "rotate-encryption-key"
+	}
+
+	if *migrate != "" {
+		if err := runMigrations(*migrate, *steps); err != nil {
+			logger.Log.WithError(err).Error("Database migration failed")
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		logger.Log.WithError(err).Error("Bot encountered an error and is shutting down")
 		logger.Log.Fatal("Exiting due to error")
@@ -93,8 +123,13 @@ func run() error {
 	services.InitializeServices()
 	cfg := configuration.Get()
 
+	if err := notifytemplates.Load(cfg.NotificationTemplatesDir); err != nil {
+		return fmt.Errorf("failed to load notification templates: %w", err)
+	}
+
 	// Check if at least one service is properly configured
-	if !cfg.CaptchaService.Capsolver.Enabled && !cfg.CaptchaService.EZCaptcha.Enabled && !cfg.CaptchaService.TwoCaptcha.Enabled {
+	if !cfg.CaptchaService.Capsolver.Enabled && !cfg.CaptchaService.EZCaptcha.Enabled && !cfg.CaptchaService.TwoCaptcha.Enabled &&
+		!cfg.CaptchaService.AntiCaptcha.Enabled && !cfg.CaptchaService.CapMonster.Enabled {
 		logger.Log.Warn("No captcha services are enabled - functionality will be limited")
 	} else {
 		var enabledServices []string
@@ -120,6 +155,14 @@ func run() error {
 			enabledServices = append(enabledServices, "2Captcha")
 			logger.Log.Info("2Captcha service enabled and configured correctly")
 		}
+		if cfg.CaptchaService.AntiCaptcha.Enabled && cfg.CaptchaService.AntiCaptcha.ClientKey != "" {
+			enabledServices = append(enabledServices, "Anti-Captcha")
+			logger.Log.Info("Anti-Captcha service enabled and configured correctly")
+		}
+		if cfg.CaptchaService.CapMonster.Enabled && cfg.CaptchaService.CapMonster.ClientKey != "" {
+			enabledServices = append(enabledServices, "CapMonster Cloud")
+			logger.Log.Info("CapMonster Cloud service enabled and configured correctly")
+		}
 
 		if len(enabledServices) == 0 {
 			logger.Log.Error("No properly configured captcha services found")
@@ -139,12 +182,29 @@ func run() error {
 		return fmt.Errorf("failed to start Discord bot: %w", err)
 	}
 	logger.Log.Info("Discord bot started successfully")
+	session := discordapi.Instrument(discord)
 
-	services.StartNotificationProcessor(discord)
+	services.StartNotificationProcessor(session)
 	logger.Log.Info("Notification processor started successfully")
 
 	periodicTasksCtx, cancelPeriodicTasks := context.WithCancel(context.Background())
-	go startPeriodicTasks(periodicTasksCtx, discord)
+	services.SetBaseContext(periodicTasksCtx)
+	startPeriodicTasks(periodicTasksCtx, session)
+
+	var statusServer *http.Server
+	if cfg.Metrics.Enabled || cfg.Health.Enabled {
+		statusServer = startStatusServer(cfg, health.NewChecker(discord, time.Now()))
+	}
+
+	var adminServer *http.Server
+	if cfg.AdminPanel.Port != "" {
+		adminServer = startAdminServer(session)
+	}
+
+	var apiServer *http.Server
+	if cfg.RESTAPI.Addr != "" {
+		apiServer = startAPIServer(session)
+	}
 
 	logger.Log.Info("COD Status Bot startup complete")
 
@@ -156,6 +216,28 @@ func run() error {
 
 	cancelPeriodicTasks()
 
+	checksDone := make(chan struct{})
+	go func() {
+		periodicTasks.Wait()
+		close(checksDone)
+	}()
+	select {
+	case <-checksDone:
+	case <-time.After(2 * time.Minute):
+		logger.Log.Warn("Timed out waiting for in-flight account checks to finish")
+	}
+
+	for name, server := range map[string]*http.Server{"status": statusServer, "admin panel": adminServer, "REST API": apiServer} {
+		if server == nil {
+			continue
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Log.WithError(err).Errorf("Error shutting down %s server", name)
+		}
+		cancel()
+	}
+
 	if err := discord.Close(); err != nil {
 		logger.Log.WithError(err).Error("Error closing Discord session")
 	}
@@ -168,17 +250,136 @@ func run() error {
 	return nil
 }
 
-func startPeriodicTasks(ctx context.Context, s *discordgo.Session) {
+func runMigrations(command string, steps int) error {
+	if err := loadEnv("config.env"); err != nil {
+		return fmt.Errorf("failed to load environment variables: %w", err)
+	}
+
+	if err := configuration.Load(); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := database.Open(); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if err := database.CloseConnection(); err != nil {
+			logger.Log.WithError(err).Error("Error closing database connection")
+		}
+	}()
+
+	switch command {
+	case "up":
+		applied, err := database.MigrateUp(database.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
+	case "down":
+		if steps < 1 {
+			return fmt.Errorf("-steps must be at least 1, got %d", steps)
+		}
+		reverted, err := database.MigrateDown(database.DB, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", reverted)
+	case "status":
+		states, err := database.MigrationStatus(database.DB)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.Applied {
+				applied = "applied " + state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-32s %s\n", state.Version, state.Name, applied)
+		}
+	case "rotate-encryption-key":
+		if _, err := database.MigrateUp(database.DB); err != nil {
+			return err
+		}
+		rewritten, err := database.RotateEncryptionKey(database.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Re-encrypted %d value(s) with the current key\n", rewritten)
+	default:
+		return fmt.Errorf("unknown -migrate command %q (expected up, down or status)", command)
+	}
+	return nil
+}
+
+// startStatusServer serves the enabled metrics and health endpoints on STATUS_ADDR until the
+// returned server is shut down.
+func startStatusServer(cfg *configuration.Config, checker *health.Checker) *http.Server {
+	mux := http.NewServeMux()
+	if cfg.Metrics.Enabled {
+		mux.Handle("/metrics", metrics.Handler())
+	}
+	if cfg.Health.Enabled {
+		mux.HandleFunc("/healthz", checker.Liveness)
+		mux.HandleFunc("/readyz", checker.Readiness)
+	}
+
+	server := &http.Server{
+		Addr:              cfg.StatusServer.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.WithError(err).Error("Status server stopped")
+		}
+	}()
+	logger.Log.Infof("Serving metrics and health endpoints on %s", cfg.StatusServer.Addr)
+	return server
+}
+
+// startAdminServer serves the admin panel on ADMIN_PORT until the returned server is shut down. A
+// misconfigured panel is logged and left off rather than stopping the bot.
+func startAdminServer(s discordapi.Session) *http.Server {
+	panel, err := admin.NewServer(s)
+	if err != nil {
+		logger.Log.WithError(err).Error("Admin panel disabled")
+		return nil
+	}
+
+	server := panel.HTTPServer()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.WithError(err).Error("Admin panel server stopped")
+		}
+	}()
+	logger.Log.Infof("Serving admin panel on %s", server.Addr)
+	return server
+}
+
+// startAPIServer serves the REST API on REST_API_ADDR until the returned server is shut down.
+func startAPIServer(s discordapi.Session) *http.Server {
+	server := api.NewServer(s).HTTPServer()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.WithError(err).Error("REST API server stopped")
+		}
+	}()
+	logger.Log.Infof("Serving REST API on %s", server.Addr)
+	return server
+}
+
+func startPeriodicTasks(ctx context.Context, s discordapi.Session) {
 	cfg := configuration.Get()
 
+	periodicTasks.Add(1)
 	go func() {
+		defer periodicTasks.Done()
 		for {
+			services.CheckAccounts(ctx, s)
 			select {
 			case <-ctx.Done():
 				return
-			default:
-				services.CheckAccounts(s)
-				time.Sleep(time.Duration(cfg.Intervals.Sleep) * time.Minute)
+			case <-time.After(time.Duration(cfg.Intervals.Sleep) * time.Minute):
 			}
 		}
 	}()
@@ -206,7 +407,7 @@ func startPeriodicTasks(ctx context.Context, s *discordgo.Session) {
 
 					if time.Since(user.LastDailyUpdateNotification) >=
 						time.Duration(cfg.Intervals.Notification)*time.Hour {
-						services.SendConsolidatedDailyUpdate(s, user.UserID, user, accounts)
+						services.SendConsolidatedDailyUpdate(ctx, s, user.UserID, user, accounts)
 					}
 				}
 
@@ -215,7 +416,19 @@ func startPeriodicTasks(ctx context.Context, s *discordgo.Session) {
 		}
 	}()
 
-	go services.ScheduleBalanceChecks(s)
+	periodicTasks.Add(1)
+	go func() {
+		defer periodicTasks.Done()
+		services.RunScheduler(ctx, s)
+	}()
+
+	periodicTasks.Add(1)
+	go func() {
+		defer periodicTasks.Done()
+		services.RunWebhookDispatcher(ctx)
+	}()
+
+	go services.ScheduleBalanceChecks(ctx, s)
 
 	go func() {
 		for {
@@ -0,0 +1,186 @@
+// Package metrics holds the Prometheus collectors the bot exports on /metrics. The metric and
+// label names are part of the bot's interface for dashboards and alerts, so rename them only
+// alongside a note in the release.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "codstatusbot"
+
+// Registry holds every collector below plus the Go runtime and process collectors. It is separate
+// from the client library's default registry so nothing a dependency registers leaks into /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// AccountChecks counts finished account checks by resulting status, or "error" when the check
+	// failed before Activision answered.
+	AccountChecks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "account_checks_total",
+		Help:      "Account checks by result status.",
+	}, []string{"status"})
+
+	// CheckAccountDuration is the wall time of CheckAccount, captcha solve included.
+	CheckAccountDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "check_account_duration_seconds",
+		Help:      "Latency of CheckAccount, including solving the captcha.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 180},
+	})
+
+	// CaptchaSolveDuration is the latency of each solve attempt, successful or not, per provider.
+	CaptchaSolveDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "captcha_solve_duration_seconds",
+		Help:      "Latency of captcha solve attempts by provider.",
+		Buckets:   []float64{1, 2.5, 5, 10, 15, 20, 30, 45, 60, 90, 120},
+	}, []string{"provider"})
+
+	// CaptchaSolveFailures counts solve attempts that returned no usable token, per provider.
+	CaptchaSolveFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "captcha_solve_failures_total",
+		Help:      "Failed captcha solve attempts by provider.",
+	}, []string{"provider"})
+
+	// CaptchaTokenPoolHits counts checks served a solved token from the pool. A prefetched token's
+	// first use is a hit too, so hits are not solves saved; see CaptchaTokenPoolSaved.
+	CaptchaTokenPoolHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "captcha_token_pool_hits_total",
+		Help:      "Captcha token pool lookups that found a usable token.",
+	})
+
+	// CaptchaTokenPoolMisses counts pool lookups that found nothing, each followed by a fresh solve.
+	CaptchaTokenPoolMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "captcha_token_pool_misses_total",
+		Help:      "Captcha token pool lookups that found no usable token.",
+	})
+
+	// CaptchaTokenPoolSaved counts checks the appeal endpoint accepted with a token that had
+	// already been used, each one a solve that was not paid for.
+	CaptchaTokenPoolSaved = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "captcha_token_pool_saved_total",
+		Help:      "Accepted checks that reused an earlier check's captcha token.",
+	})
+
+	// CaptchaTokenPoolExpired counts pooled tokens dropped after their TTL.
+	CaptchaTokenPoolExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "captcha_token_pool_expired_total",
+		Help:      "Pooled captcha tokens dropped unused or partly used after their TTL.",
+	})
+
+	// Notifications counts notifications by type and whether they were sent or suppressed by the
+	// notification rate limiter.
+	Notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Notifications by type and outcome (sent or suppressed).",
+	}, []string{"type", "outcome"})
+
+	// NotificationQueueDepth is the number of direct messages waiting in the notification queue.
+	NotificationQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "notification_queue_depth",
+		Help:      "Direct messages waiting in the notification queue.",
+	})
+
+	// DiscordAPIErrors counts failed Discord API calls by session method.
+	DiscordAPIErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "discord_api_errors_total",
+		Help:      "Failed Discord API calls by method.",
+	}, []string{"method"})
+
+	// WebhookDeliveries counts webhook delivery attempts by event and outcome.
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by event and outcome (delivered, retry or dead).",
+	}, []string{"event", "outcome"})
+
+	// NotifierDeliveries counts notifications handed to each backend by outcome.
+	NotifierDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifier_deliveries_total",
+		Help:      "Notification deliveries by backend and outcome (sent or failed).",
+	}, []string{"backend", "outcome"})
+
+	// DBQueryDuration is the latency of database statements by GORM operation.
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of database statements by operation.",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"operation"})
+)
+
+// Notification outcomes.
+const (
+	OutcomeSent       = "sent"
+	OutcomeSuppressed = "suppressed"
+	OutcomeFailed     = "failed"
+)
+
+// Webhook delivery outcomes.
+const (
+	OutcomeDelivered = "delivered"
+	OutcomeRetry     = "retry"
+	OutcomeDead      = "dead"
+)
+
+// StatusError is the account_checks_total status for checks that ended in an error.
+const StatusError = "error"
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		AccountChecks,
+		CheckAccountDuration,
+		CaptchaSolveDuration,
+		CaptchaSolveFailures,
+		CaptchaTokenPoolHits,
+		CaptchaTokenPoolMisses,
+		CaptchaTokenPoolSaved,
+		CaptchaTokenPoolExpired,
+		Notifications,
+		NotificationQueueDepth,
+		DiscordAPIErrors,
+		WebhookDeliveries,
+		NotifierDeliveries,
+		DBQueryDuration,
+	)
+}
+
+// Handler serves Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveAccountCheck records one CheckAccount call that finished with status, or with err.
+func ObserveAccountCheck(status string, err error, elapsed time.Duration) {
+	if err != nil {
+		status = StatusError
+	}
+	AccountChecks.WithLabelValues(status).Inc()
+	CheckAccountDuration.Observe(elapsed.Seconds())
+}
+
+// ObserveCaptchaSolve records one solve attempt against provider.
+func ObserveCaptchaSolve(provider string, err error, elapsed time.Duration) {
+	CaptchaSolveDuration.WithLabelValues(provider).Observe(elapsed.Seconds())
+	if err != nil {
+		CaptchaSolveFailures.WithLabelValues(provider).Inc()
+	}
+}
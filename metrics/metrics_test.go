@@ -0,0 +1,119 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/metrics"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+	"github.com/glebarez/sqlite"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// stableNames are the metric families dashboards and alerts rely on. A rename has to change this
+// list too, which is the point: it should never happen by accident.
+var stableNames = map[string]dto.MetricType{
+	"codstatusbot_account_checks_total":             dto.MetricType_COUNTER,
+	"codstatusbot_check_account_duration_seconds":   dto.MetricType_HISTOGRAM,
+	"codstatusbot_captcha_solve_duration_seconds":   dto.MetricType_HISTOGRAM,
+	"codstatusbot_captcha_solve_failures_total":     dto.MetricType_COUNTER,
+	"codstatusbot_captcha_token_pool_hits_total":    dto.MetricType_COUNTER,
+	"codstatusbot_captcha_token_pool_misses_total":  dto.MetricType_COUNTER,
+	"codstatusbot_captcha_token_pool_saved_total":   dto.MetricType_COUNTER,
+	"codstatusbot_captcha_token_pool_expired_total": dto.MetricType_COUNTER,
+	"codstatusbot_notifications_total":              dto.MetricType_COUNTER,
+	"codstatusbot_notification_queue_depth":         dto.MetricType_GAUGE,
+	"codstatusbot_discord_api_errors_total":         dto.MetricType_COUNTER,
+	"codstatusbot_db_query_duration_seconds":        dto.MetricType_HISTOGRAM,
+}
+
+// scrape fetches url and parses the Prometheus text exposition it returns.
+func scrape(t testing.TB, url string) map[string]*dto.MetricFamily {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", url, resp.StatusCode)
+	}
+
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		t.Fatalf("parse %s: %v", url, err)
+	}
+	return families
+}
+
+// TestMetricsEndpoint serves metrics.Handler, drives each instrumented path once and scrapes the
+// endpoint, failing if any of stableNames is missing or has the wrong type. It swaps database.DB
+// for an in-memory SQLite database while it runs, so it must not run in parallel with tests that
+// use the database.
+func TestMetricsEndpoint(t *testing.T) {
+
+	previousDB := database.DB
+	t.Cleanup(func() { database.DB = previousDB })
+	if err := database.Connect(sqlite.Open("file::memory:")); err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	var count int64
+	if err := database.DB.Model(&models.Account{}).Count(&count).Error; err == nil {
+		t.Fatalf("counting accounts in an empty database succeeded, want a missing table error")
+	}
+
+	session := fakediscord.New()
+	session.FailWith("ChannelMessageSend", errors.New("discord unavailable"))
+	if _, err := discordapi.Instrument(session).ChannelMessageSend("channel", "hello"); err == nil {
+		t.Fatalf("ChannelMessageSend succeeded, want the injected error")
+	}
+
+	metrics.ObserveAccountCheck(string(models.StatusGood), nil, time.Second)
+	metrics.ObserveCaptchaSolve("capsolver", errors.New("solve failed"), 5*time.Second)
+	metrics.Notifications.WithLabelValues("status_change", metrics.OutcomeSent).Inc()
+	metrics.NotificationQueueDepth.Set(0)
+
+	server := httptest.NewServer(metrics.Handler())
+	t.Cleanup(server.Close)
+	families := scrape(t, server.URL+"/metrics")
+
+	for name, want := range stableNames {
+		family, ok := families[name]
+		if !ok {
+			t.Errorf("metric %s is not exported", name)
+			continue
+		}
+		if family.GetType() != want {
+			t.Errorf("metric %s has type %s, want %s", name, family.GetType(), want)
+		}
+	}
+
+	if got := counterValue(families["codstatusbot_discord_api_errors_total"], "method", "ChannelMessageSend"); got < 1 {
+		t.Errorf("discord_api_errors_total{method=\"ChannelMessageSend\"} = %v, want at least 1", got)
+	}
+	if got := counterValue(families["codstatusbot_account_checks_total"], "status", string(models.StatusGood)); got < 1 {
+		t.Errorf("account_checks_total{status=%q} = %v, want at least 1", models.StatusGood, got)
+	}
+}
+
+// counterValue returns the value of the counter in family whose label has the given value.
+func counterValue(family *dto.MetricFamily, label, value string) float64 {
+	for _, m := range family.GetMetric() {
+		for _, l := range m.GetLabel() {
+			if l.GetName() == label && l.GetValue() == value {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
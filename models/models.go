@@ -15,13 +15,10 @@ type Account struct {
 	LastCheck              int64     `gorm:"default:0"`       // The timestamp of the last check performed on the account.
 	LastNotification       int64     // The timestamp of the last daily notification sent out on the account.
 	LastCookieNotification int64     // The timestamp of the last notification sent out on the account for an expired ssocookie.
-	SSOCookie              string    // The SSO cookie associated with the account.
+	SSOCookie              string    `gorm:"serializer:encrypted"` // The SSO cookie associated with the account, encrypted at rest.
 	Created                int64     // The timestamp of when the account was created on Activision.
 	IsExpiredCookie        bool      `gorm:"default:false"`   // A flag indicating if the SSO cookie has expired.
-	NotificationType       string    `gorm:"default:channel"` // User preference for location of notifications either channel or dm
-	IsPermabanned          bool      `gorm:"default:false"`   // A flag indicating if the account is permanently banned
-	IsShadowbanned         bool      `gorm:"default:false"`   // A flag indicating if the account is shadowbanned
-	IsTempbanned           bool      `gorm:"default:false"`   // A flag indicating if the account is temporarily banned
+	NotificationType       string    `gorm:"default:channel"` // Copy of the user's primary notification destination
 	IsVIP                  bool      `gorm:"default:false"`   // A flag indicating if the account is a VIP
 	LastCookieCheck        int64     `gorm:"default:0"`       // The timestamp of the last cookie check for permanently banned accounts.
 	LastStatusChange       int64     `gorm:"default:0"`       // The timestamp of the last status change
@@ -39,21 +36,25 @@ type Account struct {
 type UserSettings struct {
 	gorm.Model
 	UserID                       string               `gorm:"type:varchar(255);uniqueIndex"` // The ID of the user.
-	CapSolverAPIKey              string               // User's own Capsolver API key, if provided
-	EZCaptchaAPIKey              string               // User's own EZCaptcha API key, if provided
-	TwoCaptchaAPIKey             string               // User's own 2captcha API key, if provided
-	PreferredCaptchaProvider     string               `gorm:"default:'capsolver'"` // 'capsolver', 'ezcaptcha' or '2captcha'
+	CapSolverAPIKey              string               `gorm:"serializer:encrypted"`          // User's own Capsolver API key, if provided, encrypted at rest
+	EZCaptchaAPIKey              string               `gorm:"serializer:encrypted"`          // User's own EZCaptcha API key, if provided, encrypted at rest
+	TwoCaptchaAPIKey             string               `gorm:"serializer:encrypted"`          // User's own 2captcha API key, if provided, encrypted at rest
+	AntiCaptchaAPIKey            string               `gorm:"serializer:encrypted"`          // User's own Anti-Captcha API key, if provided, encrypted at rest
+	CapMonsterAPIKey             string               `gorm:"serializer:encrypted"`          // User's own CapMonster Cloud API key, if provided, encrypted at rest
+	PreferredCaptchaProvider     string               `gorm:"default:'capsolver'"`           // 'capsolver', 'ezcaptcha', '2captcha', 'anticaptcha' or 'capmonster'
+	CaptchaProviderOrder         []string             `gorm:"serializer:json;type:text"`     // Providers to fail over to after the preferred one, most preferred first
 	CaptchaBalance               float64              // Current balance for the selected provider
 	LastBalanceCheck             time.Time            // Last time the balance was checked
 	CheckInterval                int                  // the user's set check interval
 	NotificationInterval         float64              // the user's preferred notification interval
 	CooldownDuration             float64              // the user's cooldown duration for actions
 	StatusChangeCooldown         float64              // the user's cooldown duration for status changes
-	HasSeenAnnouncement          bool                 `gorm:"default:false"`   // Flag to track if the user has seen the global announcement.
-	NotificationType             string               `gorm:"default:channel"` // User preference for location of notifications either channel or dm
-	NotificationTimes            map[string]time.Time `gorm:"serializer:json"` // For all notification cooldowns
-	ActionCounts                 map[string]int       `gorm:"serializer:json"` // For counting actions within time windows
-	LastActionTimes              map[string]time.Time `gorm:"serializer:json"` // For tracking when actions were last performed
+	HasSeenAnnouncement          bool                 `gorm:"default:false"`             // Flag to track if the user has seen the global announcement.
+	NotificationType             string               `gorm:"default:channel"`           // Primary notification destination: channel, dm, email, telegram or ntfy
+	NotificationRoutes           map[string][]string  `gorm:"serializer:json;type:text"` // Destinations by notification category ("default", "status", "daily", "cookie"); empty to use NotificationType
+	NotificationTimes            map[string]time.Time `gorm:"serializer:json"`           // For all notification cooldowns
+	ActionCounts                 map[string]int       `gorm:"serializer:json"`           // For counting actions within time windows
+	LastActionTimes              map[string]time.Time `gorm:"serializer:json"`           // For tracking when actions were last performed
 	LastNotification             time.Time            // Timestamp of the last notification
 	LastDisabledNotification     time.Time            // Timestamp of the last disabled notification
 	LastStatusChangeNotification time.Time            // Timestamp of the last status change notification
@@ -61,24 +62,46 @@ type UserSettings struct {
 	LastCookieExpirationWarning  time.Time            // Timestamp of the last cookie expiration warning
 	LastBalanceNotification      time.Time            // Timestamp of the last balance notification
 	LastErrorNotification        time.Time            // Timestamp of the last error notification
-	CustomSettings               bool                 `gorm:"default:false"`   // Flag to indicate if user has custom settings
-	LastCommandTimes             map[string]time.Time `gorm:"serializer:json"` // Map of command names to their last execution time
-	RateLimitExpiration          map[string]time.Time `gorm:"serializer:json"` // Map of command names to their rate limit expiration time
+	MonthlyCaptchaBudget         float64              `gorm:"default:0"` // Estimated captcha spend in USD after which scheduled checks pause for the month, 0 for no limit
+	LastBudgetNotification       time.Time            // Timestamp of the last notification that scheduled checks were paused by the budget
+	IsDisabled                   bool                 `gorm:"default:false"` // Set by an administrator to block the user from the bot
+	DisabledReason               string               // Reason the administrator gave for disabling the user
+	WebhookSecret                string               `gorm:"serializer:encrypted"` // Key the user's webhook deliveries are signed with, encrypted at rest
+	Email                        string               `gorm:"serializer:encrypted"` // Verified address for email notifications, encrypted at rest
+	TelegramChatID               string               `gorm:"serializer:encrypted"` // Telegram chat notifications are sent to, encrypted at rest
+	NtfyTopic                    string               `gorm:"serializer:encrypted"` // ntfy topic notifications are published to, encrypted at rest
+	Language                     string               `gorm:"type:varchar(8)"`      // Language code set with /setlanguage, empty to follow the Discord client
+	CustomSettings               bool                 `gorm:"default:false"`        // Flag to indicate if user has custom settings
+	LastCommandTimes             map[string]time.Time `gorm:"serializer:json"`      // Map of command names to their last execution time
+	RateLimitExpiration          map[string]time.Time `gorm:"serializer:json"`      // Map of command names to their rate limit expiration time
 }
 
 type Ban struct {
 	gorm.Model
-	Account         Account   // The account that has a status history.
-	AccountID       uint      // The ID of the account.
-	Status          Status    // The status of the ban.
-	LogType         string    // Type of log entry ("status_change", "account_added", "cookie_update", "check_disabled", "error")
-	Message         string    // Detailed message about the log entry
-	PreviousStatus  Status    // Store the previous status for better tracking
-	TempBanDuration string    // Duration of the temporary ban (if applicable)
-	AffectedGames   string    // Comma-separated list of affected games
-	Timestamp       time.Time // When this log entry was created
-	Initiator       string    // "auto_check" or "manual_check" or "system"
-	ErrorDetails    string    // For storing error information when relevant
+	Account         Account            // The account that has a status history.
+	AccountID       uint               // The ID of the account.
+	Status          Status             // The status of the ban.
+	LogType         string             // Type of log entry ("status_change", "account_added", "cookie_update", "check_disabled", "error")
+	Message         string             // Detailed message about the log entry
+	PreviousStatus  Status             // Store the previous status for better tracking
+	TempBanDuration string             // Duration of the temporary ban (if applicable)
+	AffectedGames   string             // Comma-separated list of affected games
+	Timestamp       time.Time          // When this log entry was created
+	Initiator       string             // "auto_check" or "manual_check" or "system"
+	ErrorDetails    string             // For storing error information when relevant
+	TempBanEndsAt   time.Time          // When the temporary ban ends, if Activision reported it
+	CanAppeal       bool               // Whether Activision allows the ban to be appealed
+	AppealStatus    string             // Status of the most recent appeal, if one was filed
+	Enforcements    []TitleEnforcement `gorm:"serializer:json;type:text"` // Per-title enforcement returned by the appeal API
+}
+
+type TitleEnforcement struct {
+	Title        string    `json:"title"`                  // The game title the enforcement applies to.
+	Enforcement  string    `json:"enforcement"`            // PERMANENT, TEMPORARY or UNDER_REVIEW.
+	CanAppeal    bool      `json:"canAppeal"`              // Whether this enforcement can be appealed.
+	EndsAt       time.Time `json:"endsAt"`                 // When a temporary enforcement ends, zero if unknown.
+	AppealStatus string    `json:"appealStatus,omitempty"` // Status of the appeal for this title, if any.
+	CaseNumber   string    `json:"caseNumber,omitempty"`   // Activision's appeal case number, if any.
 }
 type SuppressedNotification struct {
 	gorm.Model
@@ -88,6 +111,84 @@ type SuppressedNotification struct {
 	Timestamp        time.Time `gorm:"index"`     // The timestamp of the suppressed notification.
 }
 
+type CaptchaSolve struct {
+	gorm.Model
+	UserID         string    `gorm:"index"`                  // The ID of the user the solve was made for.
+	AccountID      uint      `gorm:"index"`                  // The ID of the account being checked, 0 when the solve was not for a stored account.
+	Provider       string    `gorm:"type:varchar(32);index"` // The captcha provider that attempted the solve.
+	UsedDefaultKey bool      // Whether the bot's default key was used rather than the user's own.
+	Success        bool      // Whether the provider returned a usable token.
+	DurationMs     int64     // How long the attempt took, in milliseconds.
+	Cost           float64   // Estimated cost in USD, 0 for failed attempts since providers do not charge for them.
+	Error          string    `gorm:"type:text"` // The error from a failed attempt.
+	SolvedAt       time.Time `gorm:"index"`     // When the attempt finished.
+}
+
+type APIToken struct {
+	gorm.Model
+	UserID     string    `gorm:"index"` // The ID of the user the token acts as.
+	Name       string    // User assigned label for the token.
+	Prefix     string    `gorm:"type:varchar(16)"`             // The first characters of the token, shown to tell tokens apart.
+	TokenHash  string    `gorm:"type:varchar(64);uniqueIndex"` // SHA-256 of the token; the token itself is never stored.
+	LastUsedAt time.Time // When the token last authenticated a request.
+}
+
+type EmailVerification struct {
+	gorm.Model
+	UserID    string    `gorm:"type:varchar(255);uniqueIndex"` // The ID of the user verifying an address.
+	Email     string    `gorm:"serializer:encrypted"`          // The address the code was sent to, encrypted at rest.
+	CodeHash  string    `gorm:"type:varchar(64)"`              // SHA-256 of the one-time code; the code itself is never stored.
+	ExpiresAt time.Time // When the code stops being accepted.
+	Attempts  int       `gorm:"default:0"` // Wrong codes entered so far.
+}
+
+type Webhook struct {
+	gorm.Model
+	UserID    string `gorm:"index"`                // The ID of the user the webhook belongs to.
+	AccountID uint   `gorm:"index"`                // The ID of the account the webhook is limited to, 0 for all of the user's accounts.
+	URL       string `gorm:"serializer:encrypted"` // The HTTPS endpoint events are posted to, encrypted at rest.
+}
+
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID      uint           `gorm:"index"` // The ID of the webhook the event is delivered to.
+	UserID         string         `gorm:"index"` // The ID of the user the webhook belongs to.
+	AccountID      uint           // The ID of the account the event is about, 0 for events not tied to an account.
+	EventID        string         `gorm:"type:varchar(32);index"`                 // Identifies the event; retries and redeliveries reuse it so receivers can deduplicate.
+	Event          WebhookEvent   `gorm:"type:varchar(64)"`                       // The kind of event.
+	Payload        string         `gorm:"type:text"`                              // The JSON body posted to the webhook.
+	Status         DeliveryStatus `gorm:"type:varchar(16);index;default:pending"` // Where the delivery is in its lifecycle.
+	Attempts       int            `gorm:"default:0"`                              // The number of times delivery has been attempted.
+	NextAttemptAt  time.Time      `gorm:"index"`                                  // When a pending delivery is next attempted.
+	LastStatusCode int            // The HTTP status of the most recent attempt, 0 if no response was received.
+	LastError      string         `gorm:"type:text"` // The error from the most recent failed attempt.
+	DeliveredAt    time.Time      // When the webhook accepted the delivery.
+}
+
+type AccountTitleStatus struct {
+	gorm.Model
+	AccountID        uint      `gorm:"uniqueIndex:idx_account_title"`                   // The ID of the account.
+	Title            string    `gorm:"type:varchar(128);uniqueIndex:idx_account_title"` // The game title as reported by the appeal API.
+	Status           Status    `gorm:"type:varchar(32)"`                                // The account's status in this title.
+	EndsAt           time.Time // When a temporary ban in this title ends, zero if unknown.
+	CanAppeal        bool      // Whether the enforcement in this title can be appealed.
+	LastStatusChange time.Time // When Status last changed.
+}
+
+type ScheduledJob struct {
+	gorm.Model
+	Type        JobType   `gorm:"type:varchar(64);index"`                 // The kind of work the job performs.
+	UserID      string    `gorm:"index"`                                  // The ID of the user the job belongs to.
+	AccountID   uint      `gorm:"index"`                                  // The ID of the account the job acts on, if any.
+	DedupeKey   string    `gorm:"type:varchar(191);index"`                // Jobs sharing a non-empty key are only scheduled once.
+	RunAt       time.Time `gorm:"index"`                                  // When the job becomes due.
+	Status      JobStatus `gorm:"type:varchar(16);index;default:pending"` // Where the job is in its lifecycle.
+	Attempts    int       `gorm:"default:0"`                              // The number of times the job has been started.
+	LockedUntil time.Time // When a running job's lease expires and it may be picked up again.
+	LastError   string    `gorm:"type:text"` // The error from the most recent failed attempt.
+	Payload     string    `gorm:"type:text"` // JSON encoded job arguments.
+}
+
 type Status string
 
 const (
@@ -99,14 +200,61 @@ const (
 	StatusTempban       Status = "Temporary"      // The account status returned as temporarily banned.
 )
 
+type JobType string
+
+const (
+	JobTempBanReminder     JobType = "tempban_reminder"      // Periodic reminder while an account is temporarily banned.
+	JobTempBanRecheck      JobType = "tempban_recheck"       // Re-check an account once its temporary ban should have ended.
+	JobCookieExpiryWarning JobType = "cookie_expiry_warning" // Warn the user before an account's SSO cookie expires.
+)
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending" // Waiting for RunAt.
+	JobRunning JobStatus = "running" // Claimed by the scheduler until LockedUntil.
+	JobDone    JobStatus = "done"    // Finished successfully.
+	JobFailed  JobStatus = "failed"  // Gave up after the maximum number of attempts.
+)
+
+type WebhookEvent string
+
+const (
+	WebhookStatusChanged      WebhookEvent = "account.status_changed"       // The account's overall status changed.
+	WebhookTitleStatusChanged WebhookEvent = "account.title_status_changed" // The account's status in one or more titles changed while the overall status did not.
+	WebhookCookieExpiring     WebhookEvent = "account.cookie_expiring"      // The account's SSO cookie expires soon.
+	WebhookCookieExpired      WebhookEvent = "account.cookie_expired"       // Activision no longer accepts the account's SSO cookie.
+	WebhookChecksDisabled     WebhookEvent = "account.checks_disabled"      // Checks for the account were stopped by the bot or an administrator.
+	WebhookPing               WebhookEvent = "ping"                         // A test event sent with /webhooks test.
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"   // Waiting for NextAttemptAt.
+	DeliveryDelivered DeliveryStatus = "delivered" // Accepted by the webhook with a 2xx response.
+	DeliveryDead      DeliveryStatus = "dead"      // Gave up after the maximum number of attempts.
+)
+
 type CaptchaProvider string
 
 const (
-	Capsolver  CaptchaProvider = "capsolver"
-	EZCaptcha  CaptchaProvider = "ezcaptcha"
-	TwoCaptcha CaptchaProvider = "2captcha"
+	Capsolver   CaptchaProvider = "capsolver"
+	EZCaptcha   CaptchaProvider = "ezcaptcha"
+	TwoCaptcha  CaptchaProvider = "2captcha"
+	AntiCaptcha CaptchaProvider = "anticaptcha"
+	CapMonster  CaptchaProvider = "capmonster"
 )
 
+// HasCustomCaptchaKey reports whether the user has saved an API key for any captcha provider.
+func (u *UserSettings) HasCustomCaptchaKey() bool {
+	return u.CapSolverAPIKey != "" ||
+		u.EZCaptchaAPIKey != "" ||
+		u.TwoCaptchaAPIKey != "" ||
+		u.AntiCaptchaAPIKey != "" ||
+		u.CapMonsterAPIKey != ""
+}
+
 func (u *UserSettings) EnsureMapsInitialized() {
 	if u.NotificationTimes == nil {
 		u.NotificationTimes = make(map[string]time.Time)
@@ -0,0 +1,157 @@
+package notifytemplates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/models"
+)
+
+// TestGolden renders every built-in template with goldenFixtures in every language and compares
+// the result with testdata/<lang>/<kind>.golden. Setting UPDATE_GOLDEN=1 rewrites the golden
+// files instead.
+func TestGolden(t *testing.T) {
+	if err := Load(""); err != nil {
+		t.Fatalf("load built-in templates: %v", err)
+	}
+	update := os.Getenv("UPDATE_GOLDEN") == "1"
+
+	for _, lang := range i18n.Languages {
+		fixtures := goldenFixtures(lang)
+		for _, kind := range Kinds {
+			t.Run(string(lang)+"/"+string(kind), func(t *testing.T) {
+				data, ok := fixtures[kind]
+				if !ok {
+					t.Fatalf("no fixture for %s", kind)
+				}
+				msg, err := Render(kind, data)
+				if err != nil {
+					t.Fatalf("render: %v", err)
+				}
+				got := formatGolden(msg)
+
+				path := filepath.Join("testdata", string(lang), string(kind)+".golden")
+				if update {
+					if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+						t.Fatalf("create golden directory: %v", err)
+					}
+					if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+						t.Fatalf("write golden file: %v", err)
+					}
+					return
+				}
+				want, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("read golden file (run with UPDATE_GOLDEN=1 to create it): %v", err)
+				}
+				if got != string(want) {
+					t.Errorf("%s does not match the rendered template\n--- got\n%s\n--- want\n%s", path, got, want)
+				}
+			})
+		}
+	}
+}
+
+// formatGolden renders a message as the plain text stored in golden files.
+func formatGolden(msg Message) string {
+	var b strings.Builder
+	b.WriteString("Title: " + msg.Title + "\n")
+	if msg.Description != "" {
+		b.WriteString("\nDescription:\n" + msg.Description + "\n")
+	}
+	for _, field := range msg.Fields {
+		kind := "Field"
+		if field.Inline {
+			kind = "Inline field"
+		}
+		b.WriteString("\n" + kind + ": " + field.Name + "\n" + field.Value + "\n")
+	}
+	if msg.Footer != "" {
+		b.WriteString("\nFooter: " + msg.Footer + "\n")
+	}
+	return b.String()
+}
+
+// goldenFixtures returns the data the golden files are rendered from, in lang for every kind.
+func goldenFixtures(lang i18n.Lang) map[Kind]Data {
+	checked := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	banEnds := checked.Add(52 * time.Hour)
+	status := func(status models.Status) string { return i18n.T(lang, "status."+string(status)) }
+
+	tempBanned := Account{
+		Title:     "Main Account",
+		Status:    models.StatusTempban,
+		Icon:      ":stopwatch:",
+		LastCheck: checked,
+		Age:       i18n.T(lang, "duration.years_months", i18n.Plural(lang, "unit.year", 3), i18n.Plural(lang, "unit.month", 2)),
+		VIPKnown:  true,
+		VIP:       true,
+		Expiry:    Expiry{At: checked.Add(12*24*time.Hour + 6*time.Hour), Remaining: i18n.T(lang, "duration.short.days", 12, 6, 0)},
+		Ban:       Ban{EndsAt: banEnds, Remaining: i18n.T(lang, "duration.days_hours", i18n.Plural(lang, "unit.day", 2), i18n.Plural(lang, "unit.hour", 4))},
+	}
+	good := Account{
+		Title:     "Alt Account",
+		Status:    models.StatusGood,
+		Icon:      ":white_check_mark:",
+		LastCheck: checked,
+		Age:       i18n.T(lang, "duration.years_months", i18n.Plural(lang, "unit.year", 0), i18n.Plural(lang, "unit.month", 7)),
+		VIPKnown:  true,
+		Expiry:    Expiry{At: checked.Add(20 * time.Hour), Remaining: i18n.T(lang, "duration.short.hours", 20, 0)},
+	}
+	permabanned := Account{
+		Title:             "Old Account",
+		Status:            models.StatusPermaban,
+		Icon:              ":no_entry:",
+		LastCheck:         checked,
+		Expiry:            Expiry{At: checked.Add(3*24*time.Hour + 90*time.Minute), Remaining: i18n.T(lang, "duration.short.days", 3, 1, 30)},
+		ConsecutiveErrors: 2,
+	}
+	shadowbanned := Account{
+		Title:     "Smurf",
+		Status:    models.StatusShadowban,
+		Icon:      ":information_source:",
+		LastCheck: checked,
+		Expiry:    Expiry{At: checked.Add(45 * time.Minute), Remaining: i18n.T(lang, "duration.short.minutes", 45)},
+	}
+	games := []string{"Call of Duty: Modern Warfare III", "Call of Duty: Warzone"}
+
+	return map[Kind]Data{
+		StatusChange: {
+			Account:        tempBanned,
+			Status:         models.StatusTempban,
+			PreviousStatus: models.StatusGood,
+			Ban:            tempBanned.Ban,
+			AffectedGames:  games,
+			TitleChanges:   games[1] + ": " + status(models.StatusGood) + " -> " + status(models.StatusTempban),
+			TitleStatuses:  games[0] + ": " + status(models.StatusTempban) + "\n" + games[1] + ": " + status(models.StatusTempban),
+			Lang:           lang,
+		},
+		Permaban: {
+			Account:       permabanned,
+			Status:        models.StatusPermaban,
+			AffectedGames: games,
+			Lang:          lang,
+		},
+		Shadowban: {
+			Account:        shadowbanned,
+			Status:         models.StatusShadowban,
+			PreviousStatus: models.StatusGood,
+			AffectedGames:  games[1:],
+			Lang:           lang,
+		},
+		DailyUpdate: {
+			Accounts:      []Account{tempBanned, good, permabanned, shadowbanned},
+			TotalAccounts: 5,
+			IntervalHours: 24,
+			Lang:          lang,
+		},
+		CookieExpiring: {
+			Accounts: []Account{good, shadowbanned},
+			Lang:     lang,
+		},
+	}
+}
@@ -0,0 +1,407 @@
+// Package notifytemplates renders the text of notification embeds from text/template files.
+//
+// Each notification kind has a built-in template in builtin/<kind>.tmpl defining up to four
+// blocks: "title" (required), "description", "fields" and "footer". Inside "fields", every
+// {{field "Name"}} or {{inlineField "Name"}} starts a new embed field whose value is everything
+// rendered up to the next field; fields that render empty are dropped. Blocks shared between
+// kinds live in builtin/common.tmpl.
+//
+// The built-in templates take their text from the i18n catalog with {{t "id" args...}}, which
+// translates into the recipient's language; {{status .Status}} and {{datetime .Account.LastCheck}}
+// name a status and write a time the same way.
+//
+// Operators can replace any of these files by putting a file with the same name in the
+// directory passed to Load. Overrides are checked against sample data when they are loaded, and
+// if one still fails at send time the built-in template is used instead.
+package notifytemplates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+)
+
+// Kind names a notification template.
+type Kind string
+
+const (
+	StatusChange   Kind = "status_change"   // An account's overall status changed
+	Permaban       Kind = "permaban"        // Follow-up asking the user to remove a permanently banned account
+	Shadowban      Kind = "shadowban"       // An account was placed under review
+	DailyUpdate    Kind = "daily_update"    // The periodic report on all of a user's accounts
+	CookieExpiring Kind = "cookie_expiring" // SSO cookies that will expire soon
+)
+
+// Kinds lists every notification kind with a built-in template.
+var Kinds = []Kind{StatusChange, Permaban, Shadowban, DailyUpdate, CookieExpiring}
+
+// commonFile holds blocks available to every kind.
+const commonFile = "common.tmpl"
+
+// Discord's embed limits. Rendered text is cut to fit so a long template cannot make a
+// notification undeliverable.
+const (
+	maxTitle       = 256
+	maxDescription = 4096
+	maxFieldName   = 256
+	maxFieldValue  = 1024
+	maxFields      = 25
+	maxFooter      = 2048
+)
+
+// Field markers are emitted by the field functions and split out after execution. They use
+// ASCII separator characters that never appear in notification text.
+const (
+	fieldStart = "\x1e"
+	fieldSep   = "\x1f"
+)
+
+//go:embed builtin/*.tmpl
+var builtinFS embed.FS
+
+// Data is what every notification template is executed with. Sections that do not apply to a
+// kind are left at their zero values.
+type Data struct {
+	Account        Account       // The account the notification is about
+	Status         models.Status // Its new status: Good, Permaban, Shadowban, Temporary or Unknown
+	PreviousStatus models.Status // Its status before this check, for status_change
+	Ban            Ban           // The ban that was just recorded
+	AffectedGames  []string      // Games the ban applies to
+	TitleChanges   string        // One line per game whose status changed in this check
+	TitleStatuses  string        // One line per game with its current status
+	Accounts       []Account     // Every account in the report, for daily_update and cookie_expiring
+	TotalAccounts  int           // Monitored accounts including ones that are not being checked, for daily_update
+	IntervalHours  float64       // Hours between reports, for daily_update
+	Lang           i18n.Lang     // Language the notification is written in, English if empty
+}
+
+// Account describes one monitored account.
+type Account struct {
+	Title             string
+	Status            models.Status
+	Icon              string    // Emoji configured for Status
+	LastCheck         time.Time // When the account was last checked
+	Age               string    // Time since the Activision account was created, empty if unknown
+	VIPKnown          bool      // Whether VIP could be checked
+	VIP               bool
+	Expiry            Expiry // When the SSO cookie expires
+	CookieExpired     bool
+	ChecksDisabled    bool
+	ConsecutiveErrors int
+	Ban               Ban // The latest ban, for temporarily banned accounts
+}
+
+// Ban describes a ban.
+type Ban struct {
+	EndsAt    time.Time // When a temporary ban ends, zero if Activision did not say
+	Remaining string    // Time left on a temporary ban, e.g. "2 days, 4 hours"
+}
+
+// Expiry describes when an SSO cookie stops working.
+type Expiry struct {
+	At        time.Time
+	Remaining string // Time left, e.g. "3d 4h 10m", empty if unknown
+}
+
+// Message is a rendered notification.
+type Message struct {
+	Title       string
+	Description string
+	Fields      []Field
+	Footer      string
+}
+
+// Field is one embed field.
+type Field struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+var funcs = template.FuncMap{
+	"field":       func(name string) string { return fieldStart + name + fieldSep + "false" + fieldSep },
+	"inlineField": func(name string) string { return fieldStart + name + fieldSep + "true" + fieldSep },
+	"join":        strings.Join,
+	"add":         func(a, b int) int { return a + b },
+	"rfc1123":     func(t time.Time) string { return t.Format(time.RFC1123) },
+	"relative":    func(t time.Time) string { return fmt.Sprintf("<t:%d:R>", t.Unix()) },
+	"statuses": func() []models.Status {
+		return []models.Status{models.StatusGood, models.StatusTempban, models.StatusShadowban,
+			models.StatusPermaban, models.StatusInvalidCookie, models.StatusUnknown}
+	},
+	// t, status and datetime are replaced by functions for the notification's language when it
+	// is rendered.
+	"t":        func(id string, args ...any) string { return id },
+	"status":   func(status models.Status) string { return string(status) },
+	"datetime": func(t time.Time) string { return t.String() },
+	"withStatus": func(accounts []Account, status models.Status) []Account {
+		var matching []Account
+		for _, account := range accounts {
+			if account.Status == status {
+				matching = append(matching, account)
+			}
+		}
+		return matching
+	},
+}
+
+type set struct {
+	templates  map[Kind]*template.Template
+	overridden map[Kind]bool
+}
+
+var (
+	mu       sync.RWMutex
+	builtins *set
+	current  *set
+)
+
+func init() {
+	var err error
+	builtins, err = parse(nil, nil)
+	if err != nil {
+		panic(fmt.Sprintf("built-in notification templates: %v", err))
+	}
+	current = builtins
+}
+
+// Load replaces the built-in templates with any <kind>.tmpl or common.tmpl files in dir. An
+// empty dir restores the built-in templates. Overrides are used for every language. Every
+// template is rendered with sampleData in each language before it is accepted, so mistakes are
+// reported at startup rather than when a notification is sent.
+func Load(dir string) error {
+	if dir == "" {
+		mu.Lock()
+		current = builtins
+		mu.Unlock()
+		return nil
+	}
+
+	overrides := make(map[string]bool)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read template directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".tmpl" {
+			overrides[entry.Name()] = true
+		}
+	}
+
+	loaded, err := parse(os.DirFS(dir), overrides)
+	if err != nil {
+		return err
+	}
+	for _, lang := range i18n.Languages {
+		for _, kind := range Kinds {
+			if _, err := execute(loaded.templates[kind], sampleData(lang)); err != nil {
+				return fmt.Errorf("template %s: %w", kind, err)
+			}
+		}
+	}
+
+	mu.Lock()
+	current = loaded
+	mu.Unlock()
+
+	for name := range overrides {
+		logger.Log.Infof("Using notification template override %s", filepath.Join(dir, name))
+	}
+	return nil
+}
+
+// sampleData fills every field Data can carry, so rendering it runs each branch a template is
+// likely to take for any kind.
+func sampleData(lang i18n.Lang) Data {
+	now := time.Now()
+	account := Account{
+		Title:             "Account",
+		Status:            models.StatusTempban,
+		Icon:              ":stopwatch:",
+		LastCheck:         now,
+		Age:               "1y",
+		VIPKnown:          true,
+		VIP:               true,
+		Expiry:            Expiry{At: now.Add(24 * time.Hour), Remaining: "1d"},
+		CookieExpired:     true,
+		ChecksDisabled:    true,
+		ConsecutiveErrors: 1,
+		Ban:               Ban{EndsAt: now.Add(time.Hour), Remaining: "1h"},
+	}
+	var accounts []Account
+	for _, status := range []models.Status{models.StatusGood, models.StatusTempban, models.StatusShadowban,
+		models.StatusPermaban, models.StatusInvalidCookie, models.StatusUnknown} {
+		account.Status = status
+		accounts = append(accounts, account)
+	}
+	return Data{
+		Account:        accounts[1],
+		Status:         models.StatusTempban,
+		PreviousStatus: models.StatusGood,
+		Ban:            account.Ban,
+		AffectedGames:  []string{"Game"},
+		TitleChanges:   "Game",
+		TitleStatuses:  "Game",
+		Accounts:       accounts,
+		TotalAccounts:  len(accounts),
+		IntervalHours:  24,
+		Lang:           lang,
+	}
+}
+
+// parse builds the template for every kind, reading files named in overrides from fsys and
+// everything else from the built-in templates.
+func parse(fsys fs.FS, overrides map[string]bool) (*set, error) {
+	read := func(name string) (string, error) {
+		var data []byte
+		var err error
+		if overrides[name] {
+			data, err = fs.ReadFile(fsys, name)
+		} else {
+			data, err = builtinFS.ReadFile("builtin/" + name)
+		}
+		return string(data), err
+	}
+
+	common, err := read(commonFile)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := &set{templates: make(map[Kind]*template.Template), overridden: make(map[Kind]bool)}
+	for _, kind := range Kinds {
+		name := string(kind) + ".tmpl"
+		text, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(common)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", commonFile, err)
+		}
+		if _, err := t.Parse(text); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if t.Lookup("title") == nil {
+			return nil, fmt.Errorf("%s: no \"title\" block", name)
+		}
+
+		loaded.templates[kind] = t
+		loaded.overridden[kind] = overrides[name] || overrides[commonFile]
+	}
+	return loaded, nil
+}
+
+// Render renders the notification for kind with data.
+func Render(kind Kind, data Data) (Message, error) {
+	mu.RLock()
+	loaded := current
+	mu.RUnlock()
+
+	t, ok := loaded.templates[kind]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown notification template %q", kind)
+	}
+
+	msg, err := execute(t, data)
+	if err != nil && loaded.overridden[kind] {
+		logger.Log.WithError(err).Errorf("Notification template override %s failed, using the built-in template", kind)
+		return execute(builtins.templates[kind], data)
+	}
+	return msg, err
+}
+
+func execute(t *template.Template, data Data) (Message, error) {
+	lang := data.Lang
+	if lang == "" {
+		lang = i18n.English
+	}
+	t, err := t.Clone()
+	if err != nil {
+		return Message{}, err
+	}
+	t.Funcs(template.FuncMap{
+		"t":        func(id string, args ...any) string { return i18n.T(lang, id, args...) },
+		"status":   func(status models.Status) string { return i18n.T(lang, "status."+string(status)) },
+		"datetime": func(t time.Time) string { return i18n.FormatDateTime(lang, t) },
+	})
+
+	block := func(name string) (string, error) {
+		if t.Lookup(name) == nil {
+			return "", nil
+		}
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	var msg Message
+	var title, description, fields, footer string
+	if title, err = block("title"); err != nil {
+		return msg, err
+	}
+	if description, err = block("description"); err != nil {
+		return msg, err
+	}
+	if fields, err = block("fields"); err != nil {
+		return msg, err
+	}
+	if footer, err = block("footer"); err != nil {
+		return msg, err
+	}
+
+	msg.Title = truncate(strings.TrimSpace(title), maxTitle)
+	if msg.Title == "" {
+		return msg, errors.New("title rendered empty")
+	}
+	msg.Description = truncate(strings.TrimSpace(description), maxDescription)
+	msg.Footer = truncate(strings.TrimSpace(footer), maxFooter)
+	msg.Fields = splitFields(fields)
+	return msg, nil
+}
+
+func splitFields(rendered string) []Field {
+	var fields []Field
+	for _, chunk := range strings.Split(rendered, fieldStart)[1:] {
+		parts := strings.SplitN(chunk, fieldSep, 3)
+		if len(parts) != 3 {
+			continue
+		}
+		name, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[2])
+		if name == "" || value == "" {
+			continue
+		}
+		fields = append(fields, Field{
+			Name:   truncate(name, maxFieldName),
+			Value:  truncate(value, maxFieldValue),
+			Inline: parts[1] == "true",
+		})
+		if len(fields) == maxFields {
+			break
+		}
+	}
+	return fields
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,48 @@
+package notifytemplates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadOverrides(t *testing.T) {
+	t.Cleanup(func() { Load("") })
+
+	tests := []struct {
+		name     string
+		override string
+		wantErr  bool
+	}{
+		{name: "valid", override: `{{define "title"}}{{.Account.Title}} is now {{status .Status}}{{end}}`},
+		{name: "parse error", override: `{{define "title"}}{{.Account.Title}{{end}}`, wantErr: true},
+		{name: "unknown field", override: `{{define "title"}}{{.Account.Nickname}}{{end}}`, wantErr: true},
+		{name: "missing title", override: `{{define "description"}}text{{end}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, string(StatusChange)+".tmpl"), []byte(tt.override), 0o644); err != nil {
+				t.Fatalf("write override: %v", err)
+			}
+			err := Load(dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load accepted a broken override")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			msg, err := Render(StatusChange, Data{Account: Account{Title: "Main"}, Status: "Good"})
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if !strings.HasPrefix(msg.Title, "Main is now ") {
+				t.Errorf("Title = %q, want the override", msg.Title)
+			}
+		})
+	}
+}
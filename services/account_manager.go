@@ -1,11 +1,15 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bwmarrin/discordgo"
@@ -86,22 +90,52 @@ func getActionLimit(action string) int {
 	}
 }
 
-func processUserAccounts(s *discordgo.Session, userID string, accounts []models.Account) {
+// userCheckBatch holds one user's share of a periodic check run. Accounts are checked by the
+// worker pool and the batch is finished once the last of them completes or is skipped.
+type userCheckBatch struct {
+	userID          string
+	settings        models.UserSettings
+	toCheck         []models.Account
+	forDailyUpdate  []models.Account
+	shouldSendDaily bool
+	slots           chan struct{}
+
+	mu        sync.Mutex
+	remaining int
+	toUpdate  []models.Account
+	toNotify  []statusChange
+}
+
+// statusChange pairs an account whose status moved with the check that detected it.
+type statusChange struct {
+	account models.Account
+	check   BanCheck
+}
+
+func prepareUserAccounts(ctx context.Context, s discordapi.Session, userID string, accounts []models.Account) *userCheckBatch {
 	if len(accounts) == 0 {
-		return
+		return nil
 	}
 
 	cfg := configuration.Get()
 	userSettings, err := GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Errorf("Failed to get user settings for user %s", userID)
-		return
+		return nil
+	}
+
+	if reached, spent, err := CaptchaBudgetReached(userSettings); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to check captcha budget for user %s", userID)
+	} else if reached {
+		logger.Log.Infof("User %s has reached their monthly captcha budget ($%.4f of $%.2f), skipping scheduled checks", userID, spent, userSettings.MonthlyCaptchaBudget)
+		notifyCaptchaBudgetReached(s, accounts[0], userSettings, spent)
+		return nil
 	}
 
-	if err := validateUserCaptchaService(userID, userSettings); err != nil {
+	if err := validateUserCaptchaService(ctx, userID, userSettings); err != nil {
 		logger.Log.WithError(err).Errorf("Captcha service validation failed for user %s", userID)
 		notifyUserOfServiceIssue(s, userID, err)
-		return
+		return nil
 	}
 
 	notificationInterval := time.Duration(userSettings.NotificationInterval) * time.Hour
@@ -109,13 +143,21 @@ func processUserAccounts(s *discordgo.Session, userID string, accounts []models.
 		notificationInterval = time.Duration(cfg.Intervals.Notification) * time.Hour
 	}
 
-	shouldSendDaily := time.Since(userSettings.LastDailyUpdateNotification) >= notificationInterval
+	perUser := cfg.CheckPool.PerUser
+	if perUser < 1 {
+		perUser = 1
+	}
 
-	var accountsToUpdate, accountsToNotify, accountsForDailyUpdate []models.Account
+	batch := &userCheckBatch{
+		userID:          userID,
+		settings:        userSettings,
+		shouldSendDaily: time.Since(userSettings.LastDailyUpdateNotification) >= notificationInterval,
+		slots:           make(chan struct{}, perUser),
+	}
 
 	for _, account := range accounts {
 		if !account.IsCheckDisabled && !account.IsExpiredCookie {
-			accountsForDailyUpdate = append(accountsForDailyUpdate, account)
+			batch.forDailyUpdate = append(batch.forDailyUpdate, account)
 		}
 
 		if !shouldCheckAccount(account, userSettings) {
@@ -127,25 +169,62 @@ func processUserAccounts(s *discordgo.Session, userID string, accounts []models.
 			continue
 		}
 
-		result, err := CheckAccount(account.SSOCookie, userID, "")
+		batch.toCheck = append(batch.toCheck, account)
+	}
+
+	batch.remaining = len(batch.toCheck)
+	return batch
+}
+
+func (b *userCheckBatch) recordResult(account models.Account, check BanCheck) {
+	now := time.Now()
+	account.LastCheck = now.Unix()
+	account.LastSuccessfulCheck = now
+	account.ConsecutiveErrors = 0
+
+	// HandleStatusChange compares against the status the account had before this check.
+	previous := account
+
+	statusChanged := hasStatusChanged(account, check.Status)
+	titlesChanged := false
+	if !statusChanged {
+		changed, err := TitleStatusesChanged(account.ID, check)
 		if err != nil {
-			handleCheckError(s, &account, err)
-			continue
+			logger.Log.WithError(err).Errorf("Failed to compare per-title statuses for account %s", account.Title)
 		}
+		titlesChanged = changed
+	}
 
-		now := time.Now()
-		account.LastCheck = now.Unix()
-		account.LastSuccessfulCheck = now
-		account.ConsecutiveErrors = 0
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-		if hasStatusChanged(account, result) {
-			account.LastStatus = result
-			account.LastStatusChange = now.Unix()
-			accountsToNotify = append(accountsToNotify, account)
-		}
+	if statusChanged {
+		account.LastStatus = check.Status
+		account.LastStatusChange = now.Unix()
+	}
+	if statusChanged || titlesChanged {
+		b.toNotify = append(b.toNotify, statusChange{account: previous, check: check})
+	}
+
+	b.toUpdate = append(b.toUpdate, account)
+}
+
+// complete marks one account as done and finishes the batch after the last one.
+func (b *userCheckBatch) complete(ctx context.Context, s discordapi.Session) {
+	b.mu.Lock()
+	b.remaining--
+	last := b.remaining == 0
+	b.mu.Unlock()
 
-		accountsToUpdate = append(accountsToUpdate, account)
+	if last {
+		finishUserAccounts(ctx, s, b)
 	}
+}
+
+func finishUserAccounts(ctx context.Context, s discordapi.Session, b *userCheckBatch) {
+	b.mu.Lock()
+	accountsToUpdate, accountsToNotify := b.toUpdate, b.toNotify
+	b.mu.Unlock()
 
 	if len(accountsToUpdate) > 0 {
 		DBMutex.Lock()
@@ -156,15 +235,15 @@ func processUserAccounts(s *discordgo.Session, userID string, accounts []models.
 	}
 
 	if len(accountsToNotify) > 0 {
-		processNotifications(s, accountsToNotify, userSettings)
+		processNotifications(ctx, s, accountsToNotify, b.settings)
 	}
 
-	if shouldSendDaily && len(accountsForDailyUpdate) > 0 {
-		SendConsolidatedDailyUpdate(s, userID, userSettings, accountsForDailyUpdate)
+	if b.shouldSendDaily && len(b.forDailyUpdate) > 0 {
+		SendConsolidatedDailyUpdate(ctx, s, b.userID, b.settings, b.forDailyUpdate)
 	}
 }
 
-func notifyUserOfServiceIssue(s *discordgo.Session, userID string, err error) {
+func notifyUserOfServiceIssue(s discordapi.Session, userID string, err error) {
 	cfg := configuration.Get()
 	if userID != cfg.Discord.DeveloperID {
 		return
@@ -213,7 +292,7 @@ func shouldCheckAccount(account models.Account, settings models.UserSettings) bo
 		return false
 	}
 
-	if account.IsPermabanned {
+	if account.LastStatus == models.StatusPermaban {
 		logger.Log.Debugf("Account %s is permanently banned, skipping check", account.Title)
 		return false
 	}
@@ -224,7 +303,7 @@ func shouldCheckAccount(account models.Account, settings models.UserSettings) bo
 
 	lastCheckTime := time.Unix(account.LastCheck, 0)
 	checkInterval := time.Duration(settings.CheckInterval) * time.Minute
-	hasCustomKey := settings.CapSolverAPIKey != "" || settings.EZCaptchaAPIKey != "" || settings.TwoCaptchaAPIKey != ""
+	hasCustomKey := settings.HasCustomCaptchaKey()
 
 	if !hasCustomKey && time.Since(lastCheckTime) < cfg.RateLimits.Default {
 		return false
@@ -242,7 +321,7 @@ func shouldCheckAccount(account models.Account, settings models.UserSettings) bo
 	}
 
 	var nextCheckTime time.Time
-	if account.IsPermabanned {
+	if account.LastStatus == models.StatusPermaban {
 		nextCheckTime = time.Unix(account.LastCheck, 0).Add(time.Duration(cfg.Intervals.PermaBanCheck) * time.Hour)
 	} else {
 		checkInterval := settings.CheckInterval
@@ -252,7 +331,7 @@ func shouldCheckAccount(account models.Account, settings models.UserSettings) bo
 		nextCheckTime = time.Unix(account.LastCheck, 0).Add(time.Duration(checkInterval) * time.Minute)
 	}
 
-	if settings.CapSolverAPIKey != "" || settings.EZCaptchaAPIKey != "" || settings.TwoCaptchaAPIKey != "" {
+	if settings.HasCustomCaptchaKey() {
 		return now.After(nextCheckTime)
 	}
 
@@ -266,7 +345,7 @@ func hasStatusChanged(account models.Account, newStatus models.Status) bool {
 	return account.LastStatus != newStatus
 }
 
-func handleCheckError(s *discordgo.Session, account *models.Account, err error) {
+func handleCheckError(s discordapi.Session, account *models.Account, err error) {
 	cfg := configuration.Get()
 	account.ConsecutiveErrors++
 	account.LastErrorTime = time.Now()
@@ -282,19 +361,20 @@ func handleCheckError(s *discordgo.Session, account *models.Account, err error)
 	}
 }
 
-func processNotifications(s *discordgo.Session, accounts []models.Account, userSettings models.UserSettings) {
-	for _, account := range accounts {
+func processNotifications(ctx context.Context, s discordapi.Session, changes []statusChange, userSettings models.UserSettings) {
+	for _, change := range changes {
+		account := change.account
 		if !validateRateLimit(account.UserID, "notification", time.Hour) {
 			logger.Log.Infof("Notification rate limit reached for user %s", account.UserID)
 			continue
 		}
 
-		switch account.LastStatus {
+		switch change.check.Status {
 		case models.StatusPermaban, models.StatusShadowban, models.StatusTempban:
-			HandleStatusChange(s, account, account.LastStatus, userSettings)
+			HandleStatusChange(ctx, s, account, change.check, userSettings)
 		case models.StatusGood:
 			if isComingFromBannedState(account) {
-				HandleStatusChange(s, account, account.LastStatus, userSettings)
+				HandleStatusChange(ctx, s, account, change.check, userSettings)
 			}
 		}
 	}
@@ -329,13 +409,13 @@ func shouldCheckExpiration(account models.Account, now time.Time) bool {
 	return timeUntilExpiration > 0 && timeUntilExpiration <= time.Duration(cfg.Intervals.CookieExpiration)*time.Hour
 }
 
-func validateUserCaptchaService(userID string, userSettings models.UserSettings) error {
+func validateUserCaptchaService(ctx context.Context, userID string, userSettings models.UserSettings) error {
 	if !IsServiceEnabled(userSettings.PreferredCaptchaProvider) {
 		return fmt.Errorf("captcha service %s is disabled", userSettings.PreferredCaptchaProvider)
 	}
 
-	if userSettings.CapSolverAPIKey != "" || userSettings.EZCaptchaAPIKey != "" || userSettings.TwoCaptchaAPIKey != "" {
-		_, balance, err := GetUserCaptchaKey(userID)
+	if userSettings.HasCustomCaptchaKey() {
+		_, balance, err := GetUserCaptchaKey(ctx, userID)
 		if err != nil {
 			return fmt.Errorf("failed to validate captcha key: %w", err)
 		}
@@ -356,7 +436,7 @@ func ValidateDefaultCapsolverConfig() error {
 		return fmt.Errorf("capsolver App ID not configured")
 	}
 
-	isValid, _, err := validateCapsolverKey(cfg.CaptchaService.Capsolver.ClientKey)
+	isValid, _, err := validateCapsolverKey(context.Background(), cfg.CaptchaService.Capsolver.ClientKey)
 	if err != nil {
 		return fmt.Errorf("failed to validate Capsolver key: %w", err)
 	}
@@ -367,9 +447,9 @@ func ValidateDefaultCapsolverConfig() error {
 	return nil
 }
 
-func GetCheckStatus(isCheckDisabled bool) string {
+func GetCheckStatus(lang i18n.Lang, isCheckDisabled bool) string {
 	if isCheckDisabled {
-		return "Disabled"
+		return i18n.T(lang, "common.checks_disabled")
 	}
-	return "Enabled"
+	return i18n.T(lang, "common.checks_enabled")
 }
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Account titles must fit the limits of the /addaccount modal.
+const (
+	MinAccountTitleLength = 3
+	MaxAccountTitleLength = 40
+)
+
+var (
+	// ErrAccountNotFound is returned for accounts that do not exist or belong to another user.
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrInvalidSSOCookie is returned when Activision does not accept an SSO cookie.
+	ErrInvalidSSOCookie = errors.New("invalid SSO cookie")
+	// ErrInvalidAccountTitle is returned for titles outside the allowed length.
+	ErrInvalidAccountTitle = fmt.Errorf("account title must be %d to %d characters", MinAccountTitleLength, MaxAccountTitleLength)
+)
+
+// AccountLimitError is returned by AddAccount when the user already monitors Max accounts.
+type AccountLimitError struct {
+	Max          int
+	HasCustomKey bool
+}
+
+func (e *AccountLimitError) Error() string {
+	return fmt.Sprintf("maximum of %d accounts reached", e.Max)
+}
+
+// CheckNowQuotaError is returned by ReserveCheckNow when a default key user has too few checks left.
+type CheckNowQuotaError struct {
+	Needed    int
+	Remaining int
+	Max       int
+	ResetIn   time.Duration
+}
+
+func (e *CheckNowQuotaError) Error() string {
+	return fmt.Sprintf("%d checks needed but %d of %d remaining", e.Needed, e.Remaining, e.Max)
+}
+
+// MaxAccounts is how many accounts a user may monitor.
+func MaxAccounts(hasCustomKey bool) int {
+	cfg := configuration.Get()
+	if hasCustomKey {
+		return cfg.RateLimits.PremiumMaxAccounts
+	}
+	return cfg.RateLimits.DefaultMaxAccounts
+}
+
+// AllowAddAccount applies the add account rate limit for users on the bot's default key,
+// recording the attempt when it is allowed.
+func AllowAddAccount(userID string) bool {
+	var userSettings models.UserSettings
+	if err := database.DB.Where("user_id = ?", userID).First(&userSettings).Error; err != nil {
+		logger.Log.WithError(err).Error("Error fetching user settings")
+		return false
+	}
+
+	userSettings.EnsureMapsInitialized()
+	lastAddTime := userSettings.LastCommandTimes["add_account"]
+
+	if lastAddTime.IsZero() || time.Since(lastAddTime) >= configuration.Get().RateLimits.CheckNow {
+		userSettings.LastCommandTimes["add_account"] = time.Now()
+		if err := database.DB.Save(&userSettings).Error; err != nil {
+			logger.Log.WithError(err).Error("Error saving user settings")
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// GetUserAccount loads the account if it belongs to userID.
+func GetUserAccount(userID string, accountID uint) (models.Account, error) {
+	var account models.Account
+	err := database.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return account, ErrAccountNotFound
+	}
+	if err != nil {
+		return account, fmt.Errorf("failed to fetch account: %w", err)
+	}
+	return account, nil
+}
+
+// ValidateAccountTitle sanitizes a user supplied account title and checks its length.
+func ValidateAccountTitle(title string) (string, error) {
+	title = utils.SanitizeInput(title)
+	if n := utf8.RuneCountInString(title); n < MinAccountTitleLength || n > MaxAccountTitleLength {
+		return "", ErrInvalidAccountTitle
+	}
+	return title, nil
+}
+
+// AddAccount validates ssoCookie with Activision and starts monitoring it for userID, notifying
+// in channelID. It returns the new account and how many account slots the user has left.
+func AddAccount(ctx context.Context, userID, title, ssoCookie, channelID string) (models.Account, int, error) {
+	title, err := ValidateAccountTitle(title)
+	if err != nil {
+		return models.Account{}, 0, err
+	}
+
+	validationResult, err := ValidateAndGetAccountInfo(ctx, ssoCookie)
+	if err != nil {
+		return models.Account{}, 0, fmt.Errorf("failed to validate SSO cookie: %w", err)
+	}
+	if !validationResult.IsValid {
+		return models.Account{}, 0, ErrInvalidSSOCookie
+	}
+
+	userSettings, err := GetUserSettings(userID)
+	if err != nil {
+		return models.Account{}, 0, fmt.Errorf("failed to fetch user settings: %w", err)
+	}
+
+	var accountCount int64
+	if err := database.DB.Model(&models.Account{}).Where("user_id = ?", userID).Count(&accountCount).Error; err != nil {
+		return models.Account{}, 0, fmt.Errorf("failed to count user accounts: %w", err)
+	}
+
+	hasCustomKey := userSettings.HasCustomCaptchaKey()
+	maxAccounts := MaxAccounts(hasCustomKey)
+	if accountCount >= int64(maxAccounts) {
+		return models.Account{}, 0, &AccountLimitError{Max: maxAccounts, HasCustomKey: hasCustomKey}
+	}
+
+	account := models.Account{
+		UserID:              userID,
+		Title:               title,
+		SSOCookie:           ssoCookie,
+		SSOCookieExpiration: validationResult.ExpiresAt,
+		Created:             validationResult.Created,
+		IsVIP:               validationResult.IsVIP,
+		ChannelID:           channelID,
+		NotificationType:    userSettings.NotificationType,
+		LastSuccessfulCheck: time.Now(),
+		LastStatus:          models.StatusUnknown,
+	}
+
+	if err := database.DB.Create(&account).Error; err != nil {
+		logger.Log.WithError(err).WithFields(logrus.Fields{"userID": userID, "title": title}).Error("Error creating account")
+		return models.Account{}, 0, fmt.Errorf("failed to create account: %w", err)
+	}
+
+	accountLog := models.Ban{
+		AccountID: account.ID,
+		Status:    models.StatusUnknown,
+		LogType:   "account_added",
+		Message:   fmt.Sprintf("Account '%s' was added to monitoring", account.Title),
+		Timestamp: time.Now(),
+	}
+	if err := database.DB.Create(&accountLog).Error; err != nil {
+		logger.Log.WithError(err).Error("Failed to create account creation log")
+	}
+
+	if err := ScheduleCookieExpiryWarning(account); err != nil {
+		logger.Log.WithError(err).Error("Failed to schedule cookie expiry warning")
+	}
+
+	return account, maxAccounts - int(accountCount) - 1, nil
+}
+
+// RenameAccount changes the title of account.
+func RenameAccount(account *models.Account, title string) error {
+	title, err := ValidateAccountTitle(title)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Model(account).Update("title", title).Error; err != nil {
+		return fmt.Errorf("failed to rename account: %w", err)
+	}
+	account.Title = title
+	return nil
+}
+
+// UpdateAccountCookie validates ssoCookie and stores it on account, clearing any expired cookie or
+// error state and re-enabling checks. It reports whether checks had been disabled.
+func UpdateAccountCookie(ctx context.Context, account *models.Account, ssoCookie string) (bool, error) {
+	validationResult, err := ValidateAndGetAccountInfo(ctx, ssoCookie)
+	if err != nil {
+		return false, fmt.Errorf("failed to validate SSO cookie: %w", err)
+	}
+	if !validationResult.IsValid {
+		return false, ErrInvalidSSOCookie
+	}
+
+	DBMutex.Lock()
+	account.LastNotification = time.Now().Unix()
+	account.LastCookieNotification = 0
+	account.SSOCookie = ssoCookie
+	account.SSOCookieExpiration = validationResult.ExpiresAt
+	account.Created = validationResult.Created
+	account.IsVIP = validationResult.IsVIP
+	account.IsExpiredCookie = false
+	wasDisabled := account.IsCheckDisabled
+	account.IsCheckDisabled = false
+	account.DisabledReason = ""
+	account.ConsecutiveErrors = 0
+	account.LastSuccessfulCheck = time.Now()
+	err = database.DB.Save(account).Error
+	DBMutex.Unlock()
+	if err != nil {
+		return false, fmt.Errorf("failed to update account: %w", err)
+	}
+
+	statusLog := models.Ban{
+		AccountID: account.ID,
+		Status:    account.LastStatus,
+		LogType:   "cookie_update",
+		Message:   "SSO Cookie updated",
+		Timestamp: time.Now(),
+		Initiator: "user",
+	}
+	if err := database.DB.Create(&statusLog).Error; err != nil {
+		logger.Log.WithError(err).Error("Failed to log cookie update")
+	}
+
+	if err := ScheduleCookieExpiryWarning(*account); err != nil {
+		logger.Log.WithError(err).Error("Failed to schedule cookie expiry warning")
+	}
+
+	return wasDisabled, nil
+}
+
+// SetAccountChecksDisabled pauses or resumes checks for account at its owner's request.
+func SetAccountChecksDisabled(account *models.Account, disabled bool) error {
+	account.IsCheckDisabled = disabled
+	account.DisabledReason = ""
+	if disabled {
+		account.DisabledReason = "Manually disabled by user"
+	} else {
+		account.ConsecutiveErrors = 0
+	}
+	if err := database.DB.Save(account).Error; err != nil {
+		return fmt.Errorf("failed to save account: %w", err)
+	}
+	return nil
+}
+
+// RemoveAccount deletes account together with its logs, scheduled jobs and per-title statuses.
+func RemoveAccount(account models.Account) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", account.ID).Delete(&models.Ban{}).Error; err != nil {
+			return fmt.Errorf("failed to delete associated bans: %w", err)
+		}
+		if err := tx.Unscoped().Where("account_id = ?", account.ID).Delete(&models.ScheduledJob{}).Error; err != nil {
+			return fmt.Errorf("failed to delete scheduled jobs: %w", err)
+		}
+		if err := tx.Unscoped().Where("account_id = ?", account.ID).Delete(&models.AccountTitleStatus{}).Error; err != nil {
+			return fmt.Errorf("failed to delete title statuses: %w", err)
+		}
+		if err := tx.Delete(&account).Error; err != nil {
+			return fmt.Errorf("failed to delete account: %w", err)
+		}
+		return nil
+	})
+}
+
+// ReserveCheckNow takes n checks from the hourly manual check allowance of a user on the bot's
+// default key, starting a new window once CHECK_NOW_RATE_LIMIT has passed. Users with their own
+// key are not limited.
+func ReserveCheckNow(userSettings *models.UserSettings, n int) error {
+	if userSettings.HasCustomCaptchaKey() {
+		return nil
+	}
+
+	cfg := configuration.Get()
+	userSettings.EnsureMapsInitialized()
+	lastCheck := userSettings.LastCommandTimes["check_now"]
+	maxChecks := cfg.RateLimits.DefaultMaxAccounts
+
+	if lastCheck.IsZero() || time.Since(lastCheck) >= cfg.RateLimits.CheckNow {
+		lastCheck = time.Now()
+		userSettings.ActionCounts["check_now"] = 0
+		userSettings.LastCommandTimes["check_now"] = lastCheck
+	}
+
+	checksUsed := userSettings.ActionCounts["check_now"]
+	if n > maxChecks-checksUsed {
+		return &CheckNowQuotaError{
+			Needed:    n,
+			Remaining: maxChecks - checksUsed,
+			Max:       maxChecks,
+			ResetIn:   cfg.RateLimits.CheckNow - time.Since(lastCheck),
+		}
+	}
+
+	userSettings.ActionCounts["check_now"] = checksUsed + n
+	if err := database.DB.Save(userSettings).Error; err != nil {
+		return fmt.Errorf("failed to save check count: %w", err)
+	}
+	return nil
+}
+
+// CheckAccountNow checks account immediately and applies the result the way a scheduled check
+// would, returning the account as stored afterwards.
+func CheckAccountNow(ctx context.Context, s discordapi.Session, account models.Account, userSettings models.UserSettings) (models.Account, BanCheck, error) {
+	check, err := CheckAccount(WithCaptchaAccount(ctx, account.ID), account.SSOCookie, account.UserID, "")
+	if err != nil {
+		return account, check, err
+	}
+
+	HandleStatusChange(ctx, s, account, check, userSettings)
+
+	if err := database.DB.First(&account, account.ID).Error; err != nil {
+		return account, check, fmt.Errorf("failed to reload account: %w", err)
+	}
+	return account, check, nil
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"gorm.io/gorm"
+)
+
+// AdminDisabledReason is the prefix of the DisabledReason the admin panel sets on accounts.
+const AdminDisabledReason = "Disabled by an administrator"
+
+// ErrUserDisabled is returned for actions by a user an administrator has disabled.
+var ErrUserDisabled = errors.New("user has been disabled by an administrator")
+
+// AccountStatusCount is the number of accounts last seen with Status.
+type AccountStatusCount struct {
+	Status models.Status
+	Count  int64
+}
+
+// AdminOverview is the summary shown on the admin dashboard.
+type AdminOverview struct {
+	Users            int64
+	DisabledUsers    int64
+	Accounts         int64
+	DisabledAccounts int64
+	ByStatus         []AccountStatusCount
+}
+
+// GetAdminOverview counts users and accounts, with accounts grouped by LastStatus.
+func GetAdminOverview() (AdminOverview, error) {
+	var overview AdminOverview
+	if err := database.DB.Model(&models.UserSettings{}).Count(&overview.Users).Error; err != nil {
+		return overview, fmt.Errorf("failed to count users: %w", err)
+	}
+	if err := database.DB.Model(&models.UserSettings{}).Where("is_disabled = ?", true).Count(&overview.DisabledUsers).Error; err != nil {
+		return overview, fmt.Errorf("failed to count disabled users: %w", err)
+	}
+	if err := database.DB.Model(&models.Account{}).Count(&overview.Accounts).Error; err != nil {
+		return overview, fmt.Errorf("failed to count accounts: %w", err)
+	}
+	if err := database.DB.Model(&models.Account{}).Where("is_check_disabled = ?", true).Count(&overview.DisabledAccounts).Error; err != nil {
+		return overview, fmt.Errorf("failed to count disabled accounts: %w", err)
+	}
+	if err := database.DB.Model(&models.Account{}).
+		Select("last_status AS status, COUNT(*) AS count").
+		Group("last_status").
+		Order("COUNT(*) DESC").
+		Scan(&overview.ByStatus).Error; err != nil {
+		return overview, fmt.Errorf("failed to count accounts by status: %w", err)
+	}
+	return overview, nil
+}
+
+// AdminUser is a user with the number of accounts they monitor.
+type AdminUser struct {
+	models.UserSettings
+	AccountCount int64
+}
+
+// ListAdminUsers returns a page of users, newest first, whose ID contains query.
+func ListAdminUsers(query string, limit, offset int) ([]AdminUser, int64, error) {
+	tx := database.DB.Model(&models.UserSettings{})
+	if query != "" {
+		tx = tx.Where("user_id LIKE ?", "%"+query+"%")
+	}
+
+	tx = tx.Session(&gorm.Session{})
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	var settings []models.UserSettings
+	if err := tx.Order("id DESC").Limit(limit).Offset(offset).Find(&settings).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	users := make([]AdminUser, 0, len(settings))
+	for _, s := range settings {
+		user := AdminUser{UserSettings: s}
+		if err := database.DB.Model(&models.Account{}).Where("user_id = ?", s.UserID).Count(&user.AccountCount).Error; err != nil {
+			return nil, 0, fmt.Errorf("failed to count accounts for user %s: %w", s.UserID, err)
+		}
+		users = append(users, user)
+	}
+	return users, total, nil
+}
+
+// AccountFilter narrows ListAdminAccounts. Empty fields match everything.
+type AccountFilter struct {
+	UserID string
+	Title  string
+	Status models.Status
+}
+
+// ListAdminAccounts returns a page of accounts, newest first, matching filter.
+func ListAdminAccounts(filter AccountFilter, limit, offset int) ([]models.Account, int64, error) {
+	tx := database.DB.Model(&models.Account{})
+	if filter.UserID != "" {
+		tx = tx.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Title != "" {
+		tx = tx.Where("title LIKE ?", "%"+filter.Title+"%")
+	}
+	if filter.Status != "" {
+		tx = tx.Where("last_status = ?", filter.Status)
+	}
+
+	tx = tx.Session(&gorm.Session{})
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count accounts: %w", err)
+	}
+
+	var accounts []models.Account
+	if err := tx.Order("id DESC").Limit(limit).Offset(offset).Find(&accounts).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	return accounts, total, nil
+}
+
+// BanFilter narrows ListBanLog. Empty fields match everything.
+type BanFilter struct {
+	AccountID uint
+	UserID    string
+	Status    models.Status
+	LogType   string
+}
+
+// ListBanLog returns a page of ban log entries with their accounts, newest first.
+func ListBanLog(filter BanFilter, limit, offset int) ([]models.Ban, int64, error) {
+	tx := database.DB.Model(&models.Ban{})
+	if filter.AccountID != 0 {
+		tx = tx.Where("bans.account_id = ?", filter.AccountID)
+	}
+	if filter.UserID != "" {
+		tx = tx.Joins("JOIN accounts ON accounts.id = bans.account_id").Where("accounts.user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		tx = tx.Where("bans.status = ?", filter.Status)
+	}
+	if filter.LogType != "" {
+		tx = tx.Where("bans.log_type = ?", filter.LogType)
+	}
+
+	tx = tx.Session(&gorm.Session{})
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count ban log entries: %w", err)
+	}
+
+	var bans []models.Ban
+	if err := tx.Preload("Account").Order("bans.id DESC").Limit(limit).Offset(offset).Find(&bans).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list ban log entries: %w", err)
+	}
+	return bans, total, nil
+}
+
+// AdminDisableAccount stops checks for the account and tells its owner why.
+func AdminDisableAccount(s discordapi.Session, accountID uint, reason string) error {
+	var account models.Account
+	if err := database.DB.First(&account, accountID).Error; err != nil {
+		return fmt.Errorf("failed to load account %d: %w", accountID, err)
+	}
+	if account.IsCheckDisabled {
+		return nil
+	}
+
+	account.IsCheckDisabled = true
+	account.DisabledReason = adminReason(reason)
+	if err := database.DB.Save(&account).Error; err != nil {
+		return fmt.Errorf("failed to disable account %d: %w", accountID, err)
+	}
+
+	logger.Log.Infof("Administrator disabled account %d (%s). Reason: %s", account.ID, account.Title, account.DisabledReason)
+	NotifyUserAboutDisabledAccount(s, account, account.DisabledReason)
+	return nil
+}
+
+// AdminEnableAccount resumes checks for an account, unless its owner is disabled.
+func AdminEnableAccount(accountID uint) error {
+	var account models.Account
+	if err := database.DB.First(&account, accountID).Error; err != nil {
+		return fmt.Errorf("failed to load account %d: %w", accountID, err)
+	}
+
+	var settings models.UserSettings
+	err := database.DB.Where("user_id = ?", account.UserID).First(&settings).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to load settings for user %s: %w", account.UserID, err)
+	}
+	if settings.IsDisabled {
+		return ErrUserDisabled
+	}
+
+	return database.DB.Model(&account).Updates(map[string]interface{}{
+		"is_check_disabled":  false,
+		"disabled_reason":    "",
+		"consecutive_errors": 0,
+	}).Error
+}
+
+// AdminDisableUser blocks the user from the bot's commands and disables checks for all of their
+// accounts.
+func AdminDisableUser(userID, reason string) error {
+	reason = adminReason(reason)
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.UserSettings{}).
+			Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"is_disabled": true, "disabled_reason": reason})
+		if result.Error != nil {
+			return fmt.Errorf("failed to disable user %s: %w", userID, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("user %s: %w", userID, gorm.ErrRecordNotFound)
+		}
+
+		if err := tx.Model(&models.Account{}).
+			Where("user_id = ? AND is_check_disabled = ?", userID, false).
+			Updates(map[string]interface{}{"is_check_disabled": true, "disabled_reason": reason}).Error; err != nil {
+			return fmt.Errorf("failed to disable accounts for user %s: %w", userID, err)
+		}
+
+		logger.Log.Infof("Administrator disabled user %s. Reason: %s", userID, reason)
+		return nil
+	})
+}
+
+// AdminEnableUser lets the user use the bot again. Their accounts stay disabled until re-enabled
+// one by one, so checks do not restart for accounts the user had disabled themselves.
+func AdminEnableUser(userID string) error {
+	result := database.DB.Model(&models.UserSettings{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"is_disabled": false, "disabled_reason": ""})
+	if result.Error != nil {
+		return fmt.Errorf("failed to enable user %s: %w", userID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user %s: %w", userID, gorm.ErrRecordNotFound)
+	}
+	logger.Log.Infof("Administrator re-enabled user %s", userID)
+	return nil
+}
+
+// UserDisabledReason returns why an administrator disabled the user, and false if they have not.
+func UserDisabledReason(userID string) (string, bool) {
+	var settings models.UserSettings
+	err := database.DB.Select("is_disabled", "disabled_reason").Where("user_id = ?", userID).First(&settings).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.WithError(err).Errorf("Failed to check whether user %s is disabled", userID)
+		}
+		return "", false
+	}
+	return settings.DisabledReason, settings.IsDisabled
+}
+
+func adminReason(reason string) string {
+	if reason == "" {
+		return AdminDisabledReason
+	}
+	return AdminDisabledReason + ": " + reason
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MaxAPITokensPerUser caps how many REST API tokens a user may hold at once.
+	MaxAPITokensPerUser = 5
+
+	// apiTokenPrefix marks bot API tokens so they are recognisable in config files and secret scanners.
+	apiTokenPrefix = "csb_"
+)
+
+var (
+	// ErrAPITokenLimit is returned by CreateAPIToken once the user holds MaxAPITokensPerUser tokens.
+	ErrAPITokenLimit = fmt.Errorf("a user may hold at most %d API tokens", MaxAPITokensPerUser)
+	// ErrAPITokenNotFound is returned for tokens that do not exist or belong to another user.
+	ErrAPITokenNotFound = errors.New("API token not found")
+	// ErrInvalidAPIToken is returned when a presented token is unknown or revoked.
+	ErrInvalidAPIToken = errors.New("invalid API token")
+)
+
+// CreateAPIToken issues a REST API token for userID. The token is returned only here; the
+// database keeps its hash. The user's settings row is locked while the limit is checked, so
+// concurrent requests cannot take the user past MaxAPITokensPerUser.
+func CreateAPIToken(userID, name string) (string, models.APIToken, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", models.APIToken{}, fmt.Errorf("failed to generate API token: %w", err)
+	}
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	record := models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    token[:len(apiTokenPrefix)+6],
+		TokenHash: hashAPIToken(token),
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var settings models.UserSettings
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(models.UserSettings{UserID: userID}).FirstOrCreate(&settings).Error; err != nil {
+			return fmt.Errorf("failed to lock user settings: %w", err)
+		}
+
+		var count int64
+		if err := tx.Model(&models.APIToken{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count API tokens: %w", err)
+		}
+		if count >= MaxAPITokensPerUser {
+			return ErrAPITokenLimit
+		}
+
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to save API token: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", models.APIToken{}, err
+	}
+	return token, record, nil
+}
+
+// ListAPITokens returns the user's active tokens, oldest first.
+func ListAPITokens(userID string) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	if err := database.DB.Where("user_id = ?", userID).Order("id").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes one of the user's tokens so it can no longer authenticate.
+func RevokeAPIToken(userID string, tokenID uint) error {
+	result := database.DB.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&models.APIToken{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke API token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIToken looks up the active token matching token and records that it was used.
+func AuthenticateAPIToken(token string) (models.APIToken, error) {
+	var record models.APIToken
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return record, ErrInvalidAPIToken
+	}
+
+	err := database.DB.Where("token_hash = ?", hashAPIToken(token)).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return record, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return record, fmt.Errorf("failed to look up API token: %w", err)
+	}
+
+	// Only write the timestamp once a minute so busy clients do not turn every read into a write.
+	if now := time.Now(); now.Sub(record.LastUsedAt) >= time.Minute {
+		record.LastUsedAt = now
+		if err := database.DB.Model(&record).UpdateColumn("last_used_at", now).Error; err != nil {
+			logger.Log.WithError(err).Warn("Failed to record API token use")
+		}
+	}
+	return record, nil
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
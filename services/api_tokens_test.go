@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCreateAPITokenLimitUnderConcurrency(t *testing.T) {
+	useTestDatabase(t)
+
+	const attempts = 3 * MaxAPITokensPerUser
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+		limited int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := CreateAPIToken("user-1", "ci")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, ErrAPITokenLimit):
+				limited++
+			default:
+				t.Errorf("CreateAPIToken: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created != MaxAPITokensPerUser || limited != attempts-MaxAPITokensPerUser {
+		t.Fatalf("created %d tokens and refused %d, want %d and %d", created, limited, MaxAPITokensPerUser, attempts-MaxAPITokensPerUser)
+	}
+
+	tokens, err := ListAPITokens("user-1")
+	if err != nil {
+		t.Fatalf("ListAPITokens: %v", err)
+	}
+	if len(tokens) != MaxAPITokensPerUser {
+		t.Fatalf("user holds %d tokens, want %d", len(tokens), MaxAPITokensPerUser)
+	}
+}
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	useTestDatabase(t)
+
+	token, record, err := CreateAPIToken("user-1", "ci")
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	got, err := AuthenticateAPIToken(token)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIToken: %v", err)
+	}
+	if got.ID != record.ID || got.UserID != "user-1" {
+		t.Fatalf("authenticated token %d for %s, want %d for user-1", got.ID, got.UserID, record.ID)
+	}
+
+	if err := RevokeAPIToken("user-1", record.ID); err != nil {
+		t.Fatalf("RevokeAPIToken: %v", err)
+	}
+	if _, err := AuthenticateAPIToken(token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("revoked token authenticated with err = %v, want ErrInvalidAPIToken", err)
+	}
+}
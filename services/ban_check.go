@@ -0,0 +1,207 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+)
+
+// BanCheck is the outcome of an appeal API lookup: the overall status plus everything Activision
+// reported about each title.
+type BanCheck struct {
+	Status       models.Status
+	CanAppeal    bool
+	Enforcements []models.TitleEnforcement
+}
+
+type appealResponse struct {
+	Error     string      `json:"error"`
+	Success   string      `json:"success"`
+	CanAppeal bool        `json:"canAppeal"`
+	Bans      []appealBan `json:"bans"`
+}
+
+type appealBan struct {
+	Enforcement string    `json:"enforcement"`
+	Title       string    `json:"title"`
+	CanAppeal   bool      `json:"canAppeal"`
+	EndDate     banTime   `json:"endDate"`
+	Bar         appealBar `json:"bar"`
+}
+
+// appealBar is the ban appeal request attached to a ban once one has been filed.
+type appealBar struct {
+	CaseNumber string `json:"CaseNumber"`
+	Status     string `json:"Status"`
+}
+
+// UnmarshalJSON reads the case number and status when they are there and ignores everything
+// else, so a change to the appeal details never fails the whole check.
+func (b *appealBar) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		logger.Log.WithField("bar", string(data)).Warn("Ignoring unrecognised ban appeal details")
+		return nil
+	}
+
+	for key, value := range fields {
+		switch strings.ToLower(key) {
+		case "casenumber":
+			b.CaseNumber = barString(value)
+		case "status":
+			b.Status = barString(value)
+		}
+	}
+	return nil
+}
+
+// barString returns a string or numeric appeal field as text, or "" for anything else.
+func barString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// banTime accepts the end date as an RFC 3339 string or as Unix seconds or milliseconds, either
+// as a number or a numeric string. Any other value is logged and left as the zero time, which
+// the rest of the check treats as an unknown end date.
+type banTime struct {
+	time.Time
+}
+
+func (t *banTime) UnmarshalJSON(data []byte) error {
+	raw := strings.Trim(string(data), `"`)
+	if raw == "" || raw == "null" {
+		return nil
+	}
+
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		if n > 1e12 {
+			t.Time = time.UnixMilli(n)
+		} else {
+			t.Time = time.Unix(n, 0)
+		}
+		return nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		logger.Log.WithField("endDate", string(data)).Warn("Ignoring unrecognised ban end date")
+		return nil
+	}
+	t.Time = parsed
+	return nil
+}
+
+// enforcementStatus maps appeal API enforcement values to account statuses, most severe first.
+var enforcementStatus = []struct {
+	enforcement string
+	status      models.Status
+}{
+	{"PERMANENT", models.StatusPermaban},
+	{"TEMPORARY", models.StatusTempban},
+	{"UNDER_REVIEW", models.StatusShadowban},
+}
+
+// parseAppealResponse turns the appeal API body into a BanCheck. When several titles carry
+// different enforcements, the most severe one decides the overall status.
+func parseAppealResponse(data appealResponse) BanCheck {
+	check := BanCheck{Status: models.StatusUnknown, CanAppeal: data.CanAppeal}
+	if data.Success == "true" && len(data.Bans) == 0 {
+		check.Status = models.StatusGood
+		return check
+	}
+
+	seen := make(map[string]bool)
+	for _, ban := range data.Bans {
+		check.Enforcements = append(check.Enforcements, models.TitleEnforcement{
+			Title:        ban.Title,
+			Enforcement:  ban.Enforcement,
+			CanAppeal:    ban.CanAppeal,
+			EndsAt:       ban.EndDate.Time,
+			AppealStatus: ban.Bar.Status,
+			CaseNumber:   ban.Bar.CaseNumber,
+		})
+		seen[ban.Enforcement] = true
+	}
+
+	for _, e := range enforcementStatus {
+		if seen[e.enforcement] {
+			check.Status = e.status
+			break
+		}
+	}
+	return check
+}
+
+// TempBanEndsAt returns when the last temporary enforcement ends, or the zero time if the API
+// did not say.
+func (c BanCheck) TempBanEndsAt() time.Time {
+	var endsAt time.Time
+	for _, e := range c.Enforcements {
+		if e.Enforcement == "TEMPORARY" && e.EndsAt.After(endsAt) {
+			endsAt = e.EndsAt
+		}
+	}
+	return endsAt
+}
+
+// AppealStatus returns the first appeal status reported for any title.
+func (c BanCheck) AppealStatus() string {
+	for _, e := range c.Enforcements {
+		if e.AppealStatus != "" {
+			return e.AppealStatus
+		}
+	}
+	return ""
+}
+
+// AffectedGames lists the distinct titles with an enforcement, in a stable order.
+func (c BanCheck) AffectedGames() string {
+	seen := make(map[string]bool)
+	var games []string
+	for _, e := range c.Enforcements {
+		if e.Title != "" && !seen[e.Title] {
+			seen[e.Title] = true
+			games = append(games, e.Title)
+		}
+	}
+	sort.Strings(games)
+	return strings.Join(games, ", ")
+}
+
+// applyTo copies the structured ban details onto a ban record.
+func (c BanCheck) applyTo(ban *models.Ban) {
+	ban.CanAppeal = c.CanAppeal
+	ban.AppealStatus = c.AppealStatus()
+	ban.Enforcements = c.Enforcements
+	if c.Status == models.StatusTempban {
+		ban.TempBanEndsAt = c.TempBanEndsAt()
+		ban.TempBanDuration = tempBanRemaining(i18n.English, *ban)
+	}
+}
+
+// tempBanRemaining is the countdown shown for a temporary ban, computed from the recorded end
+// time so it stays accurate however long ago the ban was detected. The duration stored on a ban
+// record is always in English.
+func tempBanRemaining(lang i18n.Lang, ban models.Ban) string {
+	if ban.TempBanEndsAt.IsZero() {
+		if ban.TempBanDuration != "" {
+			return ban.TempBanDuration
+		}
+		return i18n.T(lang, "duration.unknown")
+	}
+	return calculateBanDuration(lang, ban.TempBanEndsAt)
+}
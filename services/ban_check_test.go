@@ -0,0 +1,94 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/models"
+)
+
+func TestParseAppealResponseLenient(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus models.Status
+		wantEnds   time.Time
+		wantCase   string
+		wantAppeal string
+	}{
+		{
+			name:       "rfc3339 end date",
+			body:       `{"success":"true","bans":[{"enforcement":"TEMPORARY","title":"MW3","endDate":"2026-01-02T03:04:05Z"}]}`,
+			wantStatus: models.StatusTempban,
+			wantEnds:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:       "millisecond end date",
+			body:       `{"success":"true","bans":[{"enforcement":"TEMPORARY","title":"MW3","endDate":1767323045000}]}`,
+			wantStatus: models.StatusTempban,
+			wantEnds:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:       "unknown end date format",
+			body:       `{"success":"true","bans":[{"enforcement":"TEMPORARY","title":"MW3","endDate":"in 3 days"}]}`,
+			wantStatus: models.StatusTempban,
+		},
+		{
+			name:       "end date object",
+			body:       `{"success":"true","bans":[{"enforcement":"TEMPORARY","title":"MW3","endDate":{"days":3}}]}`,
+			wantStatus: models.StatusTempban,
+		},
+		{
+			name:       "appeal details",
+			body:       `{"success":"true","bans":[{"enforcement":"PERMANENT","title":"MW3","bar":{"CaseNumber":"0123","Status":"Open"}}]}`,
+			wantStatus: models.StatusPermaban,
+			wantCase:   "0123",
+			wantAppeal: "Open",
+		},
+		{
+			name:       "numeric case number and extra fields",
+			body:       `{"success":"true","bans":[{"enforcement":"PERMANENT","title":"MW3","bar":{"caseNumber":4567,"status":"Closed","updated":{"at":1},"notes":[1,2]}}]}`,
+			wantStatus: models.StatusPermaban,
+			wantCase:   "4567",
+			wantAppeal: "Closed",
+		},
+		{
+			name:       "non-string appeal status",
+			body:       `{"success":"true","bans":[{"enforcement":"UNDER_REVIEW","title":"MW3","bar":{"CaseNumber":"89","Status":{"code":2}}}]}`,
+			wantStatus: models.StatusShadowban,
+			wantCase:   "89",
+		},
+		{
+			name:       "appeal details not an object",
+			body:       `{"success":"true","bans":[{"enforcement":"PERMANENT","title":"MW3","bar":"pending"}]}`,
+			wantStatus: models.StatusPermaban,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data appealResponse
+			if err := json.Unmarshal([]byte(tt.body), &data); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			check := parseAppealResponse(data)
+			if check.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", check.Status, tt.wantStatus)
+			}
+			if len(check.Enforcements) != 1 {
+				t.Fatalf("got %d enforcements, want 1", len(check.Enforcements))
+			}
+			e := check.Enforcements[0]
+			if !e.EndsAt.Equal(tt.wantEnds) {
+				t.Errorf("EndsAt = %v, want %v", e.EndsAt, tt.wantEnds)
+			}
+			if e.CaseNumber != tt.wantCase {
+				t.Errorf("CaseNumber = %q, want %q", e.CaseNumber, tt.wantCase)
+			}
+			if e.AppealStatus != tt.wantAppeal {
+				t.Errorf("AppealStatus = %q, want %q", e.AppealStatus, tt.wantAppeal)
+			}
+		})
+	}
+}
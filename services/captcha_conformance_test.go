@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/testsupport/fakecaptcha"
+)
+
+const (
+	conformanceSiteKey = "6LdB2NUpAAAAANcdcy9YcjBOBD4rY-TIHOeolkkk"
+	conformanceURL     = "https://support.activision.com"
+)
+
+func TestCaptchaSolverConformance(t *testing.T) {
+	for _, protocol := range fakecaptcha.Protocols {
+		t.Run(string(protocol), func(t *testing.T) {
+			runConformance(t, protocol)
+		})
+	}
+}
+
+// runConformance checks that the solver NewCaptchaSolver builds for protocol speaks the provider
+// protocol correctly: balance queries, polling through processing states, provider errors, zero
+// balance, cancellation and giving up. The subtests rewrite the shared configuration and restore
+// it afterwards, so they must not run in parallel.
+func runConformance(t *testing.T, protocol fakecaptcha.Protocol) {
+	t.Helper()
+	provider := string(protocol)
+
+	t.Run("balance", func(t *testing.T) {
+		startConformanceServer(t, protocol)
+		valid, balance, err := ValidateCaptchaKey(context.Background(), fakecaptcha.DefaultKey, provider)
+		if err != nil || !valid {
+			t.Fatalf("ValidateCaptchaKey(fakecaptcha.DefaultKey) = %v, %v, %v; want valid", valid, balance, err)
+		}
+		if balance != fakecaptcha.DefaultBalance {
+			t.Fatalf("balance = %v, want %v", balance, fakecaptcha.DefaultBalance)
+		}
+	})
+
+	t.Run("balance rejects unknown key", func(t *testing.T) {
+		startConformanceServer(t, protocol)
+		valid, _, _ := ValidateCaptchaKey(context.Background(), "not-a-key", provider)
+		if valid {
+			t.Fatal("ValidateCaptchaKey accepted a key the provider does not know")
+		}
+	})
+
+	t.Run("solves after processing", func(t *testing.T) {
+		srv := startConformanceServer(t, protocol)
+		srv.SetProcessingPolls(2)
+
+		token, err := solve(t, context.Background(), fakecaptcha.DefaultKey, provider)
+		if err != nil {
+			t.Fatalf("SolveReCaptchaV2: %v", err)
+		}
+		if len(token) < 50 {
+			t.Fatalf("token %q is too short to be a reCAPTCHA response", token)
+		}
+		if n := srv.Count(fakecaptcha.ResultPath); n != 3 {
+			t.Fatalf("solver polled %d times, want 3", n)
+		}
+		if got, want := srv.Balance(fakecaptcha.DefaultKey), fakecaptcha.DefaultBalance-fakecaptcha.DefaultPrice; got != want {
+			t.Fatalf("balance after solve = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("zero balance", func(t *testing.T) {
+		srv := startConformanceServer(t, protocol)
+		srv.AddKey("empty-key", 0)
+
+		_, err := solve(t, context.Background(), "empty-key", provider)
+		if !errors.Is(err, ErrInsufficientBalance) {
+			t.Fatalf("err = %v, want ErrInsufficientBalance", err)
+		}
+		if n := srv.Count(fakecaptcha.ResultPath); n != 0 {
+			t.Fatalf("solver polled %d times after task creation failed", n)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		srv := startConformanceServer(t, protocol)
+		if _, err := solve(t, context.Background(), "not-a-key", provider); err == nil {
+			t.Fatal("solve succeeded with an unknown key")
+		}
+		if n := srv.Count(fakecaptcha.ResultPath); n != 0 {
+			t.Fatalf("solver polled %d times after task creation failed", n)
+		}
+	})
+
+	t.Run("unsolvable", func(t *testing.T) {
+		srv := startConformanceServer(t, protocol)
+		srv.SetUnsolvable(true)
+
+		if _, err := solve(t, context.Background(), fakecaptcha.DefaultKey, provider); err == nil {
+			t.Fatal("solve succeeded for an unsolvable task")
+		}
+		if got := srv.Balance(fakecaptcha.DefaultKey); got != fakecaptcha.DefaultBalance {
+			t.Fatalf("unsolvable task was charged, balance = %v", got)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		srv := startConformanceServer(t, protocol)
+		srv.SetProcessingPolls(1 << 20)
+
+		if _, err := solve(t, context.Background(), fakecaptcha.DefaultKey, provider); err == nil {
+			t.Fatal("solve succeeded for a task that never finished")
+		}
+		if n := srv.Count(fakecaptcha.ResultPath); n == 0 || n > 5 {
+			t.Fatalf("solver polled %d times, want between 1 and the configured 5", n)
+		}
+	})
+
+	t.Run("stops when the context is cancelled", func(t *testing.T) {
+		srv := startConformanceServer(t, protocol)
+		srv.SetProcessingPolls(1 << 20)
+		setPollInterval(configuration.Get(), protocol, time.Minute)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := solve(t, ctx, fakecaptcha.DefaultKey, provider)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Fatalf("solver took %v to notice the cancelled context", elapsed)
+		}
+	})
+}
+
+// startConformanceServer starts a fake for protocol and points the shared configuration at it
+// for the rest of the test.
+func startConformanceServer(t *testing.T, protocol fakecaptcha.Protocol) *fakecaptcha.Server {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := *cfg
+
+	srv := fakecaptcha.New(protocol)
+	srv.Apply(cfg)
+	t.Cleanup(func() {
+		srv.Close()
+		*cfg = saved
+	})
+	return srv
+}
+
+func solve(t *testing.T, ctx context.Context, apiKey, provider string) (string, error) {
+	t.Helper()
+	solver, err := NewCaptchaSolver(apiKey, provider)
+	if err != nil {
+		t.Fatalf("NewCaptchaSolver(%s): %v", provider, err)
+	}
+	return solver.SolveReCaptchaV2(ctx, conformanceSiteKey, conformanceURL)
+}
+
+func setPollInterval(cfg *configuration.Config, protocol fakecaptcha.Protocol, d time.Duration) {
+	if protocol == fakecaptcha.Capsolver {
+		cfg.CaptchaService.Capsolver.RetryInterval = d
+		return
+	}
+	cfg.CaptchaEndpoints.RetryInterval = d
+}
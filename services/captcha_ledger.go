@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bwmarrin/discordgo"
+)
+
+type captchaAccountKey struct{}
+
+// WithCaptchaAccount attributes the captcha solves made under ctx to the account being checked.
+func WithCaptchaAccount(ctx context.Context, accountID uint) context.Context {
+	return context.WithValue(ctx, captchaAccountKey{}, accountID)
+}
+
+func captchaAccountFromContext(ctx context.Context) uint {
+	accountID, _ := ctx.Value(captchaAccountKey{}).(uint)
+	return accountID
+}
+
+// recordCaptchaSolve adds one solve attempt to the ledger. Failed attempts are recorded at no
+// cost since the providers only charge for solved tasks.
+func recordCaptchaSolve(ctx context.Context, userID string, c captchaCandidate, elapsed time.Duration, solveErr error) {
+	entry := models.CaptchaSolve{
+		UserID:         userID,
+		AccountID:      captchaAccountFromContext(ctx),
+		Provider:       c.provider.Name,
+		UsedDefaultKey: c.defaultKey,
+		Success:        solveErr == nil,
+		DurationMs:     elapsed.Milliseconds(),
+		SolvedAt:       time.Now(),
+	}
+	if solveErr == nil {
+		entry.Cost = c.provider.CostPerSolve
+	} else {
+		entry.Error = solveErr.Error()
+	}
+
+	if err := database.DB.Create(&entry).Error; err != nil {
+		logger.Log.WithError(err).Errorf("Failed to record %s captcha solve for user %s", c.provider.Name, userID)
+	}
+}
+
+// CaptchaUsage totals a set of ledger entries.
+type CaptchaUsage struct {
+	Solves    int64
+	Successes int64
+	Cost      float64
+}
+
+// CaptchaUsageReport is a user's captcha spend for the current day and month, both in UTC.
+type CaptchaUsageReport struct {
+	Today      CaptchaUsage
+	Month      CaptchaUsage
+	ByProvider map[string]CaptchaUsage // This month's usage per provider.
+	Budget     float64                 // The user's monthly budget, 0 for no limit.
+}
+
+// BudgetReached reports whether this month's spend has used up the budget.
+func (r CaptchaUsageReport) BudgetReached() bool {
+	return r.Budget > 0 && r.Month.Cost >= r.Budget
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+type captchaUsageRow struct {
+	Provider  string
+	Solves    int64
+	Successes int64
+	Cost      float64
+}
+
+func queryCaptchaUsage(userID string, since time.Time) ([]captchaUsageRow, error) {
+	var rows []captchaUsageRow
+	err := database.DB.Model(&models.CaptchaSolve{}).
+		Select("provider, COUNT(*) AS solves, SUM(CASE WHEN success THEN 1 ELSE 0 END) AS successes, COALESCE(SUM(cost), 0) AS cost").
+		Where("user_id = ? AND solved_at >= ?", userID, since).
+		Group("provider").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetCaptchaUsage summarises the user's ledger for today and the current month.
+func GetCaptchaUsage(userID string) (CaptchaUsageReport, error) {
+	settings, err := GetUserSettings(userID)
+	if err != nil {
+		return CaptchaUsageReport{}, err
+	}
+
+	now := time.Now()
+	report := CaptchaUsageReport{
+		ByProvider: make(map[string]CaptchaUsage),
+		Budget:     settings.MonthlyCaptchaBudget,
+	}
+
+	monthRows, err := queryCaptchaUsage(userID, startOfMonth(now))
+	if err != nil {
+		return CaptchaUsageReport{}, fmt.Errorf("failed to load monthly captcha usage: %w", err)
+	}
+	for _, row := range monthRows {
+		usage := CaptchaUsage{Solves: row.Solves, Successes: row.Successes, Cost: row.Cost}
+		report.ByProvider[row.Provider] = usage
+		report.Month.Solves += usage.Solves
+		report.Month.Successes += usage.Successes
+		report.Month.Cost += usage.Cost
+	}
+
+	dayRows, err := queryCaptchaUsage(userID, startOfDay(now))
+	if err != nil {
+		return CaptchaUsageReport{}, fmt.Errorf("failed to load daily captcha usage: %w", err)
+	}
+	for _, row := range dayRows {
+		report.Today.Solves += row.Solves
+		report.Today.Successes += row.Successes
+		report.Today.Cost += row.Cost
+	}
+
+	return report, nil
+}
+
+// monthlyCaptchaSpend returns the user's estimated spend since the start of the month.
+func monthlyCaptchaSpend(userID string) (float64, error) {
+	var spent float64
+	err := database.DB.Model(&models.CaptchaSolve{}).
+		Select("COALESCE(SUM(cost), 0)").
+		Where("user_id = ? AND solved_at >= ?", userID, startOfMonth(time.Now())).
+		Scan(&spent).Error
+	return spent, err
+}
+
+// CaptchaBudgetReached reports whether the user has a monthly budget and has spent it, along with
+// the month's spend so far.
+func CaptchaBudgetReached(settings models.UserSettings) (bool, float64, error) {
+	if settings.MonthlyCaptchaBudget <= 0 {
+		return false, 0, nil
+	}
+	spent, err := monthlyCaptchaSpend(settings.UserID)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to load monthly captcha spend: %w", err)
+	}
+	return spent >= settings.MonthlyCaptchaBudget, spent, nil
+}
+
+// SetMonthlyCaptchaBudget stores the user's monthly budget in USD. A budget of 0 removes the limit.
+func SetMonthlyCaptchaBudget(userID string, budget float64) error {
+	if budget < 0 {
+		return fmt.Errorf("budget cannot be negative")
+	}
+	if _, err := GetUserSettings(userID); err != nil {
+		return err
+	}
+	// Clearing the notification time lets the user hear about the new budget if it is reached again.
+	return database.DB.Model(&models.UserSettings{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"monthly_captcha_budget":   budget,
+			"last_budget_notification": time.Time{},
+		}).Error
+}
+
+// notifyCaptchaBudgetReached tells the user, once per month, that their scheduled checks are paused.
+func notifyCaptchaBudgetReached(s discordapi.Session, account models.Account, settings models.UserSettings, spent float64) {
+	if !settings.LastBudgetNotification.Before(startOfMonth(time.Now())) {
+		return
+	}
+
+	lang := UserLanguage(settings)
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "budget_reached.title"),
+		Description: i18n.T(lang, "budget_reached.description", spent, settings.MonthlyCaptchaBudget),
+		Color:       0xFFA500,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+	// Sent directly rather than through SendNotification: it goes out at most once a month, and a
+	// notification dropped by the rate limiter would leave the user unaware that checks stopped.
+	channelID, err := GetNotificationChannel(s, account, settings)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to get notification channel for captcha budget notification to user %s", settings.UserID)
+		return
+	}
+	if _, err := s.ChannelMessageSendEmbed(channelID, embed); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to send captcha budget notification to user %s", settings.UserID)
+		return
+	}
+
+	if err := database.DB.Model(&models.UserSettings{}).
+		Where("user_id = ?", settings.UserID).
+		Update("last_budget_notification", time.Now()).Error; err != nil {
+		logger.Log.WithError(err).Errorf("Failed to record captcha budget notification for user %s", settings.UserID)
+	}
+}
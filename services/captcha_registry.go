@@ -0,0 +1,440 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/metrics"
+	"github.com/bradselph/CODStatusBot/models"
+)
+
+// CaptchaCapability is a kind of challenge a provider can solve.
+type CaptchaCapability string
+
+const (
+	CapabilityReCaptchaV2           CaptchaCapability = "recaptcha_v2"
+	CapabilityReCaptchaV2Enterprise CaptchaCapability = "recaptcha_v2_enterprise"
+)
+
+// CaptchaProvider describes a solving service. Providers register themselves from init, and
+// everything that used to switch on the provider name looks them up here instead.
+type CaptchaProvider struct {
+	Name         string // Stored in UserSettings.PreferredCaptchaProvider, e.g. "capsolver".
+	DisplayName  string // Shown to users, e.g. "Capsolver".
+	Capabilities []CaptchaCapability
+	CostPerSolve float64 // Estimated USD per reCAPTCHA solve.
+
+	Enabled       func(cfg *configuration.Config) bool
+	DefaultKey    func(cfg *configuration.Config) string
+	BalanceMin    func(cfg *configuration.Config) float64
+	MaxConcurrent func(cfg *configuration.Config) int
+	UserKey       func(settings models.UserSettings) string
+
+	NewSolver   func(apiKey string) CaptchaSolver
+	ValidateKey func(ctx context.Context, apiKey string) (bool, float64, error)
+}
+
+// Supports reports whether the provider can solve capability.
+func (p CaptchaProvider) Supports(capability CaptchaCapability) bool {
+	for _, c := range p.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+var captchaRegistry = struct {
+	sync.RWMutex
+	providers map[string]CaptchaProvider
+	order     []string
+	breakers  map[string]*circuitBreaker
+}{
+	providers: make(map[string]CaptchaProvider),
+	breakers:  make(map[string]*circuitBreaker),
+}
+
+// RegisterCaptchaProvider adds p to the registry, replacing any provider with the same name.
+// Registration order is the default fallback order.
+func RegisterCaptchaProvider(p CaptchaProvider) {
+	captchaRegistry.Lock()
+	defer captchaRegistry.Unlock()
+	if _, ok := captchaRegistry.providers[p.Name]; !ok {
+		captchaRegistry.order = append(captchaRegistry.order, p.Name)
+	}
+	captchaRegistry.providers[p.Name] = p
+	if _, ok := captchaRegistry.breakers[p.Name]; !ok {
+		captchaRegistry.breakers[p.Name] = &circuitBreaker{}
+	}
+}
+
+// LookupCaptchaProvider returns the registered provider called name.
+func LookupCaptchaProvider(name string) (CaptchaProvider, bool) {
+	captchaRegistry.RLock()
+	defer captchaRegistry.RUnlock()
+	p, ok := captchaRegistry.providers[name]
+	return p, ok
+}
+
+// CaptchaProviders returns every registered provider in registration order.
+func CaptchaProviders() []CaptchaProvider {
+	captchaRegistry.RLock()
+	defer captchaRegistry.RUnlock()
+	providers := make([]CaptchaProvider, 0, len(captchaRegistry.order))
+	for _, name := range captchaRegistry.order {
+		providers = append(providers, captchaRegistry.providers[name])
+	}
+	return providers
+}
+
+// EnabledCaptchaProviders returns the providers the operator has switched on, in registration
+// order.
+func EnabledCaptchaProviders() []CaptchaProvider {
+	var enabled []CaptchaProvider
+	for _, p := range CaptchaProviders() {
+		if IsServiceEnabled(p.Name) {
+			enabled = append(enabled, p)
+		}
+	}
+	return enabled
+}
+
+func providerBreaker(name string) *circuitBreaker {
+	captchaRegistry.RLock()
+	defer captchaRegistry.RUnlock()
+	return captchaRegistry.breakers[name]
+}
+
+// CaptchaProviderOrder returns the user's providers in the order a solve tries them: the
+// preferred provider, then the user's saved order, then every other registered provider in
+// registration order. Unknown names are dropped.
+func CaptchaProviderOrder(settings models.UserSettings) []string {
+	seen := make(map[string]bool)
+	var order []string
+	add := func(name string) {
+		if _, ok := LookupCaptchaProvider(name); ok && !seen[name] {
+			seen[name] = true
+			order = append(order, name)
+		}
+	}
+
+	add(settings.PreferredCaptchaProvider)
+	for _, name := range settings.CaptchaProviderOrder {
+		add(name)
+	}
+	for _, p := range CaptchaProviders() {
+		add(p.Name)
+	}
+	return order
+}
+
+// PreferCaptchaProvider moves name to the front of the user's provider order.
+func PreferCaptchaProvider(settings *models.UserSettings, name string) {
+	order := []string{name}
+	for _, existing := range settings.CaptchaProviderOrder {
+		if existing != name {
+			order = append(order, existing)
+		}
+	}
+	settings.PreferredCaptchaProvider = name
+	settings.CaptchaProviderOrder = order
+}
+
+// captchaCandidate is one provider and key a solve may try.
+type captchaCandidate struct {
+	provider   CaptchaProvider
+	apiKey     string
+	defaultKey bool
+}
+
+// captchaCandidates lists what a solve for the user may try. Users who brought their own keys
+// only fail over between those keys; everyone else fails over between the bot's default keys.
+func captchaCandidates(settings models.UserSettings) []captchaCandidate {
+	cfg := configuration.Get()
+
+	var own, defaults []captchaCandidate
+	for _, name := range CaptchaProviderOrder(settings) {
+		p, _ := LookupCaptchaProvider(name)
+		if !p.Enabled(cfg) || !p.Supports(CapabilityReCaptchaV2Enterprise) {
+			continue
+		}
+		if key := p.UserKey(settings); key != "" {
+			own = append(own, captchaCandidate{provider: p, apiKey: key})
+		}
+		if key := p.DefaultKey(cfg); key != "" {
+			defaults = append(defaults, captchaCandidate{provider: p, apiKey: key, defaultKey: true})
+		}
+	}
+	if len(own) > 0 {
+		return own
+	}
+	return defaults
+}
+
+// CaptchaSolution is a solved token and where it came from.
+type CaptchaSolution struct {
+	Token          string
+	Provider       string
+	UsedDefaultKey bool
+}
+
+// SolveCaptcha solves the reCAPTCHA for userID, walking the user's provider order. Providers
+// whose circuit is open are skipped, keys below their provider's minimum balance are tried
+// only after every other candidate, and a provider that errors or exceeds the solve timeout
+// hands over to the next one. The error wraps ErrInsufficientBalance only when every candidate
+// failed for lack of funds.
+func SolveCaptcha(ctx context.Context, userID, siteKey, pageURL string) (CaptchaSolution, error) {
+	settings, err := GetUserSettings(userID)
+	if err != nil {
+		return CaptchaSolution{}, fmt.Errorf("failed to get user settings: %w", err)
+	}
+
+	candidates := captchaCandidates(settings)
+	if len(candidates) == 0 {
+		return CaptchaSolution{}, errors.New("no captcha services are currently enabled")
+	}
+
+	cfg := configuration.Get()
+	var (
+		lowBalance []captchaCandidate
+		failures   []error
+		allBalance = true
+	)
+
+	attempt := func(c captchaCandidate) (string, error) {
+		slot := providerSlot(ctx, c.provider.Name)
+		if !acquire(ctx, slot) {
+			providerBreaker(c.provider.Name).release()
+			return "", ctx.Err()
+		}
+		defer release(slot)
+
+		solveCtx := ctx
+		if timeout := cfg.CaptchaFailover.SolveTimeout; timeout > 0 {
+			var cancel context.CancelFunc
+			solveCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		start := time.Now()
+		token, err := c.provider.NewSolver(c.apiKey).SolveReCaptchaV2(solveCtx, siteKey, pageURL)
+		if err == nil && len(token) < 50 {
+			err = errors.New("invalid captcha response received")
+		}
+		if ctx.Err() != nil {
+			providerBreaker(c.provider.Name).release()
+			return "", ctx.Err()
+		}
+		elapsed := time.Since(start)
+		recordProviderResult(c, err)
+		recordCaptchaSolve(ctx, userID, c, elapsed, err)
+		metrics.ObserveCaptchaSolve(c.provider.Name, err, elapsed)
+		return token, err
+	}
+
+	for pass := 0; pass < 2; pass++ {
+		queue := candidates
+		if pass == 1 {
+			queue = lowBalance
+		}
+
+		for _, c := range queue {
+			if !providerBreaker(c.provider.Name).allow() {
+				failures = append(failures, fmt.Errorf("%s: circuit open", c.provider.Name))
+				allBalance = false
+				continue
+			}
+			if pass == 0 {
+				valid, balance, err := c.provider.ValidateKey(ctx, c.apiKey)
+				if ctx.Err() != nil {
+					providerBreaker(c.provider.Name).release()
+					return CaptchaSolution{}, ctx.Err()
+				}
+				if err != nil {
+					recordProviderResult(c, err)
+					failures = append(failures, fmt.Errorf("%s: %w", c.provider.Name, err))
+					allBalance = false
+					continue
+				}
+				if !valid {
+					providerBreaker(c.provider.Name).release()
+					failures = append(failures, fmt.Errorf("%s: invalid API key", c.provider.Name))
+					allBalance = false
+					continue
+				}
+				if min := c.provider.BalanceMin(cfg); balance < min {
+					logger.Log.Warnf("%s balance %.4f for user %s is below %.4f, trying other providers first", c.provider.Name, balance, userID, min)
+					// Give back a half-open trial; the low-balance pass claims it again.
+					providerBreaker(c.provider.Name).release()
+					lowBalance = append(lowBalance, c)
+					continue
+				}
+			}
+
+			token, err := attempt(c)
+			if err == nil {
+				return CaptchaSolution{Token: token, Provider: c.provider.Name, UsedDefaultKey: c.defaultKey}, nil
+			}
+			if ctx.Err() != nil {
+				return CaptchaSolution{}, ctx.Err()
+			}
+			logger.Log.WithError(err).Warnf("%s failed to solve for user %s, failing over", c.provider.Name, userID)
+			failures = append(failures, fmt.Errorf("%s: %w", c.provider.Name, err))
+			if !errors.Is(err, ErrInsufficientBalance) {
+				allBalance = false
+			}
+		}
+	}
+
+	if allBalance {
+		return CaptchaSolution{}, fmt.Errorf("%w: every captcha provider is out of funds", ErrInsufficientBalance)
+	}
+	return CaptchaSolution{}, fmt.Errorf("all captcha providers failed: %w", errors.Join(failures...))
+}
+
+// recordProviderResult feeds a solve attempt into the provider's circuit breaker. Problems with
+// the key itself say nothing about the provider's health, so only the bot's shared keys count
+// them.
+func recordProviderResult(c captchaCandidate, err error) {
+	breaker := providerBreaker(c.provider.Name)
+	if err == nil {
+		breaker.success()
+		return
+	}
+	if errors.Is(err, ErrInsufficientBalance) && !c.defaultKey {
+		breaker.release()
+		return
+	}
+	if breaker.failure(err) {
+		logger.Log.WithError(err).Warnf("Captcha provider %s failed repeatedly, skipping it for %s", c.provider.Name, configuration.Get().CaptchaFailover.BreakerCooldown)
+	}
+}
+
+// BreakerState is the state of a provider's circuit breaker.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Healthy, solves go through.
+	BreakerOpen     BreakerState = "open"      // Failing, skipped until the cooldown ends.
+	BreakerHalfOpen BreakerState = "half_open" // Cooldown over, one trial solve allowed.
+)
+
+// ProviderHealth is a snapshot of a provider's circuit breaker.
+type ProviderHealth struct {
+	Provider            string
+	State               BreakerState
+	ConsecutiveFailures int
+	Successes           int64
+	Failures            int64
+	LastError           string
+	LastFailure         time.Time
+	OpenUntil           time.Time
+}
+
+// CaptchaProviderHealth reports the health of every registered provider.
+func CaptchaProviderHealth() []ProviderHealth {
+	providers := CaptchaProviders()
+	health := make([]ProviderHealth, 0, len(providers))
+	for _, p := range providers {
+		h := providerBreaker(p.Name).snapshot()
+		h.Provider = p.Name
+		health = append(health, h)
+	}
+	return health
+}
+
+// circuitBreaker stops a provider from being tried after CAPTCHA_BREAKER_THRESHOLD consecutive
+// failures. Once CAPTCHA_BREAKER_COOLDOWN has passed a single trial solve is let through; its
+// result closes the circuit again or restarts the cooldown.
+type circuitBreaker struct {
+	mu                  sync.Mutex
+	consecutiveFailures int
+	openUntil           time.Time
+	trialInFlight       bool
+	successes           int64
+	failures            int64
+	lastError           string
+	lastFailure         time.Time
+}
+
+func (b *circuitBreaker) state(now time.Time) BreakerState {
+	switch {
+	case b.openUntil.IsZero():
+		return BreakerClosed
+	case now.Before(b.openUntil):
+		return BreakerOpen
+	default:
+		return BreakerHalfOpen
+	}
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state(time.Now()) {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.trialInFlight {
+			return false
+		}
+		b.trialInFlight = true
+	}
+	return true
+}
+
+// release gives back a half-open trial that was never used for a solve.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialInFlight = false
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.successes++
+	b.consecutiveFailures = 0
+	b.openUntil = time.Time{}
+	b.trialInFlight = false
+}
+
+// failure records a failed solve and reports whether it opened the circuit.
+func (b *circuitBreaker) failure(err error) bool {
+	cfg := configuration.Get()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.failures++
+	b.consecutiveFailures++
+	b.lastError = err.Error()
+	b.lastFailure = now
+
+	wasHalfOpen := b.state(now) == BreakerHalfOpen
+	b.trialInFlight = false
+	if wasHalfOpen || b.consecutiveFailures >= max(cfg.CaptchaFailover.BreakerThreshold, 1) {
+		b.openUntil = now.Add(cfg.CaptchaFailover.BreakerCooldown)
+		return true
+	}
+	return false
+}
+
+func (b *circuitBreaker) snapshot() ProviderHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return ProviderHealth{
+		State:               b.state(time.Now()),
+		ConsecutiveFailures: b.consecutiveFailures,
+		Successes:           b.successes,
+		Failures:            b.failures,
+		LastError:           b.lastError,
+		LastFailure:         b.lastFailure,
+		OpenUntil:           b.openUntil,
+	}
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/testsupport/fakecaptcha"
+)
+
+// startCaptchaFakes enables exactly the given providers, each backed by its own fake server, and
+// gives every provider a fresh circuit breaker for the rest of the test.
+func startCaptchaFakes(t *testing.T, protocols ...fakecaptcha.Protocol) map[fakecaptcha.Protocol]*fakecaptcha.Server {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := *cfg
+	cfg.CaptchaService.Capsolver.Enabled = false
+	cfg.CaptchaService.EZCaptcha.Enabled = false
+	cfg.CaptchaService.TwoCaptcha.Enabled = false
+	cfg.CaptchaService.AntiCaptcha.Enabled = false
+	cfg.CaptchaService.CapMonster.Enabled = false
+	cfg.CaptchaFailover.SolveTimeout = 5 * time.Second
+	cfg.CaptchaFailover.BreakerThreshold = 1
+	cfg.CaptchaFailover.BreakerCooldown = time.Minute
+
+	servers := make(map[fakecaptcha.Protocol]*fakecaptcha.Server)
+	for _, protocol := range protocols {
+		srv := fakecaptcha.New(protocol)
+		srv.Apply(cfg)
+		servers[protocol] = srv
+	}
+
+	captchaRegistry.Lock()
+	savedBreakers := captchaRegistry.breakers
+	captchaRegistry.breakers = make(map[string]*circuitBreaker)
+	for name := range captchaRegistry.providers {
+		captchaRegistry.breakers[name] = &circuitBreaker{}
+	}
+	captchaRegistry.Unlock()
+
+	t.Cleanup(func() {
+		for _, srv := range servers {
+			srv.Close()
+		}
+		*cfg = saved
+		captchaRegistry.Lock()
+		captchaRegistry.breakers = savedBreakers
+		captchaRegistry.Unlock()
+	})
+	return servers
+}
+
+func candidateNames(candidates []captchaCandidate) []string {
+	var names []string
+	for _, c := range candidates {
+		name := c.provider.Name
+		if c.defaultKey {
+			name += "(default)"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestCaptchaCandidates(t *testing.T) {
+	startCaptchaFakes(t, fakecaptcha.Capsolver, fakecaptcha.TwoCaptcha, fakecaptcha.AntiCaptcha)
+
+	tests := []struct {
+		name     string
+		settings models.UserSettings
+		want     []string
+	}{
+		{
+			name:     "default keys in registration order",
+			settings: models.UserSettings{PreferredCaptchaProvider: "capsolver"},
+			want:     []string{"capsolver(default)", "2captcha(default)", "anticaptcha(default)"},
+		},
+		{
+			name:     "preferred provider first",
+			settings: models.UserSettings{PreferredCaptchaProvider: "anticaptcha"},
+			want:     []string{"anticaptcha(default)", "capsolver(default)", "2captcha(default)"},
+		},
+		{
+			name:     "own 2captcha key only",
+			settings: models.UserSettings{PreferredCaptchaProvider: "2captcha", TwoCaptchaAPIKey: "own-key"},
+			want:     []string{"2captcha"},
+		},
+		{
+			name: "own keys in the saved order",
+			settings: models.UserSettings{
+				PreferredCaptchaProvider: "capsolver",
+				CaptchaProviderOrder:     []string{"capsolver", "anticaptcha", "2captcha"},
+				CapSolverAPIKey:          "own-capsolver",
+				TwoCaptchaAPIKey:         "own-2captcha",
+				AntiCaptchaAPIKey:        "own-anticaptcha",
+			},
+			want: []string{"capsolver", "anticaptcha", "2captcha"},
+		},
+		{
+			name:     "own key for a disabled provider",
+			settings: models.UserSettings{PreferredCaptchaProvider: "capmonster", CapMonsterAPIKey: "own-key"},
+			want:     []string{"capsolver(default)", "2captcha(default)", "anticaptcha(default)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := candidateNames(captchaCandidates(tt.settings)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("candidates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// saveSettings stores settings for userID so SolveCaptcha picks them up.
+func saveSettings(t *testing.T, settings models.UserSettings) {
+	t.Helper()
+	if err := database.DB.Create(&settings).Error; err != nil {
+		t.Fatalf("create user settings: %v", err)
+	}
+}
+
+func TestSolveCaptchaFailover(t *testing.T) {
+	const siteKey, pageURL = "site-key", "https://support.activision.com"
+
+	tests := []struct {
+		name         string
+		settings     models.UserSettings
+		setup        func(cfg *configuration.Config, fakes map[fakecaptcha.Protocol]*fakecaptcha.Server)
+		wantProvider string
+		wantDefault  bool
+		wantCreates  map[fakecaptcha.Protocol]int
+	}{
+		{
+			name:         "preferred provider solves",
+			settings:     models.UserSettings{PreferredCaptchaProvider: "capsolver"},
+			wantProvider: "capsolver",
+			wantDefault:  true,
+			wantCreates:  map[fakecaptcha.Protocol]int{fakecaptcha.Capsolver: 1, fakecaptcha.TwoCaptcha: 0},
+		},
+		{
+			name:     "fails over when the preferred provider cannot solve",
+			settings: models.UserSettings{PreferredCaptchaProvider: "capsolver"},
+			setup: func(cfg *configuration.Config, fakes map[fakecaptcha.Protocol]*fakecaptcha.Server) {
+				fakes[fakecaptcha.Capsolver].SetUnsolvable(true)
+			},
+			wantProvider: "2captcha",
+			wantDefault:  true,
+			wantCreates:  map[fakecaptcha.Protocol]int{fakecaptcha.Capsolver: 1, fakecaptcha.TwoCaptcha: 1},
+		},
+		{
+			name:     "low balance is tried after the others",
+			settings: models.UserSettings{PreferredCaptchaProvider: "capsolver"},
+			setup: func(cfg *configuration.Config, fakes map[fakecaptcha.Protocol]*fakecaptcha.Server) {
+				cfg.CaptchaService.Capsolver.BalanceMin = 1
+				fakes[fakecaptcha.Capsolver].AddKey(fakecaptcha.DefaultKey, 0.5)
+			},
+			wantProvider: "2captcha",
+			wantDefault:  true,
+			wantCreates:  map[fakecaptcha.Protocol]int{fakecaptcha.Capsolver: 0, fakecaptcha.TwoCaptcha: 1},
+		},
+		{
+			name:     "low balance is the last resort",
+			settings: models.UserSettings{PreferredCaptchaProvider: "capsolver"},
+			setup: func(cfg *configuration.Config, fakes map[fakecaptcha.Protocol]*fakecaptcha.Server) {
+				cfg.CaptchaService.Capsolver.BalanceMin = 1
+				fakes[fakecaptcha.Capsolver].AddKey(fakecaptcha.DefaultKey, 0.5)
+				fakes[fakecaptcha.TwoCaptcha].SetUnsolvable(true)
+			},
+			wantProvider: "capsolver",
+			wantDefault:  true,
+			wantCreates:  map[fakecaptcha.Protocol]int{fakecaptcha.Capsolver: 1, fakecaptcha.TwoCaptcha: 1},
+		},
+		{
+			name:     "own 2captcha key",
+			settings: models.UserSettings{PreferredCaptchaProvider: "2captcha", TwoCaptchaAPIKey: "own-key"},
+			setup: func(cfg *configuration.Config, fakes map[fakecaptcha.Protocol]*fakecaptcha.Server) {
+				fakes[fakecaptcha.TwoCaptcha].AddKey("own-key", 5)
+			},
+			wantProvider: "2captcha",
+			wantCreates:  map[fakecaptcha.Protocol]int{fakecaptcha.Capsolver: 0, fakecaptcha.TwoCaptcha: 1},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestDatabase(t)
+			fakes := startCaptchaFakes(t, fakecaptcha.Capsolver, fakecaptcha.TwoCaptcha)
+			if tt.setup != nil {
+				tt.setup(configuration.Get(), fakes)
+			}
+			tt.settings.UserID = string(rune('a' + i))
+			saveSettings(t, tt.settings)
+
+			solution, err := SolveCaptcha(context.Background(), tt.settings.UserID, siteKey, pageURL)
+			if err != nil {
+				t.Fatalf("SolveCaptcha: %v", err)
+			}
+			if solution.Provider != tt.wantProvider || solution.UsedDefaultKey != tt.wantDefault {
+				t.Errorf("solved by %s (default key %v), want %s (default key %v)",
+					solution.Provider, solution.UsedDefaultKey, tt.wantProvider, tt.wantDefault)
+			}
+			for protocol, want := range tt.wantCreates {
+				if got := fakes[protocol].Count(fakecaptcha.CreatePath); got != want {
+					t.Errorf("%s created %d tasks, want %d", protocol, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTwoCaptchaSolvesEnterpriseTasks(t *testing.T) {
+	useTestDatabase(t)
+	fakes := startCaptchaFakes(t, fakecaptcha.TwoCaptcha)
+	saveSettings(t, models.UserSettings{UserID: "1", PreferredCaptchaProvider: "2captcha"})
+
+	if _, err := SolveCaptcha(context.Background(), "1", "site-key", "https://support.activision.com"); err != nil {
+		t.Fatalf("SolveCaptcha: %v", err)
+	}
+	for _, r := range fakes[fakecaptcha.TwoCaptcha].Requests() {
+		if r.Path == fakecaptcha.CreatePath && r.TaskType != "RecaptchaV2EnterpriseTaskProxyless" {
+			t.Errorf("2Captcha task type = %q, want RecaptchaV2EnterpriseTaskProxyless", r.TaskType)
+		}
+	}
+}
+
+func TestSolveCaptchaSkipsOpenCircuit(t *testing.T) {
+	useTestDatabase(t)
+	fakes := startCaptchaFakes(t, fakecaptcha.Capsolver, fakecaptcha.TwoCaptcha)
+	fakes[fakecaptcha.Capsolver].SetUnsolvable(true)
+	saveSettings(t, models.UserSettings{UserID: "1", PreferredCaptchaProvider: "capsolver"})
+
+	for i := 0; i < 2; i++ {
+		solution, err := SolveCaptcha(context.Background(), "1", "site-key", "https://support.activision.com")
+		if err != nil || solution.Provider != "2captcha" {
+			t.Fatalf("solve %d = %+v, %v; want 2captcha", i+1, solution, err)
+		}
+	}
+	if got := fakes[fakecaptcha.Capsolver].Count(fakecaptcha.CreatePath); got != 1 {
+		t.Errorf("capsolver created %d tasks, want 1 before its circuit opened", got)
+	}
+	if state := providerBreaker("capsolver").snapshot().State; state != BreakerOpen {
+		t.Errorf("capsolver breaker is %s, want open", state)
+	}
+}
+
+func TestSolveCaptchaReleasesHalfOpenTrial(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(cfg *configuration.Config, fakes map[fakecaptcha.Protocol]*fakecaptcha.Server)
+		user  models.UserSettings
+	}{
+		{
+			name: "low balance candidate passed over",
+			setup: func(cfg *configuration.Config, fakes map[fakecaptcha.Protocol]*fakecaptcha.Server) {
+				cfg.CaptchaService.Capsolver.BalanceMin = 1
+				fakes[fakecaptcha.Capsolver].AddKey(fakecaptcha.DefaultKey, 0.5)
+			},
+			user: models.UserSettings{UserID: "1", PreferredCaptchaProvider: "capsolver"},
+		},
+		{
+			name: "own key out of funds",
+			setup: func(cfg *configuration.Config, fakes map[fakecaptcha.Protocol]*fakecaptcha.Server) {
+				fakes[fakecaptcha.Capsolver].AddKey("own-capsolver", 0)
+				fakes[fakecaptcha.TwoCaptcha].AddKey("own-2captcha", 5)
+			},
+			user: models.UserSettings{
+				UserID:                   "1",
+				PreferredCaptchaProvider: "capsolver",
+				CapSolverAPIKey:          "own-capsolver",
+				TwoCaptchaAPIKey:         "own-2captcha",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestDatabase(t)
+			fakes := startCaptchaFakes(t, fakecaptcha.Capsolver, fakecaptcha.TwoCaptcha)
+			tt.setup(configuration.Get(), fakes)
+			saveSettings(t, tt.user)
+
+			breaker := providerBreaker("capsolver")
+			breaker.openUntil = time.Now().Add(-time.Second)
+
+			solution, err := SolveCaptcha(context.Background(), "1", "site-key", "https://support.activision.com")
+			if err != nil || solution.Provider != "2captcha" {
+				t.Fatalf("SolveCaptcha = %+v, %v; want 2captcha", solution, err)
+			}
+			if !breaker.allow() {
+				t.Error("capsolver's half-open trial was not released")
+			}
+		})
+	}
+}
+
+func TestSolveCaptchaAllOutOfFunds(t *testing.T) {
+	useTestDatabase(t)
+	fakes := startCaptchaFakes(t, fakecaptcha.Capsolver, fakecaptcha.TwoCaptcha)
+	fakes[fakecaptcha.Capsolver].AddKey(fakecaptcha.DefaultKey, 0)
+	fakes[fakecaptcha.TwoCaptcha].AddKey(fakecaptcha.DefaultKey, 0)
+	saveSettings(t, models.UserSettings{UserID: "1", PreferredCaptchaProvider: "capsolver"})
+
+	_, err := SolveCaptcha(context.Background(), "1", "site-key", "https://support.activision.com")
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("err = %v, want ErrInsufficientBalance", err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	cfg := configuration.Get()
+	saved := cfg.CaptchaFailover
+	t.Cleanup(func() { cfg.CaptchaFailover = saved })
+	cfg.CaptchaFailover.BreakerThreshold = 2
+	cfg.CaptchaFailover.BreakerCooldown = time.Minute
+
+	b := &circuitBreaker{}
+	failure := errors.New("provider unavailable")
+	now := time.Now()
+
+	if !b.allow() || b.state(now) != BreakerClosed {
+		t.Fatal("new breaker is not closed")
+	}
+	if b.failure(failure) {
+		t.Fatal("breaker opened before reaching the threshold")
+	}
+	if !b.failure(failure) || b.state(time.Now()) != BreakerOpen {
+		t.Fatal("breaker did not open at the threshold")
+	}
+	if b.allow() {
+		t.Fatal("open breaker allowed a solve")
+	}
+
+	b.openUntil = time.Now().Add(-time.Second)
+	if b.state(time.Now()) != BreakerHalfOpen {
+		t.Fatalf("state after cooldown = %s, want half_open", b.state(time.Now()))
+	}
+	if !b.allow() {
+		t.Fatal("half-open breaker refused the trial solve")
+	}
+	if b.allow() {
+		t.Fatal("half-open breaker allowed a second trial while one is in flight")
+	}
+	b.release()
+	if !b.allow() {
+		t.Fatal("released trial was not handed out again")
+	}
+	if !b.failure(failure) || b.state(time.Now()) != BreakerOpen {
+		t.Fatal("failed trial did not reopen the circuit")
+	}
+
+	b.openUntil = time.Now().Add(-time.Second)
+	if !b.allow() {
+		t.Fatal("half-open breaker refused the trial solve")
+	}
+	b.success()
+	health := b.snapshot()
+	if health.State != BreakerClosed || health.ConsecutiveFailures != 0 {
+		t.Fatalf("after a successful trial: %+v, want closed with no failures", health)
+	}
+	if health.Successes != 1 || health.Failures != 3 || health.LastError != failure.Error() {
+		t.Errorf("counters = %+v", health)
+	}
+	if !b.allow() || !b.allow() {
+		t.Error("closed breaker limited solves")
+	}
+}
@@ -2,32 +2,37 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
 )
 
-const (
-	CapsolverCreateEndpoint  = "https://api.capsolver.com/createTask"
-	CapsolverResultEndpoint  = "https://api.capsolver.com/getTaskResult"
-	EZCaptchaCreateEndpoint  = "https://api.ez-captcha.com/createTask"
-	EZCaptchaResultEndpoint  = "https://api.ez-captcha.com/getTaskResult"
-	TwoCaptchaCreateEndpoint = "https://api.2captcha.com/createTask"
-	TwoCaptchaResultEndpoint = "https://api.2captcha.com/getTaskResult"
-	MaxRetries               = 6
-	RetryInterval            = 10 * time.Second
-)
+// ErrInsufficientBalance is returned when the provider refuses a task because the key has run
+// out of funds.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// balanceError maps a provider error to ErrInsufficientBalance when it means the key is out of
+// funds, and returns nil otherwise.
+func balanceError(code, description string) error {
+	if code == "ERROR_ZERO_BALANCE" || strings.Contains(strings.ToLower(description), "insufficient balance") {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
 
 type CaptchaSolver interface {
-	SolveReCaptchaV2(siteKey, pageURL string) (string, error)
+	SolveReCaptchaV2(ctx context.Context, siteKey, pageURL string) (string, error)
 }
 type CapsolverSolver struct {
 	APIKey string
@@ -44,18 +49,100 @@ type TwoCaptchaSolver struct {
 	SoftID string
 }
 
+type AntiCaptchaSolver struct {
+	APIKey string
+	SoftID string
+}
+
+type CapMonsterSolver struct {
+	APIKey string
+}
+
+func init() {
+	RegisterCaptchaProvider(CaptchaProvider{
+		Name:          string(models.Capsolver),
+		DisplayName:   "Capsolver",
+		Capabilities:  []CaptchaCapability{CapabilityReCaptchaV2, CapabilityReCaptchaV2Enterprise},
+		CostPerSolve:  0.0008,
+		Enabled:       func(cfg *configuration.Config) bool { return cfg.CaptchaService.Capsolver.Enabled },
+		DefaultKey:    func(cfg *configuration.Config) string { return cfg.CaptchaService.Capsolver.ClientKey },
+		BalanceMin:    func(cfg *configuration.Config) float64 { return cfg.CaptchaService.Capsolver.BalanceMin },
+		MaxConcurrent: func(cfg *configuration.Config) int { return cfg.CaptchaService.Capsolver.MaxConcurrent },
+		UserKey:       func(settings models.UserSettings) string { return settings.CapSolverAPIKey },
+		NewSolver: func(apiKey string) CaptchaSolver {
+			return &CapsolverSolver{APIKey: apiKey, AppID: configuration.Get().CaptchaService.Capsolver.AppID}
+		},
+		ValidateKey: validateCapsolverKey,
+	})
+	RegisterCaptchaProvider(CaptchaProvider{
+		Name:          string(models.EZCaptcha),
+		DisplayName:   "EZCaptcha",
+		Capabilities:  []CaptchaCapability{CapabilityReCaptchaV2, CapabilityReCaptchaV2Enterprise},
+		CostPerSolve:  0.0006,
+		Enabled:       func(cfg *configuration.Config) bool { return cfg.CaptchaService.EZCaptcha.Enabled },
+		DefaultKey:    func(cfg *configuration.Config) string { return cfg.CaptchaService.EZCaptcha.ClientKey },
+		BalanceMin:    func(cfg *configuration.Config) float64 { return cfg.CaptchaService.EZCaptcha.BalanceMin },
+		MaxConcurrent: func(cfg *configuration.Config) int { return cfg.CaptchaService.EZCaptcha.MaxConcurrent },
+		UserKey:       func(settings models.UserSettings) string { return settings.EZCaptchaAPIKey },
+		NewSolver: func(apiKey string) CaptchaSolver {
+			return &EZCaptchaSolver{APIKey: apiKey, EzappID: configuration.Get().CaptchaService.EZCaptcha.AppID}
+		},
+		ValidateKey: validateEZCaptchaKey,
+	})
+	RegisterCaptchaProvider(CaptchaProvider{
+		Name:          string(models.TwoCaptcha),
+		DisplayName:   "2Captcha",
+		Capabilities:  []CaptchaCapability{CapabilityReCaptchaV2, CapabilityReCaptchaV2Enterprise},
+		CostPerSolve:  0.00299,
+		Enabled:       func(cfg *configuration.Config) bool { return cfg.CaptchaService.TwoCaptcha.Enabled },
+		DefaultKey:    func(cfg *configuration.Config) string { return cfg.CaptchaService.TwoCaptcha.ClientKey },
+		BalanceMin:    func(cfg *configuration.Config) float64 { return cfg.CaptchaService.TwoCaptcha.BalanceMin },
+		MaxConcurrent: func(cfg *configuration.Config) int { return cfg.CaptchaService.TwoCaptcha.MaxConcurrent },
+		UserKey:       func(settings models.UserSettings) string { return settings.TwoCaptchaAPIKey },
+		NewSolver: func(apiKey string) CaptchaSolver {
+			return &TwoCaptchaSolver{APIKey: apiKey, SoftID: configuration.Get().CaptchaService.TwoCaptcha.SoftID}
+		},
+		ValidateKey: validate2CaptchaKey,
+	})
+	RegisterCaptchaProvider(CaptchaProvider{
+		Name:          string(models.AntiCaptcha),
+		DisplayName:   "Anti-Captcha",
+		Capabilities:  []CaptchaCapability{CapabilityReCaptchaV2, CapabilityReCaptchaV2Enterprise},
+		CostPerSolve:  0.002,
+		Enabled:       func(cfg *configuration.Config) bool { return cfg.CaptchaService.AntiCaptcha.Enabled },
+		DefaultKey:    func(cfg *configuration.Config) string { return cfg.CaptchaService.AntiCaptcha.ClientKey },
+		BalanceMin:    func(cfg *configuration.Config) float64 { return cfg.CaptchaService.AntiCaptcha.BalanceMin },
+		MaxConcurrent: func(cfg *configuration.Config) int { return cfg.CaptchaService.AntiCaptcha.MaxConcurrent },
+		UserKey:       func(settings models.UserSettings) string { return settings.AntiCaptchaAPIKey },
+		NewSolver: func(apiKey string) CaptchaSolver {
+			return &AntiCaptchaSolver{APIKey: apiKey, SoftID: configuration.Get().CaptchaService.AntiCaptcha.SoftID}
+		},
+		ValidateKey: validateAntiCaptchaKey,
+	})
+	RegisterCaptchaProvider(CaptchaProvider{
+		Name:          string(models.CapMonster),
+		DisplayName:   "CapMonster Cloud",
+		Capabilities:  []CaptchaCapability{CapabilityReCaptchaV2, CapabilityReCaptchaV2Enterprise},
+		CostPerSolve:  0.0008,
+		Enabled:       func(cfg *configuration.Config) bool { return cfg.CaptchaService.CapMonster.Enabled },
+		DefaultKey:    func(cfg *configuration.Config) string { return cfg.CaptchaService.CapMonster.ClientKey },
+		BalanceMin:    func(cfg *configuration.Config) float64 { return cfg.CaptchaService.CapMonster.BalanceMin },
+		MaxConcurrent: func(cfg *configuration.Config) int { return cfg.CaptchaService.CapMonster.MaxConcurrent },
+		UserKey:       func(settings models.UserSettings) string { return settings.CapMonsterAPIKey },
+		NewSolver: func(apiKey string) CaptchaSolver {
+			return &CapMonsterSolver{APIKey: apiKey}
+		},
+		ValidateKey: validateCapMonsterKey,
+	})
+}
+
 func IsServiceEnabled(provider string) bool {
-	cfg := configuration.Get()
-	switch provider {
-	case "capsolver":
-		return cfg.CaptchaService.Capsolver.Enabled && cfg.CaptchaService.Capsolver.ClientKey != ""
-	case "ezcaptcha":
-		return cfg.CaptchaService.EZCaptcha.Enabled && cfg.CaptchaService.EZCaptcha.ClientKey != ""
-	case "2captcha":
-		return cfg.CaptchaService.TwoCaptcha.Enabled && cfg.CaptchaService.TwoCaptcha.ClientKey != ""
-	default:
+	p, ok := LookupCaptchaProvider(provider)
+	if !ok {
 		return false
 	}
+	cfg := configuration.Get()
+	return p.Enabled(cfg) && p.DefaultKey(cfg) != ""
 }
 
 func VerifyEZCaptchaConfig() bool {
@@ -81,58 +168,41 @@ func VerifyEZCaptchaConfig() bool {
 }
 
 func NewCaptchaSolver(apiKey, provider string) (CaptchaSolver, error) {
-	cfg := configuration.Get()
-
+	p, ok := LookupCaptchaProvider(provider)
+	if !ok {
+		return nil, errors.New("unsupported captcha provider")
+	}
 	if !IsServiceEnabled(provider) {
 		return nil, fmt.Errorf("captcha service %s is currently disabled", provider)
 	}
-
-	switch provider {
-	case "capsolver":
-		return &CapsolverSolver{
-			APIKey: apiKey,
-			AppID:  cfg.CaptchaService.Capsolver.AppID,
-		}, nil
-	case "ezcaptcha":
-		return &EZCaptchaSolver{
-			APIKey:  apiKey,
-			EzappID: cfg.CaptchaService.EZCaptcha.AppID,
-		}, nil
-	case "2captcha":
-		return &TwoCaptchaSolver{
-			APIKey: apiKey,
-			SoftID: cfg.CaptchaService.TwoCaptcha.SoftID,
-		}, nil
-	default:
-		return nil, errors.New("unsupported captcha provider")
-	}
+	return p.NewSolver(apiKey), nil
 }
 
-func (s *CapsolverSolver) SolveReCaptchaV2(siteKey, pageURL string) (string, error) {
-	taskID, err := s.createTask(siteKey, pageURL)
+func (s *CapsolverSolver) SolveReCaptchaV2(ctx context.Context, siteKey, pageURL string) (string, error) {
+	taskID, err := s.createTask(ctx, siteKey, pageURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to create capsolver task: %w", err)
 	}
-	return s.getTaskResult(taskID)
+	return s.getTaskResult(ctx, taskID)
 }
-func (s *EZCaptchaSolver) SolveReCaptchaV2(siteKey, pageURL string) (string, error) {
+func (s *EZCaptchaSolver) SolveReCaptchaV2(ctx context.Context, siteKey, pageURL string) (string, error) {
 
-	taskID, err := s.createTask(siteKey, pageURL)
+	taskID, err := s.createTask(ctx, siteKey, pageURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to create captcha task: %w", err)
 	}
-	return s.getTaskResult(taskID)
+	return s.getTaskResult(ctx, taskID)
 }
 
-func (s *TwoCaptchaSolver) SolveReCaptchaV2(siteKey, pageURL string) (string, error) {
-	taskID, err := s.createTask(siteKey, pageURL)
+func (s *TwoCaptchaSolver) SolveReCaptchaV2(ctx context.Context, siteKey, pageURL string) (string, error) {
+	taskID, err := s.createTask(ctx, siteKey, pageURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to create captcha task: %w", err)
 	}
-	return s.getTaskResult(taskID)
+	return s.getTaskResult(ctx, taskID)
 }
 
-func (s *CapsolverSolver) createTask(siteKey, pageURL string) (string, error) {
+func (s *CapsolverSolver) createTask(ctx context.Context, siteKey, pageURL string) (string, error) {
 
 	if s.AppID == "" {
 		return "", fmt.Errorf("AppID is not configured")
@@ -149,7 +219,7 @@ func (s *CapsolverSolver) createTask(siteKey, pageURL string) (string, error) {
 		},
 	}
 
-	resp, err := sendRequest(CapsolverCreateEndpoint, payload)
+	resp, err := sendRequest(ctx, configuration.Get().CaptchaEndpoints.Capsolver.Create, payload)
 	if err != nil {
 		return "", err
 	}
@@ -166,13 +236,16 @@ func (s *CapsolverSolver) createTask(siteKey, pageURL string) (string, error) {
 	}
 
 	if result.ErrorId != 0 {
+		if err := balanceError(result.ErrorCode, result.ErrorDescription); err != nil {
+			return "", err
+		}
 		return "", fmt.Errorf("capsolver API error: %s - %s", result.ErrorCode, result.ErrorDescription)
 	}
 
 	return result.TaskId, nil
 }
 
-func (s *EZCaptchaSolver) createTask(siteKey, pageURL string) (string, error) {
+func (s *EZCaptchaSolver) createTask(ctx context.Context, siteKey, pageURL string) (string, error) {
 
 	if s.EzappID == "" {
 		return "", fmt.Errorf("EzappID is not configured")
@@ -189,7 +262,7 @@ func (s *EZCaptchaSolver) createTask(siteKey, pageURL string) (string, error) {
 		},
 	}
 
-	resp, err := sendRequest(EZCaptchaCreateEndpoint, payload)
+	resp, err := sendRequest(ctx, configuration.Get().CaptchaEndpoints.EZCaptcha.Create, payload)
 	if err != nil {
 		return "", err
 	}
@@ -206,13 +279,16 @@ func (s *EZCaptchaSolver) createTask(siteKey, pageURL string) (string, error) {
 	}
 
 	if result.ErrorId != 0 {
+		if err := balanceError(result.ErrorCode, result.ErrorDescription); err != nil {
+			return "", err
+		}
 		return "", fmt.Errorf("API error: %s - %s", result.ErrorCode, result.ErrorDescription)
 	}
 
 	return result.TaskId, nil
 }
 
-func (s *TwoCaptchaSolver) createTask(siteKey, pageURL string) (string, error) {
+func (s *TwoCaptchaSolver) createTask(ctx context.Context, siteKey, pageURL string) (string, error) {
 
 	if s.SoftID == "" {
 		return "", fmt.Errorf("SoftID is not configured")
@@ -222,13 +298,13 @@ func (s *TwoCaptchaSolver) createTask(siteKey, pageURL string) (string, error) {
 		"clientKey": s.APIKey,
 		"softId":    s.SoftID,
 		"task": map[string]interface{}{
-			"type":       "RecaptchaV2TaskProxyless",
+			"type":       "RecaptchaV2EnterpriseTaskProxyless",
 			"websiteURL": pageURL,
 			"websiteKey": siteKey,
 		},
 	}
 
-	resp, err := sendRequest(TwoCaptchaCreateEndpoint, payload)
+	resp, err := sendRequest(ctx, configuration.Get().CaptchaEndpoints.TwoCaptcha.Create, payload)
 	if err != nil {
 		return "", err
 	}
@@ -247,13 +323,16 @@ func (s *TwoCaptchaSolver) createTask(siteKey, pageURL string) (string, error) {
 	}
 
 	if result.ErrorId != 0 {
+		if err := balanceError(result.ErrorCode, result.ErrorDescription); err != nil {
+			return "", err
+		}
 		return "", fmt.Errorf("API error creating task: %s - %s", result.ErrorCode, result.ErrorDescription)
 	}
 
 	return fmt.Sprintf("%d", result.TaskId), nil
 }
 
-func (s *CapsolverSolver) getTaskResult(taskID string) (string, error) {
+func (s *CapsolverSolver) getTaskResult(ctx context.Context, taskID string) (string, error) {
 	cfg := configuration.Get()
 	MaxRetries := cfg.CaptchaService.Capsolver.MaxRetries
 	RetryInterval := cfg.CaptchaService.Capsolver.RetryInterval
@@ -264,7 +343,7 @@ func (s *CapsolverSolver) getTaskResult(taskID string) (string, error) {
 			"taskId":    taskID,
 		}
 
-		resp, err := sendRequest(CapsolverResultEndpoint, payload)
+		resp, err := sendRequest(ctx, cfg.CaptchaEndpoints.Capsolver.Result, payload)
 		if err != nil {
 			return "", err
 		}
@@ -284,13 +363,15 @@ func (s *CapsolverSolver) getTaskResult(taskID string) (string, error) {
 		}
 
 		if result.ErrorId != 0 {
-			if strings.Contains(result.ErrorDescription, "insufficient balance") {
-				return "", fmt.Errorf("insufficient balance")
+			if err := balanceError(result.ErrorCode, result.ErrorDescription); err != nil {
+				return "", err
 			}
 			if i == MaxRetries-1 {
 				return "", fmt.Errorf("capsolver API error after %d retries: %s - %s", MaxRetries, result.ErrorCode, result.ErrorDescription)
 			}
-			time.Sleep(RetryInterval)
+			if err := sleepContext(ctx, RetryInterval); err != nil {
+				return "", err
+			}
 			continue
 		}
 
@@ -301,21 +382,27 @@ func (s *CapsolverSolver) getTaskResult(taskID string) (string, error) {
 			return result.Solution.GRecaptchaResponse, nil
 		}
 
-		time.Sleep(RetryInterval)
+		if err := sleepContext(ctx, RetryInterval); err != nil {
+			return "", err
+		}
 	}
 
 	return "", fmt.Errorf("max retries reached (%d) waiting for capsolver result", MaxRetries)
 
 }
 
-func (s *EZCaptchaSolver) getTaskResult(taskID string) (string, error) {
+func (s *EZCaptchaSolver) getTaskResult(ctx context.Context, taskID string) (string, error) {
+	cfg := configuration.Get()
+	MaxRetries := cfg.CaptchaEndpoints.MaxRetries
+	RetryInterval := cfg.CaptchaEndpoints.RetryInterval
+
 	for i := 0; i < MaxRetries; i++ {
 		payload := map[string]interface{}{
 			"clientKey": s.APIKey,
 			"taskId":    taskID,
 		}
 
-		resp, err := sendRequest(EZCaptchaResultEndpoint, payload)
+		resp, err := sendRequest(ctx, cfg.CaptchaEndpoints.EZCaptcha.Result, payload)
 		if err != nil {
 			return "", err
 		}
@@ -334,13 +421,15 @@ func (s *EZCaptchaSolver) getTaskResult(taskID string) (string, error) {
 		}
 
 		if result.ErrorId != 0 {
-			if strings.Contains(result.ErrorDescription, "insufficient balance") {
-				return "", fmt.Errorf("insufficient balance")
+			if err := balanceError(result.ErrorCode, result.ErrorDescription); err != nil {
+				return "", err
 			}
 			if i == MaxRetries-1 {
 				return "", fmt.Errorf("API error after %d retries: %s - %s", MaxRetries, result.ErrorCode, result.ErrorDescription)
 			}
-			time.Sleep(RetryInterval)
+			if err := sleepContext(ctx, RetryInterval); err != nil {
+				return "", err
+			}
 			continue
 		}
 
@@ -351,28 +440,36 @@ func (s *EZCaptchaSolver) getTaskResult(taskID string) (string, error) {
 			return result.Solution.GRecaptchaResponse, nil
 		}
 
-		time.Sleep(RetryInterval)
+		if err := sleepContext(ctx, RetryInterval); err != nil {
+			return "", err
+		}
 	}
 
 	return "", errors.New("max retries reached waiting for result")
 }
 
-func (s *TwoCaptchaSolver) getTaskResult(taskID string) (string, error) {
+func (s *TwoCaptchaSolver) getTaskResult(ctx context.Context, taskID string) (string, error) {
+	cfg := configuration.Get()
+	MaxRetries := cfg.CaptchaEndpoints.MaxRetries
+	RetryInterval := cfg.CaptchaEndpoints.RetryInterval
+
 	for i := 0; i < MaxRetries; i++ {
 		payload := map[string]interface{}{
 			"clientKey": s.APIKey,
 			"taskId":    taskID,
 		}
 
-		resp, err := sendRequest(TwoCaptchaResultEndpoint, payload)
+		resp, err := sendRequest(ctx, cfg.CaptchaEndpoints.TwoCaptcha.Result, payload)
 		if err != nil {
 			return "", err
 		}
 
 		var result struct {
-			ErrorId  int    `json:"errorId"`
-			Status   string `json:"status"`
-			Solution struct {
+			ErrorId          int    `json:"errorId"`
+			ErrorCode        string `json:"errorCode"`
+			ErrorDescription string `json:"errorDescription"`
+			Status           string `json:"status"`
+			Solution         struct {
 				GRecaptchaResponse string `json:"gRecaptchaResponse"`
 			} `json:"solution"`
 		}
@@ -382,26 +479,204 @@ func (s *TwoCaptchaSolver) getTaskResult(taskID string) (string, error) {
 		}
 
 		if result.ErrorId != 0 {
-			return "", fmt.Errorf("API error getting result")
+			if err := balanceError(result.ErrorCode, result.ErrorDescription); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("API error getting result: %s - %s", result.ErrorCode, result.ErrorDescription)
 		}
 
 		if result.Status == "ready" {
+			if len(result.Solution.GRecaptchaResponse) < 50 {
+				return "", fmt.Errorf("invalid captcha response received")
+			}
 			return result.Solution.GRecaptchaResponse, nil
 		}
 
-		time.Sleep(RetryInterval)
+		if err := sleepContext(ctx, RetryInterval); err != nil {
+			return "", err
+		}
 	}
 
 	return "", errors.New("max retries reached waiting for result")
 }
 
-func sendRequest(url string, payload interface{}) ([]byte, error) {
+func (s *AntiCaptchaSolver) SolveReCaptchaV2(ctx context.Context, siteKey, pageURL string) (string, error) {
+	taskID, err := s.createTask(ctx, siteKey, pageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to create captcha task: %w", err)
+	}
+	return s.getTaskResult(ctx, taskID)
+}
+
+func (s *AntiCaptchaSolver) createTask(ctx context.Context, siteKey, pageURL string) (int64, error) {
+	payload := map[string]interface{}{
+		"clientKey": s.APIKey,
+		"task": map[string]interface{}{
+			"type":       "RecaptchaV2EnterpriseTaskProxyless",
+			"websiteURL": pageURL,
+			"websiteKey": siteKey,
+		},
+	}
+	// softId is optional for Anti-Captcha and must be numeric when sent.
+	if s.SoftID != "" {
+		softID, err := strconv.Atoi(s.SoftID)
+		if err != nil {
+			return 0, fmt.Errorf("invalid Anti-Captcha SoftID %q: %w", s.SoftID, err)
+		}
+		payload["softId"] = softID
+	}
+
+	resp, err := sendRequest(ctx, configuration.Get().CaptchaEndpoints.AntiCaptcha.Create, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		ErrorId          int    `json:"errorId"`
+		ErrorCode        string `json:"errorCode"`
+		ErrorDescription string `json:"errorDescription"`
+		TaskId           int64  `json:"taskId"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse anti-captcha response: %w", err)
+	}
+
+	if result.ErrorId != 0 {
+		if err := balanceError(result.ErrorCode, result.ErrorDescription); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("anti-captcha API error: %s - %s", result.ErrorCode, result.ErrorDescription)
+	}
+
+	return result.TaskId, nil
+}
+
+func (s *AntiCaptchaSolver) getTaskResult(ctx context.Context, taskID int64) (string, error) {
+	endpoint := configuration.Get().CaptchaEndpoints.AntiCaptcha.Result
+	return pollNumericTaskResult(ctx, "anti-captcha", endpoint, s.APIKey, taskID)
+}
+
+func (s *CapMonsterSolver) SolveReCaptchaV2(ctx context.Context, siteKey, pageURL string) (string, error) {
+	taskID, err := s.createTask(ctx, siteKey, pageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to create captcha task: %w", err)
+	}
+	return s.getTaskResult(ctx, taskID)
+}
+
+func (s *CapMonsterSolver) createTask(ctx context.Context, siteKey, pageURL string) (int64, error) {
+	payload := map[string]interface{}{
+		"clientKey": s.APIKey,
+		"task": map[string]interface{}{
+			"type":       "RecaptchaV2EnterpriseTask",
+			"websiteURL": pageURL,
+			"websiteKey": siteKey,
+		},
+	}
+
+	resp, err := sendRequest(ctx, configuration.Get().CaptchaEndpoints.CapMonster.Create, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		ErrorId          int    `json:"errorId"`
+		ErrorCode        string `json:"errorCode"`
+		ErrorDescription string `json:"errorDescription"`
+		TaskId           int64  `json:"taskId"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse capmonster response: %w", err)
+	}
+
+	if result.ErrorId != 0 {
+		if err := balanceError(result.ErrorCode, result.ErrorDescription); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("capmonster API error: %s - %s", result.ErrorCode, result.ErrorDescription)
+	}
+
+	return result.TaskId, nil
+}
+
+func (s *CapMonsterSolver) getTaskResult(ctx context.Context, taskID int64) (string, error) {
+	endpoint := configuration.Get().CaptchaEndpoints.CapMonster.Result
+	return pollNumericTaskResult(ctx, "capmonster", endpoint, s.APIKey, taskID)
+}
+
+// pollNumericTaskResult polls getTaskResult for the providers that share the Anti-Captcha
+// protocol with numeric task ids. Provider errors end the poll, since both providers only
+// report them for tasks that will never finish.
+func pollNumericTaskResult(ctx context.Context, provider, endpoint, apiKey string, taskID int64) (string, error) {
+	cfg := configuration.Get()
+	MaxRetries := cfg.CaptchaEndpoints.MaxRetries
+	RetryInterval := cfg.CaptchaEndpoints.RetryInterval
+
+	for i := 0; i < MaxRetries; i++ {
+		payload := map[string]interface{}{
+			"clientKey": apiKey,
+			"taskId":    taskID,
+		}
+
+		resp, err := sendRequest(ctx, endpoint, payload)
+		if err != nil {
+			return "", err
+		}
+
+		var result struct {
+			ErrorId          int    `json:"errorId"`
+			ErrorCode        string `json:"errorCode"`
+			ErrorDescription string `json:"errorDescription"`
+			Status           string `json:"status"`
+			Solution         struct {
+				GRecaptchaResponse string `json:"gRecaptchaResponse"`
+			} `json:"solution"`
+		}
+
+		if err := json.Unmarshal(resp, &result); err != nil {
+			return "", fmt.Errorf("failed to parse %s response: %w", provider, err)
+		}
+
+		if result.ErrorId != 0 {
+			if err := balanceError(result.ErrorCode, result.ErrorDescription); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("%s API error getting result: %s - %s", provider, result.ErrorCode, result.ErrorDescription)
+		}
+
+		if result.Status == "ready" {
+			if len(result.Solution.GRecaptchaResponse) < 50 {
+				return "", fmt.Errorf("invalid captcha response received from %s", provider)
+			}
+			return result.Solution.GRecaptchaResponse, nil
+		}
+
+		if err := sleepContext(ctx, RetryInterval); err != nil {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("max retries reached (%d) waiting for %s result", MaxRetries, provider)
+}
+
+func sendRequest(ctx context.Context, url string, payload interface{}) ([]byte, error) {
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonPayload))
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -421,43 +696,32 @@ func sendRequest(url string, payload interface{}) ([]byte, error) {
 }
 
 func getBalanceThreshold(provider string) float64 {
-	cfg := configuration.Get()
-	switch provider {
-	case "capsolver":
-		return cfg.CaptchaService.Capsolver.BalanceMin
-	case "ezcaptcha":
-		return cfg.CaptchaService.EZCaptcha.BalanceMin
-	case "2captcha":
-		return cfg.CaptchaService.TwoCaptcha.BalanceMin
-	default:
+	p, ok := LookupCaptchaProvider(provider)
+	if !ok {
 		return 0
 	}
+	return p.BalanceMin(configuration.Get())
 }
 
-func ValidateCaptchaKey(apiKey, provider string) (bool, float64, error) {
+func ValidateCaptchaKey(ctx context.Context, apiKey, provider string) (bool, float64, error) {
 	if apiKey == "" {
 		return false, 0, fmt.Errorf("empty API key provided")
 	}
 
-	switch provider {
-	case "capsolver":
-		return validateCapsolverKey(apiKey)
-	case "ezcaptcha":
-		return validateEZCaptchaKey(apiKey)
-	case "2captcha":
-		return validate2CaptchaKey(apiKey)
-	default:
+	p, ok := LookupCaptchaProvider(provider)
+	if !ok {
 		return false, 0, errors.New("unsupported captcha provider")
 	}
+	return p.ValidateKey(ctx, apiKey)
 }
 
-func validateCapsolverKey(apiKey string) (bool, float64, error) {
-	url := "https://api.capsolver.com/getBalance"
+func validateCapsolverKey(ctx context.Context, apiKey string) (bool, float64, error) {
+	url := configuration.Get().CaptchaEndpoints.Capsolver.Balance
 	payload := map[string]string{
 		"clientKey": apiKey,
 	}
 
-	resp, err := sendRequest(url, payload)
+	resp, err := sendRequest(ctx, url, payload)
 	if err != nil {
 		if strings.Contains(err.Error(), "invalid token format") {
 			return false, 0, errors.New("invalid capsolver API key format")
@@ -492,14 +756,14 @@ func validateCapsolverKey(apiKey string) (bool, float64, error) {
 	return true, result.Balance, nil
 }
 
-func validateEZCaptchaKey(apiKey string) (bool, float64, error) {
-	url := "https://api.ez-captcha.com/getBalance"
+func validateEZCaptchaKey(ctx context.Context, apiKey string) (bool, float64, error) {
+	url := configuration.Get().CaptchaEndpoints.EZCaptcha.Balance
 	payload := map[string]string{
 		"clientKey": apiKey,
 		"action":    "getBalance",
 	}
 
-	resp, err := sendRequest(url, payload)
+	resp, err := sendRequest(ctx, url, payload)
 	if err != nil {
 		return false, 0, err
 	}
@@ -520,14 +784,14 @@ func validateEZCaptchaKey(apiKey string) (bool, float64, error) {
 	return true, result.Balance, nil
 }
 
-func validate2CaptchaKey(apiKey string) (bool, float64, error) {
-	url := "https://api.2captcha.com/getBalance"
+func validate2CaptchaKey(ctx context.Context, apiKey string) (bool, float64, error) {
+	url := configuration.Get().CaptchaEndpoints.TwoCaptcha.Balance
 	payload := map[string]string{
 		"clientKey": apiKey,
 		"action":    "getBalance",
 	}
 
-	resp, err := sendRequest(url, payload)
+	resp, err := sendRequest(ctx, url, payload)
 	if err != nil {
 		return false, 0, err
 	}
@@ -547,3 +811,44 @@ func validate2CaptchaKey(apiKey string) (bool, float64, error) {
 
 	return true, result.Balance, nil
 }
+
+func validateAntiCaptchaKey(ctx context.Context, apiKey string) (bool, float64, error) {
+	return validateAntiCaptchaProtocolKey(ctx, "anti-captcha", configuration.Get().CaptchaEndpoints.AntiCaptcha.Balance, apiKey)
+}
+
+func validateCapMonsterKey(ctx context.Context, apiKey string) (bool, float64, error) {
+	return validateAntiCaptchaProtocolKey(ctx, "capmonster", configuration.Get().CaptchaEndpoints.CapMonster.Balance, apiKey)
+}
+
+// validateAntiCaptchaProtocolKey checks a key against a getBalance endpoint speaking the
+// Anti-Captcha protocol, which Anti-Captcha and CapMonster Cloud share.
+func validateAntiCaptchaProtocolKey(ctx context.Context, provider, url, apiKey string) (bool, float64, error) {
+	payload := map[string]string{
+		"clientKey": apiKey,
+	}
+
+	resp, err := sendRequest(ctx, url, payload)
+	if err != nil {
+		return false, 0, fmt.Errorf("%s balance check failed: %w", provider, err)
+	}
+
+	var result struct {
+		ErrorId          int     `json:"errorId"`
+		ErrorCode        string  `json:"errorCode"`
+		ErrorDescription string  `json:"errorDescription"`
+		Balance          float64 `json:"balance"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return false, 0, fmt.Errorf("failed to parse %s response: %w", provider, err)
+	}
+
+	if result.ErrorId != 0 {
+		if result.ErrorCode == "ERROR_KEY_DOES_NOT_EXIST" || strings.Contains(result.ErrorDescription, "Invalid key") {
+			return false, 0, fmt.Errorf("invalid %s API key", provider)
+		}
+		return false, 0, fmt.Errorf("%s API error: %s - %s", provider, result.ErrorCode, result.ErrorDescription)
+	}
+
+	return true, result.Balance, nil
+}
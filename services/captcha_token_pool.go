@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/metrics"
+)
+
+// pooledToken is a solved reCAPTCHA token waiting to be used for another check.
+type pooledToken struct {
+	solution CaptchaSolution
+	solvedAt time.Time
+	uses     int
+}
+
+// captchaTokenPool holds recently solved tokens per user and page, so accounts checked in the
+// same pass can share a solve. Tokens are only shared within one user's checks, since each solve
+// is paid for with that user's key or counted against their budget.
+type captchaTokenPool struct {
+	sync.Mutex
+	tokens map[string][]*pooledToken
+}
+
+var tokenPool = &captchaTokenPool{tokens: make(map[string][]*pooledToken)}
+
+// CaptchaTokenStats counts how solved tokens were obtained and used.
+type CaptchaTokenStats struct {
+	Fresh      uint64 // Tokens solved on demand for a check.
+	Prefetched uint64 // Tokens solved ahead of a user's checks.
+	Reused     uint64 // Checks served from the pool, each one a solve saved.
+	Rejected   uint64 // Tokens the appeal endpoint refused.
+	Expired    uint64 // Pooled tokens dropped unused or partly used after their TTL.
+}
+
+var tokenStats struct {
+	fresh, prefetched, reused, rejected, expired atomic.Uint64
+}
+
+// CaptchaTokenPoolStats returns the token counters since startup.
+func CaptchaTokenPoolStats() CaptchaTokenStats {
+	return CaptchaTokenStats{
+		Fresh:      tokenStats.fresh.Load(),
+		Prefetched: tokenStats.prefetched.Load(),
+		Reused:     tokenStats.reused.Load(),
+		Rejected:   tokenStats.rejected.Load(),
+		Expired:    tokenStats.expired.Load(),
+	}
+}
+
+func tokenPoolKey(userID, siteKey, pageURL string) string {
+	return userID + "|" + siteKey + "|" + pageURL
+}
+
+// tokenPoolEnabled reports whether solved tokens are kept at all. Prefetching works without reuse,
+// in which case each token is still used only once.
+func tokenPoolEnabled(cfg *configuration.Config) bool {
+	return cfg.CaptchaTokenPool.Enabled || cfg.CaptchaTokenPool.Prefetch > 0
+}
+
+func tokenMaxUses(cfg *configuration.Config) int {
+	if !cfg.CaptchaTokenPool.Enabled || cfg.CaptchaTokenPool.MaxUses < 1 {
+		return 1
+	}
+	return cfg.CaptchaTokenPool.MaxUses
+}
+
+// take hands out the oldest usable token for key, dropping expired ones along the way.
+func (p *captchaTokenPool) take(key string, ttl time.Duration, maxUses int) (pooledToken, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	tokens := p.tokens[key]
+	for len(tokens) > 0 {
+		t := tokens[0]
+		if time.Since(t.solvedAt) >= ttl {
+			tokenStats.expired.Add(1)
+			metrics.CaptchaTokenPoolExpired.Inc()
+			tokens = tokens[1:]
+			continue
+		}
+
+		t.uses++
+		if t.uses >= maxUses {
+			tokens = tokens[1:]
+		}
+		p.store(key, tokens)
+		metrics.CaptchaTokenPoolHits.Inc()
+		return *t, true
+	}
+	p.store(key, tokens)
+	metrics.CaptchaTokenPoolMisses.Inc()
+	return pooledToken{}, false
+}
+
+// put adds a token that has already been used uses times.
+func (p *captchaTokenPool) put(key string, solution CaptchaSolution, uses, maxUses int) {
+	if uses >= maxUses {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.tokens[key] = append(p.tokens[key], &pooledToken{solution: solution, solvedAt: time.Now(), uses: uses})
+}
+
+// discard removes token so no other check is handed it.
+func (p *captchaTokenPool) discard(key, token string) {
+	p.Lock()
+	defer p.Unlock()
+
+	tokens := p.tokens[key]
+	for i, t := range tokens {
+		if t.solution.Token == token {
+			tokens = append(tokens[:i:i], tokens[i+1:]...)
+			break
+		}
+	}
+	p.store(key, tokens)
+}
+
+// store saves the token list for key. The caller must hold p.
+func (p *captchaTokenPool) store(key string, tokens []*pooledToken) {
+	if len(tokens) == 0 {
+		delete(p.tokens, key)
+		return
+	}
+	p.tokens[key] = tokens
+}
+
+// captchaToken is a solved token for one check, with whether it came from the pool.
+type captchaToken struct {
+	CaptchaSolution
+	pooled bool
+	reuse  bool // The token was already used for an earlier check.
+}
+
+// acquireCaptchaToken returns a pooled token for the user's page when one is available and
+// solves a fresh one otherwise. Fresh tokens go into the pool for the rest of their uses.
+func acquireCaptchaToken(ctx context.Context, userID, siteKey, pageURL string, allowPooled bool) (captchaToken, error) {
+	cfg := configuration.Get()
+	key := tokenPoolKey(userID, siteKey, pageURL)
+
+	if allowPooled && tokenPoolEnabled(cfg) {
+		if t, ok := tokenPool.take(key, cfg.CaptchaTokenPool.TTL, tokenMaxUses(cfg)); ok {
+			return captchaToken{CaptchaSolution: t.solution, pooled: true, reuse: t.uses > 1}, nil
+		}
+	}
+
+	solution, err := SolveCaptcha(ctx, userID, siteKey, pageURL)
+	if err != nil {
+		return captchaToken{}, err
+	}
+	tokenStats.fresh.Add(1)
+	if cfg.CaptchaTokenPool.Enabled {
+		tokenPool.put(key, solution, 1, tokenMaxUses(cfg))
+	}
+	return captchaToken{CaptchaSolution: solution}, nil
+}
+
+// acceptCaptchaToken records that the appeal endpoint took token. Only a reused token's later
+// uses count as saved solves; a prefetched token's first use does not.
+func acceptCaptchaToken(token captchaToken) {
+	if token.reuse {
+		tokenStats.reused.Add(1)
+		metrics.CaptchaTokenPoolSaved.Inc()
+	}
+}
+
+// rejectCaptchaToken drops a token the appeal endpoint refused.
+func rejectCaptchaToken(userID, siteKey, pageURL, token string) {
+	tokenStats.rejected.Add(1)
+	tokenPool.discard(tokenPoolKey(userID, siteKey, pageURL), token)
+}
+
+// prefetchCaptchaTokens solves up to CAPTCHA_TOKEN_PREFETCH tokens ahead of a user's checks so
+// the checks find them waiting in the pool. It never solves more than the due accounts need
+// beyond the token the first check solves itself. Each solve waits for the solving provider's
+// slot like a check would.
+func prefetchCaptchaTokens(ctx context.Context, userID string, due int) {
+	cfg := configuration.Get()
+	maxUses := tokenMaxUses(cfg)
+	n := cfg.CaptchaTokenPool.Prefetch
+	if needed := (due+maxUses-1)/maxUses - 1; n > needed {
+		n = needed
+	}
+	if n <= 0 {
+		return
+	}
+
+	siteKey := cfg.CaptchaService.RecaptchaSiteKey
+	pageURL := cfg.CaptchaService.RecaptchaURL
+	key := tokenPoolKey(userID, siteKey, pageURL)
+	for i := 0; i < n; i++ {
+		solution, err := SolveCaptcha(ctx, userID, siteKey, pageURL)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Log.WithError(err).Warnf("Failed to prefetch captcha token for user %s", userID)
+			}
+			return
+		}
+		tokenStats.prefetched.Add(1)
+		tokenPool.put(key, solution, 0, maxUses)
+	}
+}
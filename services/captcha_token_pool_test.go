@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/metrics"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/testsupport/fakecaptcha"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const poolSiteKey, poolPageURL = "site-key", "https://support.activision.com"
+
+// useTokenPool gives the test an empty token pool and configures it.
+func useTokenPool(t *testing.T, enabled bool, maxUses, prefetch int) {
+	t.Helper()
+	cfg := configuration.Get()
+	saved, savedPool := cfg.CaptchaTokenPool, tokenPool
+	cfg.CaptchaTokenPool.Enabled = enabled
+	cfg.CaptchaTokenPool.TTL = time.Minute
+	cfg.CaptchaTokenPool.MaxUses = maxUses
+	cfg.CaptchaTokenPool.Prefetch = prefetch
+	tokenPool = &captchaTokenPool{tokens: make(map[string][]*pooledToken)}
+	t.Cleanup(func() {
+		cfg.CaptchaTokenPool = saved
+		tokenPool = savedPool
+	})
+}
+
+func pooledCount(key string) int {
+	tokenPool.Lock()
+	defer tokenPool.Unlock()
+	return len(tokenPool.tokens[key])
+}
+
+func savedSolves(t *testing.T) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.CaptchaTokenPoolSaved.Write(&m); err != nil {
+		t.Fatalf("read saved solves: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestTokenPoolExpiresTokens(t *testing.T) {
+	useTokenPool(t, true, 3, 0)
+	key := tokenPoolKey("1", poolSiteKey, poolPageURL)
+	tokenPool.put(key, CaptchaSolution{Token: "old"}, 1, 3)
+	tokenPool.put(key, CaptchaSolution{Token: "new"}, 1, 3)
+	tokenPool.tokens[key][0].solvedAt = time.Now().Add(-2 * time.Minute)
+	expired := CaptchaTokenPoolStats().Expired
+
+	got, ok := tokenPool.take(key, time.Minute, 3)
+	if !ok || got.solution.Token != "new" {
+		t.Fatalf("take = %q, %v; want the unexpired token", got.solution.Token, ok)
+	}
+	if n := CaptchaTokenPoolStats().Expired - expired; n != 1 {
+		t.Errorf("expired %d tokens, want 1", n)
+	}
+
+	tokenPool.tokens[key][0].solvedAt = time.Now().Add(-time.Minute)
+	if _, ok := tokenPool.take(key, time.Minute, 3); ok {
+		t.Errorf("take handed out a token exactly TTL old")
+	}
+	if n := pooledCount(key); n != 0 {
+		t.Errorf("%d tokens left in the pool, want 0", n)
+	}
+}
+
+func TestAcquireCaptchaTokenMaxUses(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		maxUses    int
+		checks     int
+		wantSolves int
+		wantSaved  int
+	}{
+		{name: "reuse disabled", enabled: false, maxUses: 3, checks: 3, wantSolves: 3},
+		{name: "one use", enabled: true, maxUses: 1, checks: 3, wantSolves: 3},
+		{name: "three uses", enabled: true, maxUses: 3, checks: 4, wantSolves: 2, wantSaved: 2},
+		{name: "three uses exactly", enabled: true, maxUses: 3, checks: 6, wantSolves: 2, wantSaved: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestDatabase(t)
+			fakes := startCaptchaFakes(t, fakecaptcha.Capsolver)
+			useTokenPool(t, tt.enabled, tt.maxUses, 0)
+			saveSettings(t, models.UserSettings{UserID: "1", PreferredCaptchaProvider: "capsolver"})
+			reused, saved := CaptchaTokenPoolStats().Reused, savedSolves(t)
+
+			uses := make(map[string]int)
+			for i := 0; i < tt.checks; i++ {
+				token, err := acquireCaptchaToken(context.Background(), "1", poolSiteKey, poolPageURL, true)
+				if err != nil {
+					t.Fatalf("check %d: %v", i, err)
+				}
+				uses[token.Token]++
+				acceptCaptchaToken(token)
+			}
+
+			if got := fakes[fakecaptcha.Capsolver].Count(fakecaptcha.CreatePath); got != tt.wantSolves {
+				t.Errorf("solved %d tokens, want %d", got, tt.wantSolves)
+			}
+			for token, n := range uses {
+				if n > tt.maxUses {
+					t.Errorf("token %s used %d times, want at most %d", token, n, tt.maxUses)
+				}
+			}
+			if got := CaptchaTokenPoolStats().Reused - reused; got != uint64(tt.wantSaved) {
+				t.Errorf("reused = %d, want %d", got, tt.wantSaved)
+			}
+			if got := savedSolves(t) - saved; got != float64(tt.wantSaved) {
+				t.Errorf("captcha_token_pool_saved_total grew by %v, want %d", got, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestRejectCaptchaTokenDiscards(t *testing.T) {
+	useTokenPool(t, true, 3, 0)
+	key := tokenPoolKey("1", poolSiteKey, poolPageURL)
+	tokenPool.put(key, CaptchaSolution{Token: "refused"}, 1, 3)
+	tokenPool.put(key, CaptchaSolution{Token: "good"}, 1, 3)
+	rejected := CaptchaTokenPoolStats().Rejected
+
+	rejectCaptchaToken("1", poolSiteKey, poolPageURL, "refused")
+
+	if n := CaptchaTokenPoolStats().Rejected - rejected; n != 1 {
+		t.Errorf("rejected = %d, want 1", n)
+	}
+	got, ok := tokenPool.take(key, time.Minute, 3)
+	if !ok || got.solution.Token != "good" {
+		t.Fatalf("take = %q, %v; want the token that was not refused", got.solution.Token, ok)
+	}
+
+	// Another user's pool is left alone.
+	other := tokenPoolKey("2", poolSiteKey, poolPageURL)
+	tokenPool.put(other, CaptchaSolution{Token: "good"}, 1, 3)
+	rejectCaptchaToken("1", poolSiteKey, poolPageURL, "good")
+	if n := pooledCount(other); n != 1 {
+		t.Errorf("user 2 has %d pooled tokens, want 1", n)
+	}
+}
+
+func TestPrefetchCaptchaTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		maxUses  int
+		prefetch int
+		due      int
+		want     int
+	}{
+		{name: "capped by the due accounts", maxUses: 1, prefetch: 5, due: 3, want: 2},
+		{name: "capped by the setting", maxUses: 1, prefetch: 2, due: 10, want: 2},
+		{name: "one account needs no prefetch", maxUses: 1, prefetch: 5, due: 1, want: 0},
+		{name: "reused tokens cover more accounts", enabled: true, maxUses: 2, prefetch: 5, due: 5, want: 2},
+		{name: "one reused token covers all", enabled: true, maxUses: 3, prefetch: 5, due: 3, want: 0},
+		{name: "prefetch disabled", maxUses: 1, prefetch: 0, due: 10, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestDatabase(t)
+			fakes := startCaptchaFakes(t, fakecaptcha.Capsolver)
+			useTokenPool(t, tt.enabled, tt.maxUses, tt.prefetch)
+			cfg := configuration.Get()
+			cfg.CaptchaService.RecaptchaSiteKey = poolSiteKey
+			cfg.CaptchaService.RecaptchaURL = poolPageURL
+			saveSettings(t, models.UserSettings{UserID: "1", PreferredCaptchaProvider: "capsolver"})
+
+			prefetchCaptchaTokens(context.Background(), "1", tt.due)
+
+			if got := fakes[fakecaptcha.Capsolver].Count(fakecaptcha.CreatePath); got != tt.want {
+				t.Errorf("solved %d tokens, want %d", got, tt.want)
+			}
+			if got := pooledCount(tokenPoolKey("1", poolSiteKey, poolPageURL)); got != tt.want {
+				t.Errorf("pooled %d tokens, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package services_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/services"
+	"github.com/bradselph/CODStatusBot/testsupport/fakeactivision"
+	"github.com/bradselph/CODStatusBot/testsupport/fakecaptcha"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+	"github.com/bwmarrin/discordgo"
+	"github.com/glebarez/sqlite"
+)
+
+// startCheckPipeline points the configuration at fake Activision and Capsolver servers and the
+// database at a fresh in-memory SQLite database for the rest of the test.
+func startCheckPipeline(t *testing.T) (*fakeactivision.Server, *fakecaptcha.Server) {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := *cfg
+	previousDB := database.DB
+	t.Cleanup(func() {
+		*cfg = saved
+		database.DB = previousDB
+	})
+
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	if err := database.SetEncryptionKeys(key, nil); err != nil {
+		t.Fatalf("SetEncryptionKeys: %v", err)
+	}
+	if err := database.Connect(sqlite.Open("file::memory:")); err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	if _, err := database.MigrateUp(database.DB); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	activision := fakeactivision.New()
+	t.Cleanup(activision.Close)
+	activision.Apply(cfg)
+
+	captcha := fakecaptcha.New(fakecaptcha.Capsolver)
+	t.Cleanup(captcha.Close)
+	captcha.Apply(cfg)
+
+	cfg.CaptchaService.RecaptchaSiteKey = "6LdB2NUpAAAAANcdcy9YcjBOBD4rY-TIHOeolkkk"
+	cfg.CaptchaService.RecaptchaURL = "https://support.activision.com"
+	cfg.CaptchaTokenPool.Enabled = false
+	cfg.CaptchaTokenPool.Prefetch = 0
+	return activision, captcha
+}
+
+func TestCheckAccountScenarios(t *testing.T) {
+	activision, _ := startCheckPipeline(t)
+	endsAt := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	activision.SetTempBanEnd(endsAt)
+
+	tests := []struct {
+		scenario   fakeactivision.Scenario
+		wantStatus models.Status
+		wantErr    bool
+	}{
+		{fakeactivision.Good, models.StatusGood, false},
+		{fakeactivision.Permaban, models.StatusPermaban, false},
+		{fakeactivision.Shadowban, models.StatusShadowban, false},
+		{fakeactivision.TempBan, models.StatusTempban, false},
+		{fakeactivision.InvalidCookie, models.StatusInvalidCookie, false},
+		{fakeactivision.ServerError, models.StatusUnknown, true},
+		{fakeactivision.MalformedJSON, models.StatusUnknown, true},
+	}
+
+	for i, tt := range tests {
+		t.Run(string(tt.scenario), func(t *testing.T) {
+			cookie := fakeactivision.Cookie(time.Now().Add(time.Duration(14*24+i) * time.Hour))
+			activision.Script(cookie, tt.scenario)
+
+			check, err := services.CheckAccount(context.Background(), cookie, fmt.Sprintf("user-%d", i), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckAccount error = %v, want error %v", err, tt.wantErr)
+			}
+			if check.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", check.Status, tt.wantStatus)
+			}
+
+			switch tt.scenario {
+			case fakeactivision.TempBan:
+				if got := check.TempBanEndsAt(); !got.Equal(endsAt) {
+					t.Errorf("temporary ban ends at %v, want %v", got, endsAt)
+				}
+			case fakeactivision.Permaban, fakeactivision.Shadowban:
+				if check.AffectedGames() == "" {
+					t.Error("no affected games reported for a ban")
+				}
+			case fakeactivision.InvalidCookie, fakeactivision.ServerError:
+				for _, r := range activision.Requests() {
+					if r.Cookie == cookie && r.Path == fakeactivision.AppealPath {
+						t.Error("the appeal endpoint was called, spending a captcha, for a cookie that failed verification")
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStatusChangeNotifiesChannel(t *testing.T) {
+	activision, captcha := startCheckPipeline(t)
+	session := fakediscord.New()
+
+	cookie := fakeactivision.Cookie(time.Now().Add(14 * 24 * time.Hour))
+	activision.Script(cookie, fakeactivision.Permaban)
+
+	account := models.Account{
+		UserID:     "user-1",
+		ChannelID:  "channel-1",
+		Title:      "main",
+		LastStatus: models.StatusGood,
+		SSOCookie:  cookie,
+	}
+	if err := database.DB.Create(&account).Error; err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	// Users with their own key are not held back by the shared notification limiter.
+	captcha.AddKey("user-capsolver-key", fakecaptcha.DefaultBalance)
+	settings, err := services.GetUserSettings(account.UserID)
+	if err != nil {
+		t.Fatalf("GetUserSettings: %v", err)
+	}
+	settings.CapSolverAPIKey = "user-capsolver-key"
+	if err := database.DB.Save(&settings).Error; err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+
+	ctx := context.Background()
+	check, err := services.CheckAccount(ctx, account.SSOCookie, account.UserID, "")
+	if err != nil {
+		t.Fatalf("CheckAccount: %v", err)
+	}
+	if got := captcha.Balance("user-capsolver-key"); got >= fakecaptcha.DefaultBalance {
+		t.Errorf("the user's own key was not charged for the solve")
+	}
+	services.HandleStatusChange(ctx, session, account, check, settings)
+
+	var statusChange, permabanNotice *discordgo.MessageEmbed
+	for _, m := range session.MessagesTo(account.ChannelID) {
+		switch {
+		case m.Send.Content == "<@user-1>":
+			statusChange = m.Send.Embed
+		case m.Send.Embed != nil:
+			permabanNotice = m.Send.Embed
+		}
+	}
+	if statusChange == nil || statusChange.Title == "" {
+		t.Fatal("no status change notification mentioning the user was sent to the account's channel")
+	}
+	if !strings.Contains(statusChange.Title, account.Title) {
+		t.Errorf("status change title %q does not name the account", statusChange.Title)
+	}
+	if permabanNotice == nil {
+		t.Error("no permaban notice was sent")
+	}
+
+	var stored models.Account
+	if err := database.DB.First(&stored, account.ID).Error; err != nil {
+		t.Fatalf("reload account: %v", err)
+	}
+	if stored.LastStatus != models.StatusPermaban {
+		t.Errorf("stored status = %s, want %s", stored.LastStatus, models.StatusPermaban)
+	}
+
+	var bans []models.Ban
+	if err := database.DB.Where("account_id = ? AND status = ?", account.ID, models.StatusPermaban).Find(&bans).Error; err != nil {
+		t.Fatalf("load bans: %v", err)
+	}
+	if len(bans) == 0 {
+		t.Error("no ban record was created for the status change")
+	}
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+)
+
+type checkJob struct {
+	batch   *userCheckBatch
+	account models.Account
+}
+
+// prepareBatches loads settings and picks the due accounts for every user, using the same
+// worker limit as the checks since captcha key validation goes over the network.
+func prepareBatches(ctx context.Context, s discordapi.Session, userIDs []string, accountsByUser map[string][]models.Account) []*userCheckBatch {
+	batches := make([]*userCheckBatch, len(userIDs))
+	sem := make(chan struct{}, checkWorkerCount())
+	var wg sync.WaitGroup
+
+	for i, userID := range userIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, userID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			batches[i] = prepareUserAccounts(ctx, s, userID, accountsByUser[userID])
+		}(i, userID)
+	}
+	wg.Wait()
+
+	prepared := batches[:0]
+	for _, batch := range batches {
+		if batch != nil {
+			prepared = append(prepared, batch)
+		}
+	}
+	return prepared
+}
+
+// runCheckPool checks every batch's accounts on a bounded set of workers. Jobs are queued
+// round-robin across users so nobody waits behind another user's full account list, each
+// user is limited to CHECK_WORKERS_PER_USER checks at once, and each captcha provider to its
+// own MAX_CONCURRENT setting. The provider limit applies to whichever provider actually
+// solves, including the ones SolveCaptcha fails over to.
+func runCheckPool(ctx context.Context, s discordapi.Session, batches []*userCheckBatch) {
+	var jobs []checkJob
+	for round := 0; ; round++ {
+		added := false
+		for _, batch := range batches {
+			if round < len(batch.toCheck) {
+				jobs = append(jobs, checkJob{batch: batch, account: batch.toCheck[round]})
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	for _, batch := range batches {
+		if len(batch.toCheck) == 0 {
+			finishUserAccounts(ctx, s, batch)
+		}
+	}
+
+	if len(jobs) == 0 {
+		return
+	}
+
+	workers := checkWorkerCount()
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	logger.Log.Infof("Checking %d accounts for %d users with %d workers", len(jobs), len(batches), workers)
+
+	ctx = withProviderSlots(ctx, newProviderSlots())
+	queue := make(chan checkJob)
+	var wg sync.WaitGroup
+
+	for _, batch := range batches {
+		if len(batch.toCheck) > 1 {
+			wg.Add(1)
+			go func(batch *userCheckBatch) {
+				defer wg.Done()
+				prefetchCaptchaTokens(ctx, batch.userID, len(batch.toCheck))
+			}(batch)
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				runCheckJob(ctx, s, job)
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		logger.Log.Info("Periodic account check cancelled")
+	}
+}
+
+func runCheckJob(ctx context.Context, s discordapi.Session, job checkJob) {
+	defer job.batch.complete(ctx, s)
+
+	if !acquire(ctx, job.batch.slots) {
+		return
+	}
+	defer release(job.batch.slots)
+
+	account := job.account
+	check, err := CheckAccount(WithCaptchaAccount(ctx, account.ID), account.SSOCookie, account.UserID, "")
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		handleCheckError(s, &account, err)
+		return
+	}
+
+	job.batch.recordResult(account, check)
+}
+
+func checkWorkerCount() int {
+	workers := configuration.Get().CheckPool.Workers
+	if workers < 1 {
+		return 1
+	}
+	return workers
+}
+
+type providerSlotsKey struct{}
+
+// withProviderSlots makes the captcha solves started under ctx wait for a slot from the solving
+// provider's semaphore in slots.
+func withProviderSlots(ctx context.Context, slots map[string]chan struct{}) context.Context {
+	return context.WithValue(ctx, providerSlotsKey{}, slots)
+}
+
+// providerSlot returns the semaphore bounding provider's solves under ctx, or nil if there is none.
+func providerSlot(ctx context.Context, provider string) chan struct{} {
+	slots, _ := ctx.Value(providerSlotsKey{}).(map[string]chan struct{})
+	return slots[provider]
+}
+
+// newProviderSlots returns one semaphore per captcha provider. A limit of zero leaves the
+// provider bounded only by the worker count.
+func newProviderSlots() map[string]chan struct{} {
+	cfg := configuration.Get()
+	slots := make(map[string]chan struct{})
+	for _, p := range CaptchaProviders() {
+		if limit := p.MaxConcurrent(cfg); limit > 0 {
+			slots[p.Name] = make(chan struct{}, limit)
+		}
+	}
+	return slots
+}
+
+// acquire takes a slot from sem, giving up if ctx is cancelled first. A nil sem is unlimited.
+func acquire(ctx context.Context, sem chan struct{}) bool {
+	if sem == nil {
+		return ctx.Err() == nil
+	}
+	select {
+	case sem <- struct{}{}:
+		if ctx.Err() != nil {
+			<-sem
+			return false
+		}
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func release(sem chan struct{}) {
+	if sem != nil {
+		<-sem
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/testsupport/fakeactivision"
+	"github.com/bradselph/CODStatusBot/testsupport/fakecaptcha"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+)
+
+// appealGate sits in front of the fake appeal endpoint, holding each check for delay and
+// tracking how many checks each user has in flight.
+type appealGate struct {
+	delay    time.Duration
+	onAppeal func()
+
+	mu       sync.Mutex
+	owners   map[string]string // SSO cookie to user ID
+	inFlight map[string]int
+	peak     map[string]int
+	total    int
+	peakAll  int
+}
+
+func (g *appealGate) enter(user string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight[user]++
+	g.total++
+	g.peak[user] = max(g.peak[user], g.inFlight[user])
+	g.peakAll = max(g.peakAll, g.total)
+}
+
+func (g *appealGate) leave(user string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight[user]--
+	g.total--
+}
+
+// startCheckPool runs checks against fake Activision and Capsolver servers, with the appeal
+// endpoint behind gate, using the given worker limit.
+func startCheckPool(t *testing.T, workers int, gate *appealGate) *fakeactivision.Server {
+	t.Helper()
+	useTestDatabase(t)
+	startCaptchaFakes(t, fakecaptcha.Capsolver)
+
+	cfg := configuration.Get()
+	cfg.CheckPool.Workers = workers
+	cfg.CaptchaService.RecaptchaSiteKey = "site-key"
+	cfg.CaptchaService.RecaptchaURL = "https://support.activision.com"
+	cfg.CaptchaService.MaxRetries = 3
+	cfg.CaptchaTokenPool.Enabled = false
+	cfg.CaptchaTokenPool.Prefetch = 0
+
+	activision := fakeactivision.New()
+	t.Cleanup(activision.Close)
+	activision.Apply(cfg)
+
+	target, _ := url.Parse(activision.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	gate.owners = make(map[string]string)
+	gate.inFlight = make(map[string]int)
+	gate.peak = make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("ACT_SSO_COOKIE"); err == nil && r.URL.Path == fakeactivision.AppealPath {
+			gate.mu.Lock()
+			user := gate.owners[cookie.Value]
+			gate.mu.Unlock()
+			gate.enter(user)
+			defer gate.leave(user)
+			if gate.onAppeal != nil {
+				gate.onAppeal()
+			}
+			time.Sleep(gate.delay)
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	cfg.API.CheckEndpoint = server.URL + fakeactivision.AppealPath
+	return activision
+}
+
+// addBatch stores count good accounts for userID and returns them as a batch allowed perUser
+// checks at once.
+func addBatch(t *testing.T, gate *appealGate, userID string, count, perUser int) *userCheckBatch {
+	t.Helper()
+	settings, err := GetUserSettings(userID)
+	if err != nil {
+		t.Fatalf("GetUserSettings: %v", err)
+	}
+	batch := &userCheckBatch{userID: userID, settings: settings, slots: make(chan struct{}, perUser)}
+	for n := 1; n <= count; n++ {
+		account := models.Account{
+			UserID:     userID,
+			Title:      fmt.Sprintf("%s-%d", userID, n),
+			LastStatus: models.StatusGood,
+			SSOCookie:  fakeactivision.Cookie(time.Now().Add(14*24*time.Hour + time.Duration(len(gate.owners))*time.Minute)),
+		}
+		if err := database.DB.Create(&account).Error; err != nil {
+			t.Fatalf("create account: %v", err)
+		}
+		gate.owners[account.SSOCookie] = userID
+		batch.toCheck = append(batch.toCheck, account)
+	}
+	batch.remaining = len(batch.toCheck)
+	return batch
+}
+
+// checkedOrder returns the titles of the batches' accounts in the order their appeal checks
+// reached Activision.
+func checkedOrder(activision *fakeactivision.Server, batches ...*userCheckBatch) []string {
+	titles := make(map[string]string)
+	for _, batch := range batches {
+		for _, account := range batch.toCheck {
+			titles[account.SSOCookie] = account.Title
+		}
+	}
+	var order []string
+	for _, r := range activision.Requests() {
+		if r.Path == fakeactivision.AppealPath {
+			order = append(order, titles[r.Cookie])
+		}
+	}
+	return order
+}
+
+func TestRunCheckPoolRoundRobin(t *testing.T) {
+	gate := &appealGate{}
+	activision := startCheckPool(t, 1, gate)
+	batches := []*userCheckBatch{
+		addBatch(t, gate, "a", 3, 1),
+		addBatch(t, gate, "b", 2, 1),
+		addBatch(t, gate, "c", 1, 1),
+	}
+
+	runCheckPool(context.Background(), fakediscord.New(), batches)
+
+	want := []string{"a-1", "b-1", "c-1", "a-2", "b-2", "a-3"}
+	if got := checkedOrder(activision, batches...); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("checked %v, want %v", got, want)
+	}
+	for _, batch := range batches {
+		for _, account := range batch.toCheck {
+			var stored models.Account
+			if err := database.DB.First(&stored, account.ID).Error; err != nil {
+				t.Fatalf("load account: %v", err)
+			}
+			if stored.LastCheck == 0 {
+				t.Errorf("account %s was not saved after its check", account.Title)
+			}
+		}
+	}
+}
+
+func TestRunCheckPoolPerUserLimit(t *testing.T) {
+	gate := &appealGate{delay: 50 * time.Millisecond}
+	activision := startCheckPool(t, 6, gate)
+	batches := []*userCheckBatch{
+		addBatch(t, gate, "one", 4, 1),
+		addBatch(t, gate, "two", 4, 2),
+	}
+
+	runCheckPool(context.Background(), fakediscord.New(), batches)
+
+	if n := len(checkedOrder(activision, batches...)); n != 8 {
+		t.Fatalf("checked %d accounts, want 8", n)
+	}
+	if got := gate.peak["one"]; got != 1 {
+		t.Errorf("user one had %d checks in flight, want 1", got)
+	}
+	if got := gate.peak["two"]; got != 2 {
+		t.Errorf("user two had %d checks in flight, want 2", got)
+	}
+	if gate.peakAll != 3 {
+		t.Errorf("%d checks were in flight at once, want 3", gate.peakAll)
+	}
+}
+
+func TestRunCheckPoolCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gate := &appealGate{onAppeal: cancel}
+	activision := startCheckPool(t, 1, gate)
+	batch := addBatch(t, gate, "a", 4, 1)
+
+	done := make(chan struct{})
+	go func() {
+		runCheckPool(ctx, fakediscord.New(), []*userCheckBatch{batch})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("runCheckPool did not return after cancellation")
+	}
+
+	if got := checkedOrder(activision, batch); len(got) > 1 {
+		t.Errorf("checked %v after cancellation, want at most the first account", got)
+	}
+	var failed int64
+	database.DB.Model(&models.Account{}).Where("consecutive_errors > 0").Count(&failed)
+	if failed != 0 {
+		t.Errorf("%d accounts were counted as failed checks because of the cancellation", failed)
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// commandTimeout bounds the work a single slash command may start, including captcha polling.
+const commandTimeout = 5 * time.Minute
+
+var (
+	baseCtx   = context.Background()
+	baseCtxMu sync.RWMutex
+
+	// httpClient is shared by every outbound request; per-request deadlines come from the context.
+	httpClient = &http.Client{}
+)
+
+// SetBaseContext sets the context that command work derives from, so cancelling it on shutdown
+// aborts checks started from Discord interactions as well as the periodic ones.
+func SetBaseContext(ctx context.Context) {
+	baseCtxMu.Lock()
+	defer baseCtxMu.Unlock()
+	baseCtx = ctx
+}
+
+// CommandContext returns a context for work triggered by a slash command.
+func CommandContext() (context.Context, context.CancelFunc) {
+	baseCtxMu.RLock()
+	defer baseCtxMu.RUnlock()
+	return context.WithTimeout(baseCtx, commandTimeout)
+}
+
+// sleepContext waits for d, returning early with the context's error if it is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
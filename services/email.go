@@ -0,0 +1,353 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+const (
+	emailCodeDigits      = 6
+	emailCodeLifetime    = 15 * time.Minute
+	emailCodeMaxAttempts = 5
+	// emailCodeResendAfter stops /email set from being used to flood an inbox.
+	emailCodeResendAfter = time.Minute
+	maxEmailLength       = 254
+)
+
+var (
+	// ErrEmailDisabled is returned when no SMTP server is configured.
+	ErrEmailDisabled = errors.New("email notifications are not enabled")
+	// ErrInvalidEmail is returned for malformed addresses.
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrEmailCodeTooSoon is returned when a code was sent less than a minute ago.
+	ErrEmailCodeTooSoon = errors.New("a verification code was sent recently")
+	// ErrNoEmailVerification is returned when the user has no code waiting to be entered.
+	ErrNoEmailVerification = errors.New("no email verification in progress")
+	// ErrEmailCodeExpired is returned for codes entered after they expire.
+	ErrEmailCodeExpired = errors.New("verification code expired")
+	// ErrEmailCodeInvalid is returned for wrong codes.
+	ErrEmailCodeInvalid = errors.New("incorrect verification code")
+	// ErrEmailCodeAttempts is returned once too many wrong codes have been entered.
+	ErrEmailCodeAttempts = errors.New("too many incorrect verification codes")
+	// ErrEmailNotVerified is returned when choosing email notifications without a verified address.
+	ErrEmailNotVerified = errors.New("no verified email address")
+)
+
+// EmailEnabled reports whether an SMTP server is configured.
+func EmailEnabled() bool {
+	cfg := configuration.Get()
+	return cfg.SMTP.Host != "" && cfg.SMTP.From != ""
+}
+
+// StartEmailVerification emails a one-time code to address. The address only becomes the
+// user's notification address once the code is entered with VerifyEmail.
+func StartEmailVerification(userID, address string) error {
+	if !EmailEnabled() {
+		return ErrEmailDisabled
+	}
+
+	address, err := normalizeEmail(address)
+	if err != nil {
+		return err
+	}
+
+	var existing models.EmailVerification
+	err = database.DB.Where("user_id = ?", userID).First(&existing).Error
+	if err == nil && time.Since(existing.UpdatedAt) < emailCodeResendAfter {
+		return ErrEmailCodeTooSoon
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to load email verification: %w", err)
+	}
+
+	code, err := newEmailCode()
+	if err != nil {
+		return err
+	}
+
+	verification := models.EmailVerification{
+		UserID:    userID,
+		Email:     address,
+		CodeHash:  hashEmailCode(code),
+		ExpiresAt: time.Now().Add(emailCodeLifetime),
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.EmailVerification{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&verification).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save email verification: %w", err)
+	}
+
+	lang := LanguageForUser(userID)
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "email.verify_mail.title"),
+		Description: i18n.T(lang, "email.verify_mail.description", code, int(emailCodeLifetime.Minutes())),
+		Color:       0x00ff00,
+	}
+	if err := sendEmbedEmail(address, embed); err != nil {
+		database.DB.Unscoped().Delete(&verification)
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+// VerifyEmail checks code against the user's pending verification and, if it matches, makes
+// the address the user's notification address. It returns the verified address.
+func VerifyEmail(userID, code string) (string, error) {
+	var verification models.EmailVerification
+	err := database.DB.Where("user_id = ?", userID).First(&verification).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNoEmailVerification
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load email verification: %w", err)
+	}
+
+	if time.Now().After(verification.ExpiresAt) {
+		database.DB.Unscoped().Delete(&verification)
+		return "", ErrEmailCodeExpired
+	}
+	if verification.Attempts >= emailCodeMaxAttempts {
+		return "", ErrEmailCodeAttempts
+	}
+
+	hash := hashEmailCode(strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(verification.CodeHash)) != 1 {
+		if err := database.DB.Model(&verification).UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+			logger.Log.WithError(err).Error("Failed to record email verification attempt")
+		}
+		return "", ErrEmailCodeInvalid
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		settings := models.UserSettings{Email: verification.Email}
+		if err := tx.Model(&models.UserSettings{}).Where("user_id = ?", userID).Select("Email").Updates(&settings).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&verification).Error
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save verified email: %w", err)
+	}
+	return verification.Email, nil
+}
+
+// RemoveEmail forgets the user's address and any pending verification, and stops routing
+// notifications to email.
+func RemoveEmail(userID string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.EmailVerification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.UserSettings{}).Where("user_id = ?", userID).Update("email", "").Error; err != nil {
+			return err
+		}
+		return removeNotificationDestination(tx, userID, "email")
+	})
+}
+
+// SendTestEmail sends a sample notification to the user's verified address.
+func SendTestEmail(userID string) error {
+	if !EmailEnabled() {
+		return ErrEmailDisabled
+	}
+
+	settings, err := GetUserSettings(userID)
+	if err != nil {
+		return err
+	}
+	if settings.Email == "" {
+		return ErrEmailNotVerified
+	}
+
+	lang := UserLanguage(settings)
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "notifier.test.title"),
+		Description: i18n.T(lang, "email.test_mail.description"),
+		Color:       0x00ff00,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+	return sendEmbedEmail(settings.Email, embed)
+}
+
+// sendEmbedEmail emails embed to address as HTML with a plain text alternative.
+func sendEmbedEmail(address string, embed *discordgo.MessageEmbed) error {
+	content, err := renderEmbedEmail(embed)
+	if err != nil {
+		return err
+	}
+	return sendEmail(address, content)
+}
+
+func normalizeEmail(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if len(address) > maxEmailLength {
+		return "", ErrInvalidEmail
+	}
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address || !strings.Contains(address[strings.LastIndex(address, "@"):], ".") {
+		return "", ErrInvalidEmail
+	}
+	return address, nil
+}
+
+func newEmailCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", emailCodeDigits, n.Int64()), nil
+}
+
+func hashEmailCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendEmail delivers content to one recipient through the configured SMTP server. SMTP_TLS
+// selects STARTTLS (the default), implicit TLS, or none for a relay on the local network.
+func sendEmail(to string, content emailContent) error {
+	cfg := configuration.Get().SMTP
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+	message, err := buildEmailMessage(from, to, content)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	tlsConfig := &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	if cfg.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if cfg.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(cfg.Timeout))
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if cfg.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("SMTP server rejected recipient: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP server rejected message: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected message: %w", err)
+	}
+	return client.Quit()
+}
+
+// buildEmailMessage encodes content as a multipart/alternative message with quoted-printable
+// plain text and HTML parts.
+func buildEmailMessage(from *mail.Address, to string, content emailContent) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", content.Text},
+		{"text/html; charset=utf-8", content.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(content.Subject)
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from.String())
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// emailContent is one notification rendered for email.
+type emailContent struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+var (
+	discordTimestamp   = regexp.MustCompile(`<t:(-?\d+)(?::[tTdDfFR])?>`)
+	discordMention     = regexp.MustCompile(`<(?:@[!&]?|#)\d+> ?`)
+	discordCustomEmoji = regexp.MustCompile(`<a?:\w+:\d+> ?`)
+	markdownBold       = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	markdownCode       = regexp.MustCompile("`([^`]+)`")
+	markdownCodeFence  = regexp.MustCompile("```[a-z]*\n?")
+)
+
+var emailTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="margin:0;padding:24px;background:#f4f5f7;font-family:Arial,Helvetica,sans-serif;color:#1f2328;">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" style="max-width:600px;margin:0 auto;background:#ffffff;border-left:4px solid {{.Color}};">
+<tr><td style="padding:20px 24px;">
+<h2 style="margin:0 0 12px;font-size:20px;">{{.Title}}</h2>
+{{- if .Description}}
+<p style="margin:0 0 16px;line-height:1.5;">{{.Description}}</p>
+{{- end}}
+{{- range .Fields}}
+<h3 style="margin:16px 0 4px;font-size:15px;">{{.Name}}</h3>
+<p style="margin:0;line-height:1.5;">{{.Value}}</p>
+{{- end}}
+{{- if .Footer}}
+<p style="margin:24px 0 0;font-size:12px;color:#6a737d;">{{.Footer}}</p>
+{{- end}}
+</td></tr>
+</table>
+</body>
+</html>
+`))
+
+type emailField struct {
+	Name  string
+	Value template.HTML
+}
+
+type emailView struct {
+	Title       string
+	Description template.HTML
+	Color       string
+	Fields      []emailField
+	Footer      string
+}
+
+// renderEmbedEmail renders a notification embed as an email with the same title, description,
+// fields and footer. Discord-only markup such as mentions, custom emoji and <t:…> timestamps is
+// replaced with plain equivalents.
+func renderEmbedEmail(embed *discordgo.MessageEmbed) (emailContent, error) {
+	title := plainDiscordText(embed.Title)
+	view := emailView{
+		Title:       title,
+		Description: htmlDiscordText(embed.Description),
+		Color:       fmt.Sprintf("#%06x", embed.Color&0xFFFFFF),
+	}
+
+	for _, field := range embed.Fields {
+		view.Fields = append(view.Fields, emailField{Name: plainDiscordText(field.Name), Value: htmlDiscordText(field.Value)})
+	}
+	if embed.Footer != nil {
+		view.Footer = plainDiscordText(embed.Footer.Text)
+	}
+
+	var body bytes.Buffer
+	if err := emailTemplate.Execute(&body, view); err != nil {
+		return emailContent{}, fmt.Errorf("failed to render email: %w", err)
+	}
+
+	return emailContent{
+		Subject: title,
+		Text:    title + "\n" + strings.Repeat("=", len([]rune(title))) + "\n\n" + plainEmbedBody(embed),
+		HTML:    body.String(),
+	}, nil
+}
+
+// plainEmbedText renders an embed as plain text, title first.
+func plainEmbedText(embed *discordgo.MessageEmbed) string {
+	return plainDiscordText(embed.Title) + "\n\n" + plainEmbedBody(embed)
+}
+
+// plainEmbedBody renders an embed's description, fields and footer as plain text.
+func plainEmbedBody(embed *discordgo.MessageEmbed) string {
+	var text strings.Builder
+	if embed.Description != "" {
+		text.WriteString(plainDiscordText(embed.Description) + "\n\n")
+	}
+	for _, field := range embed.Fields {
+		text.WriteString(plainDiscordText(field.Name) + "\n" + plainDiscordText(field.Value) + "\n\n")
+	}
+	if embed.Footer != nil && embed.Footer.Text != "" {
+		text.WriteString("-- \n" + plainDiscordText(embed.Footer.Text) + "\n")
+	}
+	return strings.TrimRight(text.String(), "\n") + "\n"
+}
+
+// stripDiscordMarkup removes markup that only means something inside Discord.
+func stripDiscordMarkup(s string) string {
+	s = discordMention.ReplaceAllString(s, "")
+	s = discordCustomEmoji.ReplaceAllString(s, "")
+	s = markdownCodeFence.ReplaceAllString(s, "")
+	return discordTimestamp.ReplaceAllStringFunc(s, func(match string) string {
+		unix, err := strconv.ParseInt(discordTimestamp.FindStringSubmatch(match)[1], 10, 64)
+		if err != nil {
+			return match
+		}
+		return time.Unix(unix, 0).UTC().Format("Jan 2, 2006 15:04 MST")
+	})
+}
+
+func plainDiscordText(s string) string {
+	s = stripDiscordMarkup(s)
+	s = markdownBold.ReplaceAllString(s, "$1")
+	return strings.TrimSpace(markdownCode.ReplaceAllString(s, "$1"))
+}
+
+func htmlDiscordText(s string) template.HTML {
+	s = html.EscapeString(strings.TrimSpace(stripDiscordMarkup(s)))
+	s = markdownBold.ReplaceAllString(s, "<strong>$1</strong>")
+	s = markdownCode.ReplaceAllString(s, "<code>$1</code>")
+	return template.HTML(strings.ReplaceAll(s, "\n", "<br>\n"))
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+	"github.com/bradselph/CODStatusBot/testsupport/fakesmtp"
+	"github.com/bwmarrin/discordgo"
+)
+
+var emailCodePattern = regexp.MustCompile(`\b\d{6}\b`)
+
+// startFakeSMTP points the SMTP configuration at a fake server for the rest of the test.
+func startFakeSMTP(t *testing.T) *fakesmtp.Server {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := cfg.SMTP
+	t.Cleanup(func() { cfg.SMTP = saved })
+
+	srv, err := fakesmtp.New()
+	if err != nil {
+		t.Fatalf("start fake SMTP server: %v", err)
+	}
+	t.Cleanup(srv.Close)
+	srv.Apply(cfg)
+	cfg.SMTP.Timeout = 5 * time.Second
+	return srv
+}
+
+// onlyMessage returns the single message srv accepted.
+func onlyMessage(t *testing.T, srv *fakesmtp.Server) fakesmtp.Message {
+	t.Helper()
+	messages := srv.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("server accepted %d messages, want 1", len(messages))
+	}
+	return messages[0]
+}
+
+func TestEmailVerificationAndStatusNotification(t *testing.T) {
+	useTestDatabase(t)
+	srv := startFakeSMTP(t)
+
+	if _, err := GetUserSettings("user-1"); err != nil {
+		t.Fatalf("GetUserSettings: %v", err)
+	}
+	if err := StartEmailVerification("user-1", "player@example.com"); err != nil {
+		t.Fatalf("StartEmailVerification: %v", err)
+	}
+
+	verification := onlyMessage(t, srv)
+	if len(verification.To) != 1 || verification.To[0] != "player@example.com" {
+		t.Fatalf("verification sent to %v", verification.To)
+	}
+	if verification.Text == "" || verification.HTML == "" {
+		t.Fatalf("verification is missing a text or HTML part:\n%s", verification.Data)
+	}
+	code := emailCodePattern.FindString(verification.Text)
+	if code == "" || !strings.Contains(verification.HTML, code) {
+		t.Fatalf("verification code missing from the text or HTML part:\n%s", verification.Data)
+	}
+
+	if err := StartEmailVerification("user-1", "player@example.com"); !errors.Is(err, ErrEmailCodeTooSoon) {
+		t.Fatalf("immediate resend err = %v, want ErrEmailCodeTooSoon", err)
+	}
+
+	address, err := VerifyEmail("user-1", code)
+	if err != nil || address != "player@example.com" {
+		t.Fatalf("VerifyEmail = %q, %v", address, err)
+	}
+	if err := database.DB.Model(&models.UserSettings{}).Where("user_id = ?", "user-1").Update("notification_type", "email").Error; err != nil {
+		t.Fatalf("route notifications to email: %v", err)
+	}
+
+	srv.Reset()
+	session := fakediscord.New()
+	account := models.Account{UserID: "user-1", ChannelID: "channel-1", Title: "main"}
+	embed := &discordgo.MessageEmbed{
+		Title:       "main - Temporary Ban",
+		Description: "Status changed to **Temporary Ban** <@123456789012345678>",
+		Fields:      []*discordgo.MessageEmbedField{{Name: "Ends", Value: "`3 days`"}},
+	}
+	if err := SendNotification(session, account, embed, "<@user-1>", "tempban"); err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+
+	notification := onlyMessage(t, srv)
+	if notification.Subject != "main - Temporary Ban" {
+		t.Errorf("subject = %q", notification.Subject)
+	}
+	if !strings.Contains(notification.Text, "Status changed to Temporary Ban") || strings.ContainsAny(notification.Text, "*`") {
+		t.Errorf("text part still carries Discord markup:\n%s", notification.Text)
+	}
+	if strings.Contains(notification.Text, "<@") || strings.Contains(notification.HTML, "&lt;@") {
+		t.Errorf("user mention leaked into the email:\n%s", notification.Data)
+	}
+	for _, want := range []string{"<strong>Temporary Ban</strong>", "<code>3 days</code>", "Ends"} {
+		if !strings.Contains(notification.HTML, want) {
+			t.Errorf("HTML part is missing %q:\n%s", want, notification.HTML)
+		}
+	}
+	if msgs := session.Messages(); len(msgs) != 0 {
+		t.Errorf("notification routed to email was also sent to Discord: %d messages", len(msgs))
+	}
+}
+
+func TestEmailRequiresStartTLSWhenConfigured(t *testing.T) {
+	srv := startFakeSMTP(t)
+	configuration.Get().SMTP.TLS = "starttls"
+
+	err := sendEmbedEmail("player@example.com", &discordgo.MessageEmbed{Title: "Test"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("err = %v, want a STARTTLS error from a server without STARTTLS", err)
+	}
+	if n := len(srv.Messages()); n != 0 {
+		t.Fatalf("message sent in plain text despite SMTP_TLS=starttls (%d accepted)", n)
+	}
+}
+
+func TestEmailWithoutTLS(t *testing.T) {
+	srv := startFakeSMTP(t)
+	configuration.Get().SMTP.TLS = "none"
+
+	if err := sendEmbedEmail("player@example.com", &discordgo.MessageEmbed{Title: "Test", Description: "Body"}); err != nil {
+		t.Fatalf("sendEmbedEmail: %v", err)
+	}
+	msg := onlyMessage(t, srv)
+	if msg.Subject != "Test" || !strings.Contains(msg.Text, "Body") || !strings.Contains(msg.HTML, "Body") {
+		t.Fatalf("unexpected message:\n%s", msg.Data)
+	}
+}
+
+func TestVerifyEmailAttemptLimit(t *testing.T) {
+	useTestDatabase(t)
+	srv := startFakeSMTP(t)
+
+	if _, err := GetUserSettings("user-2"); err != nil {
+		t.Fatalf("GetUserSettings: %v", err)
+	}
+	if err := StartEmailVerification("user-2", "other@example.com"); err != nil {
+		t.Fatalf("StartEmailVerification: %v", err)
+	}
+	code := emailCodePattern.FindString(onlyMessage(t, srv).Text)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 0; i < emailCodeMaxAttempts; i++ {
+		if _, err := VerifyEmail("user-2", wrong); !errors.Is(err, ErrEmailCodeInvalid) {
+			t.Fatalf("attempt %d err = %v, want ErrEmailCodeInvalid", i+1, err)
+		}
+	}
+	if _, err := VerifyEmail("user-2", code); !errors.Is(err, ErrEmailCodeAttempts) {
+		t.Fatalf("correct code after the limit err = %v, want ErrEmailCodeAttempts", err)
+	}
+
+	settings, err := GetUserSettings("user-2")
+	if err != nil {
+		t.Fatalf("GetUserSettings: %v", err)
+	}
+	if settings.Email != "" {
+		t.Fatalf("address %q was verified after the attempt limit", settings.Email)
+	}
+}
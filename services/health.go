@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+)
+
+// captchaBalanceTTL is how long CaptchaBalances reuses a lookup, so frequent readiness probes and
+// admin page views do not turn into a stream of provider API calls.
+const captchaBalanceTTL = 10 * time.Minute
+
+var lastCheckPass atomic.Int64
+
+func recordCheckPass(t time.Time) {
+	lastCheckPass.Store(t.UnixNano())
+}
+
+// LastCheckPass returns when CheckAccounts last ran to completion, or the zero time if it has not
+// finished a pass since startup.
+func LastCheckPass() time.Time {
+	if n := lastCheckPass.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
+}
+
+// CaptchaBalance is the state of an enabled provider's default key.
+type CaptchaBalance struct {
+	Provider  string
+	HasKey    bool
+	Valid     bool
+	Balance   float64
+	Minimum   float64
+	Error     string
+	CheckedAt time.Time
+}
+
+// Funded reports whether the key is valid and holds at least the provider's minimum balance.
+func (b CaptchaBalance) Funded() bool {
+	return b.HasKey && b.Valid && b.Error == "" && b.Balance >= b.Minimum
+}
+
+var captchaBalances struct {
+	sync.Mutex
+	checkedAt time.Time
+	balances  []CaptchaBalance
+}
+
+// CaptchaBalances looks up the default key balance of every enabled provider. Results are cached
+// for captchaBalanceTTL.
+func CaptchaBalances(ctx context.Context) []CaptchaBalance {
+	captchaBalances.Lock()
+	defer captchaBalances.Unlock()
+
+	if !captchaBalances.checkedAt.IsZero() && time.Since(captchaBalances.checkedAt) < captchaBalanceTTL {
+		return captchaBalances.balances
+	}
+
+	cfg := configuration.Get()
+	now := time.Now()
+	var balances []CaptchaBalance
+	for _, p := range EnabledCaptchaProviders() {
+		b := CaptchaBalance{Provider: p.Name, Minimum: p.BalanceMin(cfg), CheckedAt: now}
+		if apiKey := p.DefaultKey(cfg); apiKey != "" {
+			b.HasKey = true
+			valid, balance, err := p.ValidateKey(ctx, apiKey)
+			b.Valid, b.Balance = valid, balance
+			if err != nil {
+				b.Error = err.Error()
+			}
+		}
+		balances = append(balances, b)
+	}
+
+	if ctx.Err() == nil {
+		captchaBalances.checkedAt = now
+		captchaBalances.balances = balances
+	}
+	return balances
+}
+
+// FundedCaptchaProviders returns the enabled providers whose default key is valid and holds at
+// least the provider's minimum balance.
+func FundedCaptchaProviders(ctx context.Context) []string {
+	var funded []string
+	for _, b := range CaptchaBalances(ctx) {
+		if b.Funded() {
+			funded = append(funded, b.Provider)
+		}
+	}
+	return funded
+}
@@ -1,17 +1,18 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
 	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/metrics"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/sirupsen/logrus"
 )
@@ -29,169 +30,235 @@ func init() {
 	logger.Log.Infof("Initialized endpoints: Profile URL: %s", cfg.API.ProfileEndpoint)
 }
 
-func VerifySSOCookie(ssoCookie string) bool {
+// errSSOCookieRejected means the profile endpoint answered and refused the cookie, as opposed to
+// being unreachable or failing.
+var errSSOCookieRejected = errors.New("SSO cookie was rejected")
+
+func VerifySSOCookie(ctx context.Context, ssoCookie string) bool {
+	return verifySSOCookie(ctx, ssoCookie) == nil
+}
+
+// verifySSOCookie checks the cookie against the profile endpoint. The error wraps
+// errSSOCookieRejected only when the endpoint refused the cookie; transport failures and server
+// errors are retried and returned as they are.
+func verifySSOCookie(ctx context.Context, ssoCookie string) error {
 	cfg := configuration.Get()
-	logger.Log.Infof("Starting SSO cookie verification for cookie: %s", ssoCookie)
+	logger.Log.Infof("Starting SSO cookie verification, cookie length: %d", len(ssoCookie))
 
 	profileURL := cfg.API.ProfileEndpoint
 	if profileURL == "" {
 		logger.Log.Error("PROFILE_ENDPOINT not configured")
-		return false
-	}
-
-	client := &http.Client{
-		Timeout: 120 * time.Second,
+		return errors.New("PROFILE_ENDPOINT not configured")
 	}
 
 	maxRetries := 3
 	var lastError error
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		req, err := http.NewRequest("GET", profileURL, nil)
-		if err != nil {
-			lastError = fmt.Errorf("error creating verification request (attempt %d/%d): %w", attempt, maxRetries, err)
-			logger.Log.WithError(err).Error("Error creating verification request")
-			continue
+		if ctx.Err() != nil {
+			lastError = ctx.Err()
+			break
 		}
 
-		headers := GenerateHeaders(ssoCookie)
-		for k, v := range headers {
-			req.Header.Set(k, v)
+		lastError = verifySSOCookieAttempt(ctx, profileURL, ssoCookie, attempt, maxRetries)
+		if lastError == nil {
+			logger.Log.Info("SSO cookie verified successfully")
+			return nil
 		}
-
-		logger.Log.Infof("Sending verification request to: %s (attempt %d/%d)", profileURL, attempt, maxRetries)
-		resp, err := client.Do(req)
-		if err != nil {
-			lastError = fmt.Errorf("error sending verification request (attempt %d/%d): %w", attempt, maxRetries, err)
-			logger.Log.WithError(err).Error("Error sending verification request")
-			time.Sleep(time.Duration(attempt) * time.Second)
-			continue
+		if errors.Is(lastError, errSSOCookieRejected) || attempt == maxRetries {
+			break
 		}
 
-		if resp != nil && resp.Body != nil {
-			defer func(Body io.ReadCloser) {
-				err := Body.Close()
-				if err != nil {
-					logger.Log.WithError(err).Error("Failed to close response body")
-				}
-			}(resp.Body)
+		if err := sleepContext(ctx, time.Duration(attempt)*time.Second); err != nil {
+			lastError = err
+			break
 		}
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			lastError = fmt.Errorf("invalid status code (attempt %d/%d): %d", attempt, maxRetries, resp.StatusCode)
-			logger.Log.WithFields(logrus.Fields{
-				"statusCode": resp.StatusCode,
-				"attempt":    attempt,
-			}).Error("Invalid status code in SSO verification")
-			time.Sleep(time.Duration(attempt) * time.Second)
-			continue
-		}
+	logger.Log.WithError(lastError).Error("SSO cookie verification failed after all retries")
+	return lastError
+}
 
-		body, err := io.ReadAll(resp.Body)
+func verifySSOCookieAttempt(ctx context.Context, profileURL, ssoCookie string, attempt, maxRetries int) error {
+	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", profileURL, nil)
+	if err != nil {
+		logger.Log.WithError(err).Error("Error creating verification request")
+		return fmt.Errorf("error creating verification request (attempt %d/%d): %w", attempt, maxRetries, err)
+	}
+
+	headers := GenerateHeaders(ssoCookie)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	logger.Log.Infof("Sending verification request to: %s (attempt %d/%d)", profileURL, attempt, maxRetries)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		logger.Log.WithError(err).Error("Error sending verification request")
+		return fmt.Errorf("error sending verification request (attempt %d/%d): %w", attempt, maxRetries, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
 		if err != nil {
-			lastError = fmt.Errorf("error reading response body (attempt %d/%d): %w", attempt, maxRetries, err)
-			logger.Log.WithError(err).Error("Error reading response body")
-			time.Sleep(time.Duration(attempt) * time.Second)
-			continue
+			logger.Log.WithError(err).Error("Failed to close response body")
 		}
+	}(resp.Body)
 
-		if len(body) == 0 {
-			lastError = fmt.Errorf("empty response body (attempt %d/%d)", attempt, maxRetries)
-			logger.Log.Error("Empty response body in SSO verification")
-			time.Sleep(time.Duration(attempt) * time.Second)
-			continue
+	if resp.StatusCode != http.StatusOK {
+		logger.Log.WithFields(logrus.Fields{
+			"statusCode": resp.StatusCode,
+			"attempt":    attempt,
+		}).Error("Invalid status code in SSO verification")
+		if resp.StatusCode < http.StatusInternalServerError {
+			return fmt.Errorf("%w: status %d", errSSOCookieRejected, resp.StatusCode)
 		}
+		return fmt.Errorf("invalid status code (attempt %d/%d): %d", attempt, maxRetries, resp.StatusCode)
+	}
 
-		logger.Log.Info("SSO cookie verified successfully")
-		return true
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Log.WithError(err).Error("Error reading response body")
+		return fmt.Errorf("error reading response body (attempt %d/%d): %w", attempt, maxRetries, err)
 	}
 
-	logger.Log.WithError(lastError).Error("SSO cookie verification failed after all retries")
-	return false
+	if len(body) == 0 {
+		logger.Log.Error("Empty response body in SSO verification")
+		return fmt.Errorf("%w: empty response body", errSSOCookieRejected)
+	}
+
+	return nil
 }
 
-func CheckAccount(ssoCookie string, userID string, captchaAPIKey string) (models.Status, error) {
+// CheckAccount fetches the account's ban status from Activision, solving a captcha for the request.
+func CheckAccount(ctx context.Context, ssoCookie string, userID string, captchaAPIKey string) (BanCheck, error) {
+	start := time.Now()
+	result, err := checkAccount(ctx, ssoCookie, userID, captchaAPIKey)
+	metrics.ObserveAccountCheck(string(result.Status), err, time.Since(start))
+	return result, err
+}
+
+func checkAccount(ctx context.Context, ssoCookie string, userID string, captchaAPIKey string) (BanCheck, error) {
 	cfg := configuration.Get()
 	logger.Log.Info("Starting CheckAccount function")
 
 	userSettings, err := GetUserSettings(userID)
 	if err != nil {
-		return models.StatusUnknown, fmt.Errorf("failed to get user settings: %w", err)
-	}
-
-	if !VerifySSOCookie(ssoCookie) {
-		return models.StatusInvalidCookie, nil
+		return BanCheck{Status: models.StatusUnknown}, fmt.Errorf("failed to get user settings: %w", err)
 	}
 
-	if !IsServiceEnabled("ezcaptcha") &&
-		!IsServiceEnabled("capsolver") &&
-		!IsServiceEnabled("2captcha") {
-		return models.StatusUnknown, fmt.Errorf("no captcha services are currently enabled")
+	if err := verifySSOCookie(ctx, ssoCookie); err != nil {
+		if ctx.Err() != nil {
+			return BanCheck{Status: models.StatusUnknown}, ctx.Err()
+		}
+		if errors.Is(err, errSSOCookieRejected) {
+			return BanCheck{Status: models.StatusInvalidCookie}, nil
+		}
+		return BanCheck{Status: models.StatusUnknown}, fmt.Errorf("failed to verify SSO cookie: %w", err)
 	}
 
-	if !IsServiceEnabled(userSettings.PreferredCaptchaProvider) {
-		if IsServiceEnabled("capsolver") {
-			userSettings.PreferredCaptchaProvider = "capsolver"
-			database.DB.Save(&userSettings)
-		} else if IsServiceEnabled("ezcaptcha") {
-			userSettings.PreferredCaptchaProvider = "ezcaptcha"
-			database.DB.Save(&userSettings)
-		} else if IsServiceEnabled("2captcha") {
-			userSettings.PreferredCaptchaProvider = "2captcha"
-			database.DB.Save(&userSettings)
-		} else {
-			return models.StatusUnknown, fmt.Errorf("no captcha services are currently enabled")
-		}
+	if len(EnabledCaptchaProviders()) == 0 {
+		return BanCheck{Status: models.StatusUnknown}, fmt.Errorf("no captcha services are currently enabled")
 	}
 
-	isUsingDefaultKey := userSettings.CapSolverAPIKey == "" &&
-		userSettings.EZCaptchaAPIKey == "" &&
-		userSettings.TwoCaptchaAPIKey == ""
+	isUsingDefaultKey := !userSettings.HasCustomCaptchaKey()
 
 	if isUsingDefaultKey {
 		if !validateRateLimit(userID, "check_account", cfg.RateLimits.CheckNow) {
-			return models.StatusUnknown, fmt.Errorf("rate limit exceeded for default key users")
+			return BanCheck{Status: models.StatusUnknown}, fmt.Errorf("rate limit exceeded for default key users")
 		}
 	}
 
-	solver, err := GetCaptchaSolver(userID)
-	if err != nil {
-		if strings.Contains(err.Error(), "insufficient balance") {
-			if err := DisableUserCaptcha(nil, userID, "Insufficient balance"); err != nil {
-				logger.Log.WithError(err).Error("Failed to disable user captcha service")
+	siteKey := cfg.CaptchaService.RecaptchaSiteKey
+	pageURL := cfg.CaptchaService.RecaptchaURL
+
+	var body []byte
+	for allowPooled := true; ; allowPooled = false {
+		captcha, err := acquireCaptchaToken(ctx, userID, siteKey, pageURL, allowPooled)
+		if err != nil {
+			if ctx.Err() != nil {
+				return BanCheck{Status: models.StatusUnknown}, ctx.Err()
 			}
-			return models.StatusUnknown, fmt.Errorf("critical error: %w", err)
+			if errors.Is(err, ErrInsufficientBalance) {
+				if err := DisableUserCaptcha(nil, userID, "Insufficient balance"); err != nil {
+					logger.Log.WithError(err).Error("Failed to disable user captcha service")
+				}
+				return BanCheck{Status: models.StatusUnknown}, fmt.Errorf("insufficient captcha balance")
+			}
+			return BanCheck{Status: models.StatusUnknown}, fmt.Errorf("failed to solve reCAPTCHA: %w", err)
 		}
-		return models.StatusUnknown, fmt.Errorf("failed to create captcha solver: %w", err)
-	}
 
-	gRecaptchaResponse, err := solver.SolveReCaptchaV2(cfg.CaptchaService.RecaptchaSiteKey, cfg.CaptchaService.RecaptchaURL)
-	if err != nil {
-		if strings.Contains(err.Error(), "insufficient balance") {
-			if err := DisableUserCaptcha(nil, userID, "Insufficient balance"); err != nil {
-				logger.Log.WithError(err).Error("Failed to disable user captcha service")
-			}
-			return models.StatusUnknown, fmt.Errorf("insufficient captcha balance")
+		if captcha.pooled {
+			logger.Log.Infof("Using pooled reCAPTCHA response from %s", captcha.Provider)
+		} else {
+			logger.Log.Infof("Successfully received reCAPTCHA response from %s", captcha.Provider)
+		}
+
+		body, err = requestAppeal(ctx, ssoCookie, captcha.Token)
+		if err != nil {
+			return BanCheck{Status: models.StatusUnknown}, err
 		}
-		return models.StatusUnknown, fmt.Errorf("failed to solve reCAPTCHA: %w", err)
+
+		if !isCaptchaRejection(body) {
+			acceptCaptchaToken(captcha)
+			break
+		}
+		rejectCaptchaToken(userID, siteKey, pageURL, captcha.Token)
+		if !captcha.pooled {
+			break
+		}
+		logger.Log.Warnf("Pooled reCAPTCHA response from %s was rejected, solving a fresh one", captcha.Provider)
 	}
 
-	if strings.Contains(gRecaptchaResponse, "Invalid") || len(gRecaptchaResponse) < 50 {
-		return models.StatusUnknown, fmt.Errorf("invalid captcha response received")
+	var errorResponse struct {
+		Timestamp string `json:"timestamp"`
+		Path      string `json:"path"`
+		Status    int    `json:"status"`
+		Error     string `json:"error"`
+		RequestId string `json:"requestId"`
+		Exception string `json:"exception"`
 	}
 
-	logger.Log.Info("Successfully received reCAPTCHA response")
+	if err := json.Unmarshal(body, &errorResponse); err == nil {
+		logger.Log.WithField("errorResponse", errorResponse).Info("Parsed error response")
+		if errorResponse.Status == 400 && errorResponse.Path == "/api/bans/v2/appeal" {
+			return BanCheck{Status: models.StatusUnknown}, fmt.Errorf("invalid request to endpoint: %s", errorResponse.Error)
+		}
+	}
 
-	checkRequest := fmt.Sprintf("%s?locale=en&g-cc=%s", cfg.API.CheckEndpoint, gRecaptchaResponse)
-	logger.Log.WithField("url", checkRequest).Info("Constructed account check request")
+	var data appealResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return BanCheck{Status: models.StatusUnknown}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	check := parseAppealResponse(data)
+	logger.Log.WithFields(map[string]interface{}{
+		"status":       check.Status,
+		"canAppeal":    check.CanAppeal,
+		"enforcements": check.Enforcements,
+	}).Info("Parsed ban data")
 
-	client := &http.Client{
-		Timeout: 120 * time.Second,
+	if check.Status != models.StatusGood {
+		if err := UpdateCaptchaUsage(ctx, userID); err != nil {
+			logger.Log.WithError(err).Error("Failed to update captcha usage")
+		}
 	}
 
-	req, err := http.NewRequest("GET", checkRequest, nil)
+	return check, nil
+}
+
+// requestAppeal asks the ban appeal endpoint about the account behind ssoCookie, retrying
+// transport failures and server errors.
+func requestAppeal(ctx context.Context, ssoCookie, gRecaptchaResponse string) ([]byte, error) {
+	cfg := configuration.Get()
+	checkRequest := fmt.Sprintf("%s?locale=en&g-cc=%s", cfg.API.CheckEndpoint, gRecaptchaResponse)
+	logger.Log.WithField("endpoint", cfg.API.CheckEndpoint).Info("Constructed account check request")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", checkRequest, nil)
 	if err != nil {
-		return models.StatusUnknown, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	headers := GenerateHeaders(ssoCookie)
@@ -200,8 +267,7 @@ func CheckAccount(ssoCookie string, userID string, captchaAPIKey string) (models
 	}
 
 	logger.Log.WithFields(logrus.Fields{
-		"url":          checkRequest,
-		"headers":      headers,
+		"headerCount":  len(headers),
 		"cookieLength": len(ssoCookie),
 	}).Debug("Set request headers")
 	var resp *http.Response
@@ -211,37 +277,51 @@ func CheckAccount(ssoCookie string, userID string, captchaAPIKey string) (models
 	backoffDuration := time.Second
 	for i := 0; i < maxRetries; i++ {
 		logger.Log.Infof("Sending request to check account (attempt %d/%d)", i+1, maxRetries)
-		resp, err = client.Do(req)
+		attemptCtx, cancel := context.WithTimeout(ctx, 120*time.Second)
+		resp, err = httpClient.Do(req.WithContext(attemptCtx))
 		if err != nil {
-			if i == maxRetries-1 {
-				return models.StatusUnknown, fmt.Errorf("failed to send request after %d attempts: %w", maxRetries, err)
+			cancel()
+			if i == maxRetries-1 || ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to send request after %d attempts: %w", i+1, err)
 			}
 			backoffDuration *= 2
-			time.Sleep(backoffDuration)
+			if err := sleepContext(ctx, backoffDuration); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		body, err = io.ReadAll(resp.Body)
 		resp.Body.Close()
+		cancel()
 		if err != nil {
-			if i == maxRetries-1 {
-				return models.StatusUnknown, fmt.Errorf("failed to read response body after %d attempts: %w", maxRetries, err)
+			if i == maxRetries-1 || ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to read response body after %d attempts: %w", i+1, err)
+			}
+			if err := sleepContext(ctx, time.Duration(i+1)*time.Second); err != nil {
+				return nil, err
 			}
-			time.Sleep(time.Duration(i+1) * time.Second)
 			continue
 		}
 
 		logger.Log.WithFields(logrus.Fields{
-			"statusCode":  resp.StatusCode,
-			"bodyLength":  len(body),
-			"bodyContent": string(body),
+			"statusCode": resp.StatusCode,
+			"bodyLength": len(body),
 		}).Info("Received API response")
 
+		if resp.StatusCode >= http.StatusInternalServerError {
+			if i == maxRetries-1 {
+				return nil, fmt.Errorf("check endpoint returned status %d after %d attempts", resp.StatusCode, i+1)
+			}
+			if err := sleepContext(ctx, time.Duration(i+1)*time.Second); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		break
 	}
 
-	logger.Log.WithField("body", string(body)).Info("Read response body")
-
 	if resp.ContentLength == 0 {
 		logger.Log.Warn("Received empty response (Content-Length: 0)")
 	}
@@ -250,95 +330,40 @@ func CheckAccount(ssoCookie string, userID string, captchaAPIKey string) (models
 		logger.Log.Warn("Empty response body after reading")
 	}
 
-	var errorResponse struct {
-		Timestamp string `json:"timestamp"`
-		Path      string `json:"path"`
-		Status    int    `json:"status"`
-		Error     string `json:"error"`
-		RequestId string `json:"requestId"`
-		Exception string `json:"exception"`
-	}
-
-	if err := json.Unmarshal(body, &errorResponse); err == nil {
-		logger.Log.WithField("errorResponse", errorResponse).Info("Parsed error response")
-		if errorResponse.Status == 400 && errorResponse.Path == "/api/bans/v2/appeal" {
-			return models.StatusUnknown, fmt.Errorf("invalid request to endpoint: %s", errorResponse.Error)
-		}
-	}
-
-	var data struct {
-		Error     string `json:"error"`
-		Success   string `json:"success"`
-		CanAppeal bool   `json:"canAppeal"`
-		Bans      []struct {
-			Enforcement string   `json:"enforcement"`
-			Title       string   `json:"title"`
-			CanAppeal   bool     `json:"canAppeal"`
-			Bar         struct{} `json:"bar,omitempty"`
-		} `json:"bans"`
-	}
-
-	if err := json.Unmarshal(body, &data); err != nil {
-		return models.StatusUnknown, fmt.Errorf("failed to parse response: %w", err)
-	}
-	logger.Log.WithField("data", data).Info("Parsed ban data")
-
-	if data.Success == "true" && len(data.Bans) == 0 {
-		logger.Log.Info("No bans found, account status is good")
-		return models.StatusGood, nil
-	}
+	return body, nil
+}
 
-	if err := UpdateCaptchaUsage(userID); err != nil {
-		logger.Log.WithError(err).Error("Failed to update captcha usage")
+// isCaptchaRejection reports whether the appeal endpoint refused the request, which is how it
+// answers a reCAPTCHA response that has expired or was already used.
+func isCaptchaRejection(body []byte) bool {
+	var errorResponse struct {
+		Status int    `json:"status"`
+		Path   string `json:"path"`
 	}
-
-	for _, ban := range data.Bans {
-		logger.Log.WithField("ban", ban).Info("Processing ban")
-		switch ban.Enforcement {
-		case "PERMANENT":
-			logger.Log.Info("Permanent ban detected")
-			return models.StatusPermaban, nil
-		case "UNDER_REVIEW":
-			logger.Log.Info("Shadowban detected")
-			return models.StatusShadowban, nil
-		case "TEMPORARY":
-			logger.Log.Info("Temporary ban detected")
-			return models.StatusTempban, nil
-		}
+	if err := json.Unmarshal(body, &errorResponse); err != nil {
+		return false
 	}
-
-	logger.Log.Info("Unknown account status")
-	return models.StatusUnknown, nil
+	return errorResponse.Status == http.StatusBadRequest && errorResponse.Path == "/api/bans/v2/appeal"
 }
 
-func UpdateCaptchaUsage(userID string) error {
+func UpdateCaptchaUsage(ctx context.Context, userID string) error {
 	settings, err := GetUserSettings(userID)
 	if err != nil {
 		return err
 	}
 
-	if settings.EZCaptchaAPIKey == "" &&
-		settings.TwoCaptchaAPIKey == "" &&
-		settings.CapSolverAPIKey == "" {
+	if !settings.HasCustomCaptchaKey() {
 		return nil
 	}
 
-	var apiKey string
-	var provider string
-
-	switch settings.PreferredCaptchaProvider {
-	case "ezcaptcha":
-		apiKey = settings.EZCaptchaAPIKey
-		provider = "ezcaptcha"
-	case "2captcha":
-		apiKey = settings.TwoCaptchaAPIKey
-		provider = "2captcha"
-	default:
-		apiKey = settings.CapSolverAPIKey
-		provider = "capsolver"
+	p, ok := LookupCaptchaProvider(settings.PreferredCaptchaProvider)
+	if !ok {
+		p, _ = LookupCaptchaProvider(string(models.Capsolver))
 	}
+	apiKey := p.UserKey(settings)
+	provider := p.Name
 
-	isValid, balance, err := ValidateCaptchaKey(apiKey, provider)
+	isValid, balance, err := ValidateCaptchaKey(ctx, apiKey, provider)
 	if err != nil {
 		return err
 	}
@@ -353,10 +378,12 @@ func UpdateCaptchaUsage(userID string) error {
 	return database.DB.Save(&settings).Error
 }
 
-func CheckAccountAge(ssoCookie string) (int, int, int, int64, error) {
+func CheckAccountAge(ctx context.Context, ssoCookie string) (int, int, int, int64, error) {
 	logger.Log.Info("Starting CheckAccountAge function")
 	cfg := configuration.Get()
-	req, err := http.NewRequest("GET", cfg.API.ProfileEndpoint, nil)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", cfg.API.ProfileEndpoint, nil)
 	if err != nil {
 		return 0, 0, 0, 0, errors.New("failed to create HTTP request to check account age")
 	}
@@ -364,10 +391,7 @@ func CheckAccountAge(ssoCookie string) (int, int, int, int64, error) {
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-	}
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return 0, 0, 0, 0, errors.New("failed to send HTTP request to check account age")
 	}
@@ -406,10 +430,12 @@ func CheckAccountAge(ssoCookie string) (int, int, int, int64, error) {
 	return years, months, days, createdEpoch, nil
 }
 
-func CheckVIPStatus(ssoCookie string) (bool, error) {
+func CheckVIPStatus(ctx context.Context, ssoCookie string) (bool, error) {
 	cfg := configuration.Get()
 	logger.Log.Info("Checking VIP status")
-	req, err := http.NewRequest("GET", cfg.API.CheckVIPEndpoint+ssoCookie, nil)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", cfg.API.CheckVIPEndpoint+ssoCookie, nil)
 	if err != nil {
 		return false, fmt.Errorf("failed to create HTTP request to check VIP status: %w", err)
 	}
@@ -417,10 +443,7 @@ func CheckVIPStatus(ssoCookie string) (bool, error) {
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return false, fmt.Errorf("failed to send HTTP request to check VIP status: %w", err)
 	}
@@ -454,9 +477,11 @@ func CheckVIPStatus(ssoCookie string) (bool, error) {
 	return data.VIP, nil
 }
 
-func ValidateAndGetAccountInfo(ssoCookie string) (*AccountValidationResult, error) {
+func ValidateAndGetAccountInfo(ctx context.Context, ssoCookie string) (*AccountValidationResult, error) {
 	cfg := configuration.Get()
-	req, err := http.NewRequest("GET", cfg.API.ProfileEndpoint, nil)
+	profileCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(profileCtx, "GET", cfg.API.ProfileEndpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create profile request: %w", err)
 	}
@@ -466,11 +491,7 @@ func ValidateAndGetAccountInfo(ssoCookie string) (*AccountValidationResult, erro
 		req.Header.Set(k, v)
 	}
 
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-	}
-
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send profile request: %w", err)
 	}
@@ -500,7 +521,7 @@ func ValidateAndGetAccountInfo(ssoCookie string) (*AccountValidationResult, erro
 		return nil, fmt.Errorf("failed to parse creation date: %w", err)
 	}
 
-	isVIP, err := CheckVIPStatus(ssoCookie)
+	isVIP, err := CheckVIPStatus(ctx, ssoCookie)
 	if err != nil {
 		logger.Log.WithError(err).Warn("Failed to check VIP status, defaulting to false")
 		isVIP = false
@@ -1,19 +1,18 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/notifytemplates"
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -40,7 +39,9 @@ func InitializeServices() {
 		cfg.Intervals.CookieExpiration, cfg.Intervals.TempBanUpdate, cfg.RateLimits.CheckNow, cfg.RateLimits.Default)
 }
 
-func CheckAccounts(s *discordgo.Session) {
+// CheckAccounts runs one periodic check over every enabled account and blocks until it is done.
+// Cancelling ctx stops new checks from starting; results already in hand are still saved.
+func CheckAccounts(ctx context.Context, s discordapi.Session) {
 	logger.Log.Info("Starting periodic account check")
 
 	var accounts []models.Account
@@ -49,18 +50,37 @@ func CheckAccounts(s *discordgo.Session) {
 		return
 	}
 
+	var userIDs []string
 	accountsByUser := make(map[string][]models.Account)
 	for _, account := range accounts {
+		if _, ok := accountsByUser[account.UserID]; !ok {
+			userIDs = append(userIDs, account.UserID)
+		}
 		accountsByUser[account.UserID] = append(accountsByUser[account.UserID], account)
 	}
 
-	for userID, userAccounts := range accountsByUser {
-		processUserAccounts(s, userID, userAccounts)
+	batches := prepareBatches(ctx, s, userIDs, accountsByUser)
+	runCheckPool(ctx, s, batches)
+
+	if ctx.Err() == nil {
+		recordCheckPass(time.Now())
 	}
 }
 
-func HandleStatusChange(s *discordgo.Session, account models.Account, newStatus models.Status, userSettings models.UserSettings) {
-	if account.IsPermabanned && newStatus == models.StatusPermaban {
+func HandleStatusChange(ctx context.Context, s discordapi.Session, account models.Account, check BanCheck, userSettings models.UserSettings) {
+	newStatus := check.Status
+	lang := UserLanguage(userSettings)
+
+	transitions, err := UpdateTitleStatuses(account.ID, check)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to update per-title statuses for account %s", account.Title)
+	}
+	titleStatuses, err := GetTitleStatuses(account.ID)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to load per-title statuses for account %s", account.Title)
+	}
+
+	if account.LastStatus == models.StatusPermaban && newStatus == models.StatusPermaban && len(transitions) == 0 {
 		if account.LastNotification != 0 {
 			logger.Log.Debugf("Account %s already notified of permaban, skipping notification", account.Title)
 			return
@@ -78,9 +98,6 @@ func HandleStatusChange(s *discordgo.Session, account models.Account, newStatus
 
 		account.LastStatus = newStatus
 		account.LastStatusChange = now.Unix()
-		account.IsPermabanned = newStatus == models.StatusPermaban
-		account.IsShadowbanned = newStatus == models.StatusShadowban
-		account.IsTempbanned = newStatus == models.StatusTempban
 		account.LastSuccessfulCheck = now
 		account.ConsecutiveErrors = 0
 
@@ -97,31 +114,23 @@ func HandleStatusChange(s *discordgo.Session, account models.Account, newStatus
 			Message:        fmt.Sprintf("Status changed from %s to %s", previousStatus, newStatus),
 			Timestamp:      now,
 			Initiator:      "auto_check",
+			AffectedGames:  AffectedTitles(titleStatuses),
 		}
-
-		if newStatus == models.StatusPermaban || newStatus == models.StatusTempban || newStatus == models.StatusShadowban {
-			statusLog.AffectedGames = getAffectedGames(account.SSOCookie)
-		}
-
-		if newStatus == models.StatusTempban {
-			statusLog.TempBanDuration = calculateBanDuration(time.Now().Add(24 * time.Hour))
+		if len(transitions) > 0 {
+			statusLog.Message += "\n" + FormatTitleTransitions(i18n.English, transitions)
 		}
+		check.applyTo(&statusLog)
 
 		if err := database.DB.Create(&statusLog).Error; err != nil {
 			logger.Log.WithError(err).Error("Failed to create status log")
 		}
 
 		ban := models.Ban{
-			AccountID: account.ID,
-			Status:    newStatus,
-		}
-
-		if newStatus == models.StatusTempban {
-			ban.TempBanDuration = calculateBanDuration(time.Now().Add(24 * time.Hour))
-			ban.AffectedGames = getAffectedGames(account.SSOCookie)
-		} else if newStatus != models.StatusGood {
-			ban.AffectedGames = getAffectedGames(account.SSOCookie)
+			AccountID:     account.ID,
+			Status:        newStatus,
+			AffectedGames: statusLog.AffectedGames,
 		}
+		check.applyTo(&ban)
 
 		if err := database.DB.Create(&ban).Error; err != nil {
 			logger.Log.WithError(err).Error("Failed to create ban record")
@@ -129,24 +138,22 @@ func HandleStatusChange(s *discordgo.Session, account models.Account, newStatus
 			logger.Log.Infof("Created ban record for account %s: %s -> %s", account.Title, previousStatus, newStatus)
 		}
 
-		embed := &discordgo.MessageEmbed{
-			Title:       fmt.Sprintf("%s - %s", account.Title, EmbedTitleFromStatus(newStatus)),
-			Description: GetStatusDescription(newStatus, account.Title, ban),
-			Color:       GetColorForStatus(newStatus, account.IsExpiredCookie, account.IsCheckDisabled),
-			Fields:      getStatusFields(account, newStatus, ban),
-			Timestamp:   now.Format(time.RFC3339),
+		data := statusTemplateData(ctx, lang, account, newStatus, ban)
+		data.PreviousStatus = previousStatus
+		if len(transitions) > 0 {
+			data.TitleChanges = FormatTitleTransitions(lang, transitions)
 		}
-
-		if previousStatus != models.StatusUnknown {
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:   "Previous Status",
-				Value:  string(previousStatus),
-				Inline: true,
-			})
+		if len(titleStatuses) > 0 {
+			data.TitleStatuses = FormatTitleStatuses(lang, titleStatuses)
 		}
 
+		notifyWebhooksStatusChange(account, previousStatus, ban)
+
 		notificationType := getNotificationType(newStatus)
-		err := SendNotification(s, account, embed, fmt.Sprintf("<@%s>", account.UserID), notificationType)
+		embed, err := renderEmbed(notifytemplates.StatusChange, data, GetColorForStatus(newStatus, account.IsExpiredCookie, account.IsCheckDisabled))
+		if err == nil {
+			err = SendNotification(s, account, embed, fmt.Sprintf("<@%s>", account.UserID), notificationType)
+		}
 		if err != nil {
 			logger.Log.WithError(err).Errorf("Failed to send status update message for account %s", account.Title)
 		} else {
@@ -156,47 +163,20 @@ func HandleStatusChange(s *discordgo.Session, account models.Account, newStatus
 			}
 		}
 
-		switch newStatus {
-		case models.StatusTempban:
-			go ScheduleTempBanNotification(s, account, ban.TempBanDuration)
-
-		case models.StatusPermaban:
-			permaBanEmbed := &discordgo.MessageEmbed{
-				Title: fmt.Sprintf("%s - Permanent Ban Detected", account.Title),
-				Description: "This account has been permanently banned. It's recommended to remove it from monitoring " +
-					"using the /removeaccount command to free up your account slot.",
-				Color:     GetColorForStatus(newStatus, false, false),
-				Timestamp: now.Format(time.RFC3339),
-				Fields: []*discordgo.MessageEmbedField{
-					{
-						Name:   "Account Status",
-						Value:  "Permanently Banned",
-						Inline: true,
-					},
-					{
-						Name:   "Action Required",
-						Value:  "Remove account using /removeaccount",
-						Inline: true,
-					},
-					{
-						Name:   "Note",
-						Value:  "Removing this account will free up a slot for monitoring another account.",
-						Inline: false,
-					},
-				},
+		if newStatus != models.StatusTempban {
+			if err := CancelJobs(account.ID, models.JobTempBanReminder, models.JobTempBanRecheck); err != nil {
+				logger.Log.WithError(err).Errorf("Failed to cancel temp-ban jobs for account %s", account.Title)
 			}
+		}
 
-			if ban.AffectedGames != "" {
-				permaBanEmbed.Fields = append(permaBanEmbed.Fields, &discordgo.MessageEmbedField{
-					Name:   "Affected Games",
-					Value:  ban.AffectedGames,
-					Inline: false,
-				})
+		switch newStatus {
+		case models.StatusTempban:
+			if err := ScheduleTempBanFollowUps(account, ban.TempBanEndsAt); err != nil {
+				logger.Log.WithError(err).Errorf("Failed to schedule temp-ban follow-ups for account %s", account.Title)
 			}
 
-			if err := SendNotification(s, account, permaBanEmbed, "", "permaban_notice"); err != nil {
-				logger.Log.WithError(err).Error("Failed to send permaban notice")
-			}
+		case models.StatusPermaban:
+			handlePermaBanNotification(s, lang, account, ban)
 
 			account.LastNotification = now.Unix()
 		}
@@ -204,162 +184,67 @@ func HandleStatusChange(s *discordgo.Session, account models.Account, newStatus
 		if err := database.DB.Save(&account).Error; err != nil {
 			logger.Log.WithError(err).Error("Failed to save final account status")
 		}
+	} else if len(transitions) > 0 && account.LastStatus != models.StatusUnknown {
+		notifyTitleTransitions(s, lang, account, check, titleStatuses, transitions)
 	}
 }
 
-func getAffectedGames(ssoCookie string) string {
-	cfg := configuration.Get()
-
-	req, err := http.NewRequest("GET", cfg.API.CheckEndpoint, nil)
-	if err != nil {
-		logger.Log.WithError(err).Error("Failed to create request for affected games")
-		return "All Games"
+// notifyTitleTransitions reports titles that changed while the account's overall status stayed
+// the same, e.g. a shadowban in one game on an account already banned in another.
+func notifyTitleTransitions(s discordapi.Session, lang i18n.Lang, account models.Account, check BanCheck, titleStatuses []models.AccountTitleStatus, transitions []TitleTransition) {
+	now := time.Now()
+	titleLog := models.Ban{
+		AccountID:      account.ID,
+		Status:         check.Status,
+		PreviousStatus: account.LastStatus,
+		LogType:        "title_change",
+		Message:        FormatTitleTransitions(i18n.English, transitions),
+		AffectedGames:  AffectedTitles(titleStatuses),
+		Timestamp:      now,
+		Initiator:      "auto_check",
 	}
+	check.applyTo(&titleLog)
 
-	headers := GenerateHeaders(ssoCookie)
-	for k, v := range headers {
-		req.Header.Set(k, v)
+	if err := database.DB.Create(&titleLog).Error; err != nil {
+		logger.Log.WithError(err).Error("Failed to create title change log")
 	}
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	embed := &discordgo.MessageEmbed{
+		Title: i18n.T(lang, "notify.title_update.title", account.Title),
+		Description: i18n.T(lang, "notify.title_update.description",
+			len(transitions), StatusName(lang, check.Status)),
+		Color:     GetColorForStatus(check.Status, account.IsExpiredCookie, account.IsCheckDisabled),
+		Fields:    titleStatusFields(lang, titleStatuses, transitions),
+		Timestamp: now.Format(time.RFC3339),
 	}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		logger.Log.WithError(err).Error("Failed to get affected games")
-		return "All Games"
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			logger.Log.WithError(err).Error("Failed to close response body")
-		}
-	}(resp.Body)
+	notifyWebhooksTitleChange(account, check, transitions)
 
-	var data struct {
-		Bans []struct {
-			AffectedTitles []string `json:"affectedTitles"`
-		} `json:"bans"`
+	if err := SendNotification(s, account, embed, fmt.Sprintf("<@%s>", account.UserID), "title_status_change"); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to send per-title status update for account %s", account.Title)
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		logger.Log.WithError(err).Error("Failed to decode affected games response")
-		return "All Games"
-	}
-
-	affectedGames := make(map[string]bool)
-	for _, ban := range data.Bans {
-		for _, title := range ban.AffectedTitles {
-			affectedGames[title] = true
-		}
-	}
-
-	var games []string
-	for game := range affectedGames {
-		games = append(games, game)
-	}
-
-	if len(games) == 0 {
-		return "All Games"
-	}
-
-	return strings.Join(games, ", ")
 }
 
-func getStatusFields(account models.Account, status models.Status, ban models.Ban) []*discordgo.MessageEmbedField {
-	fields := []*discordgo.MessageEmbedField{
-		{
-			Name:   "Account Status",
-			Value:  string(status),
-			Inline: true,
-		},
-		{
-			Name:   "Last Checked",
-			Value:  time.Unix(account.LastCheck, 0).Format(time.RFC1123),
-			Inline: true,
-		},
-	}
-
-	if ban.AffectedGames != "" {
+func titleStatusFields(lang i18n.Lang, titleStatuses []models.AccountTitleStatus, transitions []TitleTransition) []*discordgo.MessageEmbedField {
+	var fields []*discordgo.MessageEmbedField
+	if len(transitions) > 0 {
 		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "Affected Games",
-			Value:  ban.AffectedGames,
+			Name:   i18n.T(lang, "notify.field.title_changes"),
+			Value:  FormatTitleTransitions(lang, transitions),
 			Inline: false,
 		})
 	}
-
-	if isVIP, err := CheckVIPStatus(account.SSOCookie); err == nil {
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "VIP Status",
-			Value:  formatVIPStatus(isVIP),
-			Inline: true,
-		})
-	}
-
-	if !account.IsExpiredCookie {
-		timeUntilExpiration, err := CheckSSOCookieExpiration(account.SSOCookieExpiration)
-		if err == nil {
-			fields = append(fields, &discordgo.MessageEmbedField{
-				Name:   "Cookie Expires",
-				Value:  FormatDuration(timeUntilExpiration),
-				Inline: true,
-			})
-		}
-	}
-
-	if account.Created > 0 {
-		creationDate := time.Unix(account.Created, 0)
-		accountAge := time.Since(creationDate)
-		years := int(accountAge.Hours() / 24 / 365)
-		months := int(accountAge.Hours()/24/30.44) % 12
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "Account Age",
-			Value:  fmt.Sprintf("%d years, %d months", years, months),
-			Inline: true,
-		})
-	}
-
-	switch status {
-	case models.StatusPermaban:
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "Ban Type",
-			Value:  "Permanent",
-			Inline: true,
-		})
-
-	case models.StatusTempban:
-		var latestBan models.Ban
-		if err := database.DB.Where("account_id = ?", account.ID).
-			Order("created_at DESC").
-			First(&latestBan).Error; err == nil {
-			fields = append(fields, &discordgo.MessageEmbedField{
-				Name:   "Ban Duration",
-				Value:  latestBan.TempBanDuration,
-				Inline: true,
-			})
-		}
-
-	case models.StatusShadowban:
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "Review Status",
-			Value:  "Account Under Review",
-			Inline: true,
-		})
-	}
-
-	if account.ConsecutiveErrors > 0 {
+	if len(titleStatuses) > 0 {
 		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "Check Errors",
-			Value:  fmt.Sprintf("%d consecutive errors", account.ConsecutiveErrors),
-			Inline: true,
+			Name:   i18n.T(lang, "notify.field.status_by_title"),
+			Value:  FormatTitleStatuses(lang, titleStatuses),
+			Inline: false,
 		})
 	}
-
 	return fields
 }
 
-func disableAccount(s *discordgo.Session, account models.Account, reason string) {
+func disableAccount(s discordapi.Session, account models.Account, reason string) {
 	account.IsCheckDisabled = true
 	account.DisabledReason = reason
 	account.ConsecutiveErrors = 0
@@ -373,122 +258,38 @@ func disableAccount(s *discordgo.Session, account models.Account, reason string)
 	NotifyUserAboutDisabledAccount(s, account, reason)
 }
 
-func handlePermaBanNotification(s *discordgo.Session, account models.Account, ban models.Ban) {
-	permaBanEmbed := &discordgo.MessageEmbed{
-		Title: fmt.Sprintf("%s - Permanent Ban Detected", account.Title),
-		Description: "This account has been permanently banned. The account will no longer be checked automatically.\n" +
-			"using the /removeaccount command to free up your account slot.",
-		Color:     GetColorForStatus(models.StatusPermaban, false, false),
-		Timestamp: time.Now().Format(time.RFC3339),
-		Fields: []*discordgo.MessageEmbedField{
-			{
-				Name:   "Account Status",
-				Value:  "Permanently Banned",
-				Inline: true,
-			},
-			{
-				Name:   "Suggested Action",
-				Value:  "Remove account using /removeaccount",
-				Inline: true,
-			},
-		},
+func handlePermaBanNotification(s discordapi.Session, lang i18n.Lang, account models.Account, ban models.Ban) {
+	account.LastStatus = models.StatusPermaban
+	data := notifytemplates.Data{
+		Account:       templateAccount(lang, account),
+		Status:        models.StatusPermaban,
+		Ban:           templateBan(lang, ban),
+		AffectedGames: affectedGames(ban.AffectedGames),
+		Lang:          lang,
 	}
 
-	if ban.AffectedGames != "" {
-		permaBanEmbed.Fields = append(permaBanEmbed.Fields, &discordgo.MessageEmbedField{
-			Name:   "Affected Games",
-			Value:  ban.AffectedGames,
-			Inline: false,
-		})
+	permaBanEmbed, err := renderEmbed(notifytemplates.Permaban, data, GetColorForStatus(models.StatusPermaban, false, false))
+	if err == nil {
+		err = SendNotification(s, account, permaBanEmbed, "", "permaban_notice")
 	}
-
-	if err := SendNotification(s, account, permaBanEmbed, "", "permaban_notice"); err != nil {
+	if err != nil {
 		logger.Log.WithError(err).Error("Failed to send permaban notice")
 	}
 }
 
-func handleShadowBanNotification(s *discordgo.Session, account models.Account, ban models.Ban) {
-	shadowBanEmbed := &discordgo.MessageEmbed{
-		Title: fmt.Sprintf("%s - Account Under Review", account.Title),
-		Description: "Your account has been placed under review (shadowban). " +
-			"This typically means your account is being investigated.",
-		Color:     GetColorForStatus(models.StatusShadowban, false, false),
-		Timestamp: time.Now().Format(time.RFC3339),
-		Fields:    getStatusFields(account, models.StatusShadowban, ban),
-	}
+func handleShadowBanNotification(ctx context.Context, s discordapi.Session, account models.Account, ban models.Ban) {
+	data := statusTemplateData(ctx, LanguageForUser(account.UserID), account, models.StatusShadowban, ban)
 
-	if err := SendNotification(s, account, shadowBanEmbed, "", "shadowban_notice"); err != nil {
-		logger.Log.WithError(err).Error("Failed to send shadowban notice")
+	shadowBanEmbed, err := renderEmbed(notifytemplates.Shadowban, data, GetColorForStatus(models.StatusShadowban, false, false))
+	if err == nil {
+		err = SendNotification(s, account, shadowBanEmbed, "", "shadowban_notice")
 	}
-}
-
-func ScheduleTempBanNotification(s *discordgo.Session, account models.Account, duration string) {
-	parts := strings.Split(duration, ",")
-	if len(parts) != 2 {
-		logger.Log.Errorf("Invalid duration format for account %s: %s", account.Title, duration)
-		return
-	}
-	days, _ := strconv.Atoi(strings.TrimSpace(strings.Split(parts[0], " ")[0]))
-	hours, _ := strconv.Atoi(strings.TrimSpace(strings.Split(parts[1], " ")[0]))
-
-	sleepDuration := time.Duration(days)*24*time.Hour + time.Duration(hours)*time.Hour
-
-	for remainingTime := sleepDuration; remainingTime > 0; remainingTime -= 24 * time.Hour {
-		if remainingTime > 24*time.Hour {
-			time.Sleep(24 * time.Hour)
-		} else {
-			time.Sleep(remainingTime)
-		}
-
-		embed := &discordgo.MessageEmbed{
-			Title:       fmt.Sprintf("%s - Temporary Ban Update", account.Title),
-			Description: fmt.Sprintf("Your account is still temporarily banned. Remaining time: %v", remainingTime),
-			Color:       GetColorForStatus(models.StatusTempban, false, account.IsCheckDisabled),
-			Timestamp:   time.Now().Format(time.RFC3339),
-		}
-		err := SendNotification(s, account, embed, "", "temp_ban_update")
-		if err != nil {
-			logger.Log.WithError(err).Errorf("Failed to send temporary ban update for account %s", account.Title)
-		}
-	}
-
-	result, err := CheckAccount(account.SSOCookie, account.UserID, "")
 	if err != nil {
-		logger.Log.WithError(err).Errorf("Failed to check account %s after temporary ban duration", account.Title)
-		return
-	}
-
-	var embed *discordgo.MessageEmbed
-	if result == models.StatusGood {
-		embed = &discordgo.MessageEmbed{
-			Title:       fmt.Sprintf("%s - Temporary Ban Lifted", account.Title),
-			Description: fmt.Sprintf("The temporary ban for account %s has been lifted. The account is now in good standing.", account.Title),
-			Color:       GetColorForStatus(result, false, account.IsCheckDisabled),
-			Timestamp:   time.Now().Format(time.RFC3339),
-		}
-	} else if result == models.StatusPermaban {
-		embed = &discordgo.MessageEmbed{
-			Title:       fmt.Sprintf("%s - Temporary Ban Escalated", account.Title),
-			Description: fmt.Sprintf("The temporary ban for account %s has been escalated to a permanent ban.", account.Title),
-			Color:       GetColorForStatus(result, false, account.IsCheckDisabled),
-			Timestamp:   time.Now().Format(time.RFC3339),
-		}
-	} else {
-		embed = &discordgo.MessageEmbed{
-			Title:       fmt.Sprintf("%s - Temporary Ban Update", account.Title),
-			Description: fmt.Sprintf("The temporary ban for account %s is still in effect. Current status: %s", account.Title, result),
-			Color:       GetColorForStatus(result, false, account.IsCheckDisabled),
-			Timestamp:   time.Now().Format(time.RFC3339),
-		}
-	}
-
-	err = SendNotification(s, account, embed, fmt.Sprintf("<@%s>", account.UserID), "temp_ban_update")
-	if err != nil {
-		logger.Log.WithError(err).Errorf("Failed to send temporary ban update message for account %s", account.Title)
+		logger.Log.WithError(err).Error("Failed to send shadowban notice")
 	}
 }
 
-func getChannelForAnnouncement(s *discordgo.Session, userID string, userSettings models.UserSettings) (string, error) {
+func getChannelForAnnouncement(s discordapi.Session, userID string, userSettings models.UserSettings) (string, error) {
 	if userSettings.NotificationType == "dm" {
 		channel, err := s.UserChannelCreate(userID)
 		if err != nil {
@@ -508,17 +309,10 @@ func getChannelForAnnouncement(s *discordgo.Session, userID string, userSettings
 	return account.ChannelID, nil
 }
 
-func calculateBanDuration(endTime time.Time) string {
+func calculateBanDuration(lang i18n.Lang, endTime time.Time) string {
 	duration := time.Until(endTime)
 	if duration < 0 {
-		return "Expired"
-	}
-
-	days := int(duration.Hours()) / 24
-	hours := int(duration.Hours()) % 24
-
-	if days > 0 {
-		return fmt.Sprintf("%d days, %d hours", days, hours)
+		return i18n.T(lang, "duration.expired")
 	}
-	return fmt.Sprintf("%d hours", hours)
+	return formatDaysHours(lang, duration)
 }
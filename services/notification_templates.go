@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/notifytemplates"
+	"github.com/bwmarrin/discordgo"
+)
+
+// renderEmbed renders a notification template into an embed. The color and timestamp are not
+// part of the template.
+func renderEmbed(kind notifytemplates.Kind, data notifytemplates.Data, color int) (*discordgo.MessageEmbed, error) {
+	msg, err := notifytemplates.Render(kind, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s notification: %w", kind, err)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       msg.Title,
+		Description: msg.Description,
+		Color:       color,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+	for _, field := range msg.Fields {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   field.Name,
+			Value:  field.Value,
+			Inline: field.Inline,
+		})
+	}
+	if msg.Footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: msg.Footer}
+	}
+	return embed, nil
+}
+
+// templateAccount describes an account for the notification templates, with durations written in
+// lang. VIP status needs a request to Activision and is only filled in by withVIPStatus.
+func templateAccount(lang i18n.Lang, account models.Account) notifytemplates.Account {
+	data := notifytemplates.Account{
+		Title:             account.Title,
+		Status:            account.LastStatus,
+		Icon:              GetStatusIcon(account.LastStatus),
+		LastCheck:         time.Unix(account.LastCheck, 0),
+		CookieExpired:     account.IsExpiredCookie,
+		ChecksDisabled:    account.IsCheckDisabled,
+		ConsecutiveErrors: account.ConsecutiveErrors,
+	}
+
+	if !account.IsExpiredCookie {
+		if timeUntilExpiration, err := CheckSSOCookieExpiration(account.SSOCookieExpiration); err == nil {
+			data.Expiry = notifytemplates.Expiry{
+				At:        time.Unix(account.SSOCookieExpiration, 0),
+				Remaining: FormatDuration(lang, timeUntilExpiration),
+			}
+		}
+	}
+
+	if account.Created > 0 {
+		data.Age = FormatAccountAge(lang, time.Unix(account.Created, 0))
+	}
+
+	if account.LastStatus == models.StatusTempban {
+		var latestBan models.Ban
+		if err := database.DB.Where("account_id = ?", account.ID).
+			Order("created_at DESC").
+			First(&latestBan).Error; err == nil {
+			data.Ban = templateBan(lang, latestBan)
+		}
+	}
+
+	return data
+}
+
+func withVIPStatus(ctx context.Context, data notifytemplates.Account, account models.Account) notifytemplates.Account {
+	if isVIP, err := CheckVIPStatus(ctx, account.SSOCookie); err == nil {
+		data.VIPKnown = true
+		data.VIP = isVIP
+	}
+	return data
+}
+
+func templateBan(lang i18n.Lang, ban models.Ban) notifytemplates.Ban {
+	return notifytemplates.Ban{EndsAt: ban.TempBanEndsAt, Remaining: tempBanRemaining(lang, ban)}
+}
+
+// affectedGames splits the comma separated list stored on a ban.
+func affectedGames(games string) []string {
+	var list []string
+	for _, game := range strings.Split(games, ",") {
+		if game = strings.TrimSpace(game); game != "" {
+			list = append(list, game)
+		}
+	}
+	return list
+}
+
+// statusTemplateData is the data for the notifications about a single account's status.
+func statusTemplateData(ctx context.Context, lang i18n.Lang, account models.Account, status models.Status, ban models.Ban) notifytemplates.Data {
+	account.LastStatus = status
+	return notifytemplates.Data{
+		Account:       withVIPStatus(ctx, templateAccount(lang, account), account),
+		Status:        status,
+		Ban:           templateBan(lang, ban),
+		AffectedGames: affectedGames(ban.AffectedGames),
+		Lang:          lang,
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -8,8 +9,12 @@ import (
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/metrics"
 	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/notifytemplates"
 	"github.com/bwmarrin/discordgo"
 	"github.com/patrickmn/go-cache"
 	"github.com/sirupsen/logrus"
@@ -68,7 +73,7 @@ type NotificationState struct {
 	lastSent    time.Time
 }
 
-func NotifyAdmin(s *discordgo.Session, message string) {
+func NotifyAdmin(s discordapi.Session, message string) {
 	cfg := configuration.Get()
 	adminID := cfg.Discord.DeveloperID
 	if adminID == "" {
@@ -119,7 +124,7 @@ func IsDonationsEnabled() bool {
 }
 */
 
-func GetNotificationChannel(s *discordgo.Session, account models.Account, userSettings models.UserSettings) (string, error) {
+func GetNotificationChannel(s discordapi.Session, account models.Account, userSettings models.UserSettings) (string, error) {
 	if userSettings.NotificationType == "dm" {
 		channel, err := s.UserChannelCreate(account.UserID)
 		if err != nil {
@@ -135,19 +140,40 @@ func GetNotificationChannel(s *discordgo.Session, account models.Account, userSe
 	return account.ChannelID, nil
 }
 
-func FormatDuration(d time.Duration) string {
+// FormatDuration formats d compactly in lang, e.g. "3d 4h 10m".
+func FormatDuration(lang i18n.Lang, d time.Duration) string {
 	days := int(d.Hours() / 24)
 	hours := int(d.Hours()) % 24
 	minutes := int(d.Minutes()) % 60
 
 	if days > 0 {
-		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+		return i18n.T(lang, "duration.short.days", days, hours, minutes)
 	} else if hours > 0 {
-		return fmt.Sprintf("%dh %dm", hours, minutes)
+		return i18n.T(lang, "duration.short.hours", hours, minutes)
 	}
-	return fmt.Sprintf("%dm", minutes)
+	return i18n.T(lang, "duration.short.minutes", minutes)
 }
-func CheckAndNotifyBalance(s *discordgo.Session, userID string, balance float64) {
+
+// formatDaysHours formats d in whole days and hours in lang, e.g. "2 days, 4 hours".
+func formatDaysHours(lang i18n.Lang, d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+
+	if days > 0 {
+		return i18n.T(lang, "duration.days_hours", i18n.Plural(lang, "unit.day", days), i18n.Plural(lang, "unit.hour", hours))
+	}
+	return i18n.Plural(lang, "unit.hour", hours)
+}
+
+// FormatAccountAge formats the time since an account was created in lang, e.g. "3 years, 2 months".
+func FormatAccountAge(lang i18n.Lang, created time.Time) string {
+	age := time.Since(created)
+	years := int(age.Hours() / 24 / 365)
+	months := int(age.Hours()/24/30.44) % 12
+	return i18n.T(lang, "duration.years_months", i18n.Plural(lang, "unit.year", years), i18n.Plural(lang, "unit.month", months))
+}
+
+func CheckAndNotifyBalance(ctx context.Context, s discordapi.Session, userID string, balance float64) {
 	cfg := configuration.Get()
 	userSettings, err := GetUserSettings(userID)
 
@@ -165,8 +191,10 @@ func CheckAndNotifyBalance(s *discordgo.Session, userID string, balance float64)
 		return
 	}
 
+	lang := UserLanguage(userSettings)
+
 	if balance == 0 {
-		apiKey, balance, err := GetUserCaptchaKey(userID)
+		apiKey, balance, err := GetUserCaptchaKey(ctx, userID)
 		if err != nil {
 			logger.Log.WithError(err).Error("Failed to get captcha balance")
 			return
@@ -175,14 +203,13 @@ func CheckAndNotifyBalance(s *discordgo.Session, userID string, balance float64)
 		if apiKey == cfg.CaptchaService.EZCaptcha.ClientKey {
 			if balance < cfg.CaptchaService.EZCaptcha.BalanceMin {
 				embed := &discordgo.MessageEmbed{
-					Title: "Default API Key Balance Low",
-					Description: fmt.Sprintf("The bot's default API key balance is currently low (%.2f points). "+
-						"To ensure uninterrupted service, consider the following options:", balance),
-					Color:     0xFFA500,
-					Fields:    BalanceWarningFields(cfg.Donations),
-					Timestamp: time.Now().Format(time.RFC3339),
+					Title:       i18n.T(lang, "balance.default_low.title"),
+					Description: i18n.T(lang, "balance.default_low.description", balance),
+					Color:       0xFFA500,
+					Fields:      BalanceWarningFields(lang, cfg.Donations),
+					Timestamp:   time.Now().Format(time.RFC3339),
 					Footer: &discordgo.MessageEmbedFooter{
-						Text: "Thank you for using COD Status Bot!",
+						Text: i18n.T(lang, "common.thanks"),
 					},
 				}
 
@@ -206,24 +233,23 @@ func CheckAndNotifyBalance(s *discordgo.Session, userID string, balance float64)
 
 	if balance < threshold {
 		embed := &discordgo.MessageEmbed{
-			Title: fmt.Sprintf("Low %s Balance Alert", userSettings.PreferredCaptchaProvider),
-			Description: fmt.Sprintf("Your %s balance is currently %.2f points, which is below the recommended threshold of %.2f points.",
+			Title: i18n.T(lang, "balance.low.title", userSettings.PreferredCaptchaProvider),
+			Description: i18n.T(lang, "balance.low.description",
 				userSettings.PreferredCaptchaProvider, balance, threshold),
 			Color: 0xFFA500,
 			Fields: []*discordgo.MessageEmbedField{
 				{
-					Name: "Action Required",
-					Value: fmt.Sprintf("Please recharge your %s balance to ensure uninterrupted service for your account checks.",
-						userSettings.PreferredCaptchaProvider),
+					Name:   i18n.T(lang, "notify.field.action_required"),
+					Value:  i18n.T(lang, "balance.low.action", userSettings.PreferredCaptchaProvider),
 					Inline: false,
 				},
 				{
-					Name:   "Current Provider",
+					Name:   i18n.T(lang, "balance.current_provider"),
 					Value:  userSettings.PreferredCaptchaProvider,
 					Inline: true,
 				},
 				{
-					Name:   "Current Balance",
+					Name:   i18n.T(lang, "balance.current_balance"),
 					Value:  fmt.Sprintf("%.2f", balance),
 					Inline: true,
 				},
@@ -253,27 +279,25 @@ func CheckAndNotifyBalance(s *discordgo.Session, userID string, balance float64)
 	}
 }
 
-func BalanceWarningFields(config configuration.DonationsConfig) []*discordgo.MessageEmbedField {
+func BalanceWarningFields(lang i18n.Lang, config configuration.DonationsConfig) []*discordgo.MessageEmbedField {
 	cfg := configuration.Get()
 	fields := []*discordgo.MessageEmbedField{
 		{
-			Name: "Option 1: Add Your Own API Key",
-			Value: "Use `/setcaptchaservice` to configure your own Captcha API key. " +
-				"This gives you unlimited checks and customizable intervals.",
+			Name:   i18n.T(lang, "balance.option_own_key.name"),
+			Value:  i18n.T(lang, "balance.option_own_key.value"),
 			Inline: false,
 		},
 		{
-			Name: "Option 2: Wait for Balance Refresh",
-			Value: "Continue using the bot's default key with standard rate limits. " +
-				"Checks will still work but may be delayed.",
+			Name:   i18n.T(lang, "balance.option_wait.name"),
+			Value:  i18n.T(lang, "balance.option_wait.value"),
 			Inline: false,
 		},
 	}
 
 	if cfg.Donations.Enabled {
 		supportField := &discordgo.MessageEmbedField{
-			Name:   "Support the Bot",
-			Value:  "Help maintain the service by contributing:\n",
+			Name:   i18n.T(lang, "balance.support.name"),
+			Value:  i18n.T(lang, "balance.support.value") + "\n",
 			Inline: false,
 		}
 
@@ -290,11 +314,17 @@ func BalanceWarningFields(config configuration.DonationsConfig) []*discordgo.Mes
 	return fields
 }
 
-func ScheduleBalanceChecks(s *discordgo.Session) {
+func ScheduleBalanceChecks(ctx context.Context, s discordapi.Session) {
 	ticker := time.NewTicker(6 * time.Hour)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
 		var users []models.UserSettings
 		if err := database.DB.Find(&users).Error; err != nil {
 			logger.Log.WithError(err).Error("Failed to fetch users for balance check")
@@ -306,27 +336,18 @@ func ScheduleBalanceChecks(s *discordgo.Session) {
 				continue
 			}
 
-			if user.EZCaptchaAPIKey == "" && user.TwoCaptchaAPIKey == "" && user.CapSolverAPIKey == "" {
+			if !user.HasCustomCaptchaKey() {
 				continue
 			}
 
-			var apiKey string
-			var provider string
-			switch {
-			case user.PreferredCaptchaProvider == "capsolver" && user.CapSolverAPIKey != "":
-				apiKey = user.CapSolverAPIKey
-				provider = "capsolver"
-			case user.PreferredCaptchaProvider == "2captcha" && user.TwoCaptchaAPIKey != "":
-				apiKey = user.TwoCaptchaAPIKey
-				provider = "2captcha"
-			case user.PreferredCaptchaProvider == "ezcaptcha" && user.EZCaptchaAPIKey != "":
-				apiKey = user.EZCaptchaAPIKey
-				provider = "ezcaptcha"
-			default:
+			p, ok := LookupCaptchaProvider(user.PreferredCaptchaProvider)
+			if !ok || p.UserKey(user) == "" {
 				continue
 			}
+			apiKey := p.UserKey(user)
+			provider := p.Name
 
-			isValid, balance, err := ValidateCaptchaKey(apiKey, provider)
+			isValid, balance, err := ValidateCaptchaKey(ctx, apiKey, provider)
 			if err != nil {
 				logger.Log.WithError(err).Errorf("Failed to validate %s key for user %s", provider, user.UserID)
 				continue
@@ -346,27 +367,24 @@ func ScheduleBalanceChecks(s *discordgo.Session) {
 				continue
 			}
 
-			CheckAndNotifyBalance(s, user.UserID, balance)
+			CheckAndNotifyBalance(ctx, s, user.UserID, balance)
 		}
 	}
 }
 
-func DisableUserCaptcha(s *discordgo.Session, userID string, reason string) error {
+func DisableUserCaptcha(s discordapi.Session, userID string, reason string) error {
 	var settings models.UserSettings
 	if err := database.DB.Where("user_id = ?", userID).First(&settings).Error; err != nil {
 		return err
 	}
 
 	settings.TwoCaptchaAPIKey = ""
-	switch {
-	case IsServiceEnabled("capsolver"):
-		settings.PreferredCaptchaProvider = "capsolver"
-	case IsServiceEnabled("ezcaptcha"):
-		settings.PreferredCaptchaProvider = "ezcaptcha"
-	case IsServiceEnabled("2captcha"):
-		settings.PreferredCaptchaProvider = "2captcha"
-	default:
-		settings.PreferredCaptchaProvider = "capsolver"
+	settings.AntiCaptchaAPIKey = ""
+	settings.CapMonsterAPIKey = ""
+	settings.PreferredCaptchaProvider = string(models.Capsolver)
+	settings.CaptchaProviderOrder = nil
+	if enabled := EnabledCaptchaProviders(); len(enabled) > 0 {
+		settings.PreferredCaptchaProvider = enabled[0].Name
 	}
 
 	settings.EZCaptchaAPIKey = ""
@@ -378,11 +396,10 @@ func DisableUserCaptcha(s *discordgo.Session, userID string, reason string) erro
 		return err
 	}
 
+	lang := UserLanguage(settings)
 	embed := &discordgo.MessageEmbed{
-		Title: "Captcha Service Configuration Update",
-		Description: fmt.Sprintf("Your captcha service configuration has been updated. Reason: %s\n\n"+
-			"Current available services: %s\n"+
-			"The bot will use default settings for the available service.",
+		Title: i18n.T(lang, "captcha_disabled.title"),
+		Description: i18n.T(lang, "captcha_disabled.description",
 			reason,
 			getEnabledServicesString()),
 		Color:     0xFF0000,
@@ -399,16 +416,8 @@ func DisableUserCaptcha(s *discordgo.Session, userID string, reason string) erro
 
 func getEnabledServicesString() string {
 	var enabledServices []string
-	if IsServiceEnabled("capsolver") {
-		enabledServices = append(enabledServices, "Capsolver")
-	}
-
-	if IsServiceEnabled("ezcaptcha") {
-		enabledServices = append(enabledServices, "EZCaptcha")
-	}
-
-	if IsServiceEnabled("2captcha") {
-		enabledServices = append(enabledServices, "2Captcha")
+	for _, p := range EnabledCaptchaProviders() {
+		enabledServices = append(enabledServices, p.DisplayName)
 	}
 	if len(enabledServices) == 0 {
 		return "No services currently enabled"
@@ -427,7 +436,7 @@ func (nl *NotificationLimiter) CanSendNotification(userID string, notificationTy
 		return false
 	}
 
-	if userSettings.CapSolverAPIKey != "" || userSettings.EZCaptchaAPIKey != "" || userSettings.TwoCaptchaAPIKey != "" {
+	if userSettings.HasCustomCaptchaKey() {
 		return true
 	}
 
@@ -476,9 +485,10 @@ func (nl *NotificationLimiter) CanSendNotification(userID string, notificationTy
 	return true
 }
 
-func SendNotification(s *discordgo.Session, account models.Account, embed *discordgo.MessageEmbed, content, notificationType string) error {
+func SendNotification(s discordapi.Session, account models.Account, embed *discordgo.MessageEmbed, content, notificationType string) error {
 	if !globalLimiter.CanSendNotification(account.UserID, notificationType) {
 		storeSuppressedNotification(account.UserID, notificationType, embed, content)
+		metrics.Notifications.WithLabelValues(notificationType, metrics.OutcomeSuppressed).Inc()
 		logger.Log.WithFields(logrus.Fields{
 			"userID":           account.UserID,
 			"accountTitle":     account.Title,
@@ -501,26 +511,16 @@ func SendNotification(s *discordgo.Session, account models.Account, embed *disco
 		return nil
 	}
 
-	channelID, err := GetNotificationChannel(s, account, userSettings)
-	if err != nil {
-		if userSettings.NotificationType == "dm" {
-			channel, dmErr := s.UserChannelCreate(account.UserID)
-			if dmErr != nil {
-				return fmt.Errorf("failed to create DM channel: %w", dmErr)
-			}
-			channelID = channel.ID
-		} else {
-			return fmt.Errorf("failed to get notification channel: %w", err)
-		}
-	}
-
-	_, err = s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
-		Embed:   embed,
-		Content: content,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+	if err := deliverNotification(s, Notification{
+		Type:     notificationType,
+		Account:  account,
+		Settings: userSettings,
+		Embed:    embed,
+		Content:  content,
+	}); err != nil {
+		return err
 	}
+	metrics.Notifications.WithLabelValues(notificationType, metrics.OutcomeSent).Inc()
 
 	userSettings.LastCommandTimes[notificationType] = now
 	if err := database.DB.Save(&userSettings).Error; err != nil {
@@ -554,7 +554,7 @@ func storeSuppressedNotification(userID, notificationType string, embed *discord
 	}
 }
 
-func NotifyAdminWithCooldown(s *discordgo.Session, message string, cooldownDuration time.Duration) {
+func NotifyAdminWithCooldown(s discordapi.Session, message string, cooldownDuration time.Duration) {
 	cacheKey := "admin_" + message
 	if _, found := adminNotificationCache.Get(cacheKey); found {
 		return
@@ -581,7 +581,7 @@ func NotifyAdminWithCooldown(s *discordgo.Session, message string, cooldownDurat
 	}
 }
 
-func SendGlobalAnnouncement(s *discordgo.Session, userID string) error {
+func SendGlobalAnnouncement(s discordapi.Session, userID string) error {
 	var userSettings models.UserSettings
 	result := database.DB.Where(models.UserSettings{UserID: userID}).FirstOrCreate(&userSettings)
 	if result.Error != nil {
@@ -596,7 +596,7 @@ func SendGlobalAnnouncement(s *discordgo.Session, userID string) error {
 			return err
 		}
 
-		announcementEmbed := CreateAnnouncementEmbed()
+		announcementEmbed := CreateAnnouncementEmbed(UserLanguage(userSettings))
 
 		_, err = s.ChannelMessageSendEmbed(channelID, announcementEmbed)
 		if err != nil {
@@ -614,7 +614,7 @@ func SendGlobalAnnouncement(s *discordgo.Session, userID string) error {
 	return nil
 }
 
-func SendAnnouncementToAllUsers(s *discordgo.Session) error {
+func SendAnnouncementToAllUsers(s discordapi.Session) error {
 	var users []models.UserSettings
 	if err := database.DB.Find(&users).Error; err != nil {
 		logger.Log.WithError(err).Error("Error fetching all users")
@@ -630,57 +630,52 @@ func SendAnnouncementToAllUsers(s *discordgo.Session) error {
 	return nil
 }
 
-func NotifyUserAboutDisabledAccount(s *discordgo.Session, account models.Account, reason string) {
+func NotifyUserAboutDisabledAccount(s discordapi.Session, account models.Account, reason string) {
+	lang := LanguageForUser(account.UserID)
 	embed := &discordgo.MessageEmbed{
-		Title: "Account Disabled",
-		Description: fmt.Sprintf("Your account '%s' has been disabled. Reason: %s\n\n"+
-			"To re-enable monitoring, please address the issue and use the /togglecheck command to re-enable your account.", account.Title, reason),
-		Color:     0xFF0000,
-		Timestamp: time.Now().Format(time.RFC3339),
+		Title:       i18n.T(lang, "account_disabled.title"),
+		Description: i18n.T(lang, "account_disabled.description", account.Title, reason),
+		Color:       0xFF0000,
+		Timestamp:   time.Now().Format(time.RFC3339),
 	}
 
+	notifyWebhooksChecksDisabled(account, reason)
+
 	err := SendNotification(s, account, embed, "", "account_disabled")
 	if err != nil {
 		logger.Log.WithError(err).Errorf("Failed to send account disabled notification to user %s", account.UserID)
 	}
 }
 
-func NotifyCookieExpiringSoon(s *discordgo.Session, accounts []models.Account) error {
+func NotifyCookieExpiringSoon(s discordapi.Session, accounts []models.Account) error {
 	if len(accounts) == 0 {
 		return nil
 	}
 
-	var embedFields []*discordgo.MessageEmbedField
+	data := notifytemplates.Data{Lang: LanguageForUser(accounts[0].UserID)}
 	for _, account := range accounts {
-		timeUntilExpiration, err := CheckSSOCookieExpiration(account.SSOCookieExpiration)
-		if err != nil {
+		if _, err := CheckSSOCookieExpiration(account.SSOCookieExpiration); err != nil {
 			logger.Log.WithError(err).Errorf("Error checking SSO cookie expiration for account %s", account.Title)
 			continue
 		}
+		notifyWebhooksCookieExpiring(account)
 
-		embedFields = append(embedFields, &discordgo.MessageEmbedField{
-			Name:   account.Title,
-			Value:  fmt.Sprintf("Cookie expires in %s", FormatDuration(timeUntilExpiration)),
-			Inline: false,
-		})
+		data.Accounts = append(data.Accounts, templateAccount(data.Lang, account))
 	}
 
-	if len(embedFields) == 0 {
+	if len(data.Accounts) == 0 {
 		return nil
 	}
 
-	embed := &discordgo.MessageEmbed{
-		Title:       "SSO Cookie Expiration Warning",
-		Description: "The following accounts have SSO cookies that will expire soon:",
-		Color:       0xFFA500,
-		Fields:      embedFields,
-		Timestamp:   time.Now().Format(time.RFC3339),
+	embed, err := renderEmbed(notifytemplates.CookieExpiring, data, 0xFFA500)
+	if err != nil {
+		return err
 	}
 
 	return SendNotification(s, accounts[0], embed, "", "cookie_expiring_soon")
 }
 
-func SendConsolidatedDailyUpdate(s *discordgo.Session, userID string, userSettings models.UserSettings, accounts []models.Account) {
+func SendConsolidatedDailyUpdate(ctx context.Context, s discordapi.Session, userID string, userSettings models.UserSettings, accounts []models.Account) {
 	if len(accounts) == 0 {
 		return
 	}
@@ -691,62 +686,32 @@ func SendConsolidatedDailyUpdate(s *discordgo.Session, userID string, userSettin
 		notificationInterval = time.Duration(cfg.Intervals.Notification) * time.Hour
 	}
 
-	accountsByStatus := make(map[models.Status][]models.Account)
-	for _, account := range accounts {
-		if !account.IsCheckDisabled && !account.IsExpiredCookie {
-			accountsByStatus[account.LastStatus] = append(accountsByStatus[account.LastStatus], account)
-		}
-	}
-
 	userSettings, err := GetUserSettings(userID)
 	if err != nil {
 		logger.Log.WithError(err).Errorf("Failed to get user settings for user %s", userID)
 		return
 	}
 
-	var embedFields []*discordgo.MessageEmbedField
-	embedFields = append(embedFields, &discordgo.MessageEmbedField{
-		Name: "Summary",
-		Value: fmt.Sprintf("Total Accounts: %d\nGood Standing: %d\nBanned: %d\nUnder Review: %d",
-			len(accounts),
-			len(accountsByStatus[models.StatusGood]),
-			len(accountsByStatus[models.StatusPermaban])+len(accountsByStatus[models.StatusTempban]),
-			len(accountsByStatus[models.StatusShadowban])),
-		Inline: false,
-	})
-
-	for status, statusAccounts := range accountsByStatus {
-		var description strings.Builder
-		for _, account := range statusAccounts {
-			timeUntilExpiration, err := CheckSSOCookieExpiration(account.SSOCookieExpiration)
-			if err != nil {
-				logger.Log.WithError(err).Errorf("Error checking expiration for account %s", account.Title)
-				continue
-			}
-
-			statusSymbol := GetStatusIcon(status)
-			description.WriteString(fmt.Sprintf("%s %s: %s\n", statusSymbol, account.Title,
-				formatAccountStatus(account, status, timeUntilExpiration)))
+	data := notifytemplates.Data{
+		TotalAccounts: len(accounts),
+		IntervalHours: userSettings.NotificationInterval,
+		Lang:          UserLanguage(userSettings),
+	}
+	for _, account := range accounts {
+		if account.IsCheckDisabled || account.IsExpiredCookie {
+			continue
 		}
-
-		if description.Len() > 0 {
-			embedFields = append(embedFields, &discordgo.MessageEmbedField{
-				Name:   fmt.Sprintf("%s Accounts", strings.Title(string(status))),
-				Value:  description.String(),
-				Inline: false,
-			})
+		if _, err := CheckSSOCookieExpiration(account.SSOCookieExpiration); err != nil {
+			logger.Log.WithError(err).Errorf("Error checking expiration for account %s", account.Title)
+			continue
 		}
+		data.Accounts = append(data.Accounts, withVIPStatus(ctx, templateAccount(data.Lang, account), account))
 	}
 
-	embed := &discordgo.MessageEmbed{
-		Title:       fmt.Sprintf("%.2f Hour Update - Account Status Report", userSettings.NotificationInterval),
-		Description: "Here's a consolidated update on your monitored accounts:",
-		Color:       0x00ff00,
-		Fields:      embedFields,
-		Timestamp:   time.Now().Format(time.RFC3339),
-		Footer: &discordgo.MessageEmbedFooter{
-			Text: "Use /checknow to check any account immediately",
-		},
+	embed, err := renderEmbed(notifytemplates.DailyUpdate, data, 0x00ff00)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to send consolidated daily update for user %s", userID)
+		return
 	}
 
 	if err := SendNotification(s, accounts[0], embed, "", "daily_update"); err != nil {
@@ -778,52 +743,6 @@ func GetStatusIcon(status models.Status) string {
 	}
 }
 
-func formatAccountStatus(account models.Account, status models.Status, timeUntilExpiration time.Duration) string {
-	var statusDesc strings.Builder
-
-	switch status {
-	case models.StatusGood:
-		statusDesc.WriteString(fmt.Sprintf("Good standing | Expires in %s", FormatDuration(timeUntilExpiration)))
-	case models.StatusPermaban:
-		statusDesc.WriteString("Permanently banned")
-	case models.StatusShadowban:
-		statusDesc.WriteString("Under review")
-	case models.StatusTempban:
-		var latestBan models.Ban
-		if err := database.DB.Where("account_id = ?", account.ID).
-			Order("created_at DESC").
-			First(&latestBan).Error; err == nil {
-			statusDesc.WriteString(fmt.Sprintf("Temporarily banned (%s remaining)", latestBan.TempBanDuration))
-		} else {
-			statusDesc.WriteString("Temporarily banned (duration unknown)")
-		}
-	default:
-		statusDesc.WriteString("Unknown status")
-	}
-
-	if isVIP, err := CheckVIPStatus(account.SSOCookie); err == nil {
-		statusDesc.WriteString(fmt.Sprintf(" | %s", formatVIPStatus(isVIP)))
-	}
-
-	statusDesc.WriteString(fmt.Sprintf(" | Checks: %s", formatCheckStatus(account.IsCheckDisabled)))
-
-	return statusDesc.String()
-}
-
-func formatVIPStatus(isVIP bool) string {
-	if isVIP {
-		return "VIP Account"
-	}
-	return "Regular Account"
-}
-
-func formatCheckStatus(isDisabled bool) string {
-	if isDisabled {
-		return "DISABLED"
-	}
-	return "ENABLED"
-}
-
 func getNotificationType(status models.Status) string {
 	switch status {
 	case models.StatusPermaban:
@@ -837,18 +756,16 @@ func getNotificationType(status models.Status) string {
 	}
 }
 
-func checkAccountsNeedingAttention(s *discordgo.Session, accounts []models.Account, userSettings models.UserSettings) {
-	var expiringAccounts []models.Account
+// checkAccountsNeedingAttention reports accounts whose checks keep failing. Cookie expiry
+// warnings are sent by the scheduler instead, see ScheduleCookieExpiryWarning.
+func checkAccountsNeedingAttention(s discordapi.Session, accounts []models.Account, userSettings models.UserSettings) {
 	var errorAccounts []models.Account
 
 	cfg := configuration.Get()
 	for _, account := range accounts {
 		if !account.IsExpiredCookie {
-			timeUntilExpiration, err := CheckSSOCookieExpiration(account.SSOCookieExpiration)
-			if err != nil {
+			if _, err := CheckSSOCookieExpiration(account.SSOCookieExpiration); err != nil {
 				errorAccounts = append(errorAccounts, account)
-			} else if timeUntilExpiration <= time.Duration(cfg.Intervals.CookieExpiration)*time.Hour {
-				expiringAccounts = append(expiringAccounts, account)
 			}
 		}
 
@@ -857,26 +774,21 @@ func checkAccountsNeedingAttention(s *discordgo.Session, accounts []models.Accou
 		}
 	}
 
-	if len(expiringAccounts) > 0 && time.Since(userSettings.LastCookieExpirationWarning) >= time.Hour*24 {
-		if err := NotifyCookieExpiringSoon(s, expiringAccounts); err != nil {
-			logger.Log.WithError(err).Error("Failed to send cookie expiration notifications")
-		}
-	}
-
 	if len(errorAccounts) > 0 && time.Since(userSettings.LastErrorNotification) >= time.Hour*6 {
 		notifyAccountErrors(s, errorAccounts, userSettings)
 	}
 }
 
-func notifyAccountErrors(s *discordgo.Session, errorAccounts []models.Account, userSettings models.UserSettings) {
+func notifyAccountErrors(s discordapi.Session, errorAccounts []models.Account, userSettings models.UserSettings) {
 	if len(errorAccounts) == 0 {
 		return
 	}
 
 	cfg := configuration.Get()
+	lang := UserLanguage(userSettings)
 	embed := &discordgo.MessageEmbed{
-		Title:       "Account Check Errors",
-		Description: "The following accounts have encountered errors during status checks:",
+		Title:       i18n.T(lang, "check_errors.title"),
+		Description: i18n.T(lang, "check_errors.description"),
 		Color:       0xFF0000,
 		Fields:      make([]*discordgo.MessageEmbedField, 0),
 		Timestamp:   time.Now().Format(time.RFC3339),
@@ -885,12 +797,12 @@ func notifyAccountErrors(s *discordgo.Session, errorAccounts []models.Account, u
 	for _, account := range errorAccounts {
 		var errorDescription string
 		if account.IsCheckDisabled {
-			errorDescription = fmt.Sprintf("Checks disabled - Reason: %s", account.DisabledReason)
+			errorDescription = i18n.T(lang, "check_errors.disabled", account.DisabledReason)
 		} else if account.ConsecutiveErrors >= cfg.CaptchaService.MaxRetries {
-			errorDescription = fmt.Sprintf("Multiple check failures - Last error time: %s",
+			errorDescription = i18n.T(lang, "check_errors.failures",
 				account.LastErrorTime.Format("2006-01-02 15:04:05"))
 		} else {
-			errorDescription = "Unknown error"
+			errorDescription = i18n.T(lang, "check_errors.unknown")
 		}
 
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
@@ -6,7 +6,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bradselph/CODStatusBot/discordapi"
 	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/metrics"
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -69,7 +71,7 @@ func (a *AdaptiveRateLimits) GetBackoffDuration(userID string) time.Duration {
 	return duration
 }
 
-func StartNotificationProcessor(discord *discordgo.Session) {
+func StartNotificationProcessor(discord discordapi.Session) {
 	notificationQueue.wg.Add(1)
 	go func() {
 		defer notificationQueue.wg.Done()
@@ -88,7 +90,7 @@ func StartNotificationProcessor(discord *discordgo.Session) {
 	}()
 }
 
-func (q *NotificationQueue) processNextNotification(discord *discordgo.Session) {
+func (q *NotificationQueue) processNextNotification(discord discordapi.Session) {
 	q.mutex.Lock()
 	if len(q.items) == 0 {
 		q.mutex.Unlock()
@@ -106,6 +108,7 @@ func (q *NotificationQueue) processNextNotification(discord *discordgo.Session)
 
 	q.items[highestPriorityIndex] = q.items[len(q.items)-1]
 	q.items = q.items[:len(q.items)-1]
+	metrics.NotificationQueueDepth.Set(float64(len(q.items)))
 	q.mutex.Unlock()
 
 	if IsUserRateLimited(item.UserID) {
@@ -176,7 +179,7 @@ func (q *NotificationQueue) processNextNotification(discord *discordgo.Session)
 	adaptiveRateLimits.Unlock()
 }
 
-func sendMessageWithRetry(s *discordgo.Session, channelID, content string) error {
+func sendMessageWithRetry(s discordapi.Session, channelID, content string) error {
 	var lastErr error
 	for retries := 0; retries < 3; retries++ {
 		if _, err := s.ChannelMessageSend(channelID, content); err == nil {
@@ -201,6 +204,7 @@ func (q *NotificationQueue) AddNotification(item NotificationItem) {
 	}
 
 	q.items = append(q.items, item)
+	metrics.NotificationQueueDepth.Set(float64(len(q.items)))
 }
 
 func QueueNotification(userID string, content string) {
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/metrics"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// Notification categories that can be routed to their own destinations with /setnotifications.
+// Notification types that are not listed in notificationCategories use RouteDefault.
+const (
+	RouteDefault = "default"
+	RouteStatus  = "status"
+	RouteDaily   = "daily"
+	RouteCookie  = "cookie"
+)
+
+var notificationCategories = map[string]string{
+	"status_change":        RouteStatus,
+	"permaban":             RouteStatus,
+	"shadowban":            RouteStatus,
+	"tempban":              RouteStatus,
+	"title_status_change":  RouteStatus,
+	"permaban_notice":      RouteStatus,
+	"shadowban_notice":     RouteStatus,
+	"temp_ban_update":      RouteStatus,
+	"daily_update":         RouteDaily,
+	"cookie_expiring_soon": RouteCookie,
+	"invalid_cookie":       RouteCookie,
+}
+
+var (
+	// ErrNotifierDisabled is returned when a destination's backend is not configured on this bot.
+	ErrNotifierDisabled = errors.New("notification destination is not enabled")
+	// ErrUnknownDestination is returned for destination names no notifier answers to.
+	ErrUnknownDestination = errors.New("unknown notification destination")
+	// ErrDestinationNotSetUp is returned when a user picks a destination they have not connected yet.
+	ErrDestinationNotSetUp = errors.New("notification destination is not set up")
+)
+
+// DestinationError says which destination in a list could not be used.
+type DestinationError struct {
+	Name string
+	Err  error
+}
+
+func (e *DestinationError) Error() string { return e.Name + ": " + e.Err.Error() }
+func (e *DestinationError) Unwrap() error { return e.Err }
+
+// Notification is one message handed to a Notifier.
+type Notification struct {
+	Type     string
+	Account  models.Account
+	Settings models.UserSettings
+	Embed    *discordgo.MessageEmbed
+	Content  string // Extra Discord message text such as a mention; other backends ignore it
+}
+
+// Notifier delivers notifications through one backend.
+type Notifier interface {
+	// Name is the destination users pick in /setnotifications.
+	Name() string
+	// Enabled reports whether the backend is configured on this bot.
+	Enabled() bool
+	// Ready reports whether the user has set up everything the backend needs to reach them.
+	Ready(settings models.UserSettings) bool
+	Send(s discordapi.Session, n Notification) error
+}
+
+// notifiers are the available backends in the order they are listed to users.
+var notifiers = []Notifier{
+	channelNotifier{},
+	dmNotifier{},
+	emailNotifier{},
+	telegramNotifier{},
+	ntfyNotifier{},
+}
+
+var (
+	notifierClientOnce sync.Once
+	notifierClient     *http.Client
+)
+
+// GetNotifier returns the notifier for a destination name.
+func GetNotifier(name string) (Notifier, bool) {
+	for _, notifier := range notifiers {
+		if notifier.Name() == name {
+			return notifier, true
+		}
+	}
+	return nil, false
+}
+
+// NotifierNames lists the destinations that are enabled on this bot.
+func NotifierNames() []string {
+	var names []string
+	for _, notifier := range notifiers {
+		if notifier.Enabled() {
+			names = append(names, notifier.Name())
+		}
+	}
+	return names
+}
+
+// NotificationCategory returns the route a notification type is sent through.
+func NotificationCategory(notificationType string) string {
+	if category, ok := notificationCategories[notificationType]; ok {
+		return category
+	}
+	return RouteDefault
+}
+
+// NotificationDestinations returns where a notification of the given type goes for a user: the
+// route for its category, else the default route, else the single NotificationType that was
+// chosen before routes existed.
+func NotificationDestinations(settings models.UserSettings, notificationType string) []string {
+	if destinations := settings.NotificationRoutes[NotificationCategory(notificationType)]; len(destinations) > 0 {
+		return destinations
+	}
+	if destinations := settings.NotificationRoutes[RouteDefault]; len(destinations) > 0 {
+		return destinations
+	}
+	if settings.NotificationType != "" {
+		return []string{settings.NotificationType}
+	}
+	return []string{"channel"}
+}
+
+// ParseNotificationDestinations turns a comma or space separated list such as "dm, telegram" into
+// destination names, checking that each one is enabled and set up for the user.
+func ParseNotificationDestinations(settings models.UserSettings, input string) ([]string, error) {
+	var destinations []string
+	for _, name := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		if slices.Contains(destinations, name) {
+			continue
+		}
+		notifier, ok := GetNotifier(name)
+		if !ok {
+			return nil, &DestinationError{Name: name, Err: ErrUnknownDestination}
+		}
+		if !notifier.Enabled() {
+			return nil, &DestinationError{Name: name, Err: ErrNotifierDisabled}
+		}
+		if !notifier.Ready(settings) {
+			return nil, &DestinationError{Name: name, Err: ErrDestinationNotSetUp}
+		}
+		destinations = append(destinations, name)
+	}
+	return destinations, nil
+}
+
+// deliverNotification sends n to every one of the user's destinations for its type. If none of
+// them accept it, the notification is posted to Discord so it is not lost.
+func deliverNotification(s discordapi.Session, n Notification) error {
+	var errs []error
+	attempted := make(map[string]bool)
+	delivered := false
+
+	for _, name := range NotificationDestinations(n.Settings, n.Type) {
+		notifier, ok := GetNotifier(name)
+		if !ok || !notifier.Enabled() || !notifier.Ready(n.Settings) {
+			logger.Log.Debugf("Skipping unavailable notification destination %s for user %s", name, n.Account.UserID)
+			continue
+		}
+
+		attempted[name] = true
+		if err := sendWithNotifier(s, notifier, n); err != nil {
+			logger.Log.WithError(err).Warnf("Failed to send %s notification to user %s through %s", n.Type, n.Account.UserID, name)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		delivered = true
+	}
+	if delivered {
+		return nil
+	}
+
+	fallback := Notifier(channelNotifier{})
+	if n.Settings.NotificationType == "dm" || n.Account.ChannelID == "" {
+		fallback = dmNotifier{}
+	}
+	if attempted[fallback.Name()] {
+		return errors.Join(errs...)
+	}
+	if len(errs) > 0 {
+		logger.Log.Infof("Falling back to Discord %s for user %s", fallback.Name(), n.Account.UserID)
+	}
+	if err := sendWithNotifier(s, fallback, n); err != nil {
+		return errors.Join(append(errs, fmt.Errorf("%s: %w", fallback.Name(), err))...)
+	}
+	return nil
+}
+
+// SendTestNotification sends a sample notification through one of the user's destinations.
+func SendTestNotification(s discordapi.Session, userID, name string) error {
+	notifier, ok := GetNotifier(name)
+	if !ok {
+		return ErrUnknownDestination
+	}
+	if !notifier.Enabled() {
+		return ErrNotifierDisabled
+	}
+	settings, err := GetUserSettings(userID)
+	if err != nil {
+		return err
+	}
+	if !notifier.Ready(settings) {
+		return ErrDestinationNotSetUp
+	}
+
+	return sendWithNotifier(s, notifier, Notification{
+		Type:     "test",
+		Account:  models.Account{UserID: userID},
+		Settings: settings,
+		Embed: &discordgo.MessageEmbed{
+			Title:       i18n.T(UserLanguage(settings), "notifier.test.title"),
+			Description: i18n.T(UserLanguage(settings), "notifier.test.description", notifier.Name()),
+			Color:       0x00ff00,
+			Timestamp:   time.Now().Format(time.RFC3339),
+		},
+	})
+}
+
+func sendWithNotifier(s discordapi.Session, notifier Notifier, n Notification) error {
+	err := notifier.Send(s, n)
+	outcome := metrics.OutcomeSent
+	if err != nil {
+		outcome = metrics.OutcomeFailed
+	}
+	metrics.NotifierDeliveries.WithLabelValues(notifier.Name(), outcome).Inc()
+	return err
+}
+
+// removeNotificationDestination takes a destination out of the user's routes after it has been
+// disconnected. Users whose primary destination it was go back to channel notifications.
+func removeNotificationDestination(tx *gorm.DB, userID, name string) error {
+	var settings models.UserSettings
+	if err := tx.Select("id", "notification_routes").Where("user_id = ?", userID).First(&settings).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	routes := make(map[string][]string)
+	for category, destinations := range settings.NotificationRoutes {
+		destinations = slices.DeleteFunc(slices.Clone(destinations), func(d string) bool { return d == name })
+		if len(destinations) > 0 {
+			routes[category] = destinations
+		}
+	}
+	settings.NotificationRoutes = routes
+	if err := tx.Model(&settings).Select("NotificationRoutes").Updates(&settings).Error; err != nil {
+		return err
+	}
+
+	primary := "channel"
+	if defaults := routes[RouteDefault]; len(defaults) > 0 {
+		primary = defaults[0]
+	}
+	if err := tx.Model(&models.UserSettings{}).Where("user_id = ? AND notification_type = ?", userID, name).
+		Update("notification_type", primary).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Account{}).Where("user_id = ? AND notification_type = ?", userID, name).
+		Update("notification_type", primary).Error
+}
+
+// notifierHTTPClient is shared by the HTTP based backends.
+func notifierHTTPClient() *http.Client {
+	notifierClientOnce.Do(func() {
+		notifierClient = &http.Client{Timeout: configuration.Get().NotifierTimeout}
+	})
+	return notifierClient
+}
+
+// stripURLError drops the request URL from transport errors, since Telegram URLs contain the
+// bot token.
+func stripURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+type channelNotifier struct{}
+
+func (channelNotifier) Name() string                            { return "channel" }
+func (channelNotifier) Enabled() bool                           { return true }
+func (channelNotifier) Ready(settings models.UserSettings) bool { return true }
+
+func (channelNotifier) Send(s discordapi.Session, n Notification) error {
+	if s == nil {
+		return errors.New("no Discord session")
+	}
+	if n.Account.ChannelID == "" {
+		return errors.New("no channel ID set for account")
+	}
+	return sendDiscordMessage(s, n.Account.ChannelID, n)
+}
+
+type dmNotifier struct{}
+
+func (dmNotifier) Name() string                            { return "dm" }
+func (dmNotifier) Enabled() bool                           { return true }
+func (dmNotifier) Ready(settings models.UserSettings) bool { return true }
+
+func (dmNotifier) Send(s discordapi.Session, n Notification) error {
+	if s == nil {
+		return errors.New("no Discord session")
+	}
+	channel, err := s.UserChannelCreate(n.Account.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to create DM channel: %w", err)
+	}
+	return sendDiscordMessage(s, channel.ID, n)
+}
+
+func sendDiscordMessage(s discordapi.Session, channelID string, n Notification) error {
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embed:   n.Embed,
+		Content: n.Content,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+type emailNotifier struct{}
+
+func (emailNotifier) Name() string  { return "email" }
+func (emailNotifier) Enabled() bool { return EmailEnabled() }
+
+func (emailNotifier) Ready(settings models.UserSettings) bool {
+	return settings.Email != ""
+}
+
+func (emailNotifier) Send(_ discordapi.Session, n Notification) error {
+	return sendEmbedEmail(n.Settings.Email, n.Embed)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// maxNtfyErrorBody is how much of a failed ntfy response is kept in the error.
+const maxNtfyErrorBody = 256
+
+// ErrInvalidNtfyTopic is returned for topic names ntfy would not accept.
+var ErrInvalidNtfyTopic = errors.New("invalid ntfy topic")
+
+var ntfyTopic = regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`)
+
+// ntfyNotifier publishes notifications to a topic on the configured ntfy server.
+type ntfyNotifier struct{}
+
+func (ntfyNotifier) Name() string { return "ntfy" }
+
+func (ntfyNotifier) Enabled() bool {
+	return configuration.Get().Ntfy.ServerURL != ""
+}
+
+func (ntfyNotifier) Ready(settings models.UserSettings) bool {
+	return settings.NtfyTopic != ""
+}
+
+func (ntfyNotifier) Send(_ discordapi.Session, n Notification) error {
+	priority := "default"
+	if NotificationCategory(n.Type) == RouteStatus {
+		priority = "high"
+	}
+	return publishNtfy(n.Settings.NtfyTopic, n.Embed, priority)
+}
+
+// SetNtfyTopic publishes a confirmation message to topic and, if the server accepts it, saves the
+// topic as the user's ntfy destination. Anyone who knows a topic name on a public server can
+// read it, so users should pick one that is hard to guess.
+func SetNtfyTopic(userID, topic string) error {
+	if !(ntfyNotifier{}).Enabled() {
+		return ErrNotifierDisabled
+	}
+	topic = strings.TrimSpace(topic)
+	if !ntfyTopic.MatchString(topic) {
+		return ErrInvalidNtfyTopic
+	}
+
+	lang := LanguageForUser(userID)
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "notifier.ntfy.connected.title"),
+		Description: i18n.T(lang, "notifier.ntfy.connected.description"),
+	}
+	if err := publishNtfy(topic, embed, "default"); err != nil {
+		return err
+	}
+
+	settings := models.UserSettings{NtfyTopic: topic}
+	return database.DB.Model(&models.UserSettings{}).Where("user_id = ?", userID).Select("NtfyTopic").Updates(&settings).Error
+}
+
+// RemoveNtfyTopic forgets the user's ntfy topic and stops routing notifications to it.
+func RemoveNtfyTopic(userID string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserSettings{}).Where("user_id = ?", userID).Update("ntfy_topic", "").Error; err != nil {
+			return err
+		}
+		return removeNotificationDestination(tx, userID, "ntfy")
+	})
+}
+
+func publishNtfy(topic string, embed *discordgo.MessageEmbed, priority string) error {
+	cfg := configuration.Get().Ntfy
+
+	req, err := http.NewRequest(http.MethodPost, cfg.ServerURL+"/"+url.PathEscape(topic), strings.NewReader(plainEmbedBody(embed)))
+	if err != nil {
+		return fmt.Errorf("failed to create ntfy request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", plainDiscordText(embed.Title)))
+	req.Header.Set("Priority", priority)
+	req.Header.Set("Tags", "video_game")
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+
+	resp, err := notifierHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("ntfy request failed: %w", stripURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxNtfyErrorBody))
+		return fmt.Errorf("ntfy responded with HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// maxTelegramMessage is the Bot API limit on message length.
+const maxTelegramMessage = 4096
+
+// ErrInvalidTelegramChat is returned for chat IDs that are neither numeric nor an @channel name.
+var ErrInvalidTelegramChat = errors.New("invalid Telegram chat ID")
+
+var telegramChatID = regexp.MustCompile(`^(-?\d{1,20}|@[A-Za-z][A-Za-z0-9_]{4,31})$`)
+
+// telegramNotifier sends notifications through the Telegram Bot API's sendMessage method.
+type telegramNotifier struct{}
+
+func (telegramNotifier) Name() string { return "telegram" }
+
+func (telegramNotifier) Enabled() bool {
+	cfg := configuration.Get()
+	return cfg.Telegram.BotToken != "" && cfg.Telegram.APIURL != ""
+}
+
+func (telegramNotifier) Ready(settings models.UserSettings) bool {
+	return settings.TelegramChatID != ""
+}
+
+func (telegramNotifier) Send(_ discordapi.Session, n Notification) error {
+	return sendTelegramMessage(n.Settings.TelegramChatID, n.Embed)
+}
+
+// SetTelegramChat sends a confirmation message to chatID and, if Telegram accepts it, saves the
+// chat as the user's Telegram destination. The chat must have started a conversation with the bot.
+func SetTelegramChat(userID, chatID string) error {
+	if !(telegramNotifier{}).Enabled() {
+		return ErrNotifierDisabled
+	}
+	chatID = strings.TrimSpace(chatID)
+	if !telegramChatID.MatchString(chatID) {
+		return ErrInvalidTelegramChat
+	}
+
+	lang := LanguageForUser(userID)
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "notifier.telegram.connected.title"),
+		Description: i18n.T(lang, "notifier.telegram.connected.description"),
+	}
+	if err := sendTelegramMessage(chatID, embed); err != nil {
+		return err
+	}
+
+	settings := models.UserSettings{TelegramChatID: chatID}
+	return database.DB.Model(&models.UserSettings{}).Where("user_id = ?", userID).Select("TelegramChatID").Updates(&settings).Error
+}
+
+// RemoveTelegramChat forgets the user's Telegram chat and stops routing notifications to it.
+func RemoveTelegramChat(userID string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserSettings{}).Where("user_id = ?", userID).Update("telegram_chat_id", "").Error; err != nil {
+			return err
+		}
+		return removeNotificationDestination(tx, userID, "telegram")
+	})
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+func sendTelegramMessage(chatID string, embed *discordgo.MessageEmbed) error {
+	cfg := configuration.Get().Telegram
+
+	message := map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     telegramText(embed),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	if len([]rune(message["text"].(string))) > maxTelegramMessage {
+		text := []rune(plainEmbedText(embed))
+		if len(text) > maxTelegramMessage {
+			text = append(text[:maxTelegramMessage-1], '…')
+		}
+		message["text"] = string(text)
+		delete(message, "parse_mode")
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	resp, err := notifierHTTPClient().Post(cfg.APIURL+"/bot"+cfg.BotToken+"/sendMessage", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("telegram request failed: %w", stripURLError(err))
+	}
+	defer resp.Body.Close()
+
+	var result telegramResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil || resp.StatusCode != http.StatusOK || !result.OK {
+		if result.Description != "" {
+			return fmt.Errorf("telegram responded with HTTP %d: %s", resp.StatusCode, result.Description)
+		}
+		return fmt.Errorf("telegram responded with HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// telegramText renders an embed with the HTML subset Telegram supports.
+func telegramText(embed *discordgo.MessageEmbed) string {
+	var b strings.Builder
+	b.WriteString("<b>" + html.EscapeString(plainDiscordText(embed.Title)) + "</b>")
+	if embed.Description != "" {
+		b.WriteString("\n\n" + telegramHTML(embed.Description))
+	}
+	for _, field := range embed.Fields {
+		b.WriteString("\n\n<b>" + html.EscapeString(plainDiscordText(field.Name)) + "</b>\n" + telegramHTML(field.Value))
+	}
+	if embed.Footer != nil && embed.Footer.Text != "" {
+		b.WriteString("\n\n<i>" + html.EscapeString(plainDiscordText(embed.Footer.Text)) + "</i>")
+	}
+	return b.String()
+}
+
+func telegramHTML(s string) string {
+	s = html.EscapeString(strings.TrimSpace(stripDiscordMarkup(s)))
+	s = markdownBold.ReplaceAllString(s, "<b>$1</b>")
+	return markdownCode.ReplaceAllString(s, "<code>$1</code>")
+}
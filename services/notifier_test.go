@@ -0,0 +1,347 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+	"github.com/bradselph/CODStatusBot/testsupport/fakenotifier"
+	"github.com/bwmarrin/discordgo"
+)
+
+// startNotifierFakes points the Telegram and ntfy configuration at a fake server, with email
+// off, and builds a fresh notifier client for the rest of the test.
+func startNotifierFakes(t *testing.T) *fakenotifier.Server {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := *cfg
+	srv := fakenotifier.New()
+	cfg.Telegram.BotToken = ""
+	cfg.Ntfy.Token = ""
+	cfg.SMTP.Host = ""
+	srv.Apply(cfg)
+	notifierClientOnce, notifierClient = sync.Once{}, nil
+	t.Cleanup(func() {
+		srv.Close()
+		*cfg = saved
+		notifierClientOnce, notifierClient = sync.Once{}, nil
+	})
+	return srv
+}
+
+var testEmbed = &discordgo.MessageEmbed{
+	Title:       "Account main — **banned**",
+	Description: "Status is **permaban** & `final`",
+	Fields:      []*discordgo.MessageEmbedField{{Name: "Games", Value: "MW3"}},
+	Footer:      &discordgo.MessageEmbedFooter{Text: "Checked by bot"},
+}
+
+func TestNotificationDestinations(t *testing.T) {
+	tests := []struct {
+		name             string
+		settings         models.UserSettings
+		notificationType string
+		want             []string
+	}{
+		{
+			name:             "nothing chosen",
+			notificationType: "status_change",
+			want:             []string{"channel"},
+		},
+		{
+			name:             "chosen before routes existed",
+			settings:         models.UserSettings{NotificationType: "dm"},
+			notificationType: "status_change",
+			want:             []string{"dm"},
+		},
+		{
+			name: "default route",
+			settings: models.UserSettings{
+				NotificationType:   "dm",
+				NotificationRoutes: map[string][]string{RouteDefault: {"telegram", "channel"}},
+			},
+			notificationType: "daily_update",
+			want:             []string{"telegram", "channel"},
+		},
+		{
+			name: "category route",
+			settings: models.UserSettings{
+				NotificationRoutes: map[string][]string{RouteDefault: {"channel"}, RouteStatus: {"ntfy"}},
+			},
+			notificationType: "permaban",
+			want:             []string{"ntfy"},
+		},
+		{
+			name: "uncategorised type uses the default route",
+			settings: models.UserSettings{
+				NotificationRoutes: map[string][]string{RouteDefault: {"dm"}, RouteStatus: {"ntfy"}},
+			},
+			notificationType: "account_added",
+			want:             []string{"dm"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NotificationDestinations(tt.settings, tt.notificationType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NotificationDestinations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverNotification(t *testing.T) {
+	ready := models.UserSettings{UserID: "user-1", TelegramChatID: "42", NtfyTopic: "cod-alerts"}
+
+	tests := []struct {
+		name         string
+		routes       []string
+		settings     models.UserSettings
+		channelID    string
+		fail         []string // fake backends or Discord methods that reject messages
+		wantTelegram int
+		wantNtfy     int
+		wantChannel  int
+		wantDM       int
+		wantErr      bool
+	}{
+		{name: "every routed backend", routes: []string{"telegram", "ntfy"}, settings: ready, channelID: "channel-1", wantTelegram: 1, wantNtfy: 1},
+		{name: "one backend failing", routes: []string{"telegram", "ntfy"}, settings: ready, channelID: "channel-1", fail: []string{"telegram"}, wantNtfy: 1},
+		{name: "fallback to the channel", routes: []string{"telegram", "ntfy"}, settings: ready, channelID: "channel-1", fail: []string{"telegram", "ntfy"}, wantChannel: 1},
+		{name: "fallback to a DM without a channel", routes: []string{"telegram"}, settings: ready, fail: []string{"telegram"}, wantDM: 1},
+		{name: "destination not set up", routes: []string{"telegram"}, settings: models.UserSettings{UserID: "user-1"}, channelID: "channel-1", wantChannel: 1},
+		{name: "failed channel is not retried", routes: []string{"channel"}, settings: ready, channelID: "channel-1", fail: []string{"ChannelMessageSendComplex"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startNotifierFakes(t)
+			session := fakediscord.New()
+			for _, name := range tt.fail {
+				if name == "telegram" || name == "ntfy" {
+					srv.SetFail(name, true)
+				} else {
+					session.FailWith(name, errors.New("discord unavailable"))
+				}
+			}
+
+			settings := tt.settings
+			settings.NotificationRoutes = map[string][]string{RouteStatus: tt.routes}
+			err := deliverNotification(session, Notification{
+				Type:     "status_change",
+				Account:  models.Account{UserID: "user-1", ChannelID: tt.channelID},
+				Settings: settings,
+				Embed:    testEmbed,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("deliverNotification error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if got := len(srv.TelegramMessages()); got != tt.wantTelegram {
+				t.Errorf("sent %d Telegram messages, want %d", got, tt.wantTelegram)
+			}
+			if got := len(srv.NtfyMessages()); got != tt.wantNtfy {
+				t.Errorf("published %d ntfy messages, want %d", got, tt.wantNtfy)
+			}
+			if got := len(session.MessagesTo("channel-1")); got != tt.wantChannel {
+				t.Errorf("sent %d channel messages, want %d", got, tt.wantChannel)
+			}
+			if got := len(session.DMs("user-1")); got != tt.wantDM {
+				t.Errorf("sent %d DMs, want %d", got, tt.wantDM)
+			}
+		})
+	}
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	srv := startNotifierFakes(t)
+
+	n := Notification{Type: "status_change", Settings: models.UserSettings{TelegramChatID: "-1001234"}, Embed: testEmbed}
+	if err := (telegramNotifier{}).Send(nil, n); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	want := fakenotifier.TelegramMessage{
+		Token:     fakenotifier.BotToken,
+		ChatID:    "-1001234",
+		Text:      "<b>Account main — banned</b>\n\nStatus is <b>permaban</b> &amp; <code>final</code>\n\n<b>Games</b>\nMW3\n\n<i>Checked by bot</i>",
+		ParseMode: "HTML",
+	}
+	if got := srv.TelegramMessages(); len(got) != 1 || got[0] != want {
+		t.Fatalf("Telegram messages = %+v, want %+v", got, want)
+	}
+
+	t.Run("long messages are cut as plain text", func(t *testing.T) {
+		srv.Reset()
+		long := &discordgo.MessageEmbed{Title: "Long", Description: strings.Repeat("a", 2*maxTelegramMessage)}
+		if err := (telegramNotifier{}).Send(nil, Notification{Settings: n.Settings, Embed: long}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		got := srv.TelegramMessages()[0]
+		if got.ParseMode != "" || utf8.RuneCountInString(got.Text) != maxTelegramMessage || !strings.HasSuffix(got.Text, "…") {
+			t.Errorf("long message sent with parse mode %q, %d characters", got.ParseMode, utf8.RuneCountInString(got.Text))
+		}
+	})
+
+	t.Run("errors carry Telegram's description", func(t *testing.T) {
+		srv.SetFail("telegram", true)
+		err := (telegramNotifier{}).Send(nil, n)
+		if err == nil || !strings.Contains(err.Error(), "chat not found") {
+			t.Errorf("Send error = %v, want Telegram's description", err)
+		}
+		if err != nil && strings.Contains(err.Error(), fakenotifier.BotToken) {
+			t.Errorf("Send error %q contains the bot token", err)
+		}
+	})
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	tests := []struct {
+		notificationType string
+		token            string
+		want             fakenotifier.NtfyMessage
+	}{
+		{
+			notificationType: "status_change",
+			want: fakenotifier.NtfyMessage{
+				Topic:    "cod-alerts",
+				Title:    "Account main — banned",
+				Priority: "high",
+				Tags:     "video_game",
+				Body:     "Status is permaban & final\n\nGames\nMW3\n\n-- \nChecked by bot\n",
+			},
+		},
+		{
+			notificationType: "daily_update",
+			token:            "tk_secret",
+			want: fakenotifier.NtfyMessage{
+				Topic:         "cod-alerts",
+				Title:         "Account main — banned",
+				Priority:      "default",
+				Tags:          "video_game",
+				Authorization: "Bearer tk_secret",
+				Body:          "Status is permaban & final\n\nGames\nMW3\n\n-- \nChecked by bot\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.notificationType, func(t *testing.T) {
+			srv := startNotifierFakes(t)
+			configuration.Get().Ntfy.Token = tt.token
+
+			n := Notification{Type: tt.notificationType, Settings: models.UserSettings{NtfyTopic: "cod-alerts"}, Embed: testEmbed}
+			if err := (ntfyNotifier{}).Send(nil, n); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if got := srv.NtfyMessages(); len(got) != 1 || got[0] != tt.want {
+				t.Fatalf("ntfy messages = %+v, want %+v", got, tt.want)
+			}
+
+			srv.SetFail("ntfy", true)
+			if err := (ntfyNotifier{}).Send(nil, n); err == nil || !strings.Contains(err.Error(), "HTTP 500") {
+				t.Errorf("Send error = %v, want the HTTP status", err)
+			}
+		})
+	}
+}
+
+func TestConnectNotifiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		connect func(userID, value string) error
+		value   string
+		fail    string
+		wantErr error
+		stored  func(models.UserSettings) string
+	}{
+		{name: "telegram", connect: SetTelegramChat, value: "12345", stored: func(s models.UserSettings) string { return s.TelegramChatID }},
+		{name: "telegram channel", connect: SetTelegramChat, value: "@codalerts", stored: func(s models.UserSettings) string { return s.TelegramChatID }},
+		{name: "telegram bad chat", connect: SetTelegramChat, value: "not a chat", wantErr: ErrInvalidTelegramChat, stored: func(s models.UserSettings) string { return s.TelegramChatID }},
+		{name: "telegram refuses", connect: SetTelegramChat, value: "12345", fail: "telegram", stored: func(s models.UserSettings) string { return s.TelegramChatID }},
+		{name: "ntfy", connect: SetNtfyTopic, value: "cod-alerts", stored: func(s models.UserSettings) string { return s.NtfyTopic }},
+		{name: "ntfy bad topic", connect: SetNtfyTopic, value: "cod/alerts", wantErr: ErrInvalidNtfyTopic, stored: func(s models.UserSettings) string { return s.NtfyTopic }},
+		{name: "ntfy refuses", connect: SetNtfyTopic, value: "cod-alerts", fail: "ntfy", stored: func(s models.UserSettings) string { return s.NtfyTopic }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestDatabase(t)
+			srv := startNotifierFakes(t)
+			if tt.fail != "" {
+				srv.SetFail(tt.fail, true)
+			}
+			if _, err := GetUserSettings("user-1"); err != nil {
+				t.Fatalf("GetUserSettings: %v", err)
+			}
+
+			err := tt.connect("user-1", tt.value)
+			switch {
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Fatalf("connect error = %v, want %v", err, tt.wantErr)
+			case tt.wantErr == nil && tt.fail == "" && err != nil:
+				t.Fatalf("connect: %v", err)
+			case tt.fail != "" && err == nil:
+				t.Fatalf("connect succeeded although %s refused the confirmation", tt.fail)
+			}
+
+			var settings models.UserSettings
+			if err := database.DB.Where("user_id = ?", "user-1").First(&settings).Error; err != nil {
+				t.Fatalf("load settings: %v", err)
+			}
+			want := ""
+			if err == nil {
+				want = tt.value
+			}
+			if got := tt.stored(settings); got != want {
+				t.Errorf("stored destination = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDisconnectNotifierRemovesRoutes(t *testing.T) {
+	useTestDatabase(t)
+	settings := models.UserSettings{
+		UserID:           "user-1",
+		TelegramChatID:   "12345",
+		NotificationType: "telegram",
+		NotificationRoutes: map[string][]string{
+			RouteDefault: {"telegram"},
+			RouteStatus:  {"telegram", "dm"},
+		},
+	}
+	if err := database.DB.Create(&settings).Error; err != nil {
+		t.Fatalf("create settings: %v", err)
+	}
+	account := models.Account{UserID: "user-1", Title: "main", NotificationType: "telegram"}
+	if err := database.DB.Create(&account).Error; err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	if err := RemoveTelegramChat("user-1"); err != nil {
+		t.Fatalf("RemoveTelegramChat: %v", err)
+	}
+
+	var stored models.UserSettings
+	if err := database.DB.Where("user_id = ?", "user-1").First(&stored).Error; err != nil {
+		t.Fatalf("load settings: %v", err)
+	}
+	if stored.TelegramChatID != "" {
+		t.Errorf("Telegram chat %q was kept", stored.TelegramChatID)
+	}
+	if want := map[string][]string{RouteStatus: {"dm"}}; !reflect.DeepEqual(stored.NotificationRoutes, want) {
+		t.Errorf("routes = %v, want %v", stored.NotificationRoutes, want)
+	}
+	if stored.NotificationType != "channel" {
+		t.Errorf("notification type = %q, want channel", stored.NotificationType)
+	}
+	if err := database.DB.First(&account, account.ID).Error; err != nil {
+		t.Fatalf("load account: %v", err)
+	}
+	if account.NotificationType != "channel" {
+		t.Errorf("account notification type = %q, want channel", account.NotificationType)
+	}
+}
@@ -0,0 +1,488 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+const (
+	// jobLease is how long a claimed job may run before another pass treats it as abandoned.
+	// It has to outlast a full CheckAccount call including captcha retries.
+	jobLease = 15 * time.Minute
+
+	jobBatchSize    = 50
+	jobRetention    = 30 * 24 * time.Hour
+	jobCleanupEvery = 24 * time.Hour
+)
+
+type jobHandler func(ctx context.Context, s discordapi.Session, job models.ScheduledJob) error
+
+var jobHandlers = map[models.JobType]jobHandler{
+	models.JobTempBanReminder:     runTempBanReminder,
+	models.JobTempBanRecheck:      runTempBanRecheck,
+	models.JobCookieExpiryWarning: runCookieExpiryWarning,
+}
+
+type tempBanPayload struct {
+	EndsAt time.Time `json:"ends_at"`
+}
+
+type cookieExpiryPayload struct {
+	Expiration int64 `json:"expiration"`
+}
+
+// ScheduleJob persists job so it runs at job.RunAt even if the bot restarts in between. When
+// job.DedupeKey is set and a job with that key already exists, a pending one is moved to the
+// new time and a finished one is left alone.
+func ScheduleJob(job models.ScheduledJob) error {
+	job.Status = models.JobPending
+	job.Attempts = 0
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if job.DedupeKey != "" {
+			var existing []models.ScheduledJob
+			if err := tx.Where("dedupe_key = ?", job.DedupeKey).Order("id DESC").Limit(1).Find(&existing).Error; err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				if existing[0].Status != models.JobPending {
+					return nil
+				}
+				return tx.Model(&existing[0]).Updates(map[string]interface{}{
+					"run_at":  job.RunAt,
+					"payload": job.Payload,
+				}).Error
+			}
+		}
+		return tx.Create(&job).Error
+	})
+}
+
+// CancelJobs removes the pending jobs of the given types for an account.
+func CancelJobs(accountID uint, types ...models.JobType) error {
+	return database.DB.Unscoped().
+		Where("account_id = ? AND type IN ? AND status = ?", accountID, types, models.JobPending).
+		Delete(&models.ScheduledJob{}).Error
+}
+
+// ScheduleTempBanFollowUps replaces any pending temp-ban jobs for the account with a reminder
+// every TEMP_BAN_UPDATE_INTERVAL hours and a re-check once the ban ends. When Activision did not
+// report an end time the re-check runs after one reminder interval instead.
+func ScheduleTempBanFollowUps(account models.Account, endsAt time.Time) error {
+	if err := CancelJobs(account.ID, models.JobTempBanReminder, models.JobTempBanRecheck); err != nil {
+		return fmt.Errorf("failed to cancel previous temp-ban jobs: %w", err)
+	}
+
+	if endsAt.IsZero() {
+		endsAt = time.Now().Add(tempBanReminderInterval())
+	}
+	payload, err := json.Marshal(tempBanPayload{EndsAt: endsAt})
+	if err != nil {
+		return err
+	}
+
+	if err := scheduleTempBanReminder(account, endsAt, string(payload)); err != nil {
+		return err
+	}
+
+	return ScheduleJob(models.ScheduledJob{
+		Type:      models.JobTempBanRecheck,
+		UserID:    account.UserID,
+		AccountID: account.ID,
+		RunAt:     endsAt,
+		Payload:   string(payload),
+	})
+}
+
+func scheduleTempBanReminder(account models.Account, endsAt time.Time, payload string) error {
+	next := time.Now().Add(tempBanReminderInterval())
+	if !next.Before(endsAt) {
+		return nil
+	}
+
+	return ScheduleJob(models.ScheduledJob{
+		Type:      models.JobTempBanReminder,
+		UserID:    account.UserID,
+		AccountID: account.ID,
+		RunAt:     next,
+		Payload:   payload,
+	})
+}
+
+func tempBanReminderInterval() time.Duration {
+	interval := time.Duration(configuration.Get().Intervals.TempBanUpdate * float64(time.Hour))
+	if interval <= 0 {
+		return 24 * time.Hour
+	}
+	return interval
+}
+
+// ScheduleCookieExpiryWarning schedules a warning COOKIE_EXPIRATION_WARNING hours before the
+// account's SSO cookie expires, replacing a pending warning for a previous cookie.
+func ScheduleCookieExpiryWarning(account models.Account) error {
+	key := fmt.Sprintf("%s:%d:%d", models.JobCookieExpiryWarning, account.ID, account.SSOCookieExpiration)
+
+	err := database.DB.Unscoped().
+		Where("account_id = ? AND type = ? AND status = ? AND dedupe_key <> ?",
+			account.ID, models.JobCookieExpiryWarning, models.JobPending, key).
+		Delete(&models.ScheduledJob{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to cancel previous cookie warning: %w", err)
+	}
+
+	if account.SSOCookieExpiration == 0 {
+		return nil
+	}
+	expiresAt := time.Unix(account.SSOCookieExpiration, 0)
+	if !expiresAt.After(time.Now()) {
+		return nil
+	}
+
+	warnAt := expiresAt.Add(-time.Duration(configuration.Get().Intervals.CookieExpiration * float64(time.Hour)))
+	if warnAt.Before(time.Now()) {
+		warnAt = time.Now()
+	}
+
+	payload, err := json.Marshal(cookieExpiryPayload{Expiration: account.SSOCookieExpiration})
+	if err != nil {
+		return err
+	}
+
+	return ScheduleJob(models.ScheduledJob{
+		Type:      models.JobCookieExpiryWarning,
+		UserID:    account.UserID,
+		AccountID: account.ID,
+		DedupeKey: key,
+		RunAt:     warnAt,
+		Payload:   string(payload),
+	})
+}
+
+// syncCookieExpiryJobs makes sure every monitored account has a cookie warning scheduled,
+// covering accounts added before the scheduler existed.
+func syncCookieExpiryJobs() {
+	var accounts []models.Account
+	if err := database.DB.Where("is_expired_cookie = ? AND is_check_disabled = ? AND sso_cookie_expiration > ?",
+		false, false, time.Now().Unix()).Find(&accounts).Error; err != nil {
+		logger.Log.WithError(err).Error("Failed to load accounts for cookie expiry jobs")
+		return
+	}
+
+	for _, account := range accounts {
+		if err := ScheduleCookieExpiryWarning(account); err != nil {
+			logger.Log.WithError(err).Errorf("Failed to schedule cookie expiry warning for account %s", account.Title)
+		}
+	}
+}
+
+// RunScheduler executes persisted jobs as they come due until ctx is cancelled. Jobs that came
+// due while the bot was down are picked up on the first pass, and a job whose run was cut short
+// by a crash is retried once its lease expires, so every job runs at least once.
+func RunScheduler(ctx context.Context, s discordapi.Session) {
+	cfg := configuration.Get()
+	pollInterval := cfg.Scheduler.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 30 * time.Second
+	}
+
+	var overdue int64
+	if err := database.DB.Model(&models.ScheduledJob{}).
+		Where("status IN ? AND run_at <= ?", []models.JobStatus{models.JobPending, models.JobRunning}, time.Now()).
+		Count(&overdue).Error; err != nil {
+		logger.Log.WithError(err).Error("Failed to count overdue scheduled jobs")
+	} else if overdue > 0 {
+		logger.Log.Infof("Catching up on %d scheduled jobs that came due while the bot was offline", overdue)
+	}
+
+	syncCookieExpiryJobs()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		runDueJobs(ctx, s)
+
+		if time.Since(lastCleanup) >= jobCleanupEvery {
+			purgeFinishedJobs()
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runDueJobs(ctx context.Context, s discordapi.Session) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		var jobs []models.ScheduledJob
+		if err := database.DB.
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until <= ?)",
+				models.JobPending, now, models.JobRunning, now).
+			Order("run_at").
+			Limit(jobBatchSize).
+			Find(&jobs).Error; err != nil {
+			logger.Log.WithError(err).Error("Failed to load due scheduled jobs")
+			return
+		}
+		if len(jobs) == 0 {
+			return
+		}
+
+		sem := make(chan struct{}, checkWorkerCount())
+		var wg sync.WaitGroup
+		for _, job := range jobs {
+			if !claimJob(&job) {
+				continue
+			}
+			if !acquire(ctx, sem) {
+				releaseJob(job)
+				continue
+			}
+			wg.Add(1)
+			go func(job models.ScheduledJob) {
+				defer wg.Done()
+				defer release(sem)
+				runJob(ctx, s, job)
+			}(job)
+		}
+		wg.Wait()
+
+		if len(jobs) < jobBatchSize {
+			return
+		}
+	}
+}
+
+// claimJob marks job as running under a fresh lease. The attempt counter doubles as a version
+// so two passes can never claim the same run.
+func claimJob(job *models.ScheduledJob) bool {
+	result := database.DB.Model(&models.ScheduledJob{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, job.Status, job.Attempts).
+		Updates(map[string]interface{}{
+			"status":       models.JobRunning,
+			"attempts":     job.Attempts + 1,
+			"locked_until": time.Now().Add(jobLease),
+		})
+	if result.Error != nil {
+		logger.Log.WithError(result.Error).Errorf("Failed to claim scheduled job %d", job.ID)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+
+	job.Status = models.JobRunning
+	job.Attempts++
+	return true
+}
+
+// releaseJob hands a claimed job back without counting the attempt, for runs cut short by shutdown.
+func releaseJob(job models.ScheduledJob) {
+	if err := database.DB.Model(&models.ScheduledJob{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":   models.JobPending,
+		"attempts": job.Attempts - 1,
+	}).Error; err != nil {
+		logger.Log.WithError(err).Errorf("Failed to release scheduled job %d", job.ID)
+	}
+}
+
+func runJob(ctx context.Context, s discordapi.Session, job models.ScheduledJob) {
+	handler, ok := jobHandlers[job.Type]
+	if !ok {
+		finishJob(job, models.JobFailed, fmt.Errorf("unknown job type %q", job.Type), time.Time{})
+		return
+	}
+
+	err := handler(ctx, s, job)
+	switch {
+	case err == nil:
+		finishJob(job, models.JobDone, nil, time.Time{})
+	case ctx.Err() != nil:
+		releaseJob(job)
+	case job.Attempts >= maxJobAttempts():
+		logger.Log.WithError(err).Errorf("Scheduled job %d (%s) failed after %d attempts", job.ID, job.Type, job.Attempts)
+		finishJob(job, models.JobFailed, err, time.Time{})
+	default:
+		retryAt := time.Now().Add(time.Duration(job.Attempts*job.Attempts) * time.Minute)
+		logger.Log.WithError(err).Warnf("Scheduled job %d (%s) failed, retrying at %s", job.ID, job.Type, retryAt.Format(time.RFC3339))
+		finishJob(job, models.JobPending, err, retryAt)
+	}
+}
+
+func finishJob(job models.ScheduledJob, status models.JobStatus, jobErr error, retryAt time.Time) {
+	updates := map[string]interface{}{"status": status}
+	if jobErr != nil {
+		updates["last_error"] = logger.Redact(jobErr.Error())
+	}
+	if !retryAt.IsZero() {
+		updates["run_at"] = retryAt
+	}
+
+	if err := database.DB.Model(&models.ScheduledJob{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+		logger.Log.WithError(err).Errorf("Failed to update scheduled job %d", job.ID)
+	}
+}
+
+func maxJobAttempts() int {
+	attempts := configuration.Get().Scheduler.MaxAttempts
+	if attempts < 1 {
+		return 1
+	}
+	return attempts
+}
+
+func purgeFinishedJobs() {
+	result := database.DB.Unscoped().
+		Where("status IN ? AND updated_at < ?", []models.JobStatus{models.JobDone, models.JobFailed}, time.Now().Add(-jobRetention)).
+		Delete(&models.ScheduledJob{})
+	if result.Error != nil {
+		logger.Log.WithError(result.Error).Error("Failed to purge finished scheduled jobs")
+	} else if result.RowsAffected > 0 {
+		logger.Log.Infof("Purged %d finished scheduled jobs", result.RowsAffected)
+	}
+}
+
+// loadJobAccount returns the job's account, or false if it has since been removed.
+func loadJobAccount(job models.ScheduledJob) (models.Account, bool, error) {
+	var account models.Account
+	err := database.DB.First(&account, job.AccountID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return account, false, nil
+	}
+	if err != nil {
+		return account, false, fmt.Errorf("failed to load account %d: %w", job.AccountID, err)
+	}
+	return account, true, nil
+}
+
+func runTempBanReminder(ctx context.Context, s discordapi.Session, job models.ScheduledJob) error {
+	account, ok, err := loadJobAccount(job)
+	if err != nil || !ok {
+		return err
+	}
+	if account.LastStatus != models.StatusTempban {
+		return nil
+	}
+
+	var payload tempBanPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return fmt.Errorf("invalid temp-ban job payload: %w", err)
+	}
+	remaining := time.Until(payload.EndsAt)
+	if remaining <= 0 {
+		return nil
+	}
+
+	lang := LanguageForUser(account.UserID)
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(lang, "tempban.update.title", account.Title),
+		Description: i18n.T(lang, "tempban.reminder", FormatDuration(lang, remaining)),
+		Color:       GetColorForStatus(models.StatusTempban, false, account.IsCheckDisabled),
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+	if err := SendNotification(s, account, embed, "", "temp_ban_update"); err != nil {
+		return fmt.Errorf("failed to send temporary ban update: %w", err)
+	}
+
+	return scheduleTempBanReminder(account, payload.EndsAt, job.Payload)
+}
+
+func runTempBanRecheck(ctx context.Context, s discordapi.Session, job models.ScheduledJob) error {
+	account, ok, err := loadJobAccount(job)
+	if err != nil || !ok {
+		return err
+	}
+
+	settings, err := GetUserSettings(account.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user settings: %w", err)
+	}
+	if reached, _, err := CaptchaBudgetReached(settings); err != nil {
+		return err
+	} else if reached {
+		logger.Log.Infof("Skipping temp-ban recheck for account %s, monthly captcha budget reached", account.Title)
+		return nil
+	}
+
+	check, err := CheckAccount(WithCaptchaAccount(ctx, account.ID), account.SSOCookie, account.UserID, "")
+	if err != nil {
+		return fmt.Errorf("failed to check account %s after temporary ban duration: %w", account.Title, err)
+	}
+	result := check.Status
+
+	if result == models.StatusTempban {
+		if err := ScheduleTempBanFollowUps(account, check.TempBanEndsAt()); err != nil {
+			logger.Log.WithError(err).Errorf("Failed to reschedule temp-ban follow-ups for account %s", account.Title)
+		}
+	} else if err := CancelJobs(account.ID, models.JobTempBanReminder); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to cancel temp-ban reminders for account %s", account.Title)
+	}
+
+	lang := UserLanguage(settings)
+	var embed *discordgo.MessageEmbed
+	if result == models.StatusGood {
+		embed = &discordgo.MessageEmbed{
+			Title:       i18n.T(lang, "tempban.lifted.title", account.Title),
+			Description: i18n.T(lang, "tempban.lifted.description", account.Title),
+			Color:       GetColorForStatus(result, false, account.IsCheckDisabled),
+			Timestamp:   time.Now().Format(time.RFC3339),
+		}
+	} else if result == models.StatusPermaban {
+		embed = &discordgo.MessageEmbed{
+			Title:       i18n.T(lang, "tempban.escalated.title", account.Title),
+			Description: i18n.T(lang, "tempban.escalated.description", account.Title),
+			Color:       GetColorForStatus(result, false, account.IsCheckDisabled),
+			Timestamp:   time.Now().Format(time.RFC3339),
+		}
+	} else {
+		embed = &discordgo.MessageEmbed{
+			Title:       i18n.T(lang, "tempban.update.title", account.Title),
+			Description: i18n.T(lang, "tempban.update.description", account.Title, StatusName(lang, result)),
+			Color:       GetColorForStatus(result, false, account.IsCheckDisabled),
+			Timestamp:   time.Now().Format(time.RFC3339),
+		}
+	}
+
+	if err := SendNotification(s, account, embed, fmt.Sprintf("<@%s>", account.UserID), "temp_ban_update"); err != nil {
+		return fmt.Errorf("failed to send temporary ban update: %w", err)
+	}
+	return nil
+}
+
+func runCookieExpiryWarning(ctx context.Context, s discordapi.Session, job models.ScheduledJob) error {
+	account, ok, err := loadJobAccount(job)
+	if err != nil || !ok {
+		return err
+	}
+	if account.IsExpiredCookie || account.IsCheckDisabled {
+		return nil
+	}
+
+	var payload cookieExpiryPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return fmt.Errorf("invalid cookie expiry job payload: %w", err)
+	}
+	if payload.Expiration != account.SSOCookieExpiration || payload.Expiration <= time.Now().Unix() {
+		return nil
+	}
+
+	return NotifyCookieExpiringSoon(s, []models.Account{account})
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+)
+
+const testJob models.JobType = "test_job"
+
+// useTestJobHandler registers handler for testJob and configures the scheduler for the rest of
+// the test.
+func useTestJobHandler(t *testing.T, maxAttempts int, handler jobHandler) {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := cfg.Scheduler
+	cfg.Scheduler.MaxAttempts = maxAttempts
+	cfg.Scheduler.PollInterval = time.Hour
+	jobHandlers[testJob] = handler
+	t.Cleanup(func() {
+		cfg.Scheduler = saved
+		delete(jobHandlers, testJob)
+	})
+}
+
+// countRuns returns a handler that counts its runs per job and fails with err.
+func countRuns(runs *sync.Map, err error) jobHandler {
+	return func(_ context.Context, _ discordapi.Session, job models.ScheduledJob) error {
+		n, _ := runs.LoadOrStore(job.ID, new(atomic.Int32))
+		n.(*atomic.Int32).Add(1)
+		return err
+	}
+}
+
+func runsOf(runs *sync.Map, id uint) int {
+	n, ok := runs.Load(id)
+	if !ok {
+		return 0
+	}
+	return int(n.(*atomic.Int32).Load())
+}
+
+func createJob(t *testing.T, job models.ScheduledJob) models.ScheduledJob {
+	t.Helper()
+	if job.Type == "" {
+		job.Type = testJob
+	}
+	if job.Status == "" {
+		job.Status = models.JobPending
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	return job
+}
+
+func loadJob(t *testing.T, id uint) models.ScheduledJob {
+	t.Helper()
+	var job models.ScheduledJob
+	if err := database.DB.First(&job, id).Error; err != nil {
+		t.Fatalf("load job %d: %v", id, err)
+	}
+	return job
+}
+
+func TestClaimJob(t *testing.T) {
+	useTestDatabase(t)
+	job := createJob(t, models.ScheduledJob{RunAt: time.Now().Add(-time.Minute)})
+	stale := job
+
+	if !claimJob(&job) {
+		t.Fatal("claimJob refused a pending job")
+	}
+	stored := loadJob(t, job.ID)
+	if stored.Status != models.JobRunning || stored.Attempts != 1 {
+		t.Errorf("claimed job is %s after %d attempts, want running after 1", stored.Status, stored.Attempts)
+	}
+	if lease := time.Until(stored.LockedUntil); lease < jobLease-time.Minute || lease > jobLease {
+		t.Errorf("lease ends in %v, want about %v", lease, jobLease)
+	}
+
+	if claimJob(&stale) {
+		t.Error("a second pass claimed the job with an out of date attempt count")
+	}
+
+	// Once the lease has run out the job can be claimed again, as the next attempt.
+	if err := database.DB.Model(&stored).Update("locked_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("expire lease: %v", err)
+	}
+	if !claimJob(&stored) || stored.Attempts != 2 {
+		t.Errorf("reclaiming an abandoned job gave attempt %d, want 2", stored.Attempts)
+	}
+}
+
+func TestClaimJobConcurrent(t *testing.T) {
+	useTestDatabase(t)
+	job := createJob(t, models.ScheduledJob{RunAt: time.Now().Add(-time.Minute)})
+
+	const claimers = 8
+	var (
+		wg    sync.WaitGroup
+		won   atomic.Int32
+		start = make(chan struct{})
+	)
+	for i := 0; i < claimers; i++ {
+		wg.Add(1)
+		go func(job models.ScheduledJob) {
+			defer wg.Done()
+			<-start
+			if claimJob(&job) {
+				won.Add(1)
+			}
+		}(job)
+	}
+	close(start)
+	wg.Wait()
+
+	if n := won.Load(); n != 1 {
+		t.Fatalf("%d passes claimed the same run, want 1", n)
+	}
+	if attempts := loadJob(t, job.ID).Attempts; attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestRunDueJobs(t *testing.T) {
+	tests := []struct {
+		name         string
+		job          models.ScheduledJob
+		wantRuns     int
+		wantAttempts int
+	}{
+		{
+			name:         "due",
+			job:          models.ScheduledJob{RunAt: time.Now().Add(-time.Minute)},
+			wantRuns:     1,
+			wantAttempts: 1,
+		},
+		{
+			name: "not due yet",
+			job:  models.ScheduledJob{RunAt: time.Now().Add(time.Hour)},
+		},
+		{
+			name:         "lease expired",
+			job:          models.ScheduledJob{RunAt: time.Now().Add(-time.Hour), Status: models.JobRunning, Attempts: 1, LockedUntil: time.Now().Add(-time.Second)},
+			wantRuns:     1,
+			wantAttempts: 2,
+		},
+		{
+			name:         "lease held",
+			job:          models.ScheduledJob{RunAt: time.Now().Add(-time.Hour), Status: models.JobRunning, Attempts: 1, LockedUntil: time.Now().Add(time.Minute)},
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestDatabase(t)
+			var runs sync.Map
+			useTestJobHandler(t, 3, countRuns(&runs, nil))
+			job := createJob(t, tt.job)
+
+			runDueJobs(context.Background(), fakediscord.New())
+
+			if got := runsOf(&runs, job.ID); got != tt.wantRuns {
+				t.Errorf("job ran %d times, want %d", got, tt.wantRuns)
+			}
+			stored := loadJob(t, job.ID)
+			if stored.Attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", stored.Attempts, tt.wantAttempts)
+			}
+			if tt.wantRuns > 0 && stored.Status != models.JobDone {
+				t.Errorf("status = %s after a successful run, want %s", stored.Status, models.JobDone)
+			}
+		})
+	}
+}
+
+func TestRunDueJobsRetries(t *testing.T) {
+	useTestDatabase(t)
+	var runs sync.Map
+	useTestJobHandler(t, 2, countRuns(&runs, errors.New("activision unavailable")))
+	job := createJob(t, models.ScheduledJob{RunAt: time.Now().Add(-time.Minute)})
+
+	runDueJobs(context.Background(), fakediscord.New())
+	stored := loadJob(t, job.ID)
+	if stored.Status != models.JobPending || stored.LastError == "" {
+		t.Fatalf("after a failed attempt the job is %s with error %q, want pending with the error", stored.Status, stored.LastError)
+	}
+	if !stored.RunAt.After(time.Now()) {
+		t.Errorf("retry runs at %v, want a later time", stored.RunAt)
+	}
+
+	// Bring the retry forward so the last attempt runs now.
+	if err := database.DB.Model(&stored).Update("run_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("move retry: %v", err)
+	}
+	runDueJobs(context.Background(), fakediscord.New())
+	if stored := loadJob(t, job.ID); stored.Status != models.JobFailed || stored.Attempts != 2 {
+		t.Errorf("after the last attempt the job is %s after %d attempts, want failed after 2", stored.Status, stored.Attempts)
+	}
+	if got := runsOf(&runs, job.ID); got != 2 {
+		t.Errorf("job ran %d times, want 2", got)
+	}
+}
+
+func TestRunSchedulerCatchesUp(t *testing.T) {
+	useTestDatabase(t)
+	var runs sync.Map
+	useTestJobHandler(t, 3, countRuns(&runs, nil))
+	cfg := configuration.Get()
+	saved := cfg.Intervals.CookieExpiration
+	cfg.Intervals.CookieExpiration = 24
+	t.Cleanup(func() { cfg.Intervals.CookieExpiration = saved })
+
+	// Jobs that came due while the bot was down, including one cut short by a crash.
+	missed := createJob(t, models.ScheduledJob{RunAt: time.Now().Add(-6 * time.Hour)})
+	crashed := createJob(t, models.ScheduledJob{RunAt: time.Now().Add(-6 * time.Hour), Status: models.JobRunning, Attempts: 1, LockedUntil: time.Now().Add(-time.Hour)})
+	later := createJob(t, models.ScheduledJob{RunAt: time.Now().Add(time.Hour)})
+
+	// An account added before the scheduler existed has no cookie warning yet.
+	account := models.Account{UserID: "user-1", Title: "main", SSOCookieExpiration: time.Now().Add(10 * 24 * time.Hour).Unix()}
+	if err := database.DB.Create(&account).Error; err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunScheduler(ctx, fakediscord.New())
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runsOf(&runs, missed.ID) == 0 || runsOf(&runs, crashed.ID) == 0 {
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	for _, job := range []models.ScheduledJob{missed, crashed} {
+		if got := runsOf(&runs, job.ID); got != 1 {
+			t.Errorf("overdue job %d ran %d times on startup, want 1", job.ID, got)
+		}
+	}
+	if got := runsOf(&runs, later.ID); got != 0 {
+		t.Errorf("job due in an hour ran %d times, want 0", got)
+	}
+
+	var warnings []models.ScheduledJob
+	database.DB.Where("account_id = ? AND type = ?", account.ID, models.JobCookieExpiryWarning).Find(&warnings)
+	if len(warnings) != 1 {
+		t.Fatalf("scheduled %d cookie warnings for the account, want 1", len(warnings))
+	}
+	wantWarn := time.Unix(account.SSOCookieExpiration, 0).Add(-24 * time.Hour)
+	if d := warnings[0].RunAt.Sub(wantWarn); d < -time.Second || d > time.Second {
+		t.Errorf("cookie warning runs at %v, want %v", warnings[0].RunAt, wantWarn)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 )
 
@@ -64,13 +65,13 @@ func CheckSSOCookieExpiration(expirationTimestamp int64) (time.Duration, error)
 	return timeUntilExpiration, nil
 }
 
-func FormatExpirationTime(expirationTimestamp int64) string {
+func FormatExpirationTime(lang i18n.Lang, expirationTimestamp int64) string {
 	timeUntilExpiration := time.Duration(expirationTimestamp-time.Now().Unix()) * time.Second
 
 	logger.Log.Infof("Formatting expiration time - Current time (Unix): %v, Expiration time (Unix): %v, Time until expiration: %v", time.Now().Unix(), expirationTimestamp, timeUntilExpiration)
 
 	if timeUntilExpiration <= 0 {
-		return "Expired"
+		return i18n.T(lang, "duration.expired")
 	}
 
 	maxDuration := 14 * 24 * time.Hour // 14 days
@@ -78,12 +79,5 @@ func FormatExpirationTime(expirationTimestamp int64) string {
 		timeUntilExpiration = maxDuration
 	}
 
-	days := int(timeUntilExpiration.Hours() / 24)
-	hours := int(timeUntilExpiration.Hours()) % 24
-
-	if days > 0 {
-		return fmt.Sprintf("%d days, %d hours", days, hours)
-	} else {
-		return fmt.Sprintf("%d hours", hours)
-	}
+	return formatDaysHours(lang, timeUntilExpiration)
 }
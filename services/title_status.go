@@ -0,0 +1,199 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/models"
+	"gorm.io/gorm"
+)
+
+// TitleTransition records one game title moving from one status to another.
+type TitleTransition struct {
+	Title    string
+	Previous models.Status
+	Current  models.Status
+}
+
+// titleStatusesFromCheck reduces the check's enforcements to one status per title, keeping the
+// most severe when a title appears more than once. Titles that were banned before but are no
+// longer listed are reported as good.
+func titleStatusesFromCheck(check BanCheck, existing []models.AccountTitleStatus) map[string]models.TitleEnforcement {
+	titles := make(map[string]models.TitleEnforcement)
+	for _, e := range check.Enforcements {
+		if e.Title == "" {
+			continue
+		}
+		if current, ok := titles[e.Title]; ok && enforcementRank(current.Enforcement) <= enforcementRank(e.Enforcement) {
+			continue
+		}
+		titles[e.Title] = e
+	}
+
+	for _, row := range existing {
+		if _, ok := titles[row.Title]; !ok {
+			titles[row.Title] = models.TitleEnforcement{Title: row.Title}
+		}
+	}
+	return titles
+}
+
+// enforcementRank orders enforcements by severity, lower is more severe.
+func enforcementRank(enforcement string) int {
+	for i, e := range enforcementStatus {
+		if e.enforcement == enforcement {
+			return i
+		}
+	}
+	return len(enforcementStatus)
+}
+
+func enforcementToStatus(enforcement string) models.Status {
+	for _, e := range enforcementStatus {
+		if e.enforcement == enforcement {
+			return e.status
+		}
+	}
+	return models.StatusGood
+}
+
+// titleStatusesKnown reports whether the check says anything definite about individual titles.
+func titleStatusesKnown(check BanCheck) bool {
+	switch check.Status {
+	case models.StatusGood, models.StatusPermaban, models.StatusTempban, models.StatusShadowban:
+		return true
+	}
+	return false
+}
+
+// diffTitleStatuses returns the per-title changes the check implies without writing anything.
+func diffTitleStatuses(existing []models.AccountTitleStatus, check BanCheck) []TitleTransition {
+	previous := make(map[string]models.Status, len(existing))
+	for _, row := range existing {
+		previous[row.Title] = row.Status
+	}
+
+	var transitions []TitleTransition
+	for title, e := range titleStatusesFromCheck(check, existing) {
+		current := enforcementToStatus(e.Enforcement)
+		before, ok := previous[title]
+		if !ok {
+			if current == models.StatusGood {
+				continue
+			}
+			before = models.StatusGood
+		}
+		if before != current {
+			transitions = append(transitions, TitleTransition{Title: title, Previous: before, Current: current})
+		}
+	}
+
+	sort.Slice(transitions, func(i, j int) bool { return transitions[i].Title < transitions[j].Title })
+	return transitions
+}
+
+// TitleStatusesChanged reports whether the check would change any of the account's per-title
+// statuses.
+func TitleStatusesChanged(accountID uint, check BanCheck) (bool, error) {
+	if !titleStatusesKnown(check) {
+		return false, nil
+	}
+	existing, err := GetTitleStatuses(accountID)
+	if err != nil {
+		return false, err
+	}
+	return len(diffTitleStatuses(existing, check)) > 0, nil
+}
+
+// UpdateTitleStatuses stores the per-title result of a check and returns the titles whose status
+// changed. Checks that did not reach the appeal API leave the table untouched.
+func UpdateTitleStatuses(accountID uint, check BanCheck) ([]TitleTransition, error) {
+	if !titleStatusesKnown(check) {
+		return nil, nil
+	}
+
+	var transitions []TitleTransition
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []models.AccountTitleStatus
+		if err := tx.Where("account_id = ?", accountID).Find(&existing).Error; err != nil {
+			return err
+		}
+		transitions = diffTitleStatuses(existing, check)
+
+		rows := make(map[string]models.AccountTitleStatus, len(existing))
+		for _, row := range existing {
+			rows[row.Title] = row
+		}
+
+		now := time.Now()
+		for title, e := range titleStatusesFromCheck(check, existing) {
+			status := enforcementToStatus(e.Enforcement)
+			row, ok := rows[title]
+			if !ok && status == models.StatusGood {
+				continue
+			}
+			if !ok || row.Status != status {
+				row.LastStatusChange = now
+			}
+			row.AccountID = accountID
+			row.Title = title
+			row.Status = status
+			row.EndsAt = e.EndsAt
+			row.CanAppeal = e.CanAppeal
+			if err := tx.Save(&row).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update title statuses for account %d: %w", accountID, err)
+	}
+	return transitions, nil
+}
+
+// GetTitleStatuses returns the account's per-title statuses ordered by title.
+func GetTitleStatuses(accountID uint) ([]models.AccountTitleStatus, error) {
+	var rows []models.AccountTitleStatus
+	if err := database.DB.Where("account_id = ?", accountID).Order("title").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load title statuses for account %d: %w", accountID, err)
+	}
+	return rows, nil
+}
+
+// AffectedTitles lists the titles the account is currently banned or under review in.
+func AffectedTitles(rows []models.AccountTitleStatus) string {
+	var titles []string
+	for _, row := range rows {
+		if row.Status != models.StatusGood {
+			titles = append(titles, row.Title)
+		}
+	}
+	return strings.Join(titles, ", ")
+}
+
+// FormatTitleStatuses renders one line per title in lang, e.g. "Warzone: Temporary (ends in 3 days)".
+func FormatTitleStatuses(lang i18n.Lang, rows []models.AccountTitleStatus) string {
+	var b strings.Builder
+	for _, row := range rows {
+		b.WriteString(fmt.Sprintf("%s: %s", row.Title, StatusName(lang, row.Status)))
+		if row.Status == models.StatusTempban && !row.EndsAt.IsZero() {
+			b.WriteString(" " + i18n.T(lang, "title_status.ends", fmt.Sprintf("<t:%d:R>", row.EndsAt.Unix())))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// FormatTitleTransitions renders one line per change in lang, e.g. "Warzone: Good → Shadowban".
+func FormatTitleTransitions(lang i18n.Lang, transitions []TitleTransition) string {
+	lines := make([]string, 0, len(transitions))
+	for _, t := range transitions {
+		lines = append(lines, fmt.Sprintf("%s: %s → %s", t.Title, StatusName(lang, t.Previous), StatusName(lang, t.Current)))
+	}
+	return strings.Join(lines, "\n")
+}
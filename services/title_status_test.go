@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
+	"github.com/bradselph/CODStatusBot/models"
+	"github.com/bradselph/CODStatusBot/testsupport/fakediscord"
+)
+
+func enforcement(title, enforcement string) models.TitleEnforcement {
+	return models.TitleEnforcement{Title: title, Enforcement: enforcement}
+}
+
+// storedTitleStatuses returns the account's per-title statuses keyed by title.
+func storedTitleStatuses(t *testing.T, accountID uint) map[string]models.AccountTitleStatus {
+	t.Helper()
+	rows, err := GetTitleStatuses(accountID)
+	if err != nil {
+		t.Fatalf("GetTitleStatuses: %v", err)
+	}
+	stored := make(map[string]models.AccountTitleStatus, len(rows))
+	for _, row := range rows {
+		stored[row.Title] = row
+	}
+	return stored
+}
+
+func TestUpdateTitleStatuses(t *testing.T) {
+	useTestDatabase(t)
+	const accountID = 7
+	endsAt := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+
+	// Each step checks the same account again and builds on what the previous one stored.
+	steps := []struct {
+		name   string
+		check  BanCheck
+		want   []TitleTransition
+		stored map[string]models.Status
+	}{
+		{
+			name:   "clean account stores nothing",
+			check:  BanCheck{Status: models.StatusGood},
+			stored: map[string]models.Status{},
+		},
+		{
+			name:   "first enforcement",
+			check:  BanCheck{Status: models.StatusShadowban, Enforcements: []models.TitleEnforcement{enforcement("MW3", "UNDER_REVIEW")}},
+			want:   []TitleTransition{{Title: "MW3", Previous: models.StatusGood, Current: models.StatusShadowban}},
+			stored: map[string]models.Status{"MW3": models.StatusShadowban},
+		},
+		{
+			name: "most severe enforcement per title wins",
+			check: BanCheck{Status: models.StatusTempban, Enforcements: []models.TitleEnforcement{
+				enforcement("Warzone", "UNDER_REVIEW"),
+				{Title: "MW3", Enforcement: "TEMPORARY", EndsAt: endsAt},
+				enforcement("MW3", "UNDER_REVIEW"),
+			}},
+			want: []TitleTransition{
+				{Title: "MW3", Previous: models.StatusShadowban, Current: models.StatusTempban},
+				{Title: "Warzone", Previous: models.StatusGood, Current: models.StatusShadowban},
+			},
+			stored: map[string]models.Status{"MW3": models.StatusTempban, "Warzone": models.StatusShadowban},
+		},
+		{
+			name:   "failed check leaves the titles alone",
+			check:  BanCheck{Status: models.StatusUnknown},
+			stored: map[string]models.Status{"MW3": models.StatusTempban, "Warzone": models.StatusShadowban},
+		},
+		{
+			name: "same result changes nothing",
+			check: BanCheck{Status: models.StatusTempban, Enforcements: []models.TitleEnforcement{
+				{Title: "MW3", Enforcement: "TEMPORARY", EndsAt: endsAt},
+				enforcement("Warzone", "UNDER_REVIEW"),
+			}},
+			stored: map[string]models.Status{"MW3": models.StatusTempban, "Warzone": models.StatusShadowban},
+		},
+		{
+			name:  "titles no longer listed are cleared",
+			check: BanCheck{Status: models.StatusGood},
+			want: []TitleTransition{
+				{Title: "MW3", Previous: models.StatusTempban, Current: models.StatusGood},
+				{Title: "Warzone", Previous: models.StatusShadowban, Current: models.StatusGood},
+			},
+			stored: map[string]models.Status{"MW3": models.StatusGood, "Warzone": models.StatusGood},
+		},
+	}
+
+	for _, step := range steps {
+		before := storedTitleStatuses(t, accountID)
+
+		changed, err := TitleStatusesChanged(accountID, step.check)
+		if err != nil {
+			t.Fatalf("%s: TitleStatusesChanged: %v", step.name, err)
+		}
+		if changed != (len(step.want) > 0) {
+			t.Errorf("%s: TitleStatusesChanged = %v, want %v", step.name, changed, len(step.want) > 0)
+		}
+
+		got, err := UpdateTitleStatuses(accountID, step.check)
+		if err != nil {
+			t.Fatalf("%s: UpdateTitleStatuses: %v", step.name, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: transitions = %+v, want %+v", step.name, got, step.want)
+		}
+
+		after := storedTitleStatuses(t, accountID)
+		statuses := make(map[string]models.Status, len(after))
+		for title, row := range after {
+			statuses[title] = row.Status
+			if previous, ok := before[title]; ok && previous.Status == row.Status && !previous.LastStatusChange.Equal(row.LastStatusChange) {
+				t.Errorf("%s: %s kept status %s but its last change moved", step.name, title, row.Status)
+			}
+		}
+		if !reflect.DeepEqual(statuses, step.stored) {
+			t.Errorf("%s: stored %v, want %v", step.name, statuses, step.stored)
+		}
+		if row, ok := after["MW3"]; ok && row.Status == models.StatusTempban && !row.EndsAt.Equal(endsAt) {
+			t.Errorf("%s: MW3 temporary ban ends at %v, want %v", step.name, row.EndsAt, endsAt)
+		}
+	}
+}
+
+func TestHandleStatusChangeTitleNotifications(t *testing.T) {
+	banned := BanCheck{Status: models.StatusTempban, Enforcements: []models.TitleEnforcement{
+		enforcement("MW3", "TEMPORARY"),
+		enforcement("Warzone", "UNDER_REVIEW"),
+	}}
+
+	tests := []struct {
+		name       string
+		lastStatus models.Status
+		existing   []models.TitleEnforcement
+		check      BanCheck
+		wantTitle  bool // a per-title update was sent and logged
+		wantStatus bool // an overall status change was sent and logged
+	}{
+		{
+			name:       "title changed under the same overall status",
+			lastStatus: models.StatusTempban,
+			existing:   []models.TitleEnforcement{enforcement("MW3", "TEMPORARY")},
+			check:      banned,
+			wantTitle:  true,
+		},
+		{
+			name:       "nothing changed",
+			lastStatus: models.StatusTempban,
+			existing:   banned.Enforcements,
+			check:      banned,
+		},
+		{
+			name:       "first check of the account",
+			lastStatus: models.StatusUnknown,
+			check:      banned,
+		},
+		{
+			name:       "overall status changed",
+			lastStatus: models.StatusGood,
+			check:      banned,
+			wantStatus: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestDatabase(t)
+			useWebhookConfig(t, 5, false)
+			session := fakediscord.New()
+
+			account := models.Account{UserID: "user-1", ChannelID: "channel-1", Title: "main", LastStatus: tt.lastStatus}
+			if err := database.DB.Create(&account).Error; err != nil {
+				t.Fatalf("create account: %v", err)
+			}
+			if err := database.DB.Create(&models.Webhook{UserID: account.UserID, URL: "https://hooks.example.com/cod"}).Error; err != nil {
+				t.Fatalf("create webhook: %v", err)
+			}
+			if _, err := UpdateTitleStatuses(account.ID, BanCheck{Status: tt.lastStatus, Enforcements: tt.existing}); err != nil {
+				t.Fatalf("store existing titles: %v", err)
+			}
+			settings, err := GetUserSettings(account.UserID)
+			if err != nil {
+				t.Fatalf("GetUserSettings: %v", err)
+			}
+
+			HandleStatusChange(context.Background(), session, account, tt.check, settings)
+
+			if got := storedTitleStatuses(t, account.ID); got["Warzone"].Status != models.StatusShadowban {
+				t.Errorf("Warzone stored as %q, want %s", got["Warzone"].Status, models.StatusShadowban)
+			}
+
+			var logs []models.Ban
+			database.DB.Where("account_id = ? AND log_type <> ''", account.ID).Find(&logs)
+			var deliveries []models.WebhookDelivery
+			database.DB.Where("account_id = ?", account.ID).Find(&deliveries)
+			messages := session.MessagesTo(account.ChannelID)
+
+			switch {
+			case tt.wantTitle:
+				// MW3 was stored as temporary before Warzone appeared, so only Warzone moved.
+				wantChanges := "Warzone: " + StatusName(i18n.English, models.StatusGood) + " → " + StatusName(i18n.English, models.StatusShadowban)
+				if len(messages) != 1 || messages[0].Send.Embed == nil {
+					t.Fatalf("sent %d channel messages, want one per-title update", len(messages))
+				}
+				embed := messages[0].Send.Embed
+				if want := i18n.T(i18n.English, "notify.title_update.title", account.Title); embed.Title != want {
+					t.Errorf("embed title = %q, want %q", embed.Title, want)
+				}
+				if want := i18n.T(i18n.English, "notify.title_update.description", 1, StatusName(i18n.English, models.StatusTempban)); embed.Description != want {
+					t.Errorf("embed description = %q, want %q", embed.Description, want)
+				}
+				if len(embed.Fields) != 2 || embed.Fields[0].Value != wantChanges {
+					t.Fatalf("embed fields = %+v, want the change %q and the status by title", embed.Fields, wantChanges)
+				}
+				if status := embed.Fields[1].Value; !strings.Contains(status, "MW3: ") || !strings.Contains(status, "Warzone: ") {
+					t.Errorf("status by title %q does not list both titles", status)
+				}
+
+				if len(logs) != 1 || logs[0].LogType != "title_change" || logs[0].Message != wantChanges {
+					t.Errorf("logs = %+v, want one title_change log of %q", logs, wantChanges)
+				}
+				if len(deliveries) != 1 || deliveries[0].Event != models.WebhookTitleStatusChanged {
+					t.Fatalf("queued %+v, want one %s webhook event", deliveries, models.WebhookTitleStatusChanged)
+				}
+				var payload struct {
+					Data webhookTitleStatusChange `json:"data"`
+				}
+				if err := json.Unmarshal([]byte(deliveries[0].Payload), &payload); err != nil {
+					t.Fatalf("decode payload: %v", err)
+				}
+				want := webhookTitleStatusChange{
+					Status:      models.StatusTempban,
+					Transitions: []webhookTitleChange{{Title: "Warzone", PreviousStatus: models.StatusGood, Status: models.StatusShadowban}},
+				}
+				if !reflect.DeepEqual(payload.Data, want) {
+					t.Errorf("webhook data = %+v, want %+v", payload.Data, want)
+				}
+
+			case tt.wantStatus:
+				var mentioned int
+				for _, m := range messages {
+					if m.Send.Content == "<@user-1>" {
+						mentioned++
+					}
+				}
+				if mentioned != 1 {
+					t.Errorf("sent %d status change notifications, want 1", mentioned)
+				}
+				if len(logs) != 1 || logs[0].LogType != "status_change" {
+					t.Fatalf("logs = %+v, want one status_change log", logs)
+				}
+				wantChanges := FormatTitleTransitions(i18n.English, []TitleTransition{
+					{Title: "MW3", Previous: models.StatusGood, Current: models.StatusTempban},
+					{Title: "Warzone", Previous: models.StatusGood, Current: models.StatusShadowban},
+				})
+				if !strings.HasSuffix(logs[0].Message, "\n"+wantChanges) {
+					t.Errorf("status change log %q does not list the title changes %q", logs[0].Message, wantChanges)
+				}
+				if logs[0].AffectedGames != "MW3, Warzone" {
+					t.Errorf("affected games = %q, want %q", logs[0].AffectedGames, "MW3, Warzone")
+				}
+				if len(deliveries) != 1 || deliveries[0].Event != models.WebhookStatusChanged {
+					t.Errorf("queued %+v, want one %s webhook event", deliveries, models.WebhookStatusChanged)
+				}
+
+			default:
+				if len(messages) != 0 || len(logs) != 0 || len(deliveries) != 0 {
+					t.Errorf("sent %d messages, logged %d changes and queued %d webhook events, want none", len(messages), len(logs), len(deliveries))
+				}
+			}
+		})
+	}
+}
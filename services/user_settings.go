@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/bradselph/CODStatusBot/configuration"
 	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/i18n"
 	"github.com/bradselph/CODStatusBot/logger"
 	"github.com/bradselph/CODStatusBot/models"
 	"github.com/bwmarrin/discordgo"
@@ -25,12 +27,11 @@ func initDefaultSettings() {
 		CustomSettings:           false,
 	}
 
-	if cfg.CaptchaService.Capsolver.Enabled {
-		defaultSettings.PreferredCaptchaProvider = "capsolver"
-	} else if cfg.CaptchaService.EZCaptcha.Enabled {
-		defaultSettings.PreferredCaptchaProvider = "ezcaptcha"
-	} else if cfg.CaptchaService.TwoCaptcha.Enabled {
-		defaultSettings.PreferredCaptchaProvider = "2captcha"
+	for _, p := range CaptchaProviders() {
+		if p.Enabled(cfg) {
+			defaultSettings.PreferredCaptchaProvider = p.Name
+			break
+		}
 	}
 }
 
@@ -44,9 +45,7 @@ func GetUserSettings(userID string) (models.UserSettings, error) {
 	}
 
 	// Check if user has custom API key
-	hasCustomKey := settings.CapSolverAPIKey != "" ||
-		settings.EZCaptchaAPIKey != "" ||
-		settings.TwoCaptchaAPIKey != ""
+	hasCustomKey := settings.HasCustomCaptchaKey()
 
 	settings.EnsureMapsInitialized()
 
@@ -94,7 +93,10 @@ func GetUserSettings(userID string) (models.UserSettings, error) {
 	return settings, nil
 }
 
-func GetUserCaptchaKey(userID string) (string, float64, error) {
+// GetUserCaptchaKey returns the first key in the user's provider order that validates, along
+// with its balance. The user's own keys are tried if they have any, the bot's default keys
+// otherwise.
+func GetUserCaptchaKey(ctx context.Context, userID string) (string, float64, error) {
 	var settings models.UserSettings
 	result := database.DB.Where(models.UserSettings{UserID: userID}).First(&settings)
 	if result.Error != nil {
@@ -102,141 +104,29 @@ func GetUserCaptchaKey(userID string) (string, float64, error) {
 		return "", 0, result.Error
 	}
 
-	cfg := configuration.Get()
-
-	switch settings.PreferredCaptchaProvider {
-	case "capsolver":
-		if !cfg.CaptchaService.Capsolver.Enabled {
-			logger.Log.Warn("Capsolver service disabled, checking for alternative services")
-
-			if settings.EZCaptchaAPIKey != "" {
-				settings.PreferredCaptchaProvider = "ezcaptcha"
-				if err := database.DB.Save(&settings).Error; err != nil {
-					logger.Log.WithError(err).Error("Failed to update preferred provider")
-				}
-				return settings.EZCaptchaAPIKey, 0, nil
-			}
-			if settings.TwoCaptchaAPIKey != "" {
-				settings.PreferredCaptchaProvider = "2captcha"
-				if err := database.DB.Save(&settings).Error; err != nil {
-					logger.Log.WithError(err).Error("Failed to update preferred provider")
-				}
-				return settings.TwoCaptchaAPIKey, 0, nil
-			}
-			return "", 0, fmt.Errorf("capsolver service is currently disabled")
-		}
-
-		if settings.CapSolverAPIKey != "" {
-			isValid, balance, err := ValidateCaptchaKey(settings.CapSolverAPIKey, "capsolver")
-			if err != nil {
-				return "", 0, err
-			}
-			if !isValid {
-				return "", 0, fmt.Errorf("invalid capsolver API key")
-			}
-			return settings.CapSolverAPIKey, balance, nil
-		}
-
-		defaultKey := cfg.CaptchaService.Capsolver.ClientKey
-		isValid, balance, err := ValidateCaptchaKey(defaultKey, "capsolver")
-		if err != nil {
-			return "", 0, err
-		}
-		if !isValid {
-			return "", 0, fmt.Errorf("invalid default capsolver API key")
-		}
-		return defaultKey, balance, nil
-
-	case "ezcaptcha":
-		if !cfg.CaptchaService.EZCaptcha.Enabled {
-			if cfg.CaptchaService.Capsolver.Enabled {
-				settings.PreferredCaptchaProvider = "capsolver"
-				if err := database.DB.Save(&settings).Error; err != nil {
-					logger.Log.WithError(err).Error("Failed to update preferred provider")
-				}
-				return GetUserCaptchaKey(userID)
-			}
-			return "", 0, fmt.Errorf("ezcaptcha service is currently disabled")
-		}
-		if settings.EZCaptchaAPIKey != "" {
-			isValid, balance, err := ValidateCaptchaKey(settings.EZCaptchaAPIKey, "ezcaptcha")
-			if err != nil {
-				return "", 0, err
-			}
-			if !isValid {
-				return "", 0, fmt.Errorf("invalid ezcaptcha API key")
-			}
-			return settings.EZCaptchaAPIKey, balance, nil
-		}
-
-	case "2captcha":
-		if !cfg.CaptchaService.TwoCaptcha.Enabled {
-			if cfg.CaptchaService.Capsolver.Enabled {
-				settings.PreferredCaptchaProvider = "capsolver"
-				if err := database.DB.Save(&settings).Error; err != nil {
-					logger.Log.WithError(err).Error("Failed to update preferred provider")
-				}
-				return GetUserCaptchaKey(userID)
-			}
-			return "", 0, fmt.Errorf("2captcha service is currently disabled")
-		}
-		if settings.TwoCaptchaAPIKey != "" {
-			isValid, balance, err := ValidateCaptchaKey(settings.TwoCaptchaAPIKey, "2captcha")
-			if err != nil {
-				return "", 0, err
-			}
-			if !isValid {
-				return "", 0, fmt.Errorf("invalid 2captcha API key")
-			}
-			return settings.TwoCaptchaAPIKey, balance, nil
-		}
-	}
-
-	// If no custom key is set or no specific provider is selected, use default Capsolver
-	if cfg.CaptchaService.Capsolver.Enabled {
-		defaultKey := cfg.CaptchaService.Capsolver.ClientKey
-		isValid, balance, err := ValidateCaptchaKey(defaultKey, "capsolver")
-		if err != nil {
-			return "", 0, err
-		}
-		if !isValid {
-			return "", 0, fmt.Errorf("invalid default capsolver API key")
-		}
-		return defaultKey, balance, nil
+	candidates := captchaCandidates(settings)
+	if len(candidates) == 0 {
+		return "", 0, fmt.Errorf("no valid API key found for provider %s", settings.PreferredCaptchaProvider)
 	}
 
-	// If Capsolver is disabled, try other enabled services in order of preference
-	if cfg.CaptchaService.EZCaptcha.Enabled {
-		settings.PreferredCaptchaProvider = "ezcaptcha"
-		if err := database.DB.Save(&settings).Error; err != nil {
-			logger.Log.WithError(err).Error("Failed to update preferred provider")
-		}
-		defaultKey := cfg.CaptchaService.EZCaptcha.ClientKey
-		isValid, balance, err := ValidateCaptchaKey(defaultKey, "ezcaptcha")
+	var lastErr error
+	for _, c := range candidates {
+		isValid, balance, err := c.provider.ValidateKey(ctx, c.apiKey)
 		if err != nil {
-			return "", 0, err
+			lastErr = err
+			continue
 		}
 		if !isValid {
-			return "", 0, fmt.Errorf("invalid default ezcaptcha API key")
+			if c.defaultKey {
+				lastErr = fmt.Errorf("invalid default %s API key", c.provider.Name)
+			} else {
+				lastErr = fmt.Errorf("invalid %s API key", c.provider.Name)
+			}
+			continue
 		}
-		return defaultKey, balance, nil
-	}
-
-	return "", 0, fmt.Errorf("no valid API key found for provider %s", settings.PreferredCaptchaProvider)
-}
-
-func GetCaptchaSolver(userID string) (CaptchaSolver, error) {
-	settings, err := GetUserSettings(userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user settings: %w", err)
+		return c.apiKey, balance, nil
 	}
-
-	apiKey, _, err := GetUserCaptchaKey(userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user captcha key: %w", err)
-	}
-
-	return NewCaptchaSolver(apiKey, settings.PreferredCaptchaProvider)
+	return "", 0, lastErr
 }
 
 func GetDefaultSettings() (models.UserSettings, error) {
@@ -255,9 +145,7 @@ func RemoveCaptchaKey(userID string) error {
 	}
 
 	// Check if user had custom keys before removal
-	hadCustomKey := settings.CapSolverAPIKey != "" ||
-		settings.EZCaptchaAPIKey != "" ||
-		settings.TwoCaptchaAPIKey != ""
+	hadCustomKey := settings.HasCustomCaptchaKey()
 
 	// Get configuration and count accounts only once
 	cfg := configuration.Get()
@@ -269,9 +157,12 @@ func RemoveCaptchaKey(userID string) error {
 	settings.CapSolverAPIKey = ""
 	settings.EZCaptchaAPIKey = ""
 	settings.TwoCaptchaAPIKey = ""
+	settings.AntiCaptchaAPIKey = ""
+	settings.CapMonsterAPIKey = ""
 
 	// Reset to default settings
 	settings.PreferredCaptchaProvider = defaultSettings.PreferredCaptchaProvider
+	settings.CaptchaProviderOrder = nil
 	settings.CustomSettings = false
 	settings.CheckInterval = defaultSettings.CheckInterval
 	settings.NotificationInterval = defaultSettings.NotificationInterval
@@ -300,25 +191,24 @@ func RemoveCaptchaKey(userID string) error {
 		}
 
 		// Create warning embed
+		lang := UserLanguage(settings)
 		embed := &discordgo.MessageEmbed{
-			Title: "Account Limit Warning",
-			Description: fmt.Sprintf("You currently have %d accounts monitored, which exceeds the default limit of %d accounts.\n"+
-				"To continue monitoring all accounts, please add your own Capsolver API key using /setcaptchaservice.",
-				accountCount, defaultMax),
-			Color: 0xFFA500,
+			Title:       i18n.T(lang, "account_limit.title"),
+			Description: i18n.T(lang, "account_limit.description", accountCount, defaultMax),
+			Color:       0xFFA500,
 			Fields: []*discordgo.MessageEmbedField{
 				{
-					Name:   "Action Required",
-					Value:  fmt.Sprintf("Get your Capsolver API key at https://dashboard.capsolver.com/passport/register?inviteCode=6YjROhACQnvP"),
+					Name:   i18n.T(lang, "notify.field.action_required"),
+					Value:  i18n.T(lang, "account_limit.action", "https://dashboard.capsolver.com/passport/register?inviteCode=6YjROhACQnvP"),
 					Inline: false,
 				},
 				{
-					Name:   "Current Accounts",
+					Name:   i18n.T(lang, "account_limit.current"),
 					Value:  fmt.Sprintf("%d", accountCount),
 					Inline: true,
 				},
 				{
-					Name:   "Default Limit",
+					Name:   i18n.T(lang, "account_limit.default"),
 					Value:  fmt.Sprintf("%d", defaultMax),
 					Inline: true,
 				},
@@ -343,3 +233,53 @@ func RemoveCaptchaKey(userID string) error {
 	logger.Log.Infof("Reset settings for user %s (had custom key: %v)", userID, hadCustomKey)
 	return nil
 }
+
+// UserLanguage returns the language notifications are sent to a user in: the one they picked with
+// /setlanguage, or English.
+func UserLanguage(settings models.UserSettings) i18n.Lang {
+	if lang, ok := i18n.Parse(settings.Language); ok {
+		return lang
+	}
+	return i18n.English
+}
+
+// InteractionLanguage returns the language to answer an interaction in: the one the user picked
+// with /setlanguage, or the language of their Discord client.
+func InteractionLanguage(i *discordgo.InteractionCreate) i18n.Lang {
+	if userID, err := GetUserID(i); err == nil {
+		if lang, ok := storedLanguage(userID); ok {
+			return lang
+		}
+	}
+	return i18n.FromLocale(i.Locale)
+}
+
+// LanguageForUser returns the language to notify a user in when their settings are not at hand.
+func LanguageForUser(userID string) i18n.Lang {
+	if lang, ok := storedLanguage(userID); ok {
+		return lang
+	}
+	return i18n.English
+}
+
+func storedLanguage(userID string) (i18n.Lang, bool) {
+	var settings models.UserSettings
+	if err := database.DB.Select("language").Where("user_id = ?", userID).Take(&settings).Error; err != nil {
+		return "", false
+	}
+	return i18n.Parse(settings.Language)
+}
+
+// SetUserLanguage stores the user's language. An empty code goes back to following their Discord
+// client.
+func SetUserLanguage(userID, code string) error {
+	if code != "" {
+		if _, ok := i18n.Parse(code); !ok {
+			return fmt.Errorf("unsupported language %q", code)
+		}
+	}
+	if _, err := GetUserSettings(userID); err != nil {
+		return err
+	}
+	return database.DB.Model(&models.UserSettings{}).Where("user_id = ?", userID).Update("language", code).Error
+}
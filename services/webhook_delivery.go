@@ -0,0 +1,329 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/metrics"
+	"github.com/bradselph/CODStatusBot/models"
+	"gorm.io/gorm"
+)
+
+const (
+	webhookPollInterval = 15 * time.Second
+	webhookBatchSize    = 50
+	webhookWorkers      = 4
+
+	// Failed deliveries wait webhookBaseBackoff, doubling after every attempt up to webhookMaxBackoff.
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+
+	webhookDeliveredRetention = 7 * 24 * time.Hour
+	webhookDeadRetention      = 30 * 24 * time.Hour
+	webhookCleanupEvery       = 24 * time.Hour
+
+	// maxWebhookErrorBody is how much of a failed response's body is kept for the dead-letter list.
+	maxWebhookErrorBody = 256
+)
+
+// Every delivery carries these headers. The signature is "t=<unix seconds>,v1=<hex HMAC-SHA256>"
+// computed with the user's webhook secret over "<t>.<body>", so receivers can reject both forged
+// and replayed requests.
+const (
+	WebhookSignatureHeader = "X-CODStatusBot-Signature"
+	WebhookEventHeader     = "X-CODStatusBot-Event"
+	WebhookDeliveryHeader  = "X-CODStatusBot-Delivery"
+)
+
+var (
+	errPrivateWebhookAddress = errors.New("webhook resolves to a private or loopback address")
+
+	// sharedAddressSpace is the carrier-grade NAT range, which net.IP.IsPrivate does not cover.
+	sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+	webhookWake = make(chan struct{}, 1)
+
+	webhookClientOnce sync.Once
+	webhookClient     *http.Client
+)
+
+// wakeWebhookDispatcher makes the dispatcher look for due deliveries without waiting for its
+// next poll.
+func wakeWebhookDispatcher() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// RunWebhookDispatcher delivers queued webhook events until ctx is cancelled. Deliveries are
+// persisted before they are attempted, so events queued before a restart are still sent; a
+// delivery cut short by shutdown is attempted again, and receivers should deduplicate on the
+// delivery header.
+func RunWebhookDispatcher(ctx context.Context) {
+	if !configuration.Get().Webhooks.Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		deliverDueWebhooks(ctx)
+
+		if time.Since(lastCleanup) >= webhookCleanupEvery {
+			purgeWebhookDeliveries()
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-webhookWake:
+		}
+	}
+}
+
+func deliverDueWebhooks(ctx context.Context) {
+	for ctx.Err() == nil {
+		var deliveries []models.WebhookDelivery
+		if err := database.DB.
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(webhookBatchSize).
+			Find(&deliveries).Error; err != nil {
+			logger.Log.WithError(err).Error("Failed to load due webhook deliveries")
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+
+		sem := make(chan struct{}, webhookWorkers)
+		var wg sync.WaitGroup
+		var progressed atomic.Int64
+		for _, delivery := range deliveries {
+			if !acquire(ctx, sem) {
+				break
+			}
+			wg.Add(1)
+			go func(delivery models.WebhookDelivery) {
+				defer wg.Done()
+				defer release(sem)
+				if deliverWebhook(ctx, delivery) {
+					progressed.Add(1)
+				}
+			}(delivery)
+		}
+		wg.Wait()
+
+		// A pass that moved nothing out of the due set would load the same batch again.
+		if len(deliveries) < webhookBatchSize || progressed.Load() == 0 {
+			return
+		}
+	}
+}
+
+// deliverWebhook makes one attempt at delivery and reports whether its row was updated, so
+// that it is no longer due.
+func deliverWebhook(ctx context.Context, delivery models.WebhookDelivery) bool {
+	var webhook models.Webhook
+	err := database.DB.First(&webhook, delivery.WebhookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return finishWebhookDelivery(delivery, models.DeliveryDead, 0, errors.New("webhook was removed"))
+	}
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to load webhook %d", delivery.WebhookID)
+		delivery.Attempts++
+		return failWebhookDelivery(delivery, 0, fmt.Errorf("failed to load webhook: %w", err))
+	}
+
+	var settings models.UserSettings
+	if err := database.DB.Select("webhook_secret").Where("user_id = ?", delivery.UserID).First(&settings).Error; err != nil {
+		logger.Log.WithError(err).Errorf("Failed to load webhook secret for user %s", delivery.UserID)
+		delivery.Attempts++
+		return failWebhookDelivery(delivery, 0, fmt.Errorf("failed to load webhook secret: %w", err))
+	}
+
+	delivery.Attempts++
+	statusCode, err := postWebhook(ctx, webhook.URL, settings.WebhookSecret, delivery)
+	switch {
+	case err == nil:
+		return finishWebhookDelivery(delivery, models.DeliveryDelivered, statusCode, nil)
+	case ctx.Err() != nil:
+		// Shutting down; the delivery stays pending with its attempt uncounted.
+		return false
+	default:
+		return failWebhookDelivery(delivery, statusCode, err)
+	}
+}
+
+// failWebhookDelivery schedules another attempt after a backoff, or gives up once delivery has
+// used all of its attempts.
+func failWebhookDelivery(delivery models.WebhookDelivery, statusCode int, err error) bool {
+	if delivery.Attempts >= maxWebhookAttempts() {
+		logger.Log.WithError(err).Warnf("Webhook delivery %d (%s) for user %s failed after %d attempts",
+			delivery.ID, delivery.Event, delivery.UserID, delivery.Attempts)
+		return finishWebhookDelivery(delivery, models.DeliveryDead, statusCode, err)
+	}
+	return finishWebhookDelivery(delivery, models.DeliveryPending, statusCode, err)
+}
+
+// postWebhook sends one signed attempt of delivery to webhookURL, returning the response status.
+func postWebhook(ctx context.Context, webhookURL, secret string, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CODStatusBot-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, string(delivery.Event))
+	req.Header.Set(WebhookDeliveryHeader, delivery.EventID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(secret, time.Now(), []byte(delivery.Payload)))
+
+	resp, err := webhookHTTPClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the signature header value for body sent at t.
+func SignWebhookPayload(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// finishWebhookDelivery records the outcome of an attempt and reports whether the row was updated.
+func finishWebhookDelivery(delivery models.WebhookDelivery, status models.DeliveryStatus, statusCode int, deliveryErr error) bool {
+	updates := map[string]interface{}{
+		"status":           status,
+		"attempts":         delivery.Attempts,
+		"last_status_code": statusCode,
+		"last_error":       "",
+	}
+	if deliveryErr != nil {
+		updates["last_error"] = logger.Redact(deliveryErr.Error())
+	}
+
+	outcome := metrics.OutcomeDead
+	switch status {
+	case models.DeliveryDelivered:
+		outcome = metrics.OutcomeDelivered
+		updates["delivered_at"] = time.Now()
+	case models.DeliveryPending:
+		outcome = metrics.OutcomeRetry
+		updates["next_attempt_at"] = time.Now().Add(webhookBackoff(delivery.Attempts))
+	}
+	metrics.WebhookDeliveries.WithLabelValues(string(delivery.Event), outcome).Inc()
+
+	if err := database.DB.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+		logger.Log.WithError(err).Errorf("Failed to update webhook delivery %d", delivery.ID)
+		return false
+	}
+	return true
+}
+
+// webhookBackoff is how long to wait after the given number of failed attempts.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
+
+func maxWebhookAttempts() int {
+	attempts := configuration.Get().Webhooks.MaxAttempts
+	if attempts < 1 {
+		return 1
+	}
+	return attempts
+}
+
+func purgeWebhookDeliveries() {
+	now := time.Now()
+	result := database.DB.Unscoped().
+		Where("(status = ? AND updated_at < ?) OR (status = ? AND updated_at < ?)",
+			models.DeliveryDelivered, now.Add(-webhookDeliveredRetention),
+			models.DeliveryDead, now.Add(-webhookDeadRetention)).
+		Delete(&models.WebhookDelivery{})
+	if result.Error != nil {
+		logger.Log.WithError(result.Error).Error("Failed to purge old webhook deliveries")
+	} else if result.RowsAffected > 0 {
+		logger.Log.Infof("Purged %d old webhook deliveries", result.RowsAffected)
+	}
+}
+
+// webhookHTTPClient returns the client deliveries are sent with. It ignores proxy settings and
+// does not follow redirects, and unless WEBHOOK_ALLOW_PRIVATE_NETWORKS is set it refuses to
+// connect to private addresses, so a webhook cannot be used to reach the bot's own network.
+func webhookHTTPClient() *http.Client {
+	webhookClientOnce.Do(func() {
+		cfg := configuration.Get()
+
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		if !cfg.Webhooks.AllowPrivateNetworks {
+			dialer.Control = func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return errPrivateWebhookAddress
+				}
+				return nil
+			}
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = dialer.DialContext
+
+		webhookClient = &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Webhooks.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	})
+	return webhookClient
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsMulticast() &&
+		!sharedAddressSpace.Contains(ip)
+}
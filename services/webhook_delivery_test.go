@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/models"
+)
+
+// queueUndeliverableWebhook stores a webhook whose URL cannot be decrypted and a due delivery for it.
+func queueUndeliverableWebhook(t *testing.T) models.WebhookDelivery {
+	t.Helper()
+	if err := database.DB.Exec(
+		"INSERT INTO webhooks (created_at, updated_at, user_id, account_id, url) VALUES (?, ?, ?, 0, ?)",
+		time.Now(), time.Now(), "user-1", "enc:v1:00000000:bm90:YSBrZXk=",
+	).Error; err != nil {
+		t.Fatalf("insert webhook: %v", err)
+	}
+	var webhook models.Webhook
+	if err := database.DB.Unscoped().Select("id").Last(&webhook).Error; err != nil {
+		t.Fatalf("read webhook id: %v", err)
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		UserID:        "user-1",
+		EventID:       "event-1",
+		Event:         "status_change",
+		Payload:       `{}`,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now().Add(-time.Minute),
+	}
+	if err := database.DB.Create(&delivery).Error; err != nil {
+		t.Fatalf("create delivery: %v", err)
+	}
+	return delivery
+}
+
+func TestDeliverDueWebhooksBacksOffWhenWebhookCannotLoad(t *testing.T) {
+	useTestDatabase(t)
+	cfg := configuration.Get()
+	saved := cfg.Webhooks
+	t.Cleanup(func() { cfg.Webhooks = saved })
+	cfg.Webhooks.MaxAttempts = 3
+
+	delivery := queueUndeliverableWebhook(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	deliverDueWebhooks(ctx)
+	if ctx.Err() != nil {
+		t.Fatal("deliverDueWebhooks kept looping over a delivery it could not send")
+	}
+
+	var got models.WebhookDelivery
+	if err := database.DB.First(&got, delivery.ID).Error; err != nil {
+		t.Fatalf("reload delivery: %v", err)
+	}
+	if got.Status != models.DeliveryPending || got.Attempts != 1 {
+		t.Fatalf("delivery is %s after %d attempts, want pending after 1", got.Status, got.Attempts)
+	}
+	if !got.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("next attempt at %v is not in the future", got.NextAttemptAt)
+	}
+	if got.LastError == "" {
+		t.Fatal("load failure was not recorded")
+	}
+}
+
+func TestDeliverDueWebhooksGivesUpWhenWebhookCannotLoad(t *testing.T) {
+	useTestDatabase(t)
+	cfg := configuration.Get()
+	saved := cfg.Webhooks
+	t.Cleanup(func() { cfg.Webhooks = saved })
+	cfg.Webhooks.MaxAttempts = 1
+
+	delivery := queueUndeliverableWebhook(t)
+	deliverDueWebhooks(context.Background())
+
+	var got models.WebhookDelivery
+	if err := database.DB.First(&got, delivery.ID).Error; err != nil {
+		t.Fatalf("reload delivery: %v", err)
+	}
+	if got.Status != models.DeliveryDead {
+		t.Fatalf("delivery is %s, want dead", got.Status)
+	}
+}
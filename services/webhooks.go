@@ -0,0 +1,387 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/database"
+	"github.com/bradselph/CODStatusBot/logger"
+	"github.com/bradselph/CODStatusBot/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxWebhookURLLength keeps webhook URLs within what Discord can show back to the user.
+const maxWebhookURLLength = 512
+
+var (
+	// ErrWebhooksDisabled is returned when WEBHOOKS_ENABLED is off.
+	ErrWebhooksDisabled = errors.New("webhooks are not enabled")
+	// ErrWebhookLimit is returned by AddWebhook once the user has WEBHOOK_MAX_PER_USER webhooks.
+	ErrWebhookLimit = errors.New("webhook limit reached")
+	// ErrWebhookNotFound is returned for webhooks that do not exist or belong to another user.
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrDeliveryNotFound is returned for deliveries that do not exist, belong to another user or
+	// are not in the dead-letter list.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// InvalidWebhookURLError explains why a URL cannot be registered as a webhook.
+type InvalidWebhookURLError struct {
+	Reason string
+}
+
+func (e *InvalidWebhookURLError) Error() string {
+	return "invalid webhook URL: " + e.Reason
+}
+
+// WebhookPayload is the JSON body of every webhook delivery.
+type WebhookPayload struct {
+	ID        string              `json:"id"`
+	Event     models.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"created_at"`
+	UserID    string              `json:"user_id"`
+	Account   *WebhookAccount     `json:"account,omitempty"`
+	Data      interface{}         `json:"data,omitempty"`
+}
+
+// WebhookAccount identifies the account an event is about.
+type WebhookAccount struct {
+	ID     uint          `json:"id"`
+	Title  string        `json:"title"`
+	Status models.Status `json:"status"`
+}
+
+type webhookStatusChange struct {
+	PreviousStatus models.Status `json:"previous_status"`
+	Status         models.Status `json:"status"`
+	AffectedGames  string        `json:"affected_games,omitempty"`
+	CanAppeal      bool          `json:"can_appeal"`
+	TempBanEndsAt  *time.Time    `json:"temp_ban_ends_at,omitempty"`
+}
+
+type webhookTitleChange struct {
+	Title          string        `json:"title"`
+	PreviousStatus models.Status `json:"previous_status"`
+	Status         models.Status `json:"status"`
+}
+
+type webhookTitleStatusChange struct {
+	Status      models.Status        `json:"status"`
+	Transitions []webhookTitleChange `json:"transitions"`
+}
+
+type webhookCookieExpiring struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type webhookChecksDisabled struct {
+	Reason string `json:"reason"`
+}
+
+// AddWebhook registers rawURL to receive events for the account with accountID, or for all of
+// the user's accounts when accountID is 0.
+func AddWebhook(userID string, accountID uint, rawURL string) (models.Webhook, error) {
+	cfg := configuration.Get()
+	if !cfg.Webhooks.Enabled {
+		return models.Webhook{}, ErrWebhooksDisabled
+	}
+
+	webhookURL, err := ValidateWebhookURL(rawURL)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if accountID != 0 {
+		if _, err := GetUserAccount(userID, accountID); err != nil {
+			return models.Webhook{}, err
+		}
+	}
+
+	webhook := models.Webhook{UserID: userID, AccountID: accountID, URL: webhookURL}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var settings models.UserSettings
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(models.UserSettings{UserID: userID}).FirstOrCreate(&settings).Error; err != nil {
+			return fmt.Errorf("failed to lock user settings: %w", err)
+		}
+
+		var count int64
+		if err := tx.Model(&models.Webhook{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count webhooks: %w", err)
+		}
+		if count >= int64(cfg.Webhooks.MaxPerUser) {
+			return ErrWebhookLimit
+		}
+
+		if settings.WebhookSecret == "" {
+			if err := saveWebhookSecret(tx, &settings); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(&webhook).Error; err != nil {
+			return fmt.Errorf("failed to save webhook: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// ValidateWebhookURL checks that rawURL is an absolute HTTPS URL without credentials. Unless
+// WEBHOOK_ALLOW_PRIVATE_NETWORKS is set, literal private and loopback addresses are refused here;
+// host names are checked again when each delivery connects.
+func ValidateWebhookURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if len(rawURL) > maxWebhookURLLength {
+		return "", &InvalidWebhookURLError{Reason: fmt.Sprintf("it must be at most %d characters", maxWebhookURLLength)}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", &InvalidWebhookURLError{Reason: "it is not an absolute URL"}
+	}
+	if u.Scheme != "https" {
+		return "", &InvalidWebhookURLError{Reason: "it must use https"}
+	}
+	if u.User != nil {
+		return "", &InvalidWebhookURLError{Reason: "it must not contain a username or password"}
+	}
+
+	if !configuration.Get().Webhooks.AllowPrivateNetworks {
+		host := u.Hostname()
+		if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(host, "localhost") {
+			return "", &InvalidWebhookURLError{Reason: "it points to a private or loopback address"}
+		}
+	}
+
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// ListWebhooks returns the user's webhooks, oldest first.
+func ListWebhooks(userID string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := database.DB.Where("user_id = ?", userID).Order("id").Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetUserWebhook loads the webhook if it belongs to userID.
+func GetUserWebhook(userID string, webhookID uint) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := database.DB.Where("id = ? AND user_id = ?", webhookID, userID).First(&webhook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return webhook, ErrWebhookNotFound
+	}
+	if err != nil {
+		return webhook, fmt.Errorf("failed to fetch webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// RemoveWebhook deletes the user's webhook and drops its undelivered events.
+func RemoveWebhook(userID string, webhookID uint) error {
+	webhook, err := GetUserWebhook(userID, webhookID)
+	if err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+		if err := tx.Delete(&webhook).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		return nil
+	})
+}
+
+// WebhookSecret returns the key the user's deliveries are signed with, creating one on first use
+// or replacing it when rotate is set.
+func WebhookSecret(userID string, rotate bool) (string, error) {
+	settings, err := GetUserSettings(userID)
+	if err != nil {
+		return "", err
+	}
+	if settings.WebhookSecret != "" && !rotate {
+		return settings.WebhookSecret, nil
+	}
+
+	if err := saveWebhookSecret(database.DB, &settings); err != nil {
+		return "", err
+	}
+	return settings.WebhookSecret, nil
+}
+
+// saveWebhookSecret gives settings a new signing key and stores it through db.
+func saveWebhookSecret(db *gorm.DB, settings *models.UserSettings) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	settings.WebhookSecret = "whsec_" + hex.EncodeToString(secret)
+
+	if err := db.Model(settings).Select("WebhookSecret").Updates(settings).Error; err != nil {
+		return fmt.Errorf("failed to save webhook secret: %w", err)
+	}
+	return nil
+}
+
+// SendTestWebhook queues a ping event for one of the user's webhooks.
+func SendTestWebhook(userID string, webhookID uint) error {
+	webhook, err := GetUserWebhook(userID, webhookID)
+	if err != nil {
+		return err
+	}
+	return queueWebhookEvent([]models.Webhook{webhook}, userID, nil, models.WebhookPing, nil)
+}
+
+// ListDeadWebhookDeliveries returns the user's deliveries that ran out of attempts, newest first.
+func ListDeadWebhookDeliveries(userID string, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := database.DB.Where("user_id = ? AND status = ?", userID, models.DeliveryDead).
+		Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list dead webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RedeliverWebhook moves a dead delivery back into the queue with a fresh set of attempts.
+func RedeliverWebhook(userID string, deliveryID uint) error {
+	result := database.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND user_id = ? AND status = ?", deliveryID, userID, models.DeliveryDead).
+		Updates(map[string]interface{}{
+			"status":          models.DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to requeue webhook delivery: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeliveryNotFound
+	}
+	wakeWebhookDispatcher()
+	return nil
+}
+
+// notifyWebhooksStatusChange reports an overall status change detected by HandleStatusChange.
+func notifyWebhooksStatusChange(account models.Account, previous models.Status, ban models.Ban) {
+	data := webhookStatusChange{
+		PreviousStatus: previous,
+		Status:         account.LastStatus,
+		AffectedGames:  ban.AffectedGames,
+		CanAppeal:      ban.CanAppeal,
+	}
+	if !ban.TempBanEndsAt.IsZero() {
+		endsAt := ban.TempBanEndsAt.UTC()
+		data.TempBanEndsAt = &endsAt
+	}
+	emitWebhookEvent(account, models.WebhookStatusChanged, data)
+}
+
+// notifyWebhooksTitleChange reports per-title changes that left the overall status unchanged.
+func notifyWebhooksTitleChange(account models.Account, check BanCheck, transitions []TitleTransition) {
+	data := webhookTitleStatusChange{Status: check.Status}
+	for _, t := range transitions {
+		data.Transitions = append(data.Transitions, webhookTitleChange{Title: t.Title, PreviousStatus: t.Previous, Status: t.Current})
+	}
+	emitWebhookEvent(account, models.WebhookTitleStatusChanged, data)
+}
+
+// notifyWebhooksCookieExpiring reports that the account's SSO cookie is about to expire.
+func notifyWebhooksCookieExpiring(account models.Account) {
+	data := webhookCookieExpiring{ExpiresAt: time.Unix(account.SSOCookieExpiration, 0).UTC()}
+	emitWebhookEvent(account, models.WebhookCookieExpiring, data)
+}
+
+// notifyWebhooksChecksDisabled reports that checks for the account were stopped.
+func notifyWebhooksChecksDisabled(account models.Account, reason string) {
+	emitWebhookEvent(account, models.WebhookChecksDisabled, webhookChecksDisabled{Reason: reason})
+}
+
+// NotifyWebhooksCookieExpired reports that Activision stopped accepting the account's SSO cookie.
+func NotifyWebhooksCookieExpired(account models.Account) {
+	emitWebhookEvent(account, models.WebhookCookieExpired, nil)
+}
+
+// emitWebhookEvent queues event for every webhook that covers the account. Failures are logged
+// rather than returned so a webhook problem never holds up the Discord notification.
+func emitWebhookEvent(account models.Account, event models.WebhookEvent, data interface{}) {
+	if !configuration.Get().Webhooks.Enabled {
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := database.DB.Where("user_id = ? AND (account_id = 0 OR account_id = ?)", account.UserID, account.ID).
+		Find(&webhooks).Error; err != nil {
+		logger.Log.WithError(err).Errorf("Failed to load webhooks for user %s", account.UserID)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	subject := &WebhookAccount{ID: account.ID, Title: account.Title, Status: account.LastStatus}
+	if err := queueWebhookEvent(webhooks, account.UserID, subject, event, data); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to queue %s webhook event for account %s", event, account.Title)
+	}
+}
+
+func queueWebhookEvent(webhooks []models.Webhook, userID string, account *WebhookAccount, event models.WebhookEvent, data interface{}) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to generate event ID: %w", err)
+	}
+
+	payload := WebhookPayload{
+		ID:        hex.EncodeToString(id),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		UserID:    userID,
+		Account:   account,
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	var accountID uint
+	if account != nil {
+		accountID = account.ID
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			UserID:        userID,
+			AccountID:     accountID,
+			EventID:       payload.ID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: time.Now(),
+		})
+	}
+	if err := database.DB.Create(&deliveries).Error; err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+
+	wakeWebhookDispatcher()
+	return nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+	"github.com/bradselph/CODStatusBot/models"
+)
+
+// useWebhookConfig enables webhooks with the given limit and network policy, and builds a fresh
+// delivery client for the rest of the test.
+func useWebhookConfig(t *testing.T, maxPerUser int, allowPrivate bool) {
+	t.Helper()
+	cfg := configuration.Get()
+	saved := cfg.Webhooks
+	cfg.Webhooks.Enabled = true
+	cfg.Webhooks.Timeout = 5 * time.Second
+	cfg.Webhooks.MaxPerUser = maxPerUser
+	cfg.Webhooks.AllowPrivateNetworks = allowPrivate
+	webhookClientOnce, webhookClient = sync.Once{}, nil
+	t.Cleanup(func() {
+		cfg.Webhooks = saved
+		webhookClientOnce, webhookClient = sync.Once{}, nil
+	})
+}
+
+func TestAddWebhookLimitUnderConcurrency(t *testing.T) {
+	useTestDatabase(t)
+	const limit = 3
+	useWebhookConfig(t, limit, false)
+
+	const attempts = 3 * limit
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+		limited int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := AddWebhook("user-1", 0, "https://example.com/hook")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, ErrWebhookLimit):
+				limited++
+			default:
+				t.Errorf("AddWebhook: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created != limit || limited != attempts-limit {
+		t.Fatalf("created %d webhooks and refused %d, want %d and %d", created, limited, limit, attempts-limit)
+	}
+
+	webhooks, err := ListWebhooks("user-1")
+	if err != nil {
+		t.Fatalf("ListWebhooks: %v", err)
+	}
+	if len(webhooks) != limit {
+		t.Fatalf("user holds %d webhooks, want %d", len(webhooks), limit)
+	}
+
+	secret, err := WebhookSecret("user-1", false)
+	if err != nil {
+		t.Fatalf("WebhookSecret: %v", err)
+	}
+	if !strings.HasPrefix(secret, "whsec_") {
+		t.Errorf("webhook secret %q was not created with the first webhook", secret)
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"id":"evt_1","event":"ping"}`)
+	got := SignWebhookPayload("whsec_test", time.Unix(1700000000, 0), body)
+	want := "t=1700000000,v1=21be02980bdc406710677f86bd02cd3b81c0453943d2e471b9d2cab22e7d28f7"
+	if got != want {
+		t.Fatalf("SignWebhookPayload = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	delivery := models.WebhookDelivery{EventID: "event-1", Event: models.WebhookPing, Payload: `{}`}
+
+	t.Run("refused", func(t *testing.T) {
+		useWebhookConfig(t, 1, false)
+		for _, webhookURL := range []string{
+			server.URL,
+			"http://[::1]:1/",
+			"http://10.0.0.1:1/",
+			"http://192.168.1.1:1/",
+			"http://169.254.169.254:1/",
+			"http://100.64.0.1:1/",
+			"http://0.0.0.0:1/",
+		} {
+			_, err := postWebhook(context.Background(), webhookURL, "whsec_test", delivery)
+			if !errors.Is(err, errPrivateWebhookAddress) {
+				t.Errorf("POST %s: err = %v, want %v", webhookURL, err, errPrivateWebhookAddress)
+			}
+		}
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		useWebhookConfig(t, 1, true)
+		status, err := postWebhook(context.Background(), server.URL, "whsec_test", delivery)
+		if err != nil || status != http.StatusNoContent {
+			t.Fatalf("POST %s = %d, %v; want %d", server.URL, status, err, http.StatusNoContent)
+		}
+	})
+}
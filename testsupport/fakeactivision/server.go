@@ -0,0 +1,314 @@
+// Package fakeactivision serves local stand-ins for the Activision profile, VIP and ban appeal
+// endpoints so the account check pipeline can run without touching the real API.
+//
+// A typical integration test starts a server, points the configuration at it and scripts what
+// each SSO cookie should see:
+//
+//	srv := fakeactivision.New()
+//	defer srv.Close()
+//	srv.Apply(configuration.Get())
+//
+//	cookie := fakeactivision.Cookie(time.Now().Add(14 * 24 * time.Hour))
+//	srv.Script(cookie, fakeactivision.Good, fakeactivision.TempBan)
+package fakeactivision
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+)
+
+const (
+	AppealPath  = "/api/bans/v2/appeal"
+	ProfilePath = "/api/profile"
+	VIPPath     = "/api/vip/"
+)
+
+type Scenario string
+
+const (
+	Good          Scenario = "good"           // Profile works and the appeal API reports no bans.
+	Permaban      Scenario = "permaban"       // A permanent ban in every configured title.
+	Shadowban     Scenario = "shadowban"      // Every configured title under review.
+	TempBan       Scenario = "tempban"        // A temporary ban ending at TempBanEndsAt.
+	InvalidCookie Scenario = "invalid_cookie" // The profile endpoint rejects the cookie.
+	ServerError   Scenario = "server_error"   // Every endpoint answers 500.
+	MalformedJSON Scenario = "malformed_json" // Every endpoint answers 200 with a broken body.
+)
+
+// Request is one call the server received.
+type Request struct {
+	Path      string
+	Cookie    string
+	Captcha   string
+	Scenario  Scenario
+	Timestamp time.Time
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	fallback  Scenario
+	scripts   map[string][]Scenario
+	vip       map[string]bool
+	titles    []string
+	endsAt    time.Time
+	canAppeal bool
+	requests  []Request
+
+	singleUseCaptcha bool
+	seenCaptcha      map[string]bool
+}
+
+// New starts a server that answers Good for any cookie until told otherwise.
+func New() *Server {
+	s := &Server{
+		fallback:  Good,
+		scripts:   make(map[string][]Scenario),
+		vip:       make(map[string]bool),
+		titles:    []string{"Call of Duty: Modern Warfare III"},
+		endsAt:    time.Now().Add(72 * time.Hour),
+		canAppeal: true,
+
+		seenCaptcha: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(AppealPath, s.handleAppeal)
+	mux.HandleFunc(ProfilePath, s.handleProfile)
+	mux.HandleFunc(VIPPath, s.handleVIP)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Apply points cfg's Activision endpoints at the server.
+func (s *Server) Apply(cfg *configuration.Config) {
+	cfg.API.CheckEndpoint = s.URL + AppealPath
+	cfg.API.ProfileEndpoint = s.URL + ProfilePath
+	cfg.API.CheckVIPEndpoint = s.URL + VIPPath
+}
+
+// SetDefault changes the scenario used for cookies without a script.
+func (s *Server) SetDefault(scenario Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = scenario
+}
+
+// Script queues scenarios for a cookie. Each ban appeal call consumes one; the last one sticks,
+// so Script(cookie, Good, TempBan) is good on the first check and banned from then on. Profile
+// and VIP calls use the scenario at the head of the queue without consuming it.
+func (s *Server) Script(cookie string, scenarios ...Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[cookie] = append([]Scenario(nil), scenarios...)
+}
+
+func (s *Server) SetVIP(cookie string, vip bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vip[cookie] = vip
+}
+
+// SetSingleUseCaptcha makes the appeal endpoint refuse a captcha response it has already seen,
+// the way the real one does once a token is spent.
+func (s *Server) SetSingleUseCaptcha(singleUse bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.singleUseCaptcha = singleUse
+}
+
+// SetTitles changes the game titles listed in ban responses.
+func (s *Server) SetTitles(titles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.titles = append([]string(nil), titles...)
+}
+
+// SetTempBanEnd changes the end date reported for TempBan.
+func (s *Server) SetTempBanEnd(endsAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endsAt = endsAt
+}
+
+// Requests returns a copy of every request received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Cookie builds an SSO cookie that services.DecodeSSOCookie reads as expiring at expiresAt.
+func Cookie(expiresAt time.Time) string {
+	raw := fmt.Sprintf("%d:%d:%x", expiresAt.UnixNano()%1e6, expiresAt.UnixMilli(), expiresAt.UnixNano())
+	return base64.StdEncoding.EncodeToString([]byte(raw))
+}
+
+// scenarioFor returns the cookie's current scenario, consuming it from the script if consume is set.
+func (s *Server) scenarioFor(r *http.Request, path string, consume bool) (string, Scenario) {
+	cookie := ssoCookie(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scenario := s.fallback
+	if script := s.scripts[cookie]; len(script) > 0 {
+		scenario = script[0]
+		if consume && len(script) > 1 {
+			s.scripts[cookie] = script[1:]
+		}
+	}
+
+	s.requests = append(s.requests, Request{
+		Path:      path,
+		Cookie:    cookie,
+		Captcha:   r.URL.Query().Get("g-cc"),
+		Scenario:  scenario,
+		Timestamp: time.Now(),
+	})
+	return cookie, scenario
+}
+
+func ssoCookie(r *http.Request) string {
+	if c, err := r.Cookie("ACT_SSO_COOKIE"); err == nil {
+		return c.Value
+	}
+	return strings.TrimPrefix(r.URL.Path, VIPPath)
+}
+
+// writeFailure handles the scenarios that look the same on every endpoint.
+func writeFailure(w http.ResponseWriter, scenario Scenario) bool {
+	switch scenario {
+	case ServerError:
+		http.Error(w, `{"error":"internal server error"}`, http.StatusInternalServerError)
+		return true
+	case MalformedJSON:
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"success":"true","bans":[{"enforcement":`))
+		return true
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeBadRequest answers with the error shape the appeal API uses for a missing or refused
+// captcha response.
+func writeBadRequest(w http.ResponseWriter, exception string) {
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"path":      AppealPath,
+		"status":    http.StatusBadRequest,
+		"error":     "Bad Request",
+		"exception": exception,
+	})
+}
+
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	cookie, scenario := s.scenarioFor(r, ProfilePath, false)
+	if writeFailure(w, scenario) {
+		return
+	}
+	if scenario == InvalidCookie || cookie == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"username": "fakeplayer",
+		"email":    "fakeplayer@example.com",
+		"created":  "2019-10-25T00:00:00Z",
+	})
+}
+
+func (s *Server) handleVIP(w http.ResponseWriter, r *http.Request) {
+	cookie, scenario := s.scenarioFor(r, VIPPath, false)
+	if writeFailure(w, scenario) {
+		return
+	}
+	if scenario == InvalidCookie {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+		return
+	}
+
+	s.mu.Lock()
+	vip := s.vip[cookie]
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]bool{"vip": vip})
+}
+
+func (s *Server) handleAppeal(w http.ResponseWriter, r *http.Request) {
+	_, scenario := s.scenarioFor(r, AppealPath, true)
+	if writeFailure(w, scenario) {
+		return
+	}
+
+	captcha := r.URL.Query().Get("g-cc")
+	if captcha == "" {
+		writeBadRequest(w, "MissingServletRequestParameterException")
+		return
+	}
+
+	s.mu.Lock()
+	reused := s.singleUseCaptcha && s.seenCaptcha[captcha]
+	s.seenCaptcha[captcha] = true
+	s.mu.Unlock()
+	if reused {
+		writeBadRequest(w, "InvalidCaptchaException")
+		return
+	}
+
+	s.mu.Lock()
+	titles := append([]string(nil), s.titles...)
+	endsAt := s.endsAt
+	canAppeal := s.canAppeal
+	s.mu.Unlock()
+
+	enforcement := ""
+	switch scenario {
+	case Permaban:
+		enforcement = "PERMANENT"
+	case Shadowban:
+		enforcement = "UNDER_REVIEW"
+	case TempBan:
+		enforcement = "TEMPORARY"
+	case InvalidCookie:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "not authenticated"})
+		return
+	}
+
+	bans := make([]map[string]interface{}, 0, len(titles))
+	if enforcement != "" {
+		for _, title := range titles {
+			ban := map[string]interface{}{
+				"enforcement": enforcement,
+				"title":       title,
+				"canAppeal":   canAppeal,
+			}
+			if scenario == TempBan {
+				ban["endDate"] = endsAt.UTC().Format(time.RFC3339)
+			}
+			bans = append(bans, ban)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"error":     "",
+		"success":   "true",
+		"canAppeal": canAppeal && enforcement != "",
+		"bans":      bans,
+	})
+}
@@ -0,0 +1,366 @@
+// Package fakecaptcha serves local stand-ins for the captcha providers the bot supports, speaking
+// each provider's createTask/getTaskResult/getBalance protocol closely enough that the real
+// solvers can run against them without spending money.
+//
+//	srv := fakecaptcha.New(fakecaptcha.Capsolver)
+//	defer srv.Close()
+//	srv.Apply(configuration.Get())
+//
+//	srv.SetProcessingPolls(2)
+//	solver, _ := services.NewCaptchaSolver(fakecaptcha.DefaultKey, "capsolver")
+//	token, err := solver.SolveReCaptchaV2(ctx, siteKey, pageURL)
+package fakecaptcha
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+)
+
+// Protocol names a provider API. The values match the provider names used by
+// services.NewCaptchaSolver.
+type Protocol string
+
+const (
+	Capsolver   Protocol = "capsolver"
+	EZCaptcha   Protocol = "ezcaptcha"
+	TwoCaptcha  Protocol = "2captcha"
+	AntiCaptcha Protocol = "anticaptcha"
+	CapMonster  Protocol = "capmonster"
+)
+
+// Protocols lists every protocol the package can fake.
+var Protocols = []Protocol{Capsolver, EZCaptcha, TwoCaptcha, AntiCaptcha, CapMonster}
+
+const (
+	CreatePath  = "/createTask"
+	ResultPath  = "/getTaskResult"
+	BalancePath = "/getBalance"
+
+	// DefaultKey is registered on every new server with DefaultBalance.
+	DefaultKey     = "fake-client-key"
+	DefaultBalance = 10.0
+	// DefaultPrice is what a solved task costs unless changed with SetPrice.
+	DefaultPrice = 0.003
+)
+
+// Error codes returned by the fake, using the names the real providers use.
+const (
+	ErrKeyDoesNotExist = "ERROR_KEY_DOES_NOT_EXIST"
+	ErrZeroBalance     = "ERROR_ZERO_BALANCE"
+	ErrNoSuchTask      = "ERROR_NO_SUCH_CAPCHA_ID"
+	ErrBadParameters   = "ERROR_BAD_PARAMETERS"
+	ErrUnsolvable      = "ERROR_CAPTCHA_UNSOLVABLE"
+)
+
+// Request is one call the server received.
+type Request struct {
+	Path      string
+	ClientKey string
+	TaskID    string
+	TaskType  string // The task type sent to CreatePath, empty for other paths.
+	Timestamp time.Time
+}
+
+type task struct {
+	clientKey string
+	polls     int
+	token     string
+	charged   bool
+}
+
+type Server struct {
+	*httptest.Server
+	Protocol Protocol
+
+	mu         sync.Mutex
+	balances   map[string]float64
+	tasks      map[string]*task
+	nextTaskID int64
+	processing int
+	unsolvable bool
+	price      float64
+	requests   []Request
+}
+
+// New starts a server speaking protocol with DefaultKey registered. Tasks are ready on the
+// first poll until told otherwise.
+func New(protocol Protocol) *Server {
+	s := &Server{
+		Protocol:   protocol,
+		balances:   map[string]float64{DefaultKey: DefaultBalance},
+		tasks:      make(map[string]*task),
+		nextTaskID: 1,
+		price:      DefaultPrice,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(CreatePath, s.handleCreate)
+	mux.HandleFunc(ResultPath, s.handleResult)
+	mux.HandleFunc(BalancePath, s.handleBalance)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Apply points cfg's endpoints for the server's provider at it, enables the provider with
+// DefaultKey and shortens result polling so tests finish quickly.
+func (s *Server) Apply(cfg *configuration.Config) {
+	endpoints := configuration.NewCaptchaProviderEndpoints(s.URL)
+	switch s.Protocol {
+	case Capsolver:
+		cfg.CaptchaEndpoints.Capsolver = endpoints
+		cfg.CaptchaService.Capsolver.Enabled = true
+		cfg.CaptchaService.Capsolver.ClientKey = DefaultKey
+		cfg.CaptchaService.Capsolver.AppID = "fake-app-id"
+		cfg.CaptchaService.Capsolver.MaxRetries = 5
+		cfg.CaptchaService.Capsolver.RetryInterval = 10 * time.Millisecond
+	case EZCaptcha:
+		cfg.CaptchaEndpoints.EZCaptcha = endpoints
+		cfg.CaptchaService.EZCaptcha.Enabled = true
+		cfg.CaptchaService.EZCaptcha.ClientKey = DefaultKey
+		cfg.CaptchaService.EZCaptcha.AppID = "fake-app-id"
+	case TwoCaptcha:
+		cfg.CaptchaEndpoints.TwoCaptcha = endpoints
+		cfg.CaptchaService.TwoCaptcha.Enabled = true
+		cfg.CaptchaService.TwoCaptcha.ClientKey = DefaultKey
+		cfg.CaptchaService.TwoCaptcha.SoftID = "fake-soft-id"
+	case AntiCaptcha:
+		cfg.CaptchaEndpoints.AntiCaptcha = endpoints
+		cfg.CaptchaService.AntiCaptcha.Enabled = true
+		cfg.CaptchaService.AntiCaptcha.ClientKey = DefaultKey
+		cfg.CaptchaService.AntiCaptcha.SoftID = "1234"
+	case CapMonster:
+		cfg.CaptchaEndpoints.CapMonster = endpoints
+		cfg.CaptchaService.CapMonster.Enabled = true
+		cfg.CaptchaService.CapMonster.ClientKey = DefaultKey
+	}
+	cfg.CaptchaEndpoints.MaxRetries = 5
+	cfg.CaptchaEndpoints.RetryInterval = 10 * time.Millisecond
+}
+
+// AddKey registers a client key with the given balance, replacing any earlier balance.
+func (s *Server) AddKey(key string, balance float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.balances[key] = balance
+}
+
+// Balance returns the key's current balance.
+func (s *Server) Balance(key string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.balances[key]
+}
+
+// SetProcessingPolls makes each new task answer "processing" n times before it is ready.
+func (s *Server) SetProcessingPolls(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processing = n
+}
+
+// SetUnsolvable makes new tasks fail with ERROR_CAPTCHA_UNSOLVABLE instead of producing a token.
+// Failed tasks are not charged.
+func (s *Server) SetUnsolvable(unsolvable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unsolvable = unsolvable
+}
+
+// SetPrice changes what a solved task costs.
+func (s *Server) SetPrice(price float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.price = price
+}
+
+// Requests returns a copy of every request received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Count returns how many requests hit path.
+func (s *Server) Count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		if r.Path == path {
+			n++
+		}
+	}
+	return n
+}
+
+type requestBody struct {
+	ClientKey string          `json:"clientKey"`
+	TaskID    json.RawMessage `json:"taskId"`
+	Task      struct {
+		Type       string `json:"type"`
+		WebsiteURL string `json:"websiteURL"`
+		WebsiteKey string `json:"websiteKey"`
+	} `json:"task"`
+}
+
+// taskID reads the task id as sent, accepting both the string ids of Capsolver and EZCaptcha and
+// the numeric ids of 2Captcha, Anti-Captcha and CapMonster.
+func (b requestBody) taskID() string {
+	var id string
+	if err := json.Unmarshal(b.TaskID, &id); err == nil {
+		return id
+	}
+	return string(b.TaskID)
+}
+
+// decode reads the body and records the request. The caller must hold s.mu.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request) (requestBody, bool) {
+	var body requestBody
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return body, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, ErrBadParameters, "request body is not valid JSON")
+		return body, false
+	}
+	s.requests = append(s.requests, Request{
+		Path:      r.URL.Path,
+		ClientKey: body.ClientKey,
+		TaskID:    body.taskID(),
+		TaskType:  body.Task.Type,
+		Timestamp: time.Now(),
+	})
+	return body, true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers with the error shape every supported provider uses. Like the real APIs it
+// still returns 200.
+func writeError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, map[string]interface{}{
+		"errorId":          1,
+		"errorCode":        code,
+		"errorDescription": description,
+	})
+}
+
+func (s *Server) handleBalance(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, ok := s.decode(w, r)
+	if !ok {
+		return
+	}
+	balance, known := s.balances[body.ClientKey]
+	if !known {
+		writeError(w, ErrKeyDoesNotExist, "Invalid key")
+		return
+	}
+	writeJSON(w, map[string]interface{}{"errorId": 0, "balance": balance})
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, ok := s.decode(w, r)
+	if !ok {
+		return
+	}
+	balance, known := s.balances[body.ClientKey]
+	switch {
+	case !known:
+		writeError(w, ErrKeyDoesNotExist, "Invalid key")
+		return
+	case balance < s.price:
+		writeError(w, ErrZeroBalance, "Account has zero or negative balance")
+		return
+	case body.Task.Type == "" || body.Task.WebsiteURL == "" || body.Task.WebsiteKey == "":
+		writeError(w, ErrBadParameters, "task type, websiteURL and websiteKey are required")
+		return
+	}
+
+	id := s.nextTaskID
+	s.nextTaskID++
+	key := strconv.FormatInt(id, 10)
+	t := &task{clientKey: body.ClientKey, polls: s.processing}
+	if !s.unsolvable {
+		t.token = token(s.Protocol, key)
+	}
+	s.tasks[key] = t
+
+	if s.numericTaskIDs() {
+		writeJSON(w, map[string]interface{}{"errorId": 0, "taskId": id})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"errorId": 0, "taskId": key})
+}
+
+func (s *Server) handleResult(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, ok := s.decode(w, r)
+	if !ok {
+		return
+	}
+	if _, known := s.balances[body.ClientKey]; !known {
+		writeError(w, ErrKeyDoesNotExist, "Invalid key")
+		return
+	}
+	t, found := s.tasks[body.taskID()]
+	if !found || t.clientKey != body.ClientKey {
+		writeError(w, ErrNoSuchTask, "Task not found")
+		return
+	}
+
+	if t.polls > 0 {
+		t.polls--
+		writeJSON(w, map[string]interface{}{"errorId": 0, "status": "processing"})
+		return
+	}
+	if t.token == "" {
+		writeError(w, ErrUnsolvable, "Captcha could not be solved")
+		return
+	}
+	if !t.charged {
+		t.charged = true
+		s.balances[t.clientKey] -= s.price
+	}
+	writeJSON(w, map[string]interface{}{
+		"errorId":  0,
+		"status":   "ready",
+		"solution": map[string]string{"gRecaptchaResponse": t.token},
+		"cost":     fmt.Sprintf("%.5f", s.price),
+	})
+}
+
+// numericTaskIDs reports whether the protocol hands out task ids as JSON numbers.
+func (s *Server) numericTaskIDs() bool {
+	switch s.Protocol {
+	case TwoCaptcha, AntiCaptcha, CapMonster:
+		return true
+	}
+	return false
+}
+
+// token builds a deterministic response token long enough to pass the solvers' sanity checks.
+func token(protocol Protocol, taskID string) string {
+	sum := sha256.Sum256([]byte(string(protocol) + ":" + taskID))
+	return "03AFcWeA" + hex.EncodeToString(sum[:]) + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,85 @@
+package fakediscord
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var interactionSeq atomic.Int64
+
+// Field is one text input submitted with a modal.
+type Field struct {
+	CustomID string
+	Value    string
+}
+
+// newInteraction builds a DM interaction from userID, which is how most users talk to the bot.
+func newInteraction(userID string, typ discordgo.InteractionType, data discordgo.InteractionData) *discordgo.InteractionCreate {
+	id := interactionSeq.Add(1)
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        fmt.Sprintf("interaction-%d", id),
+		AppID:     "fake-app",
+		Type:      typ,
+		Data:      data,
+		ChannelID: DMChannelID(userID),
+		User:      &discordgo.User{ID: userID, Username: userID},
+		Token:     fmt.Sprintf("token-%d", id),
+	}}
+}
+
+// InGuild moves the interaction into a guild channel, with the user as a member rather than a
+// DM recipient.
+func InGuild(i *discordgo.InteractionCreate, guildID, channelID string) *discordgo.InteractionCreate {
+	i.GuildID = guildID
+	i.ChannelID = channelID
+	i.Member = &discordgo.Member{GuildID: guildID, User: i.User}
+	i.User = nil
+	return i
+}
+
+// SlashCommand builds the interaction for /name with the given options.
+func SlashCommand(userID, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return newInteraction(userID, discordgo.InteractionApplicationCommand, discordgo.ApplicationCommandInteractionData{
+		Name:    name,
+		Options: options,
+	})
+}
+
+// StringOption builds a string option for SlashCommand.
+func StringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionString,
+		Value: value,
+	}
+}
+
+// Component builds a button press, or a select menu choice when values are given.
+func Component(userID, customID string, values ...string) *discordgo.InteractionCreate {
+	componentType := discordgo.ButtonComponent
+	if len(values) > 0 {
+		componentType = discordgo.SelectMenuComponent
+	}
+	return newInteraction(userID, discordgo.InteractionMessageComponent, discordgo.MessageComponentInteractionData{
+		CustomID:      customID,
+		ComponentType: componentType,
+		Values:        values,
+	})
+}
+
+// ModalSubmit builds a modal submission with one action row per field, in order, matching the
+// way the bot's modals lay out their inputs.
+func ModalSubmit(userID, customID string, fields ...Field) *discordgo.InteractionCreate {
+	rows := make([]discordgo.MessageComponent, 0, len(fields))
+	for _, f := range fields {
+		rows = append(rows, &discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			&discordgo.TextInput{CustomID: f.CustomID, Value: f.Value},
+		}})
+	}
+	return newInteraction(userID, discordgo.InteractionModalSubmit, discordgo.ModalSubmitInteractionData{
+		CustomID:   customID,
+		Components: rows,
+	})
+}
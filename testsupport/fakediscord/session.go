@@ -0,0 +1,233 @@
+// Package fakediscord is an in-memory discordapi.Session that records everything the bot sends,
+// so command flows and notifications can be asserted on without a Discord connection.
+//
+//	s := fakediscord.New()
+//	addaccount.CommandAddAccount(s, fakediscord.SlashCommand("user-1", "addaccount"))
+//	resp := s.LastResponse()
+//	if resp.Response.Type != discordgo.InteractionResponseModal { ... }
+package fakediscord
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bradselph/CODStatusBot/discordapi"
+	"github.com/bwmarrin/discordgo"
+)
+
+var _ discordapi.Session = (*Session)(nil)
+
+// Response is one InteractionRespond call.
+type Response struct {
+	Interaction *discordgo.Interaction
+	Response    *discordgo.InteractionResponse
+}
+
+// Followup is one FollowupMessageCreate call.
+type Followup struct {
+	Interaction *discordgo.Interaction
+	Params      *discordgo.WebhookParams
+}
+
+// Message is a message sent to a channel through any of the ChannelMessageSend variants. Plain
+// and embed-only sends are recorded in the same shape as ChannelMessageSendComplex.
+type Message struct {
+	ChannelID string
+	Send      *discordgo.MessageSend
+}
+
+type Session struct {
+	mu        sync.Mutex
+	responses []Response
+	followups []Followup
+	messages  []Message
+	status    string
+	errs      map[string]error
+	nextID    int
+}
+
+func New() *Session {
+	return &Session{
+		errs: make(map[string]error),
+	}
+}
+
+// FailWith makes every later call to the named method, e.g. "UserChannelCreate", return err
+// without recording anything. A nil err clears the failure.
+func (s *Session) FailWith(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.errs, method)
+		return
+	}
+	s.errs[method] = err
+}
+
+// Reset forgets everything recorded so far. Failures set with FailWith are kept.
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = nil
+	s.followups = nil
+	s.messages = nil
+	s.status = ""
+}
+
+func (s *Session) Responses() []Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Response(nil), s.responses...)
+}
+
+// LastResponse returns the most recent interaction response, or the zero Response if there was none.
+func (s *Session) LastResponse() Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.responses) == 0 {
+		return Response{}
+	}
+	return s.responses[len(s.responses)-1]
+}
+
+func (s *Session) Followups() []Followup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Followup(nil), s.followups...)
+}
+
+func (s *Session) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// MessagesTo returns the messages sent to channelID.
+func (s *Session) MessagesTo(channelID string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Message
+	for _, m := range s.messages {
+		if m.ChannelID == channelID {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// DMChannelID returns the ID UserChannelCreate hands out for userID.
+func DMChannelID(userID string) string {
+	return "dm-" + userID
+}
+
+// DMs returns the messages sent to userID's direct message channel.
+func (s *Session) DMs(userID string) []Message {
+	return s.MessagesTo(DMChannelID(userID))
+}
+
+// Status returns the last watch status set through UpdateWatchStatus.
+func (s *Session) Status() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// fail returns the error configured for method. The caller must hold s.mu.
+func (s *Session) fail(method string) error {
+	return s.errs[method]
+}
+
+// message records a sent message and returns what Discord would have answered. The caller
+// must hold s.mu.
+func (s *Session) message(channelID string, send *discordgo.MessageSend) *discordgo.Message {
+	s.messages = append(s.messages, Message{ChannelID: channelID, Send: send})
+	s.nextID++
+	return &discordgo.Message{
+		ID:         fmt.Sprintf("message-%d", s.nextID),
+		ChannelID:  channelID,
+		Content:    send.Content,
+		Embeds:     send.Embeds,
+		Components: send.Components,
+		Timestamp:  time.Now(),
+	}
+}
+
+func (s *Session) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("InteractionRespond"); err != nil {
+		return err
+	}
+	s.responses = append(s.responses, Response{Interaction: interaction, Response: resp})
+	return nil
+}
+
+func (s *Session) FollowupMessageCreate(interaction *discordgo.Interaction, _ bool, data *discordgo.WebhookParams, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("FollowupMessageCreate"); err != nil {
+		return nil, err
+	}
+	s.followups = append(s.followups, Followup{Interaction: interaction, Params: data})
+	s.nextID++
+	return &discordgo.Message{
+		ID:         fmt.Sprintf("message-%d", s.nextID),
+		ChannelID:  interaction.ChannelID,
+		Content:    data.Content,
+		Embeds:     data.Embeds,
+		Components: data.Components,
+		Timestamp:  time.Now(),
+	}, nil
+}
+
+func (s *Session) UserChannelCreate(recipientID string, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("UserChannelCreate"); err != nil {
+		return nil, err
+	}
+	id := DMChannelID(recipientID)
+	return &discordgo.Channel{
+		ID:         id,
+		Type:       discordgo.ChannelTypeDM,
+		Recipients: []*discordgo.User{{ID: recipientID}},
+	}, nil
+}
+
+func (s *Session) ChannelMessageSend(channelID string, content string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("ChannelMessageSend"); err != nil {
+		return nil, err
+	}
+	return s.message(channelID, &discordgo.MessageSend{Content: content}), nil
+}
+
+func (s *Session) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("ChannelMessageSendEmbed"); err != nil {
+		return nil, err
+	}
+	return s.message(channelID, &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}}), nil
+}
+
+func (s *Session) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("ChannelMessageSendComplex"); err != nil {
+		return nil, err
+	}
+	return s.message(channelID, data), nil
+}
+
+func (s *Session) UpdateWatchStatus(_ int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("UpdateWatchStatus"); err != nil {
+		return err
+	}
+	s.status = name
+	return nil
+}
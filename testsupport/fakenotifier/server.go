@@ -0,0 +1,174 @@
+// Package fakenotifier serves local stand-ins for the Telegram Bot API and an ntfy server and
+// records what the bot sends to them.
+//
+//	srv := fakenotifier.New()
+//	defer srv.Close()
+//	srv.Apply(configuration.Get())
+//
+//	// ... trigger a notification ...
+//	msg := srv.TelegramMessages()[0]
+//	fmt.Println(msg.ChatID, msg.Text)
+package fakenotifier
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+)
+
+// BotToken is the Telegram token Apply configures when none is set.
+const BotToken = "123456:test-token"
+
+// TelegramMessage is one sendMessage call.
+type TelegramMessage struct {
+	Token     string
+	ChatID    string
+	Text      string
+	ParseMode string
+}
+
+// NtfyMessage is one message published to a topic.
+type NtfyMessage struct {
+	Topic         string
+	Title         string
+	Priority      string
+	Tags          string
+	Authorization string
+	Body          string
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	telegram []TelegramMessage
+	ntfy     []NtfyMessage
+	fail     map[string]bool
+}
+
+// New starts a server that accepts every message.
+func New() *Server {
+	s := &Server{fail: make(map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Apply points the Telegram and ntfy configuration at the server.
+func (s *Server) Apply(cfg *configuration.Config) {
+	cfg.Telegram.APIURL = s.URL + "/telegram"
+	if cfg.Telegram.BotToken == "" {
+		cfg.Telegram.BotToken = BotToken
+	}
+	cfg.Ntfy.ServerURL = s.URL + "/ntfy"
+}
+
+// SetFail makes the "telegram" or "ntfy" backend reject every message.
+func (s *Server) SetFail(backend string, fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail[backend] = fail
+}
+
+// TelegramMessages returns the Telegram messages accepted so far.
+func (s *Server) TelegramMessages() []TelegramMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TelegramMessage(nil), s.telegram...)
+}
+
+// NtfyMessages returns the ntfy messages accepted so far.
+func (s *Server) NtfyMessages() []NtfyMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]NtfyMessage(nil), s.ntfy...)
+}
+
+// Reset forgets the accepted messages.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.telegram = nil
+	s.ntfy = nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/telegram/bot"):
+		s.handleTelegram(w, r)
+	case strings.HasPrefix(r.URL.Path, "/ntfy/"):
+		s.handleNtfy(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleTelegram(w http.ResponseWriter, r *http.Request) {
+	token, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/telegram/bot"), "/")
+	w.Header().Set("Content-Type", "application/json")
+	if method != "sendMessage" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": 404, "description": "Not Found"})
+		return
+	}
+
+	var req struct {
+		ChatID    json.RawMessage `json:"chat_id"`
+		Text      string          `json:"text"`
+		ParseMode string          `json:"parse_mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Text == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": 400, "description": "Bad Request: message text is empty"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail["telegram"] {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"})
+		return
+	}
+	s.telegram = append(s.telegram, TelegramMessage{
+		Token:     token,
+		ChatID:    strings.Trim(string(req.ChatID), `"`),
+		Text:      req.Text,
+		ParseMode: req.ParseMode,
+	})
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": map[string]interface{}{"message_id": len(s.telegram)}})
+}
+
+func (s *Server) handleNtfy(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	title, err := new(mime.WordDecoder).DecodeHeader(r.Header.Get("Title"))
+	if err != nil {
+		title = r.Header.Get("Title")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail["ntfy"] {
+		http.Error(w, `{"code":50001,"http":500,"error":"internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	s.ntfy = append(s.ntfy, NtfyMessage{
+		Topic:         strings.TrimPrefix(r.URL.Path, "/ntfy/"),
+		Title:         title,
+		Priority:      r.Header.Get("Priority"),
+		Tags:          r.Header.Get("Tags"),
+		Authorization: r.Header.Get("Authorization"),
+		Body:          string(body),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"event": "message", "topic": strings.TrimPrefix(r.URL.Path, "/ntfy/")})
+}
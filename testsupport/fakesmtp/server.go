@@ -0,0 +1,258 @@
+// Package fakesmtp runs a minimal in-process SMTP server that records the messages it is sent,
+// so email notifications can be exercised without a real mail server.
+//
+//	srv, err := fakesmtp.New()
+//	if err != nil { ... }
+//	defer srv.Close()
+//	srv.Apply(configuration.Get())
+//
+//	// ... trigger a notification ...
+//	msg := srv.Messages()[0]
+//	fmt.Println(msg.To, msg.Subject, msg.Text)
+//
+// The server speaks plain SMTP only: it does not offer STARTTLS or AUTH, so Apply sets
+// SMTP_TLS to "none" and leaves the credentials empty.
+package fakesmtp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bradselph/CODStatusBot/configuration"
+)
+
+// Message is one message the server accepted.
+type Message struct {
+	From string
+	To   []string
+	Data string
+
+	// Subject, Text and HTML are decoded from Data. They are empty if Data could not be parsed.
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+	reject   bool
+}
+
+// New starts a server listening on a free port on 127.0.0.1.
+func New() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr is the host:port the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and waits for open sessions to finish.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// Apply points the SMTP configuration at the server.
+func (s *Server) Apply(cfg *configuration.Config) {
+	host, port, _ := net.SplitHostPort(s.Addr())
+	cfg.SMTP.Host = host
+	cfg.SMTP.Port, _ = strconv.Atoi(port)
+	cfg.SMTP.TLS = "none"
+	cfg.SMTP.Username = ""
+	cfg.SMTP.Password = ""
+	if cfg.SMTP.From == "" {
+		cfg.SMTP.From = "COD Status Bot <bot@example.com>"
+	}
+}
+
+// SetReject makes the server refuse every message with a permanent failure.
+func (s *Server) SetReject(reject bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = reject
+}
+
+// Messages returns the messages accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Reset forgets the accepted messages.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.session(conn)
+		}()
+	}
+}
+
+func (s *Server) session(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 fakesmtp ready")
+
+	var from string
+	var to []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-fakesmtp")
+			reply("250 8BITMIME")
+		case "HELO":
+			reply("250 fakesmtp")
+		case "MAIL":
+			from = addressArg(arg)
+			to = nil
+			reply("250 OK")
+		case "RCPT":
+			to = append(to, addressArg(arg))
+			reply("250 OK")
+		case "DATA":
+			if len(to) == 0 {
+				reply("503 RCPT first")
+				continue
+			}
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			reject := s.reject
+			if !reject {
+				s.messages = append(s.messages, newMessage(from, to, data))
+			}
+			s.mu.Unlock()
+
+			if reject {
+				reply("554 Message rejected")
+			} else {
+				reply("250 OK")
+			}
+			from, to = "", nil
+		case "RSET":
+			from, to = "", nil
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// addressArg extracts the address from "FROM:<a@b>" or "TO:<a@b>".
+func addressArg(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ")
+	return strings.Trim(addr, "<>")
+}
+
+func readData(r *bufio.Reader) (string, error) {
+	var data strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return data.String(), nil
+		}
+		data.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+func newMessage(from string, to []string, data string) Message {
+	msg := Message{From: from, To: to, Data: data}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		return msg
+	}
+	msg.Subject, _ = new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		return msg
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, _ := io.ReadAll(decodeBody(parsed.Header.Get("Content-Transfer-Encoding"), parsed.Body))
+		msg.Text = string(body)
+		return msg
+	}
+
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			return msg
+		}
+		body, _ := io.ReadAll(decodeBody(part.Header.Get("Content-Transfer-Encoding"), part))
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "text/plain":
+			msg.Text = string(body)
+		case "text/html":
+			msg.HTML = string(body)
+		}
+	}
+}
+
+func decodeBody(encoding string, r io.Reader) io.Reader {
+	if strings.EqualFold(encoding, "quoted-printable") {
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}